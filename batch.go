@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// -------------------- 多账户批量模式 --------------------
+
+// BatchAccount 为批量任务中的一个账户；Proxy 非空时该账户的所有请求走此代理
+type BatchAccount struct {
+	Label string
+	Creds aws.CredentialsProvider
+	Proxy string
+}
+
+type BatchFailure struct {
+	Account string `json:"account" yaml:"account"`
+	Reason  string `json:"reason" yaml:"reason"`
+	Error   string `json:"error" yaml:"error"`
+}
+
+type AccountEC2Row struct {
+	Account        string `json:"account" yaml:"account"`
+	EC2InstanceRow `yaml:",inline"`
+}
+
+type AccountLSRow struct {
+	Account       string `json:"account" yaml:"account"`
+	LSInstanceRow `yaml:",inline"`
+}
+
+type AccountQuotaRow struct {
+	Account  string `json:"account" yaml:"account"`
+	QuotaRow `yaml:",inline"`
+}
+
+type AccountIdentityRow struct {
+	Account   string `json:"account" yaml:"account"`
+	AccountID string `json:"account_id" yaml:"account_id"`
+	ARN       string `json:"arn" yaml:"arn"`
+}
+
+type batchResult struct {
+	EC2      []AccountEC2Row      `json:"ec2,omitempty" yaml:"ec2,omitempty"`
+	LS       []AccountLSRow       `json:"lightsail,omitempty" yaml:"lightsail,omitempty"`
+	Quotas   []AccountQuotaRow    `json:"quotas,omitempty" yaml:"quotas,omitempty"`
+	Identity []AccountIdentityRow `json:"identity,omitempty" yaml:"identity,omitempty"`
+	Failures []BatchFailure       `json:"failures" yaml:"failures"`
+}
+
+var batchOps = []string{"ec2-list", "ls-list", "quota", "sts"}
+
+// forEachAccount 以最多 concurrency 个账户并发执行 fn，账户与其代理通过 ctx 传入
+func forEachAccount(ctx context.Context, accounts []BatchAccount, concurrency int, fn func(ctx context.Context, i int, a BatchAccount)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i, a := range accounts {
+		wg.Add(1)
+		go func(i int, a BatchAccount) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			actx := accountCtx(ctx, a.Label, a.Proxy)
+			fmt.Fprintf(os.Stderr, "▶ [%s] 开始\n", a.Label)
+			fn(actx, i, a)
+		}(i, a)
+	}
+	wg.Wait()
+}
+
+// runBatch 对每个账户执行 op，单个账户失败只记录不中断；
+// 结果按账户输入顺序合并，与完成先后无关
+func runBatch(ctx context.Context, accounts []BatchAccount, op string, concurrency int) batchResult {
+	slots := make([]batchResult, len(accounts))
+	forEachAccount(ctx, accounts, concurrency, func(ctx context.Context, i int, a BatchAccount) {
+		if err := batchAccountOp(ctx, a, op, &slots[i]); err != nil {
+			slots[i].Failures = append(slots[i].Failures, BatchFailure{Account: a.Label, Reason: classifyAWSError(err), Error: err.Error()})
+		}
+	})
+	res := batchResult{Failures: []BatchFailure{}}
+	for _, s := range slots {
+		res.EC2 = append(res.EC2, s.EC2...)
+		res.LS = append(res.LS, s.LS...)
+		res.Quotas = append(res.Quotas, s.Quotas...)
+		res.Identity = append(res.Identity, s.Identity...)
+		res.Failures = append(res.Failures, s.Failures...)
+	}
+	return res
+}
+
+func batchAccountOp(ctx context.Context, a BatchAccount, op string, out *batchResult) error {
+	id, err := stsIdentity(ctx, bootstrapRegion, a.Creds)
+	if err != nil {
+		return err
+	}
+	switch op {
+	case "sts":
+		out.Identity = append(out.Identity, AccountIdentityRow{Account: a.Label, AccountID: aws.ToString(id.Account), ARN: aws.ToString(id.Arn)})
+	case "quota":
+		v, err := getVCPUQuota(ctx, a.Creds)
+		if err != nil {
+			return err
+		}
+		out.Quotas = append(out.Quotas, AccountQuotaRow{Account: a.Label, QuotaRow: QuotaRow{Service: "ec2", Code: vcpuQuotaCode, Name: "Running On-Demand Standard instances (vCPU)", Value: v}})
+	case "ec2-list":
+		regions, err := getEC2RegionsWithStatus(ctx, a.Creds)
+		if err != nil {
+			return err
+		}
+		rows, _ := ec2ListAll(ctx, regionNames(regions), a.Creds)
+		for _, r := range rows {
+			out.EC2 = append(out.EC2, AccountEC2Row{Account: a.Label, EC2InstanceRow: r})
+		}
+	case "ls-list":
+		regions, err := getLightsailRegions(ctx, a.Creds)
+		if err != nil {
+			return err
+		}
+		rows, _ := lsListAll(ctx, regions, a.Creds)
+		for _, r := range rows {
+			out.LS = append(out.LS, AccountLSRow{Account: a.Label, LSInstanceRow: r})
+		}
+	}
+	return nil
+}
+
+func batchReport(op string, res batchResult) Report {
+	r := Report{Data: res}
+	switch op {
+	case "sts":
+		r.Header = "账户\t账户ID\tARN"
+		r.Columns = []string{"account", "account_id", "arn"}
+		for _, x := range res.Identity {
+			r.Rows = append(r.Rows, []string{x.Account, x.AccountID, x.ARN})
+		}
+	case "quota":
+		r.Header = "账户\t服务\t配额代码\t数值"
+		r.Columns = []string{"account", "service", "code", "value"}
+		for _, x := range res.Quotas {
+			r.Rows = append(r.Rows, []string{x.Account, x.Service, x.Code, strconv.FormatFloat(x.Value, 'f', -1, 64)})
+		}
+	case "ec2-list":
+		r.Header = "账户\t区域\tID\t名称\t状态\t配置\t公网IP\t内网IP\tIPv6\t网络"
+		r.Columns = []string{"account", "region", "id", "name", "state", "type", "public_ip", "private_ip", "ipv6", "net", "az"}
+		r.Cut = map[int]int{3: 10}
+		for _, x := range res.EC2 {
+			r.Rows = append(r.Rows, []string{x.Account, x.Region, x.ID, x.Name, x.State, x.Type, x.PubIP, x.PrivIP, x.IPv6, netModeName(x.Net), x.AZ})
+		}
+	case "ls-list":
+		r.Header = "账户\t区域\t名称\t状态\t配置\tIPv4\tIPv6\t网络"
+		r.Columns = []string{"account", "region", "name", "state", "bundle", "ipv4", "ipv6", "ip_type", "az"}
+		r.Cut = map[int]int{4: 14}
+		for _, x := range res.LS {
+			r.Rows = append(r.Rows, []string{x.Account, x.Region, x.Name, x.State, x.Bundle, x.IP, x.IPv6, x.ipTypeName(), x.AZ})
+		}
+	}
+	return r
+}
+
+// printBatchResult 输出合并结果；table/csv 模式下失败账户单独输出到 stderr
+func printBatchResult(op, format string, res batchResult) error {
+	if err := writeReport(os.Stdout, format, batchReport(op, res)); err != nil {
+		return err
+	}
+	if len(res.Failures) == 0 || format == "json" || format == "yaml" {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "\n❌ %d 个账户失败:\n", len(res.Failures))
+	fr := Report{Header: "账户\t原因\t错误"}
+	for _, f := range res.Failures {
+		fr.Rows = append(fr.Rows, []string{f.Account, f.Reason, cut(f.Error, 80)})
+	}
+	return writeReport(os.Stderr, "table", fr)
+}
+
+var accessKeyRe = regexp.MustCompile(`^(AKIA|ASIA)[A-Z0-9]{12,}$`)
+
+// parseAccountLine 解析一行账户信息，字段可用逗号、制表符、空格、| 或 ---- 分隔。
+// 以 AKIA/ASIA 开头的字段视为 AK，其后为 SK；AK 之前的字段作为标签，SK 之后的字段作为代理
+func parseAccountLine(line string) (label, ak, sk, proxy string, ok bool) {
+	line = strings.ReplaceAll(line, "----", ",")
+	fields := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || r == '\t' || r == ' ' || r == '|'
+	})
+	for i, f := range fields {
+		if accessKeyRe.MatchString(f) && i+1 < len(fields) {
+			ak, sk = f, fields[i+1]
+			if i > 0 {
+				label = strings.Join(fields[:i], " ")
+			}
+			if i+2 < len(fields) {
+				proxy = fields[i+2]
+			}
+			if label == "" {
+				label = ak
+			}
+			return label, ak, sk, proxy, true
+		}
+	}
+	return "", "", "", "", false
+}
+
+// loadAccountFile 读取 AK/SK 列表文件，空行与 # 开头的注释行会被忽略
+func loadAccountFile(path string) ([]BatchAccount, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []BatchAccount
+	sc := bufio.NewScanner(f)
+	n := 0
+	for sc.Scan() {
+		n++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		label, ak, sk, proxy, ok := parseAccountLine(line)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "⚠️ 第 %d 行无法识别，已跳过\n", n)
+			continue
+		}
+		out = append(out, BatchAccount{Label: label, Creds: staticCreds(ak, sk, ""), Proxy: proxy})
+	}
+	return out, sc.Err()
+}
+
+func vaultBatchAccounts(v *Vault, labels []string) ([]BatchAccount, error) {
+	var out []BatchAccount
+	if len(labels) == 0 {
+		for _, a := range v.Accounts {
+			out = append(out, BatchAccount{Label: a.Label, Creds: staticCreds(a.AK, a.SK, ""), Proxy: a.Proxy})
+		}
+		return out, nil
+	}
+	for _, l := range labels {
+		a := v.Find(l)
+		if a == nil {
+			return nil, fmt.Errorf("保险库中没有账户 %s", l)
+		}
+		out = append(out, BatchAccount{Label: a.Label, Creds: staticCreds(a.AK, a.SK, ""), Proxy: a.Proxy})
+	}
+	return out, nil
+}
+
+func profileBatchAccounts(ctx context.Context, names []string) ([]BatchAccount, error) {
+	var out []BatchAccount
+	for _, n := range names {
+		creds, err := loadChainCreds(ctx, n)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %v", n, err)
+		}
+		out = append(out, BatchAccount{Label: "profile:" + n, Creds: creds})
+	}
+	return out, nil
+}
+
+// verifiedLabels 返回批量结果中没有失败记录的账户标签
+func (r batchResult) verifiedLabels(accounts []BatchAccount) []string {
+	failed := map[string]bool{}
+	for _, f := range r.Failures {
+		failed[f.Account] = true
+	}
+	var out []string
+	for _, a := range accounts {
+		if !failed[a.Label] {
+			out = append(out, a.Label)
+		}
+	}
+	return out
+}
+
+// markBatchVerified 把通过验证的保险库账户写回上次验证时间
+func markBatchVerified(v *Vault, labels []string) {
+	if v == nil {
+		return
+	}
+	for _, l := range labels {
+		if acct := v.Find(l); acct != nil {
+			acct.LastVerified = time.Now()
+		}
+	}
+	if err := v.Save(); err != nil {
+		fmt.Fprintln(os.Stderr, "⚠️ 保险库更新失败:", err)
+	}
+}
+
+// pickBatchAccounts 交互式选择批量任务的账户来源；来源为保险库时一并返回已解锁的保险库
+func pickBatchAccounts(ctx context.Context) ([]BatchAccount, *Vault, error) {
+	fmt.Println("账户来源:")
+	fmt.Println(" 1) 🔐 本地加密保险库 (全部账户) [默认]")
+	fmt.Println(" 2) AK/SK 列表文件")
+	fmt.Println(" 3) AWS 配置文件 Profile (全部)")
+	switch input("选择 [1]: ", "1") {
+	case "2":
+		accounts, err := loadAccountFile(input("文件路径: ", ""))
+		return accounts, nil, err
+	case "3":
+		accounts, err := profileBatchAccounts(ctx, listProfiles())
+		return accounts, nil, err
+	}
+	vault, err := unlockVault()
+	if err != nil {
+		return nil, nil, err
+	}
+	accounts, err := vaultBatchAccounts(vault, nil)
+	return accounts, vault, err
+}
+
+// batchMenu 为交互式批量入口
+func batchMenu(ctx context.Context) {
+	fmt.Println("\n====== 📦 多账户批量操作 ======")
+	accounts, vault, err := pickBatchAccounts(ctx)
+	if err != nil {
+		fmt.Println("❌ 失败:", err)
+		return
+	}
+	if len(accounts) == 0 {
+		fmt.Println("❌ 没有可用账户")
+		return
+	}
+	fmt.Printf("\n已载入 %d 个账户，请选择操作:\n", len(accounts))
+	fmt.Println(" 1) 凭证验证 (STS)")
+	fmt.Println(" 2) 查询 vCPU 配额")
+	fmt.Println(" 3) 列出全部 EC2 实例")
+	fmt.Println(" 4) 列出全部 Lightsail 实例")
+	fmt.Println(" 5) 🩺 账户健康检查 (密钥/封禁/开通/配额诊断)")
+	sel := input("选择 [1]: ", "1")
+	conc := mustInt(input("并发账户数 [4]: ", "4"))
+	fmt.Println()
+	if sel == "5" {
+		rows := runHealth(ctx, accounts, conc)
+		markBatchVerified(vault, healthyLabels(rows))
+		fmt.Println()
+		writeReport(os.Stdout, "table", healthReport(rows))
+		if path := input("\n保存报告到文件 (.csv/.json/.yaml，留空跳过): ", ""); path != "" {
+			if err := saveReport(path, healthReport(rows)); err != nil {
+				fmt.Println("❌ 保存失败:", err)
+			} else {
+				fmt.Println("✅ 已保存:", path)
+			}
+		}
+		input("\n按回车返回...", "")
+		return
+	}
+	op := map[string]string{"1": "sts", "2": "quota", "3": "ec2-list", "4": "ls-list"}[sel]
+	if op == "" {
+		op = "sts"
+	}
+	res := runBatch(ctx, accounts, op, conc)
+	markBatchVerified(vault, res.verifiedLabels(accounts))
+	fmt.Println()
+	printBatchResult(op, "table", res)
+	input("\n按回车返回...", "")
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2t "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+// -------------------- 容量不足自动换区 / 换类型 --------------------
+
+// LaunchAttempt 记录一次 RunInstances 尝试
+type LaunchAttempt struct {
+	Type   string `json:"type" yaml:"type"`
+	AZ     string `json:"az" yaml:"az"`
+	Market string `json:"market" yaml:"market"`
+	Result string `json:"result" yaml:"result"`
+}
+
+// isCapacityError 判断 RunInstances 失败是否因为可用区容量或不支持，换可用区 / 类型可能成功
+func isCapacityError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "InsufficientInstanceCapacity", "InsufficientCapacity", "InsufficientHostCapacity",
+		"SpotMaxPriceTooLow", "MaxSpotInstanceCountExceeded", "Unsupported":
+		return true
+	}
+	return strings.Contains(apiErr.ErrorMessage(), "capacity-not-available")
+}
+
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return err.Error()
+}
+
+// setRunSubnet 把启动请求固定到指定子网 (有网卡配置时写在网卡上)
+func setRunSubnet(in *ec2.RunInstancesInput, subnetID string) {
+	if len(in.NetworkInterfaces) > 0 {
+		in.NetworkInterfaces[0].SubnetId = aws.String(subnetID)
+		return
+	}
+	in.SubnetId = aws.String(subnetID)
+}
+
+func runSubnet(in *ec2.RunInstancesInput) string {
+	if len(in.NetworkInterfaces) > 0 {
+		return aws.ToString(in.NetworkInterfaces[0].SubnetId)
+	}
+	return aws.ToString(in.SubnetId)
+}
+
+// defaultSubnetsByAZ 返回默认 VPC 中各可用区的默认子网
+func defaultSubnetsByAZ(ctx context.Context, cli EC2API) map[string]string {
+	out, err := cli.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+		Filters: []ec2t.Filter{{Name: aws.String("default-for-az"), Values: []string{"true"}}},
+	})
+	m := map[string]string{}
+	if err != nil {
+		return m
+	}
+	for _, sn := range out.Subnets {
+		m[aws.ToString(sn.AvailabilityZone)] = aws.ToString(sn.SubnetId)
+	}
+	return m
+}
+
+// ipv6SubnetsByAZ 返回 VPC 中各可用区已配置 IPv6 的子网，native 时只取 IPv6-only 子网，否则只取双栈子网；
+// 同一可用区有多个时 prefer 优先，其次为默认子网
+func ipv6SubnetsByAZ(ctx context.Context, cli EC2API, vpcID, prefer string, native bool) map[string]string {
+	out, err := cli.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+		Filters: []ec2t.Filter{{Name: aws.String("vpc-id"), Values: []string{vpcID}}},
+	})
+	m := map[string]string{}
+	if err != nil {
+		return m
+	}
+	for _, sn := range out.Subnets {
+		if subnetIPv6CIDR(sn) == "" || aws.ToBool(sn.Ipv6Native) != native {
+			continue
+		}
+		az, id := aws.ToString(sn.AvailabilityZone), aws.ToString(sn.SubnetId)
+		if cur, ok := m[az]; !ok || id == prefer || cur != prefer && aws.ToBool(sn.DefaultForAz) {
+			m[az] = id
+		}
+	}
+	return m
+}
+
+// launchAZOrder 返回 itype 依次尝试的可用区 (只含有默认子网的)：Spot 按当前价从低到高，
+// 按需时 prefer 排在最前，其余按名称
+func launchAZOrder(ctx context.Context, cli EC2API, itype string, spot bool, subnets map[string]string, prefer string) ([]string, error) {
+	var azs []string
+	if offered, err := typeOfferings(ctx, cli, itype); err != nil {
+		azs = sortedKeys(subnets) // 无权限查询时尝试所有可用区
+	} else if azs = offered[itype]; len(azs) == 0 {
+		return nil, errors.New("区域不提供此类型")
+	}
+	azs = slices.DeleteFunc(slices.Clone(azs), func(az string) bool { return subnets[az] == "" })
+	if len(azs) == 0 {
+		return nil, errors.New("默认 VPC 中没有提供此类型的可用区")
+	}
+	rank := map[string]int{}
+	if spot {
+		if rows, err := spotPriceHistory(ctx, cli, itype, time.Hour); err == nil {
+			for i, r := range rows {
+				rank[r.AZ] = i - len(rows)
+			}
+		}
+	} else if prefer != "" {
+		rank[prefer] = -1
+	}
+	sort.SliceStable(azs, func(i, j int) bool { return rank[azs[i]] < rank[azs[j]] })
+	return azs, nil
+}
+
+// runWithFallback 启动实例；遇到容量不足 / 可用区不支持时依次换可用区，再依次换 types 中的备选类型。
+// subnets 为各可用区可换用的子网 (如已配置 IPv6 的子网)，nil 时使用默认 VPC 的默认子网，
+// 为空时只在请求中的子网换类型。Spot 全部失败且允许回退时再以按需实例重试一轮
+func runWithFallback(ctx context.Context, cli EC2API, in *ec2.RunInstancesInput, types []string, spot *SpotOptions, subnets map[string]string) (*ec2.RunInstancesOutput, []LaunchAttempt, error) {
+	if subnets == nil {
+		subnets = defaultSubnetsByAZ(ctx, cli)
+	}
+	prefer := ""
+	for az, id := range subnets {
+		if id == runSubnet(in) {
+			prefer = az
+		}
+	}
+	markets := []*SpotOptions{spot}
+	if spot != nil && spot.FallbackOnDemand {
+		markets = append(markets, nil)
+	}
+	var attempts []LaunchAttempt
+	var lastErr error
+	for _, m := range markets {
+		market := "on-demand"
+		in.InstanceMarketOptions = nil
+		if m != nil {
+			market = "spot"
+			in.InstanceMarketOptions = m.marketOptions()
+		} else if spot != nil {
+			fmt.Println("🔁 Spot 均无法启动，回退为按需实例...")
+		}
+		for _, t := range types {
+			in.InstanceType = ec2t.InstanceType(t)
+			azs := []string{""} // 空表示沿用请求中的子网
+			if len(subnets) > 0 {
+				var err error
+				if azs, err = launchAZOrder(ctx, cli, t, m != nil, subnets, prefer); err != nil {
+					attempts = append(attempts, LaunchAttempt{Type: t, Market: market, Result: err.Error()})
+					lastErr = fmt.Errorf("%s: %v", t, err)
+					continue
+				}
+			}
+			for _, az := range azs {
+				if az != "" {
+					setRunSubnet(in, subnets[az])
+				}
+				a := LaunchAttempt{Type: t, AZ: az, Market: market, Result: "✅ 成功"}
+				out, err := cli.RunInstances(ctx, in)
+				if err == nil {
+					if a.AZ == "" && len(out.Instances) > 0 && out.Instances[0].Placement != nil {
+						a.AZ = aws.ToString(out.Instances[0].Placement.AvailabilityZone)
+					}
+					return out, append(attempts, a), nil
+				}
+				a.Result = errorCode(err)
+				attempts = append(attempts, a)
+				if !isCapacityError(err) {
+					return nil, attempts, err
+				}
+				fmt.Fprintf(os.Stderr, "⚠️ %s @ %s (%s) 启动失败: %s，尝试下一个...\n", t, orDefault(az, "默认子网"), market, a.Result)
+				lastErr = err
+			}
+		}
+	}
+	return nil, attempts, fmt.Errorf("所有可用区与备选类型均无法启动: %v", lastErr)
+}
+
+// launchTypes 返回首选类型加备选类型；目录可用时去掉与架构不符或区域不提供的备选类型
+func launchTypes(ctx context.Context, cli EC2API, region, arch, primary string, alts []string) []string {
+	types := []string{primary}
+	for _, t := range alts {
+		if !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 1 {
+		return types
+	}
+	all, err := listInstanceTypes(ctx, cli, region, arch)
+	if err != nil {
+		return types
+	}
+	return slices.DeleteFunc(types, func(t string) bool {
+		if t == primary || slices.ContainsFunc(all, func(i InstanceTypeInfo) bool { return i.Type == t }) {
+			return false
+		}
+		fmt.Printf("⚠️ 备选类型 %s 不支持 %s 或在 %s 不提供，已忽略\n", t, arch, region)
+		return true
+	})
+}
+
+func launchAttemptReport(rows []LaunchAttempt) Report {
+	r := Report{
+		Header:  "序号\t类型\t可用区\t购买方式\t结果",
+		Columns: []string{"idx", "type", "az", "market", "result"},
+		Data:    rows,
+	}
+	for i, x := range rows {
+		r.Rows = append(r.Rows, []string{strconv.Itoa(i + 1), x.Type, orDefault(x.AZ, "-"), x.Market, x.Result})
+	}
+	return r
+}
+
+// printLaunchAttempts 发生过换区 / 换类型时打印尝试记录与最终结果
+func printLaunchAttempts(attempts []LaunchAttempt, err error) {
+	if len(attempts) <= 1 {
+		return
+	}
+	fmt.Println("\n📋 启动尝试记录:")
+	writeReport(os.Stdout, "table", launchAttemptReport(attempts))
+	if last := attempts[len(attempts)-1]; err == nil {
+		fmt.Printf("✅ 最终使用 %s @ %s (%s)\n", last.Type, orDefault(last.AZ, "-"), last.Market)
+	}
+}
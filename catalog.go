@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2t "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// -------------------- 实例类型目录 --------------------
+
+// InstanceTypeInfo 为区域内实际可用的一个实例类型，AZs 为提供该类型的可用区
+type InstanceTypeInfo struct {
+	Type     string   `json:"type" yaml:"type"`
+	VCPU     int32    `json:"vcpu" yaml:"vcpu"`
+	MemGiB   float64  `json:"memory_gib" yaml:"memory_gib"`
+	Network  string   `json:"network" yaml:"network"`
+	FreeTier bool     `json:"free_tier" yaml:"free_tier"`
+	IPv6     bool     `json:"ipv6" yaml:"ipv6"`
+	AZs      []string `json:"azs" yaml:"azs"`
+}
+
+func (t InstanceTypeInfo) Family() string {
+	f, _, _ := strings.Cut(t.Type, ".")
+	return f
+}
+
+// typeCatalogCache 缓存 region/arch 对应的目录，交互模式下重复创建时不必重新拉取
+var typeCatalogCache sync.Map
+
+// typeOfferings 返回区域内每个实例类型所在的可用区；types 非空时只查询这些类型
+func typeOfferings(ctx context.Context, cli EC2API, types ...string) (map[string][]string, error) {
+	in := &ec2.DescribeInstanceTypeOfferingsInput{LocationType: ec2t.LocationTypeAvailabilityZone}
+	if len(types) > 0 {
+		in.Filters = []ec2t.Filter{{Name: aws.String("instance-type"), Values: types}}
+	}
+	azs := map[string][]string{}
+	p := ec2.NewDescribeInstanceTypeOfferingsPaginator(cli, in)
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range out.InstanceTypeOfferings {
+			t := string(o.InstanceType)
+			azs[t] = append(azs[t], aws.ToString(o.Location))
+		}
+	}
+	for _, v := range azs {
+		sort.Strings(v)
+	}
+	return azs, nil
+}
+
+// listInstanceTypes 拉取区域内支持 arch 且实际提供的实例类型，按 vCPU、内存排序
+func listInstanceTypes(ctx context.Context, cli EC2API, region, arch string) ([]InstanceTypeInfo, error) {
+	key := region + "/" + arch
+	if v, ok := typeCatalogCache.Load(key); ok {
+		return v.([]InstanceTypeInfo), nil
+	}
+	offered, err := typeOfferings(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+	var out []InstanceTypeInfo
+	p := ec2.NewDescribeInstanceTypesPaginator(cli, &ec2.DescribeInstanceTypesInput{
+		Filters: []ec2t.Filter{{Name: aws.String("processor-info.supported-architecture"), Values: []string{arch}}},
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range page.InstanceTypes {
+			azs, ok := offered[string(it.InstanceType)]
+			if !ok {
+				continue
+			}
+			info := InstanceTypeInfo{Type: string(it.InstanceType), FreeTier: aws.ToBool(it.FreeTierEligible), AZs: azs}
+			if it.VCpuInfo != nil {
+				info.VCPU = aws.ToInt32(it.VCpuInfo.DefaultVCpus)
+			}
+			if it.MemoryInfo != nil {
+				info.MemGiB = float64(aws.ToInt64(it.MemoryInfo.SizeInMiB)) / 1024
+			}
+			if it.NetworkInfo != nil {
+				info.Network = aws.ToString(it.NetworkInfo.NetworkPerformance)
+				info.IPv6 = aws.ToBool(it.NetworkInfo.Ipv6Supported)
+			}
+			out = append(out, info)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.VCPU != b.VCPU {
+			return a.VCPU < b.VCPU
+		}
+		if a.MemGiB != b.MemGiB {
+			return a.MemGiB < b.MemGiB
+		}
+		return a.Type < b.Type
+	})
+	typeCatalogCache.Store(key, out)
+	return out, nil
+}
+
+// TypeFilter 为实例类型筛选条件，零值表示不过滤
+type TypeFilter struct {
+	Family   string // 支持通配符，如 t3、t*、c7*
+	MinVCPU  int32
+	MinMem   float64
+	FreeTier bool
+}
+
+func (f TypeFilter) Match(t InstanceTypeInfo) bool {
+	if f.Family != "" {
+		if ok, _ := path.Match(strings.ToLower(f.Family), t.Family()); !ok {
+			return false
+		}
+	}
+	return t.VCPU >= f.MinVCPU && t.MemGiB >= f.MinMem && (!f.FreeTier || t.FreeTier)
+}
+
+// parseTypeFilter 解析 "family=t3 vcpu=2 mem=4 free" 形式的筛选条件
+func parseTypeFilter(s string) (TypeFilter, error) {
+	var f TypeFilter
+	for _, tok := range strings.Fields(s) {
+		k, v, _ := strings.Cut(strings.ToLower(tok), "=")
+		switch k {
+		case "free", "free-tier", "free_tier":
+			f.FreeTier = true
+		case "family":
+			f.Family = v
+		case "vcpu":
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return f, fmt.Errorf("vcpu 应为整数: %s", v)
+			}
+			f.MinVCPU = int32(n)
+		case "mem", "ram":
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return f, fmt.Errorf("mem 应为数字 (GiB): %s", v)
+			}
+			f.MinMem = n
+		default:
+			return f, fmt.Errorf("未知筛选条件: %s (可用 family= vcpu= mem= free)", tok)
+		}
+	}
+	return f, nil
+}
+
+func filterTypes(all []InstanceTypeInfo, f TypeFilter) []InstanceTypeInfo {
+	var out []InstanceTypeInfo
+	for _, t := range all {
+		if f.Match(t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// defaultTypeView 在未指定筛选条件时只展示免费套餐与突发性能 (t 系列) 的小规格
+func defaultTypeView(all []InstanceTypeInfo) []InstanceTypeInfo {
+	var out []InstanceTypeInfo
+	for _, t := range all {
+		if t.FreeTier || (strings.HasPrefix(t.Type, "t") && t.VCPU <= 2) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func typeCatalogReport(rows []InstanceTypeInfo) Report {
+	r := Report{
+		Header:  "序号\t类型\tvCPU\t内存(GiB)\t网络\t免费套餐\tIPv6\t可用区",
+		Columns: []string{"idx", "type", "vcpu", "memory_gib", "network", "free_tier", "ipv6", "azs"},
+		Cut:     map[int]int{4: 20},
+		Data:    rows,
+	}
+	if rows == nil {
+		r.Data = []InstanceTypeInfo{}
+	}
+	for i, t := range rows {
+		free, v6 := "", ""
+		if t.FreeTier {
+			free = "✅"
+		}
+		if t.IPv6 {
+			v6 = "✅"
+		}
+		r.Rows = append(r.Rows, []string{
+			strconv.Itoa(i + 1), t.Type, strconv.Itoa(int(t.VCPU)), strconv.FormatFloat(t.MemGiB, 'f', -1, 64),
+			t.Network, free, v6, strings.Join(t.AZs, ","),
+		})
+	}
+	return r
+}
+
+// pickInstanceType 交互式选择实例类型；目录拉取失败时退回内置的小规格列表
+func pickInstanceType(ctx context.Context, cli EC2API, region, arch string) string {
+	all, err := listInstanceTypes(ctx, cli, region, arch)
+	if err != nil || len(all) == 0 {
+		fmt.Println("⚠️ 无法获取实例类型目录，使用内置列表:", err)
+		typeList := defaultEC2Types(arch)
+		fmt.Printf("\n请选择实例类型:\n")
+		for i, t := range typeList {
+			fmt.Printf("  %2d) %s\n", i+1, t.Type)
+		}
+		idx := mustInt(input("编号 [1]: ", "1"))
+		if idx > 0 && idx <= len(typeList) {
+			return typeList[idx-1].Type
+		}
+		return typeList[0].Type
+	}
+	view := defaultTypeView(all)
+	if len(view) == 0 {
+		view = all
+	}
+	for {
+		if len(view) > 30 {
+			view = view[:30]
+		}
+		fmt.Printf("\n%s (%s) 可用实例类型:\n", region, arch)
+		writeReport(os.Stdout, "table", typeCatalogReport(view))
+		sel := input("编号或类型名 [1]，f 重新筛选 (如 family=c7* vcpu=4 mem=8 free): ", "1")
+		if sel == "f" || sel == "F" {
+			f, err := parseTypeFilter(input("筛选条件: ", ""))
+			if err != nil {
+				fmt.Println("❌", err)
+				continue
+			}
+			if view = filterTypes(all, f); len(view) == 0 {
+				fmt.Println("⚠️ 没有符合条件的类型")
+				view = defaultTypeView(all)
+			}
+			continue
+		}
+		if idx, err := strconv.Atoi(sel); err == nil && idx > 0 && idx <= len(view) {
+			return view[idx-1].Type
+		}
+		for _, t := range all {
+			if t.Type == sel {
+				return sel
+			}
+		}
+		fmt.Println("❌ 该区域不提供此类型:", sel)
+	}
+}
+
+// checkTypeOffered 在启动前确认实例类型在区域内可用，返回提供该类型的可用区
+func checkTypeOffered(ctx context.Context, cli EC2API, region, itype string) ([]string, error) {
+	offered, err := typeOfferings(ctx, cli, itype)
+	if err != nil {
+		return nil, nil // 无权限查询时不阻止启动，交给 RunInstances 报错
+	}
+	azs := offered[itype]
+	if len(azs) == 0 {
+		return nil, fmt.Errorf("实例类型 %s 在 %s 不可用，可用 `ec2 types --region %s` 查看可选类型", itype, region, region)
+	}
+	return azs, nil
+}
+
+// subnetForTypeAZs 在类型只在部分可用区提供时，返回默认 VPC 中位于这些可用区的子网；
+// 所有默认子网都可用 (或无法判断) 时返回空，由 AWS 自行选择
+func subnetForTypeAZs(ctx context.Context, cli EC2API, azs []string) string {
+	if len(azs) == 0 {
+		return ""
+	}
+	out, err := cli.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+		Filters: []ec2t.Filter{{Name: aws.String("default-for-az"), Values: []string{"true"}}},
+	})
+	if err != nil || len(out.Subnets) == 0 {
+		return ""
+	}
+	pick := ""
+	allOffered := true
+	for _, sn := range out.Subnets {
+		if slices.Contains(azs, aws.ToString(sn.AvailabilityZone)) {
+			if pick == "" {
+				pick = aws.ToString(sn.SubnetId)
+			}
+		} else {
+			allOffered = false
+		}
+	}
+	if allOffered {
+		return ""
+	}
+	return pick
+}
@@ -0,0 +1,1788 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v3"
+)
+
+// -------------------- 非交互式子命令 (CLI) --------------------
+
+const cliUsage = `用法: aws-tool <命令> <子命令> [参数]
+
+  ec2 create   --region us-east-1 --ami debian-12 --type t3.micro --count 2 --ipv6 --open-all
+  ec2 create   --region us-east-1 --type t3.micro --net ipv6 --dns64   (IPv6-only，无公网 IPv4)
+  ec2 create   --template web-small [--count 3 --set tags.Name=web --port 22,443]
+  ec2 create   --type t3.micro --alt-types t3a.micro,t2.micro   (容量不足时自动换可用区 / 类型)
+  ec2 create   --gen-pwd | --root-pwd 'xxx' [--plain-pwd]   (root 密码，默认只把 SHA-512 哈希写入 user-data)
+  ec2 create   --type t3.micro --spot [--spot-max-price 0.005 --spot-persistent --spot-interruption stop --spot-fallback]
+  ec2 list     [--region us-east-1,ap-east-1] [--output table|json|csv|yaml]
+  ec2 regions  [--output ...]
+  ec2 types    --region ap-east-1 [--arch arm64] [--family t4g] [--min-vcpu 2] [--min-mem 4] [--free-tier] [--output ...]
+  ec2 spot-prices --region us-east-1 --type t3.micro [--hours 24] [--output ...]
+  ec2 control  --id i-xxxx --action start|stop|reboot|terminate [--region r] [--yes]
+  ec2 sg list  --id i-xxxx [--egress] [--output ...]          (实例安全组与规则)
+  ec2 sg add   --id i-xxxx --port 443 [--source my|1.2.3.0/24,::/0] [--group sg-xxx] [--desc x]
+  ec2 sg remove --id i-xxxx --port 22 --source 0.0.0.0/0,::/0 [--group sg-xxx]
+  ec2 sg attach|detach --id i-xxxx --group sg-a,sg-b
+  ec2 sg preset --id i-xxxx --preset ssh|web|ssh-web|ssh-myip [--replace]
+  ec2 ipv6-setup --region us-east-1 [--vpc vpc-xxx] [--az us-east-1a] [--egress-only] [--dry-run]
+  ec2 rotate-ip --id i-1,i-2 --yes [--method restart|eip] [--probe 22] [--concurrency 4] [--output ...]
+  ls create    --region us-east-1 --name LS-1 --bundle nano_3_0 --blueprint debian_12 [--open-all]
+  ls create    --template ls-nano --name LS-2 [--region ap-northeast-1]
+  ls create    --region us-east-1 --name LS-3 --bundle nano_ipv6_3_0   (IPv6-only 套餐，无公网 IPv4)
+  ls list      [--region us-east-1] [--output ...]
+  ls regions   [--output ...]
+  ls control   --name LS-1 --action start|stop|reboot|delete [--region r] [--yes]
+  ls ip-type   --name LS-1 --type dualstack|ipv4|ipv6 [--yes]   (开启 / 关闭 IPv6，或切换 IPv6-only)
+  ls firewall list|export --name LS-1 [--file fw.yaml] [--output ...]   (export 默认输出 YAML)
+  ls firewall open  --name LS-1 --port 443 [--source my|1.2.3.0/24,::/0|lightsail-connect]
+  ls firewall close --name LS-1 --port 22 [--source 0.0.0.0/0]   (指定来源时只删除这些来源)
+  ls firewall import --name LS-2,LS-3 --file fw.yaml [--region r]   (整体替换，可跨区域复制)
+  ls firewall preset --name LS-1 --preset ssh|web|ssh-web|ssh-myip
+  ls rotate-ip --name LS-1,LS-2 --yes [--probe 22] [--output ...]   (有固定 IP 时换新固定 IP，否则换新动态 IP)
+  quota        [--output ...]
+  batch        --op sts|quota|ec2-list|ls-list (--vault [--accounts a,b] | --profiles p1,p2 | --file keys.txt)
+               [--concurrency 4] [--output ...]
+  health       (--file keys.txt | --vault | --profiles p1,p2) [--report out.csv] [--output ...]
+  proxy check  --pool proxies.txt [--output ...]
+  key gen      --name my-key                        (生成 ed25519 密钥到本地密钥库)
+  key import   --name my-key --service ec2|ls [--region r1,r2]
+  key list     [--service ec2|ls] [--region r1,r2] [--output ...]
+  key delete   --name my-key --service ec2|ls --region r [--local]
+  ip list      [--service ec2|ls|all] [--region r1,r2] [--orphans] [--output ...]   (弹性 IP / 固定 IP 清单)
+  ip release   (--ip 1.2.3.4,eipalloc-xxx | --orphans) --yes [--service ...] [--region ...]
+  ip attach    --ip 1.2.3.4 --instance i-xxxx|LS-1
+  ssh run      --service ec2|ls --region r --targets i-1,i-2|all --cmd "uptime" [--concurrency 8] [--output ...]
+  ssh run      --host 1.2.3.4,[2600::1]:2222 --user admin --key my-key --cmd "uptime"
+  ssh connect  --service ec2|ls --region r --targets i-xxxx   (或 --host ... --user ... --key ...)
+               密码可用 --password 或环境变量 AWS_TOOL_SSH_PASSWORD；--strict 拒绝未记录的主机密钥
+  userdata snippets [--output ...]                    (列出启动脚本片段)
+  userdata render --service ec2|ls --snippet docker --snippet swap:SIZE=2G [--file init.sh --var KEY=VALUE]
+               ec2 create / ls create 同样支持 --snippet 与 --var
+  passwd gen   [--length 24] [--no-symbols] [--count 5] [--hash]
+  passwd hash  [--password xxx]                       (输出 SHA-512 crypt 哈希，默认读标准输入)
+  passwd list  [--show] [--output ...]                (本地记录的 root / 数据库密码)
+  passwd policy                                       (显示当前密码策略)
+
+公共参数: --profile / --ak / --sk / --session-token / --proxy / --proxy-pool
+  都未指定时使用 SDK 默认凭证链 (环境变量、默认 profile、credential_process、SSO 等)
+  --role-arn arn1[,arn2] [--external-id x] [--mfa-serial arn --mfa-code 123456] 以角色 (链) 访问其他账户
+不带任何参数运行时进入交互式菜单。`
+
+type cliCommon struct {
+	ak, sk, token, profile, proxy, proxyPool string
+
+	roles                          []string
+	externalID, mfaSerial, mfaCode string
+	roleDuration                   time.Duration
+}
+
+func addCommonFlags(fs *flag.FlagSet) *cliCommon {
+	c := &cliCommon{}
+	fs.StringVar(&c.ak, "ak", "", "AWS Access Key ID")
+	fs.StringVar(&c.sk, "sk", "", "AWS Secret Access Key")
+	fs.StringVar(&c.token, "session-token", "", "AWS Session Token (临时凭证)")
+	fs.StringVar(&c.profile, "profile", "", "使用 ~/.aws/credentials 或 ~/.aws/config 中的 profile")
+	fs.StringVar(&c.proxy, "proxy", "", "代理 (host:port:user:pass 或 socks5://...)")
+	fs.StringVar(&c.proxyPool, "proxy-pool", "", "代理列表文件，检测后自动选择可用代理并在失效时切换")
+	fs.Func("role-arn", "扮演的角色 ARN，可重复或逗号分隔以按顺序组成角色链", func(v string) error {
+		c.roles = append(c.roles, splitList(v)...)
+		return nil
+	})
+	fs.StringVar(&c.externalID, "external-id", "", "角色链最后一跳使用的 External ID")
+	fs.StringVar(&c.mfaSerial, "mfa-serial", "", "角色链第一跳使用的 MFA 设备 ARN")
+	fs.StringVar(&c.mfaCode, "mfa-code", "", "MFA 验证码 (不填则在需要时提示输入)")
+	fs.DurationVar(&c.roleDuration, "role-duration", 0, "临时凭证有效期，如 1h (默认由 STS 决定)")
+	return c
+}
+
+// setup 应用代理并校验凭证，返回可供后续调用的凭证提供者。
+// 优先级: --profile > --ak/--sk > SDK 默认凭证链 (环境变量、默认 profile、credential_process 等)
+func (c *cliCommon) setup(ctx context.Context) (aws.CredentialsProvider, error) {
+	if c.proxy != "" {
+		GlobalProxy = parseProxyString(c.proxy)
+	}
+	if c.proxyPool != "" {
+		if err := setupProxyPool(ctx, c.proxyPool); err != nil {
+			return nil, err
+		}
+	} else if err := proxySelfTest(ctx); err != nil {
+		return nil, err
+	}
+	var (
+		creds aws.CredentialsProvider
+		err   error
+	)
+	sessionAccount = "cli"
+	switch {
+	case c.profile != "":
+		creds, err = loadChainCreds(ctx, c.profile)
+	case c.ak != "" || c.sk != "":
+		if c.ak == "" || c.sk == "" {
+			return nil, errors.New("--ak 与 --sk 必须同时指定")
+		}
+		creds = staticCreds(c.ak, c.sk, c.token)
+	default:
+		creds, err = loadChainCreds(ctx, "")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("加载凭证失败: %v", err)
+	}
+	if err := stsCheck(ctx, bootstrapRegion, creds); err != nil {
+		return nil, fmt.Errorf("凭证验证失败: %v", err)
+	}
+	if len(c.roles) == 0 {
+		return creds, nil
+	}
+	creds, err = assumeRoleChain(ctx, creds, c.roleSpecs())
+	if err != nil {
+		return nil, fmt.Errorf("扮演角色失败: %v", err)
+	}
+	return creds, nil
+}
+
+// roleSpecs 把命令行参数转换为角色链: MFA 属于基础身份，只用于第一跳；
+// External ID 通常由目标账户的角色要求，只用于最后一跳
+func (c *cliCommon) roleSpecs() []RoleSpec {
+	specs := make([]RoleSpec, len(c.roles))
+	for i, arn := range c.roles {
+		specs[i] = RoleSpec{ARN: arn, Duration: c.roleDuration}
+	}
+	specs[0].MFASerial, specs[0].TokenCode = c.mfaSerial, c.mfaCode
+	specs[len(specs)-1].ExternalID = c.externalID
+	return specs
+}
+
+func addOutputFlag(fs *flag.FlagSet) *string {
+	return fs.String("output", "table", "输出格式 "+strings.Join(outputFormats, "|"))
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func regionNames(rs []RegionInfo) []string {
+	var names []string
+	for _, r := range rs {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func readUserDataFile(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// templateFlags 为 create 子命令提供 --template 与逐项覆盖参数
+type templateFlags struct {
+	name, file string
+	sets       []string
+	ports      []string
+	snippets   []string
+}
+
+func addTemplateFlags(fs *flag.FlagSet) *templateFlags {
+	t := &templateFlags{}
+	fs.StringVar(&t.name, "template", "", "使用模板文件中的启动模板")
+	fs.StringVar(&t.file, "templates", "", "模板文件路径 (默认 AWS_TOOL_TEMPLATES 或 "+defaultTemplateFile+")")
+	fs.Func("set", "覆盖模板字段 key=value，可重复 (如 --set count=2 --set tags.Name=web)", func(v string) error {
+		t.sets = append(t.sets, v)
+		return nil
+	})
+	fs.Func("tag", "实例标签 key=value，可重复", func(v string) error {
+		t.sets = append(t.sets, "tags."+v)
+		return nil
+	})
+	fs.Func("port", "放行端口，可重复或逗号分隔 (22、80/tcp、1000-2000/udp、all)", func(v string) error {
+		for _, p := range splitList(v) {
+			if _, err := parsePortRule(p); err != nil {
+				return err
+			}
+		}
+		t.ports = append(t.ports, splitList(v)...)
+		return nil
+	})
+	fs.Func("snippet", "启动脚本片段，可重复 (如 docker、swap:SIZE=2G)；指定时替换模板中的片段", func(v string) error {
+		name, _, err := parseSnippetSpec(v)
+		if err != nil {
+			return err
+		}
+		if _, err := findSnippet(name); err != nil {
+			return err
+		}
+		t.snippets = append(t.snippets, strconv.Quote(v))
+		return nil
+	})
+	fs.Func("var", "启动脚本变量 KEY=VALUE，替换片段与 user-data 中的 {{KEY}}，可重复", func(v string) error {
+		k, val, ok := strings.Cut(v, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("格式应为 KEY=VALUE: %q", v)
+		}
+		t.sets = append(t.sets, "user_data_vars."+k+"="+strconv.Quote(val))
+		return nil
+	})
+	return t
+}
+
+// use 报告参数 name 是否应写入模板：未使用模板时总是写入 (取参数默认值)，否则仅在显式指定时覆盖
+func (t *templateFlags) use(fs *flag.FlagSet) func(name string) bool {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return func(name string) bool { return t.name == "" || set[name] }
+}
+
+func (t *templateFlags) apply(dst any) error {
+	sets := t.sets
+	if len(t.ports) > 0 {
+		sets = append(sets, "ports=["+strings.Join(t.ports, ",")+"]")
+	}
+	if len(t.snippets) > 0 {
+		sets = append(sets, "snippets=["+strings.Join(t.snippets, ",")+"]")
+	}
+	return applyOverrides(dst, sets)
+}
+
+// runCLI 解析子命令并执行，返回进程退出码
+func runCLI(ctx context.Context, args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Println(cliUsage)
+		return 0
+	}
+	var err error
+	switch args[0] {
+	case "ec2":
+		err = cliEC2(ctx, args[1:])
+	case "ls", "lightsail":
+		err = cliLS(ctx, args[1:])
+	case "quota":
+		err = cliQuota(ctx, args[1:])
+	case "batch":
+		err = cliBatch(ctx, args[1:])
+	case "health":
+		err = cliHealth(ctx, args[1:])
+	case "proxy":
+		err = cliProxy(ctx, args[1:])
+	case "key":
+		err = cliKey(ctx, args[1:])
+	case "ip":
+		err = cliIP(ctx, args[1:])
+	case "ssh":
+		err = cliSSH(ctx, args[1:])
+	case "userdata":
+		err = cliUserData(ctx, args[1:])
+	case "passwd":
+		err = cliPasswd(ctx, args[1:])
+	default:
+		err = fmt.Errorf("未知命令: %s", args[0])
+	}
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	return 0
+}
+
+func cliEC2(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("缺少子命令: create|list|regions|types|spot-prices|control|sg|rotate-ip|ipv6-setup")
+	}
+	switch args[0] {
+	case "sg":
+		return cliEC2SG(ctx, args[1:])
+	case "rotate-ip":
+		return cliRotateIP(ctx, "ec2", args[1:])
+	}
+	fs := flag.NewFlagSet("ec2 "+args[0], flag.ContinueOnError)
+	common := addCommonFlags(fs)
+	region := fs.String("region", "", "区域 (list 可用逗号分隔多个，默认全部)")
+	switch args[0] {
+	case "create":
+		arch := fs.String("arch", "x86_64", "CPU 架构 x86_64|arm64")
+		ami := fs.String("ami", "debian-12", "AMI ID 或内置镜像别名 (debian-12, ubuntu-24.04, amazon-linux-2023 ...)")
+		itype := fs.String("type", "", "实例类型 (默认按架构选择最小规格)")
+		count := fs.Int("count", 1, "启动数量")
+		disk := fs.Int("disk", 0, "磁盘大小 GB (0 为镜像默认)")
+		ipv6 := fs.Bool("ipv6", false, "自动分配 IPv6 (等同 --net dual)")
+		netMode := fs.String("net", "", "网络模式 ipv4|dual|ipv6 (ipv6 为 IPv6-only，无公网 IPv4，需 Nitro 实例类型)")
+		dns64 := fs.Bool("dns64", false, "IPv6-only 子网开启 DNS64")
+		nat64 := fs.Bool("nat64", false, "IPv6-only 子网把 64:ff9b::/96 路由到已有 NAT 网关")
+		openAll := fs.Bool("open-all", false, "安全组全开端口")
+		rootPwd := fs.String("root-pwd", "", "SSH root 密码 (也可以是 $6$ 开头的 crypt 哈希)")
+		genPwd := fs.Bool("gen-pwd", false, "按密码策略生成 root 密码")
+		plainPwd := fs.Bool("plain-pwd", false, "user-data 中明文写入 root 密码 (默认只写 SHA-512 哈希)")
+		udFile := fs.String("user-data-file", "", "启动脚本文件")
+		keyName := fs.String("key-name", "", "EC2 密钥对名称")
+		altTypes := fs.String("alt-types", "", "备选实例类型 (逗号分隔)，容量不足时依次尝试")
+		spot := fs.Bool("spot", false, "以 Spot 实例启动")
+		spotMax := fs.String("spot-max-price", "", "Spot 每小时最高价 USD (默认按需价)")
+		spotPersistent := fs.Bool("spot-persistent", false, "持久 Spot 请求")
+		spotInterrupt := fs.String("spot-interruption", "terminate", "Spot 中断行为 terminate|stop|hibernate")
+		spotFallback := fs.Bool("spot-fallback", false, "Spot 容量不足时回退为按需实例")
+		tpl := addTemplateFlags(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		t := &EC2Template{}
+		if tpl.name != "" {
+			base, err := findEC2Template(tpl.file, tpl.name)
+			if err != nil {
+				return err
+			}
+			t = base
+		}
+		// 显式指定的参数覆盖模板；不使用模板时取参数默认值
+		use := tpl.use(fs)
+		if use("region") {
+			t.Region = *region
+		}
+		if use("arch") {
+			t.Arch = *arch
+		}
+		if use("ami") {
+			t.AMI = *ami
+		}
+		if use("type") {
+			t.Type = *itype
+		}
+		if use("count") {
+			t.Count = int32(*count)
+		}
+		if use("disk") {
+			t.Disk = int32(*disk)
+		}
+		if use("ipv6") {
+			t.IPv6 = *ipv6
+		}
+		if use("net") {
+			t.Net = *netMode
+		}
+		if use("dns64") {
+			t.DNS64 = *dns64
+		}
+		if use("nat64") {
+			t.NAT64 = *nat64
+		}
+		if use("open-all") {
+			t.OpenAll = *openAll
+		}
+		if use("root-pwd") {
+			t.RootPwd = *rootPwd
+		}
+		if use("gen-pwd") {
+			t.GenPwd = *genPwd
+		}
+		if use("plain-pwd") {
+			t.PlainPwd = *plainPwd
+		}
+		if use("user-data-file") {
+			t.UserData, t.UserDataFile = "", *udFile
+		}
+		if use("key-name") {
+			t.KeyName = *keyName
+		}
+		if use("alt-types") {
+			t.AltTypes = splitList(*altTypes)
+		}
+		if use("spot") && !*spot {
+			t.Spot = nil
+		} else if *spot && t.Spot == nil {
+			t.Spot = &SpotOptions{}
+		}
+		if t.Spot != nil {
+			if use("spot-max-price") {
+				t.Spot.MaxPrice = *spotMax
+			}
+			if use("spot-persistent") {
+				t.Spot.Persistent = *spotPersistent
+			}
+			if use("spot-interruption") {
+				t.Spot.Interruption = *spotInterrupt
+			}
+			if use("spot-fallback") {
+				t.Spot.FallbackOnDemand = *spotFallback
+			}
+		}
+		if err := tpl.apply(t); err != nil {
+			return err
+		}
+		if t.Region == "" {
+			t.Region = bootstrapRegion
+		}
+		opts, err := t.launchOptions()
+		if err != nil {
+			return err
+		}
+		*region = t.Region
+		creds, err := common.setup(ctx)
+		if err != nil {
+			return err
+		}
+		regions, err := getEC2RegionsWithStatus(ctx, creds)
+		if err != nil {
+			return err
+		}
+		for _, r := range regions {
+			if r.Name == *region && r.Status == "not-opted-in" {
+				return fmt.Errorf("区域 %s 未启用，请先在交互菜单中启用", *region)
+			}
+		}
+		cfg, err := mkCfg(ctx, *region, creds)
+		if err != nil {
+			return err
+		}
+		out, err := ec2Launch(ctx, newEC2Client(cfg), *region, opts)
+		if err != nil {
+			return err
+		}
+		for _, ins := range out {
+			fmt.Println("✅ 成功:", *ins.InstanceId)
+		}
+		return nil
+	case "list":
+		output := addOutputFlag(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if err := checkOutputFormat(*output); err != nil {
+			return err
+		}
+		creds, err := common.setup(ctx)
+		if err != nil {
+			return err
+		}
+		regions, err := cliEC2Regions(ctx, creds, *region)
+		if err != nil {
+			return err
+		}
+		rows, _ := ec2ListAll(ctx, regions, creds)
+		return writeReport(os.Stdout, *output, ec2Report(rows))
+	case "regions":
+		output := addOutputFlag(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if err := checkOutputFormat(*output); err != nil {
+			return err
+		}
+		creds, err := common.setup(ctx)
+		if err != nil {
+			return err
+		}
+		rs, err := getEC2RegionsWithStatus(ctx, creds)
+		if err != nil {
+			return err
+		}
+		return writeReport(os.Stdout, *output, regionReport(rs))
+	case "types":
+		arch := fs.String("arch", "x86_64", "CPU 架构 x86_64|arm64")
+		family := fs.String("family", "", "实例族，支持通配符 (t3、c7*)")
+		minVCPU := fs.Int("min-vcpu", 0, "最少 vCPU")
+		minMem := fs.Float64("min-mem", 0, "最少内存 GiB")
+		free := fs.Bool("free-tier", false, "只显示免费套餐可用的类型")
+		output := addOutputFlag(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if err := checkOutputFormat(*output); err != nil {
+			return err
+		}
+		if *region == "" {
+			*region = bootstrapRegion
+		}
+		creds, err := common.setup(ctx)
+		if err != nil {
+			return err
+		}
+		cfg, err := mkCfg(ctx, *region, creds)
+		if err != nil {
+			return err
+		}
+		all, err := listInstanceTypes(ctx, newEC2Client(cfg), *region, *arch)
+		if err != nil {
+			return err
+		}
+		f := TypeFilter{Family: *family, MinVCPU: int32(*minVCPU), MinMem: *minMem, FreeTier: *free}
+		return writeReport(os.Stdout, *output, typeCatalogReport(filterTypes(all, f)))
+	case "spot-prices":
+		itype := fs.String("type", "", "实例类型")
+		hours := fs.Int("hours", 24, "统计最近多少小时")
+		output := addOutputFlag(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if err := checkOutputFormat(*output); err != nil {
+			return err
+		}
+		if *itype == "" {
+			return errors.New("必须指定 --type")
+		}
+		if *region == "" {
+			*region = bootstrapRegion
+		}
+		creds, err := common.setup(ctx)
+		if err != nil {
+			return err
+		}
+		cfg, err := mkCfg(ctx, *region, creds)
+		if err != nil {
+			return err
+		}
+		rows, err := spotPriceHistory(ctx, newEC2Client(cfg), *itype, time.Duration(*hours)*time.Hour)
+		if err != nil {
+			return err
+		}
+		return writeReport(os.Stdout, *output, spotPriceReport(rows, *region))
+	case "control":
+		id := fs.String("id", "", "实例 ID")
+		action := fs.String("action", "", "start|stop|reboot|terminate")
+		force := fs.Bool("yes", false, "终止时跳过确认")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *id == "" || *action == "" {
+			return errors.New("必须指定 --id 与 --action")
+		}
+		if *action == "terminate" && !*force {
+			return errors.New("终止实例需要加 --yes 确认")
+		}
+		creds, err := common.setup(ctx)
+		if err != nil {
+			return err
+		}
+		target, err := cliEC2InstanceRegion(ctx, creds, *region, *id)
+		if err != nil {
+			return err
+		}
+		cfg, err := mkCfg(ctx, target, creds)
+		if err != nil {
+			return err
+		}
+		if err := ec2Action(ctx, newEC2Client(cfg), *id, *action); err != nil {
+			return err
+		}
+		fmt.Println(actionDoneMsg[*action])
+		return nil
+	case "ipv6-setup":
+		vpc := fs.String("vpc", "", "VPC ID (默认区域的默认 VPC)")
+		az := fs.String("az", "", "只配置该可用区的子网 (默认全部子网)")
+		egressOnly := fs.Bool("egress-only", false, "::/0 使用仅出口互联网网关 (只出不进)")
+		dryRun := fs.Bool("dry-run", false, "只打印计划，不做修改")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *region == "" {
+			return errors.New("必须指定 --region")
+		}
+		creds, err := common.setup(ctx)
+		if err != nil {
+			return err
+		}
+		cfg, err := mkCfg(ctx, *region, creds)
+		if err != nil {
+			return err
+		}
+		cli := newEC2Client(cfg)
+		if *vpc == "" {
+			if *vpc, err = defaultVpcID(ctx, cli); err != nil {
+				return err
+			}
+		}
+		p, err := planIPv6(ctx, cli, *vpc, IPv6Options{AZ: *az, EgressOnly: *egressOnly})
+		if err != nil {
+			return err
+		}
+		printIPv6Plan(p)
+		if *dryRun || p.changes() == 0 {
+			return nil
+		}
+		fmt.Println()
+		if err := applyIPv6Plan(ctx, cli, p); err != nil {
+			return err
+		}
+		fmt.Println("✅ IPv6 配置完成")
+		return nil
+	}
+	return fmt.Errorf("未知子命令: ec2 %s", args[0])
+}
+
+// cliEC2InstanceRegion 未指定区域时在所有区域中查找实例所在区域
+func cliEC2InstanceRegion(ctx context.Context, creds aws.CredentialsProvider, region, id string) (string, error) {
+	if region != "" {
+		return region, nil
+	}
+	regions, err := cliEC2Regions(ctx, creds, "")
+	if err != nil {
+		return "", err
+	}
+	rows, _ := ec2ListAll(ctx, regions, creds)
+	for _, r := range rows {
+		if r.ID == id {
+			return r.Region, nil
+		}
+	}
+	return "", fmt.Errorf("未找到实例 %s", id)
+}
+
+// cliEC2SG 实现 ec2 sg list|add|remove|attach|detach|preset
+func cliEC2SG(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("缺少子命令: list|add|remove|attach|detach|preset")
+	}
+	fs := flag.NewFlagSet("ec2 sg "+args[0], flag.ContinueOnError)
+	common := addCommonFlags(fs)
+	region := fs.String("region", "", "区域 (默认自动查找)")
+	id := fs.String("id", "", "实例 ID")
+	group := fs.String("group", "", "add/remove 操作的安全组 (默认实例的第一个安全组)；attach/detach 为逗号分隔的安全组 ID")
+	port := fs.String("port", "22", "端口规则 (22、80/tcp、1000-2000/udp、icmp、all)")
+	source := fs.String("source", "0.0.0.0/0,::/0", "来源，逗号分隔 (CIDR、IP、sg-xxx；my 为当前公网 IP)")
+	desc := fs.String("desc", "", "规则描述")
+	preset := fs.String("preset", "", "预设名称 (ssh、web、ssh-web、ssh-myip)")
+	replace := fs.Bool("replace", false, "preset: 卸下其他安全组，只保留预设")
+	egress := fs.Bool("egress", false, "list: 同时列出出站规则")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+	if *id == "" {
+		return errors.New("必须指定 --id")
+	}
+	var p SGPreset
+	if args[0] == "preset" {
+		var err error
+		if p, err = findSGPreset(*preset); err != nil {
+			return err
+		}
+	}
+	creds, err := common.setup(ctx)
+	if err != nil {
+		return err
+	}
+	target, err := cliEC2InstanceRegion(ctx, creds, *region, *id)
+	if err != nil {
+		return err
+	}
+	cfg, err := mkCfg(ctx, target, creds)
+	if err != nil {
+		return err
+	}
+	cli := newEC2Client(cfg)
+	cur, err := instanceSGs(ctx, cli, *id)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+		rows := sgRules(cur.Groups, false)
+		if *egress {
+			rows = append(rows, sgRules(cur.Groups, true)...)
+		}
+		return writeReport(os.Stdout, *output, sgRuleReport(rows))
+	case "add", "remove":
+		gid := *group
+		if gid == "" {
+			if len(cur.Groups) == 0 {
+				return errors.New("实例未挂载任何安全组")
+			}
+			gid = aws.ToString(cur.Groups[0].GroupId)
+		}
+		sources, err := resolveSources(ctx, splitList(*source))
+		if err != nil {
+			return err
+		}
+		perms, err := sgPermissions(*port, sources, *desc)
+		if err != nil {
+			return err
+		}
+		if args[0] == "add" {
+			if err := addSGRules(ctx, cli, gid, perms); err != nil {
+				return err
+			}
+			fmt.Printf("✅ 已在 %s 中放行 %s ← %s\n", gid, *port, strings.Join(sources, ", "))
+			return nil
+		}
+		if err := revokeSGRules(ctx, cli, gid, perms); err != nil {
+			return err
+		}
+		fmt.Printf("✅ 已从 %s 删除 %s ← %s\n", gid, *port, strings.Join(sources, ", "))
+		return nil
+	case "attach", "detach":
+		ids := splitList(*group)
+		if len(ids) == 0 {
+			return errors.New("必须指定 --group")
+		}
+		groups := cur.groupIDs()
+		if args[0] == "attach" {
+			for _, g := range ids {
+				if !slices.Contains(groups, g) {
+					groups = append(groups, g)
+				}
+			}
+		} else {
+			groups = slices.DeleteFunc(groups, func(g string) bool { return slices.Contains(ids, g) })
+		}
+		if err := setENIGroups(ctx, cli, cur.ENI, groups); err != nil {
+			return err
+		}
+		fmt.Println("✅ 当前安全组:", strings.Join(groups, ", "))
+		return nil
+	case "preset":
+		gid, err := applySGPreset(ctx, cli, cur, p, *replace)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ 已应用预设 %s (%s)\n", p.Name, gid)
+		return nil
+	}
+	return fmt.Errorf("未知子命令: ec2 sg %s", args[0])
+}
+
+func cliEC2Regions(ctx context.Context, creds aws.CredentialsProvider, sel string) ([]string, error) {
+	if sel != "" {
+		return splitList(sel), nil
+	}
+	rs, err := getEC2RegionsWithStatus(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+	return regionNames(rs), nil
+}
+
+func cliLSRegions(ctx context.Context, creds aws.CredentialsProvider, sel string) ([]string, error) {
+	if sel != "" {
+		return splitList(sel), nil
+	}
+	return getLightsailRegions(ctx, creds)
+}
+
+func cliLS(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("缺少子命令: create|list|regions|control|firewall|rotate-ip|ip-type")
+	}
+	switch args[0] {
+	case "firewall":
+		return cliLSFirewall(ctx, args[1:])
+	case "rotate-ip":
+		return cliRotateIP(ctx, "lightsail", args[1:])
+	}
+	fs := flag.NewFlagSet("ls "+args[0], flag.ContinueOnError)
+	common := addCommonFlags(fs)
+	region := fs.String("region", "", "区域 (list 可用逗号分隔多个，默认全部)")
+	switch args[0] {
+	case "create":
+		az := fs.String("az", "", "可用区 (默认 <region>a)")
+		name := fs.String("name", "LS-1", "实例名称")
+		bundle := fs.String("bundle", "nano_3_0", "套餐 ID (*_ipv6_* 为 IPv6-only 套餐)")
+		blueprint := fs.String("blueprint", "debian_12", "系统 ID")
+		ipType := fs.String("ip-type", "", "IP 地址类型 dualstack|ipv4|ipv6 (默认按套餐：IPv6 套餐为 ipv6，其余为 dualstack)")
+		openAll := fs.Bool("open-all", false, "防火墙全开 (TCP+UDP 0-65535)")
+		rootPwd := fs.String("root-pwd", "", "SSH root 密码 (也可以是 $6$ 开头的 crypt 哈希)")
+		genPwd := fs.Bool("gen-pwd", false, "按密码策略生成 root 密码")
+		plainPwd := fs.Bool("plain-pwd", false, "启动脚本中明文写入 root 密码 (默认只写 SHA-512 哈希)")
+		udFile := fs.String("user-data-file", "", "启动脚本文件")
+		keyPair := fs.String("key-pair", "", "Lightsail 密钥对名称")
+		tpl := addTemplateFlags(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		t := &LSTemplate{}
+		if tpl.name != "" {
+			base, err := findLSTemplate(tpl.file, tpl.name)
+			if err != nil {
+				return err
+			}
+			t = base
+		}
+		use := tpl.use(fs)
+		if use("region") {
+			t.Region = *region
+		}
+		if use("az") {
+			t.AZ = *az
+		}
+		if use("bundle") {
+			t.Bundle = *bundle
+		}
+		if use("blueprint") {
+			t.Blueprint = *blueprint
+		}
+		if use("ip-type") {
+			t.IPType = *ipType
+		}
+		if use("open-all") {
+			t.OpenAll = *openAll
+		}
+		if use("root-pwd") {
+			t.RootPwd = *rootPwd
+		}
+		if use("gen-pwd") {
+			t.GenPwd = *genPwd
+		}
+		if use("plain-pwd") {
+			t.PlainPwd = *plainPwd
+		}
+		if use("user-data-file") {
+			t.UserData, t.UserDataFile = "", *udFile
+		}
+		if use("key-pair") {
+			t.KeyPair = *keyPair
+		}
+		if err := tpl.apply(t); err != nil {
+			return err
+		}
+		if t.Region == "" {
+			t.Region = bootstrapRegion
+		}
+		opts, err := t.launchOptions(t.Region, *name)
+		if err != nil {
+			return err
+		}
+		*region = t.Region
+		creds, err := common.setup(ctx)
+		if err != nil {
+			return err
+		}
+		cfg, err := mkCfg(ctx, *region, creds)
+		if err != nil {
+			return err
+		}
+		return lsLaunch(ctx, newLightsailClient(cfg), opts)
+	case "list":
+		output := addOutputFlag(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if err := checkOutputFormat(*output); err != nil {
+			return err
+		}
+		creds, err := common.setup(ctx)
+		if err != nil {
+			return err
+		}
+		regions, err := cliLSRegions(ctx, creds, *region)
+		if err != nil {
+			return err
+		}
+		rows, _ := lsListAll(ctx, regions, creds)
+		return writeReport(os.Stdout, *output, lsReport(rows))
+	case "regions":
+		output := addOutputFlag(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if err := checkOutputFormat(*output); err != nil {
+			return err
+		}
+		creds, err := common.setup(ctx)
+		if err != nil {
+			return err
+		}
+		names, err := getLightsailRegions(ctx, creds)
+		if err != nil {
+			return err
+		}
+		var rs []RegionInfo
+		for _, n := range names {
+			rs = append(rs, RegionInfo{Name: n, Status: "available"})
+		}
+		return writeReport(os.Stdout, *output, regionReport(rs))
+	case "control":
+		name := fs.String("name", "", "实例名称")
+		action := fs.String("action", "", "start|stop|reboot|delete")
+		force := fs.Bool("yes", false, "删除时跳过确认")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *name == "" || *action == "" {
+			return errors.New("必须指定 --name 与 --action")
+		}
+		if *action == "delete" && !*force {
+			return errors.New("删除实例需要加 --yes 确认")
+		}
+		creds, err := common.setup(ctx)
+		if err != nil {
+			return err
+		}
+		target, err := cliLSInstanceRegion(ctx, creds, *region, *name)
+		if err != nil {
+			return err
+		}
+		cfg, err := mkCfg(ctx, target, creds)
+		if err != nil {
+			return err
+		}
+		if err := lsAction(ctx, newLightsailClient(cfg), *name, *action); err != nil {
+			return err
+		}
+		fmt.Println(actionDoneMsg[*action])
+		return nil
+	case "ip-type":
+		name := fs.String("name", "", "实例名称")
+		ipType := fs.String("type", "", "dualstack (开启 IPv6) | ipv4 (关闭 IPv6) | ipv6 (IPv6-only)")
+		force := fs.Bool("yes", false, "进出 IPv6-only 会更换套餐，需要加 --yes 确认")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *name == "" || *ipType == "" {
+			return errors.New("必须指定 --name 与 --type")
+		}
+		want, err := parseLSIPType(*ipType)
+		if err != nil {
+			return err
+		}
+		creds, err := common.setup(ctx)
+		if err != nil {
+			return err
+		}
+		target, err := cliLSInstanceRegion(ctx, creds, *region, *name)
+		if err != nil {
+			return err
+		}
+		cfg, err := mkCfg(ctx, target, creds)
+		if err != nil {
+			return err
+		}
+		if err := cliLSSetIPType(ctx, newLightsailClient(cfg), *name, want, *force); err != nil {
+			return err
+		}
+		fmt.Printf("✅ %s 已切换为%s\n", *name, lsIPTypeName(want))
+		return nil
+	}
+	return fmt.Errorf("未知子命令: ls %s", args[0])
+}
+
+// cliLSInstanceRegion 未指定区域时在所有区域中查找 Lightsail 实例所在区域
+func cliLSInstanceRegion(ctx context.Context, creds aws.CredentialsProvider, region, name string) (string, error) {
+	if region != "" {
+		return region, nil
+	}
+	regions, err := cliLSRegions(ctx, creds, "")
+	if err != nil {
+		return "", err
+	}
+	rows, _ := lsListAll(ctx, regions, creds)
+	for _, r := range rows {
+		if r.Name == name {
+			return r.Region, nil
+		}
+	}
+	return "", fmt.Errorf("未找到实例 %s", name)
+}
+
+// cliLSFirewall 实现 ls firewall list|export|open|close|import|preset
+func cliLSFirewall(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("缺少子命令: list|export|open|close|import|preset")
+	}
+	fs := flag.NewFlagSet("ls firewall "+args[0], flag.ContinueOnError)
+	common := addCommonFlags(fs)
+	region := fs.String("region", "", "区域 (默认自动查找)")
+	names := fs.String("name", "", "实例名称；import / preset 可逗号分隔多个")
+	port := fs.String("port", "22", "端口规则 (22、80/tcp、1000-2000/udp、icmp、all)")
+	source := fs.String("source", "", "来源，逗号分隔 (CIDR、IP、my、lightsail-connect)；open 默认 0.0.0.0/0,::/0，close 留空关闭整条规则")
+	file := fs.String("file", "", "export 写入 / import 读取的规则文件 (.yaml 或 .json)")
+	preset := fs.String("preset", "", "预设名称 (ssh、web、ssh-web、ssh-myip)")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+	targets := splitList(*names)
+	if len(targets) == 0 {
+		return errors.New("必须指定 --name")
+	}
+	if len(targets) > 1 && args[0] != "import" && args[0] != "preset" {
+		return fmt.Errorf("ls firewall %s 只能指定一个实例", args[0])
+	}
+	// 先解析本地输入，避免凭证校验后才报参数错误
+	var rules []LSFirewallRule
+	var err error
+	switch args[0] {
+	case "import":
+		if *file == "" {
+			return errors.New("必须指定 --file")
+		}
+		rules, err = loadLSFirewall(*file)
+	case "preset":
+		_, err = findSGPreset(*preset)
+	case "open", "close", "list", "export":
+	default:
+		return fmt.Errorf("未知子命令: ls firewall %s", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	creds, err := common.setup(ctx)
+	if err != nil {
+		return err
+	}
+	if args[0] == "preset" {
+		p, _ := findSGPreset(*preset)
+		if rules, err = lsPresetRules(ctx, p); err != nil {
+			return err
+		}
+	}
+	for _, name := range targets {
+		target, err := cliLSInstanceRegion(ctx, creds, *region, name)
+		if err != nil {
+			return err
+		}
+		cfg, err := mkCfg(ctx, target, creds)
+		if err != nil {
+			return err
+		}
+		cli := newLightsailClient(cfg)
+		switch args[0] {
+		case "list", "export":
+			cur, err := lsFirewallRules(ctx, cli, name)
+			if err != nil {
+				return err
+			}
+			if args[0] == "list" {
+				return writeReport(os.Stdout, *output, lsFirewallReport(cur))
+			}
+			if *file == "" {
+				return writeReport(os.Stdout, "yaml", lsFirewallReport(cur))
+			}
+			if err := saveReport(*file, lsFirewallReport(cur)); err != nil {
+				return err
+			}
+			fmt.Printf("✅ 已导出 %d 条规则到 %s\n", len(cur), *file)
+		case "open":
+			sources, err := resolveSources(ctx, splitList(orDefault(*source, "0.0.0.0/0,::/0")))
+			if err != nil {
+				return err
+			}
+			add, err := newLSRules(*port, sources)
+			if err != nil {
+				return err
+			}
+			cur, err := lsFirewallRules(ctx, cli, name)
+			if err != nil {
+				return err
+			}
+			if err := lsOpenRules(ctx, cli, name, cur, add); err != nil {
+				return err
+			}
+			fmt.Printf("✅ %s 已开放 %s ← %s\n", name, *port, strings.Join(sources, ", "))
+		case "close":
+			if err := cliLSClose(ctx, cli, name, *port, splitList(*source)); err != nil {
+				return err
+			}
+			fmt.Printf("✅ %s 已关闭 %s\n", name, *port)
+		case "import", "preset":
+			if err := lsPutRules(ctx, cli, name, rules); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			fmt.Printf("✅ %s (%s) 已写入 %d 条规则\n", name, target, len(rules))
+		}
+	}
+	return nil
+}
+
+// cliLSClose 关闭与 spec 端口相同的规则；sources 非空时只删除这些来源
+func cliLSClose(ctx context.Context, cli LightsailAPI, name, spec string, sources []string) error {
+	keys, err := newLSRules(spec, []string{"0.0.0.0/0", "::/0"})
+	if err != nil {
+		return err
+	}
+	closed := 0
+	for _, k := range keys {
+		cur, err := lsFirewallRules(ctx, cli, name)
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(cur, func(r LSFirewallRule) bool { return r.key() == k.key() })
+		if i < 0 {
+			continue
+		}
+		if len(sources) > 0 {
+			err = lsRemoveSources(ctx, cli, name, cur, i, sources)
+		} else {
+			err = lsCloseRule(ctx, cli, name, cur[i])
+		}
+		if err != nil {
+			return err
+		}
+		closed++
+	}
+	if closed == 0 {
+		return fmt.Errorf("%s 没有 %s 的规则", name, spec)
+	}
+	return nil
+}
+
+// cliRotateIP 实现 ec2 rotate-ip / ls rotate-ip
+func cliRotateIP(ctx context.Context, service string, args []string) error {
+	fs := flag.NewFlagSet(service+" rotate-ip", flag.ContinueOnError)
+	common := addCommonFlags(fs)
+	region := fs.String("region", "", "区域 (默认自动查找)")
+	ids := fs.String("id", "", "EC2 实例 ID，逗号分隔多个")
+	names := fs.String("name", "", "Lightsail 实例名称，逗号分隔多个")
+	method := fs.String("method", rotateRestart, "EC2 更换方式 restart (停止再启动) | eip (新弹性 IP 替换并释放旧的)")
+	probe := fs.Int("probe", 0, "更换后检测的 TCP 端口 (0 不检测)")
+	concurrency := fs.Int("concurrency", 4, "并发数")
+	force := fs.Bool("yes", false, "跳过确认 (原公网 IP 会被释放)")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+	targets, flagName := splitList(*ids), "--id"
+	if service == "lightsail" {
+		targets, flagName = splitList(*names), "--name"
+		*method = "static"
+	} else if *method != rotateRestart && *method != rotateEIP {
+		return fmt.Errorf("未知方式: %s (可选 restart/eip)", *method)
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("必须指定 %s", flagName)
+	}
+	if *probe < 0 || *probe > 65535 {
+		return errors.New("--probe 端口无效")
+	}
+	if !*force {
+		return errors.New("更换后原公网 IP 将被释放，需要加 --yes 确认")
+	}
+	creds, err := common.setup(ctx)
+	if err != nil {
+		return err
+	}
+	// 未指定区域时只扫描一次所有区域
+	where := map[string]string{}
+	if *region == "" {
+		if service == "ec2" {
+			regions, err := cliEC2Regions(ctx, creds, "")
+			if err != nil {
+				return err
+			}
+			rows, _ := ec2ListAll(ctx, regions, creds)
+			for _, r := range rows {
+				where[r.ID] = r.Region
+			}
+		} else {
+			regions, err := cliLSRegions(ctx, creds, "")
+			if err != nil {
+				return err
+			}
+			rows, _ := lsListAll(ctx, regions, creds)
+			for _, r := range rows {
+				where[r.Name] = r.Region
+			}
+		}
+	}
+	var jobs []RotateJob
+	for _, t := range targets {
+		r := orDefault(*region, where[t])
+		if r == "" {
+			return fmt.Errorf("未找到实例 %s", t)
+		}
+		jobs = append(jobs, RotateJob{Service: service, Region: r, Instance: t})
+	}
+	results := rotateAll(ctx, jobs, creds, *method, *probe, *concurrency)
+	if err := writeReport(os.Stdout, *output, rotateReport(results)); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if r.Error != "" {
+			return errors.New("部分实例更换失败")
+		}
+	}
+	return nil
+}
+
+func cliQuota(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("quota", flag.ContinueOnError)
+	common := addCommonFlags(fs)
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+	creds, err := common.setup(ctx)
+	if err != nil {
+		return err
+	}
+	v, err := getVCPUQuota(ctx, creds)
+	if err != nil {
+		return err
+	}
+	rows := []QuotaRow{{Service: "ec2", Code: vcpuQuotaCode, Name: "Running On-Demand Standard instances (vCPU)", Value: v}}
+	return writeReport(os.Stdout, *output, quotaReport(rows))
+}
+
+// batchSource 为 batch / health 共用的账户来源参数
+type batchSource struct {
+	vault                  bool
+	labels, profiles, file string
+}
+
+func addBatchSourceFlags(fs *flag.FlagSet) *batchSource {
+	s := &batchSource{}
+	fs.BoolVar(&s.vault, "vault", false, "使用本地加密保险库中的账户 (口令可由 AWS_TOOL_VAULT_PASS 提供)")
+	fs.StringVar(&s.labels, "accounts", "", "仅处理保险库中的这些标签，逗号分隔 (默认全部)")
+	fs.StringVar(&s.profiles, "profiles", "", "使用这些 profile，逗号分隔")
+	fs.StringVar(&s.file, "file", "", "AK/SK 列表文件，每行: [标签] AK SK [代理]")
+	return s
+}
+
+// load 按参数载入账户；来源为保险库时一并返回已解锁的保险库
+func (s *batchSource) load(ctx context.Context) ([]BatchAccount, *Vault, error) {
+	var (
+		accounts []BatchAccount
+		vault    *Vault
+		err      error
+	)
+	switch {
+	case s.vault:
+		if vault, err = unlockVault(); err == nil {
+			accounts, err = vaultBatchAccounts(vault, splitList(s.labels))
+		}
+	case s.profiles != "":
+		accounts, err = profileBatchAccounts(ctx, splitList(s.profiles))
+	case s.file != "":
+		accounts, err = loadAccountFile(s.file)
+	default:
+		return nil, nil, errors.New("必须指定 --vault、--profiles 或 --file 之一")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, nil, errors.New("没有可用账户")
+	}
+	return accounts, vault, nil
+}
+
+func cliBatch(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	op := fs.String("op", "sts", "操作 "+strings.Join(batchOps, "|"))
+	src := addBatchSourceFlags(fs)
+	conc := fs.Int("concurrency", 4, "同时处理的账户数")
+	proxy := fs.String("proxy", "", "未绑定代理的账户使用的代理")
+	pool := fs.String("proxy-pool", "", "代理列表文件，按账户分配代理并在失效时切换")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+	valid := false
+	for _, o := range batchOps {
+		valid = valid || o == *op
+	}
+	if !valid {
+		return fmt.Errorf("不支持的操作: %s (可选 %s)", *op, strings.Join(batchOps, "|"))
+	}
+	if *proxy != "" {
+		GlobalProxy = parseProxyString(*proxy)
+	}
+	if *pool != "" {
+		if err := setupProxyPool(ctx, *pool); err != nil {
+			return err
+		}
+	}
+	accounts, vault, err := src.load(ctx)
+	if err != nil {
+		return err
+	}
+	res := runBatch(ctx, accounts, *op, *conc)
+	markBatchVerified(vault, res.verifiedLabels(accounts))
+	return printBatchResult(*op, *output, res)
+}
+
+func cliHealth(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("health", flag.ContinueOnError)
+	src := addBatchSourceFlags(fs)
+	conc := fs.Int("concurrency", 4, "同时处理的账户数")
+	proxy := fs.String("proxy", "", "未绑定代理的账户使用的代理")
+	pool := fs.String("proxy-pool", "", "代理列表文件，按账户分配代理并在失效时切换")
+	report := fs.String("report", "", "同时把报告写入文件，格式按扩展名 (.csv/.json/.yaml)")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+	if *proxy != "" {
+		GlobalProxy = parseProxyString(*proxy)
+	}
+	if *pool != "" {
+		if err := setupProxyPool(ctx, *pool); err != nil {
+			return err
+		}
+	}
+	accounts, vault, err := src.load(ctx)
+	if err != nil {
+		return err
+	}
+	rows := runHealth(ctx, accounts, *conc)
+	markBatchVerified(vault, healthyLabels(rows))
+	if *report != "" {
+		if err := saveReport(*report, healthReport(rows)); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "✅ 报告已保存:", *report)
+	}
+	return writeReport(os.Stdout, *output, healthReport(rows))
+}
+
+func cliKey(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("缺少子命令: gen|import|list|delete")
+	}
+	fs := flag.NewFlagSet("key "+args[0], flag.ContinueOnError)
+	name := fs.String("name", "", "密钥名称")
+	if args[0] == "gen" {
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		pub, err := generateKey(*name)
+		if err != nil {
+			return err
+		}
+		fmt.Println("✅ 私钥已保存:", keyPath(*name))
+		fmt.Println(pub)
+		return nil
+	}
+	common := addCommonFlags(fs)
+	region := fs.String("region", "", "区域，逗号分隔 (默认全部)")
+	service := fs.String("service", "ec2", "ec2|ls")
+	local := fs.Bool("local", false, "同时删除本地私钥")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+	if args[0] != "list" && *name == "" {
+		return errors.New("必须指定 --name")
+	}
+	if args[0] == "delete" && *region == "" {
+		return errors.New("删除密钥对必须指定 --region")
+	}
+	creds, err := common.setup(ctx)
+	if err != nil {
+		return err
+	}
+	var regions []string
+	if *service == "ec2" {
+		regions, err = cliEC2Regions(ctx, creds, *region)
+	} else {
+		regions, err = cliLSRegions(ctx, creds, *region)
+	}
+	if err != nil {
+		return err
+	}
+	stores, err := keyStores(ctx, *service, regions, creds)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "import":
+		return importKeyToRegions(ctx, stores, *name)
+	case "list":
+		rows, errs := listKeyPairs(ctx, stores)
+		for _, e := range errs {
+			fmt.Fprintln(os.Stderr, "⚠️", e)
+		}
+		return writeReport(os.Stdout, *output, keyPairReport(rows))
+	case "delete":
+		if *local {
+			if err := checkKeyName(*name); err != nil {
+				return err
+			}
+		}
+		for _, ks := range stores {
+			if err := ks.Delete(ctx, *name); err != nil {
+				return fmt.Errorf("%s: %v", ks.Region(), err)
+			}
+			fmt.Printf("✅ 已删除 %s %s 中的密钥对 %s\n", ks.Service(), ks.Region(), *name)
+		}
+		if *local {
+			if err := deleteLocalKey(*name); err != nil {
+				return err
+			}
+			fmt.Println("✅ 已删除本地私钥")
+		}
+		return nil
+	}
+	return fmt.Errorf("未知子命令: key %s", args[0])
+}
+
+// cliIP 实现 ip list|release|attach
+func cliIP(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("缺少子命令: list|release|attach")
+	}
+	fs := flag.NewFlagSet("ip "+args[0], flag.ContinueOnError)
+	common := addCommonFlags(fs)
+	region := fs.String("region", "", "区域，逗号分隔 (默认全部)")
+	service := fs.String("service", "all", "ec2|ls|all")
+	orphans := fs.Bool("orphans", false, "list: 只列出未绑定的 IP；release: 释放全部未绑定的 IP")
+	ips := fs.String("ip", "", "IP 或 ID (EIP 分配 ID / 固定 IP 名称)，逗号分隔")
+	instance := fs.String("instance", "", "attach: 目标实例 (EC2 实例 ID 或 Lightsail 实例名称)")
+	force := fs.Bool("yes", false, "release: 确认释放")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+	case "release":
+		if *ips == "" && !*orphans {
+			return errors.New("必须指定 --ip 或 --orphans")
+		}
+		if !*force {
+			return errors.New("释放后 IP 无法找回，需要加 --yes 确认")
+		}
+	case "attach":
+		if *ips == "" || *instance == "" {
+			return errors.New("必须指定 --ip 与 --instance")
+		}
+	default:
+		return fmt.Errorf("未知子命令: ip %s", args[0])
+	}
+	if *service != "all" && *service != "ec2" && *service != "ls" {
+		return fmt.Errorf("未知服务: %s (可选 ec2|ls|all)", *service)
+	}
+	creds, err := common.setup(ctx)
+	if err != nil {
+		return err
+	}
+	var ec2Regions, lsRegions []string
+	if *service != "ls" {
+		if ec2Regions, err = cliEC2Regions(ctx, creds, *region); err != nil {
+			return err
+		}
+	}
+	if *service != "ec2" {
+		if lsRegions, err = cliLSRegions(ctx, creds, *region); err != nil {
+			return err
+		}
+	}
+	rows, errs := listPublicIPs(ctx, ec2Regions, lsRegions, creds)
+	for _, e := range errs {
+		fmt.Fprintln(os.Stderr, "⚠️", e)
+	}
+	switch args[0] {
+	case "list":
+		if *orphans {
+			rows = orphanIPs(rows)
+		}
+		return writeReport(os.Stdout, *output, publicIPReport(rows))
+	case "release":
+		sel := orphanIPs(rows)
+		if *ips != "" {
+			if sel, err = findPublicIPs(rows, splitList(*ips)); err != nil {
+				return err
+			}
+		}
+		if len(sel) == 0 {
+			fmt.Println("✅ 没有未绑定的 IP")
+			return nil
+		}
+		if n := releasePublicIPs(ctx, sel, creds); n > 0 {
+			return fmt.Errorf("%d 个 IP 释放失败", n)
+		}
+		return nil
+	}
+	sel, err := findPublicIPs(rows, splitList(*ips))
+	if err != nil {
+		return err
+	}
+	if len(sel) != 1 {
+		return fmt.Errorf("--ip 匹配到 %d 个 IP，请只指定一个 (可用 --region 缩小范围)", len(sel))
+	}
+	if err := attachPublicIP(ctx, sel[0], *instance, creds); err != nil {
+		return err
+	}
+	fmt.Printf("✅ 已把 %s 绑定到 %s\n", sel[0].IP, *instance)
+	return nil
+}
+
+func cliSSH(ctx context.Context, args []string) error {
+	if len(args) == 0 || (args[0] != "run" && args[0] != "connect") {
+		return errors.New("缺少子命令: run|connect")
+	}
+	fs := flag.NewFlagSet("ssh "+args[0], flag.ContinueOnError)
+	common := addCommonFlags(fs)
+	service := fs.String("service", "ec2", "ec2|ls")
+	region := fs.String("region", bootstrapRegion, "区域")
+	sel := fs.String("targets", "", "实例 ID (EC2) 或名称 (Lightsail)，逗号分隔；all 为区域内全部运行中实例")
+	hosts := fs.String("host", "", "直接指定主机 host[:port]，逗号分隔 (不查询 AWS)")
+	user := fs.String("user", "", "登录用户 (默认按镜像推断，--host 时默认 root)")
+	key := fs.String("key", "", "本地密钥库中的密钥名称")
+	password := fs.String("password", os.Getenv("AWS_TOOL_SSH_PASSWORD"), "root 密码 (无本地私钥时使用)")
+	cmd := fs.String("cmd", "", "要执行的命令 (run)")
+	concurrency := fs.Int("concurrency", 8, "并发数 (run)")
+	strict := fs.Bool("strict", false, "拒绝未记录的主机密钥 (默认首次连接自动记录)")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+	if args[0] == "run" && *cmd == "" {
+		return errors.New("必须指定 --cmd")
+	}
+	var targets []SSHTarget
+	if *hosts != "" {
+		for _, h := range splitList(*hosts) {
+			t := SSHTarget{Label: h, Host: h, User: orDefault(*user, "root"), KeyName: *key}
+			if host, port, err := net.SplitHostPort(h); err == nil {
+				t.Host = host
+				t.Port, _ = strconv.Atoi(port)
+			}
+			targets = append(targets, t)
+		}
+	} else {
+		if *sel == "" {
+			return errors.New("必须指定 --targets 或 --host")
+		}
+		creds, err := common.setup(ctx)
+		if err != nil {
+			return err
+		}
+		if targets, err = cliSSHTargets(ctx, creds, *service, *region, *sel); err != nil {
+			return err
+		}
+		for i := range targets {
+			if *user != "" {
+				targets[i].User = *user
+			}
+			if *key != "" {
+				targets[i].KeyName = *key
+			}
+		}
+	}
+	for i := range targets {
+		if targets[i].KeyName == "" && *password != "" {
+			targets[i].Password = *password
+		}
+	}
+	if len(targets) == 0 {
+		return errors.New("没有可登录的实例")
+	}
+	var confirm func(string, ssh.PublicKey) bool
+	if *strict {
+		confirm = func(string, ssh.PublicKey) bool { return false }
+	}
+	hostKey := pinnedHostKeys(knownHostsPath(), confirm)
+	if args[0] == "connect" {
+		if len(targets) != 1 {
+			return errors.New("connect 只能指定一台主机")
+		}
+		return sshShell(ctx, targets[0], hostKey)
+	}
+	// 结构化输出时命令输出写到 stderr，stdout 只留结果表
+	stream := os.Stdout
+	if *output != "table" {
+		stream = os.Stderr
+	}
+	results := sshRunAll(ctx, targets, *cmd, *concurrency, hostKey, stream)
+	if err := writeReport(os.Stdout, *output, sshResultReport(results)); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if r.Error != "" || r.ExitCode != 0 {
+			return errors.New("部分主机执行失败")
+		}
+	}
+	return nil
+}
+
+func cliProxy(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return errors.New("用法: proxy check --pool proxies.txt")
+	}
+	fs := flag.NewFlagSet("proxy check", flag.ContinueOnError)
+	path := fs.String("pool", "", "代理列表文件，每行一个")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return err
+	}
+	pool, err := loadProxyPool(*path)
+	if err != nil {
+		return err
+	}
+	pool.CheckAll(ctx)
+	return writeReport(os.Stdout, *output, proxyPoolReport(pool))
+}
+
+func cliUserData(ctx context.Context, args []string) error {
+	if len(args) == 0 || (args[0] != "snippets" && args[0] != "render") {
+		return errors.New("缺少子命令: snippets|render")
+	}
+	fs := flag.NewFlagSet("userdata "+args[0], flag.ContinueOnError)
+	output := addOutputFlag(fs)
+	service := fs.String("service", "ec2", "ec2|ls，决定组合方式与大小上限")
+	file := fs.String("file", "", "自定义 user-data 文件")
+	rootPwd := fs.String("root-pwd", "", "加入设置 root 密码的片段")
+	plainPwd := fs.Bool("plain-pwd", false, "明文写入 root 密码 (默认只写 SHA-512 哈希)")
+	var specs []string
+	vars := map[string]string{}
+	fs.Func("snippet", "片段，可重复 (如 docker、swap:SIZE=2G)", func(v string) error {
+		specs = append(specs, v)
+		return nil
+	})
+	fs.Func("var", "变量 KEY=VALUE，可重复", func(v string) error {
+		k, val, ok := strings.Cut(v, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("格式应为 KEY=VALUE: %q", v)
+		}
+		vars[k] = val
+		return nil
+	})
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if args[0] == "snippets" {
+		if err := checkOutputFormat(*output); err != nil {
+			return err
+		}
+		return writeReport(os.Stdout, *output, snippetReport())
+	}
+	raw, err := readUserDataFile(*file)
+	if err != nil {
+		return err
+	}
+	var doc string
+	pwd, plain := resolveRootPassword(*rootPwd, false, *plainPwd)
+	switch *service {
+	case "ec2":
+		doc, err = buildEC2UserData(EC2LaunchOptions{RootPwd: pwd, PlainPwd: plain, Snippets: specs, UserData: raw, UserDataVars: vars})
+	case "ls", "lightsail":
+		var parts []UserDataPart
+		specs, vars = withRootPassword(pwd, plain, specs, vars)
+		if parts, err = renderUserData(specs, raw, vars); err == nil {
+			doc, err = lsUserDataDoc(parts)
+		}
+	default:
+		return fmt.Errorf("未知服务: %s (可选 ec2|ls)", *service)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Print(doc)
+	limit := ec2UserDataLimit
+	if *service != "ec2" {
+		limit = lsUserDataLimit
+	}
+	fmt.Fprintf(os.Stderr, "✅ 共 %s / 上限 %s\n", byteSize(len(doc)), byteSize(limit))
+	return nil
+}
+
+func cliPasswd(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("缺少子命令: gen|hash|list|policy")
+	}
+	fs := flag.NewFlagSet("passwd "+args[0], flag.ContinueOnError)
+	policy := currentPasswordPolicy()
+	switch args[0] {
+	case "gen":
+		length := fs.Int("length", policy.Length, "长度")
+		noSymbols := fs.Bool("no-symbols", false, "不含符号")
+		count := fs.Int("count", 1, "生成数量")
+		hash := fs.Bool("hash", false, "同时输出 SHA-512 crypt 哈希")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		policy.Length = *length
+		if *noSymbols {
+			policy.Symbols = ""
+		}
+		for range *count {
+			pwd := generatePassword(policy)
+			if *hash {
+				fmt.Printf("%s\t%s\n", pwd, cryptPassword(pwd))
+			} else {
+				fmt.Println(pwd)
+			}
+		}
+		return nil
+	case "hash":
+		pwd := fs.String("password", "", "明文密码 (默认从标准输入读取一行)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *pwd == "" {
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				return errors.New("没有输入密码")
+			}
+			*pwd = strings.TrimRight(line, "\r\n")
+		}
+		fmt.Println(cryptPassword(*pwd))
+		return nil
+	case "list":
+		show := fs.Bool("show", false, "显示明文密码")
+		output := addOutputFlag(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if err := checkOutputFormat(*output); err != nil {
+			return err
+		}
+		recs, err := loadCredentials()
+		if err != nil {
+			return err
+		}
+		return writeReport(os.Stdout, *output, credentialReport(recs, *show))
+	case "policy":
+		b, err := yaml.Marshal(policy)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# %s\n%s", passwordPolicyPath(), b)
+		return nil
+	}
+	return fmt.Errorf("未知子命令: passwd %s", args[0])
+}
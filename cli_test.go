@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestSnippetFlagRejectsUnknownWithoutRecording(t *testing.T) {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	tf := addTemplateFlags(fs)
+	if err := fs.Parse([]string{"--snippet", "no-such-snippet"}); err == nil {
+		t.Fatal("未知片段应报错")
+	}
+	if len(tf.snippets) != 0 {
+		t.Fatalf("校验失败的片段不应记录: %v", tf.snippets)
+	}
+	if err := fs.Parse([]string{"--snippet", "docker"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(tf.snippets) != 1 {
+		t.Fatalf("应记录一个片段: %v", tf.snippets)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+)
+
+// -------------------- 凭证来源 (Profile / 默认凭证链 / 静态 AK/SK) --------------------
+
+func sharedConfigPaths() []string {
+	credFile := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if credFile == "" {
+		credFile = config.DefaultSharedCredentialsFilename()
+	}
+	cfgFile := os.Getenv("AWS_CONFIG_FILE")
+	if cfgFile == "" {
+		cfgFile = config.DefaultSharedConfigFilename()
+	}
+	return []string{credFile, cfgFile}
+}
+
+// listProfiles 读取 ~/.aws/credentials 与 ~/.aws/config 中的 profile 名称
+func listProfiles() []string {
+	seen := map[string]bool{}
+	for _, path := range sharedConfigPaths() {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+				continue
+			}
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if strings.HasPrefix(name, "sso-session ") || strings.HasPrefix(name, "services ") {
+				continue
+			}
+			name = strings.TrimSpace(strings.TrimPrefix(name, "profile "))
+			if name != "" {
+				seen[name] = true
+			}
+		}
+		f.Close()
+	}
+	var out []string
+	for n := range seen {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// loadChainCreds 通过 SDK 标准凭证链加载凭证：profile 为空时依次尝试环境变量、
+// 默认 profile、credential_process、SSO、实例角色等；指定 profile 时只读取该 profile
+func loadChainCreds(ctx context.Context, profile string) (aws.CredentialsProvider, error) {
+	opts := []func(*config.LoadOptions) error{
+		config.WithRegion(bootstrapRegion),
+		config.WithAssumeRoleCredentialOptions(func(o *stscreds.AssumeRoleOptions) {
+			o.TokenProvider = func() (string, error) {
+				return input("MFA 验证码: ", ""), nil
+			}
+		}),
+	}
+	if profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(profile))
+	}
+	hc, err := proxyHTTPClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if hc != nil {
+		opts = append(opts, config.WithHTTPClient(hc))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Credentials == nil {
+		return nil, errors.New("未找到可用凭证")
+	}
+	return cfg.Credentials, nil
+}
+
+func staticCreds(ak, sk, token string) aws.CredentialsProvider {
+	return credentials.NewStaticCredentialsProvider(ak, sk, token)
+}
+
+// pickCredentials 交互式选择凭证来源，返回凭证与用于显示的账户标签
+func pickCredentials(ctx context.Context) (aws.CredentialsProvider, string, error) {
+	profiles := listProfiles()
+	fmt.Println("\n请选择凭证来源:")
+	fmt.Println(" 1) 手动输入 AK/SK [默认]")
+	fmt.Printf(" 2) AWS 配置文件 Profile (已发现 %d 个)\n", len(profiles))
+	fmt.Println(" 3) 默认凭证链 (环境变量 / credential_process / SSO / 实例角色)")
+	fmt.Println(" 4) 🔐 本地加密保险库")
+	switch input("选择 [1]: ", "1") {
+	case "2":
+		if len(profiles) == 0 {
+			return nil, "", errors.New("未在 ~/.aws/credentials 或 ~/.aws/config 中找到 profile")
+		}
+		fmt.Println("\n--- Profile 列表 ---")
+		for i, p := range profiles {
+			fmt.Printf("  %2d) %s\n", i+1, p)
+		}
+		idx := mustInt(input("请输入编号 [1]: ", "1"))
+		if idx < 1 || idx > len(profiles) {
+			return nil, "", fmt.Errorf("编号无效")
+		}
+		creds, err := loadChainCreds(ctx, profiles[idx-1])
+		return creds, "profile:" + profiles[idx-1], err
+	case "3":
+		creds, err := loadChainCreds(ctx, "")
+		return creds, "默认凭证链", err
+	case "4":
+		a, err := vaultMenu()
+		if err != nil {
+			return nil, "", err
+		}
+		if a == nil {
+			return nil, "", errors.New("未选择账户")
+		}
+		creds, label := vaultAccountCreds(a)
+		return creds, label, nil
+	}
+	ak := input("AWS Access Key ID: ", "")
+	sk := inputSecret("AWS Secret Access Key: ")
+	if ak == "" || sk == "" {
+		return nil, "", errors.New("AK/SK 不能为空")
+	}
+	token := inputSecret("AWS Session Token (可留空): ")
+	if token == "" && yes(input("是否保存到本地加密保险库? [y/N]: ", "n")) {
+		if err := saveToVault(ak, sk); err != nil {
+			fmt.Println("⚠️ 保存失败:", err)
+		}
+	}
+	return staticCreds(ak, sk, token), ak, nil
+}
+
+func saveToVault(ak, sk string) error {
+	v, err := unlockVault()
+	if err != nil {
+		return err
+	}
+	label := input("账户标签: ", ak)
+	if v.Find(label) != nil {
+		return fmt.Errorf("标签 %s 已存在", label)
+	}
+	v.Accounts = append(v.Accounts, VaultAccount{Label: label, AK: ak, SK: sk})
+	if err := v.Save(); err != nil {
+		return err
+	}
+	fmt.Println("✅ 已保存到保险库")
+	return nil
+}
+
+const vaultLabelPrefix = "vault:"
+
+// vaultAccountCreds 切换到保险库中的账户，并应用其绑定的代理
+func vaultAccountCreds(a *VaultAccount) (aws.CredentialsProvider, string) {
+	label := vaultLabelPrefix + a.Label
+	if activePool != nil {
+		if a.Proxy != "" {
+			activePool.Pin(label, parseProxyString(a.Proxy))
+		}
+		return staticCreds(a.AK, a.SK, ""), label
+	}
+	if a.Proxy != "" {
+		GlobalProxy = parseProxyString(a.Proxy)
+	} else {
+		GlobalProxy = userProxy
+	}
+	if GlobalProxy != "" {
+		fmt.Println("🔄 使用代理:", maskProxy(GlobalProxy))
+	}
+	return staticCreds(a.AK, a.SK, ""), label
+}
+
+// -------------------- AssumeRole (跨账户 / 角色链) --------------------
+
+// RoleSpec 描述一次 AssumeRole；多个 RoleSpec 依次扮演即为角色链
+type RoleSpec struct {
+	ARN         string
+	ExternalID  string
+	MFASerial   string
+	TokenCode   string // 首次调用使用，凭证到期刷新时会重新提示输入
+	SessionName string
+	Duration    time.Duration
+}
+
+// mfaTokenProvider 先返回预先给出的验证码，之后每次刷新都提示输入新的验证码
+func mfaTokenProvider(code string) func() (string, error) {
+	return func() (string, error) {
+		if code != "" {
+			c := code
+			code = ""
+			return c, nil
+		}
+		return input("MFA 验证码: ", ""), nil
+	}
+}
+
+// assumeRoleCreds 以 base 凭证扮演 spec 指定的角色，返回带缓存的临时凭证，到期前自动刷新
+func assumeRoleCreds(ctx context.Context, base aws.CredentialsProvider, spec RoleSpec) (aws.CredentialsProvider, error) {
+	if !strings.HasPrefix(spec.ARN, "arn:") {
+		return nil, fmt.Errorf("角色 ARN 无效: %s", spec.ARN)
+	}
+	cfg, err := mkCfg(ctx, bootstrapRegion, base)
+	if err != nil {
+		return nil, err
+	}
+	provider := stscreds.NewAssumeRoleProvider(newSTSClient(cfg), spec.ARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = spec.SessionName
+		if o.RoleSessionName == "" {
+			o.RoleSessionName = fmt.Sprintf("aws-tool-%d", time.Now().Unix())
+		}
+		if spec.ExternalID != "" {
+			o.ExternalID = aws.String(spec.ExternalID)
+		}
+		if spec.MFASerial != "" {
+			o.SerialNumber = aws.String(spec.MFASerial)
+			o.TokenProvider = mfaTokenProvider(spec.TokenCode)
+		}
+		if spec.Duration > 0 {
+			o.Duration = spec.Duration
+		}
+	})
+	creds := aws.NewCredentialsCache(provider)
+	// 立即取一次凭证，尽早暴露 ARN 错误、信任策略或 MFA 问题
+	if _, err := creds.Retrieve(ctx); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// assumeRoleChain 依次扮演 specs 中的角色，每一跳都以上一跳的临时凭证为基础
+func assumeRoleChain(ctx context.Context, base aws.CredentialsProvider, specs []RoleSpec) (aws.CredentialsProvider, error) {
+	creds := base
+	for i, spec := range specs {
+		next, err := assumeRoleCreds(ctx, creds, spec)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 跳 %s: %v", i+1, spec.ARN, err)
+		}
+		creds = next
+	}
+	return creds, nil
+}
+
+// roleShortName 取 ARN 中的角色名与账户 ID，用于显示
+func roleShortName(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 6 {
+		return arn
+	}
+	return parts[4] + "/" + strings.TrimPrefix(parts[5], "role/")
+}
+
+// pickRole 交互式输入 AssumeRole 参数；ARN 留空时返回 nil
+func pickRole() *RoleSpec {
+	arn := input("角色 ARN (arn:aws:iam::<账户ID>:role/<名称>): ", "")
+	if arn == "" {
+		return nil
+	}
+	spec := &RoleSpec{ARN: arn}
+	spec.ExternalID = input("External ID (可留空): ", "")
+	spec.MFASerial = input("MFA 设备 ARN (可留空): ", "")
+	if spec.MFASerial != "" {
+		spec.TokenCode = input("MFA 验证码: ", "")
+	}
+	return spec
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// -------------------- 进程内代理服务器 --------------------
+
+// testProxy 为测试用的 SOCKS5 / HTTP CONNECT 代理：校验认证后把所有连接转发到 backend，
+// 并记录客户端请求的目标地址 (SOCKS5 中域名原样记录，IP 记录为字面量)
+type testProxy struct {
+	ln         net.Listener
+	backend    string
+	user, pass string
+
+	mu      sync.Mutex
+	targets []string
+}
+
+func startTestProxy(t *testing.T, backend, user, pass string, handle func(*testProxy, net.Conn)) *testProxy {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testProxy{ln: ln, backend: backend, user: user, pass: pass}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				handle(p, c)
+			}()
+		}
+	}()
+	return p
+}
+
+func (p *testProxy) addr() string { return p.ln.Addr().String() }
+
+func (p *testProxy) record(target string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.targets = append(p.targets, target)
+}
+
+func (p *testProxy) seen() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.targets...)
+}
+
+// pipe 连接 backend 并双向转发；r 为已读过请求头的客户端读端
+func (p *testProxy) pipe(c net.Conn, r io.Reader, ok func()) {
+	up, err := net.Dial("tcp", p.backend)
+	if err != nil {
+		return
+	}
+	defer up.Close()
+	ok()
+	go io.Copy(up, r)
+	io.Copy(c, up)
+}
+
+// handleSOCKS5 实现 RFC 1928 的 CONNECT 与 RFC 1929 用户名密码认证
+func handleSOCKS5(p *testProxy, c net.Conn) {
+	r := bufio.NewReader(c)
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(r, hdr); err != nil || hdr[0] != 5 {
+		return
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(r, methods); err != nil {
+		return
+	}
+	method := byte(0)
+	if p.user != "" {
+		method = 2
+	}
+	if !bytes.Contains(methods, []byte{method}) {
+		c.Write([]byte{5, 0xff})
+		return
+	}
+	c.Write([]byte{5, method})
+	if method == 2 {
+		readField := func() string {
+			n, _ := r.ReadByte()
+			b := make([]byte, n)
+			io.ReadFull(r, b)
+			return string(b)
+		}
+		r.ReadByte() // 子协商版本
+		user := readField()
+		pass := readField()
+		if user != p.user || pass != p.pass {
+			c.Write([]byte{1, 1})
+			return
+		}
+		c.Write([]byte{1, 0})
+	}
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(r, req); err != nil || req[1] != 1 {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1, 4:
+		ip := make([]byte, map[byte]int{1: 4, 4: 16}[req[3]])
+		io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	case 3:
+		n, _ := r.ReadByte()
+		name := make([]byte, n)
+		io.ReadFull(r, name)
+		host = string(name)
+	default:
+		return
+	}
+	port := make([]byte, 2)
+	io.ReadFull(r, port)
+	p.record(net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+	p.pipe(c, r, func() { c.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}) })
+}
+
+// handleCONNECT 实现带 Basic 认证的 HTTP CONNECT 代理
+func handleCONNECT(p *testProxy, c net.Conn) {
+	r := bufio.NewReader(c)
+	req, err := http.ReadRequest(r)
+	if err != nil {
+		return
+	}
+	if req.Method != http.MethodConnect {
+		io.WriteString(c, "HTTP/1.1 405 Method Not Allowed\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte(p.user+":"+p.pass))
+	if req.Header.Get("Proxy-Authorization") != want {
+		io.WriteString(c, "HTTP/1.1 407 Proxy Authentication Required\r\nProxy-Authenticate: Basic realm=\"test\"\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+	p.record(req.Host)
+	p.pipe(c, r, func() { io.WriteString(c, "HTTP/1.1 200 Connection established\r\n\r\n") })
+}
+
+// fetch 经 sel 选择的代理请求 target，返回响应正文
+func fetch(t *testing.T, sel proxySelector, target string, tlsCfg *tls.Config) (string, error) {
+	t.Helper()
+	tr := newProxyTransport(sel)
+	tr.TLSClientConfig = tlsCfg
+	tr.DisableKeepAlives = true
+	cli := &http.Client{Transport: tr, Timeout: 5 * time.Second}
+	resp, err := cli.Get(target)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	return string(b), err
+}
+
+func newBackend(t *testing.T, tlsOn bool) *httptest.Server {
+	t.Helper()
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "ok") })
+	srv := httptest.NewUnstartedServer(h)
+	if tlsOn {
+		srv.StartTLS()
+	} else {
+		srv.Start()
+	}
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func mustProxyURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := parseProxyURL(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// -------------------- SOCKS5 --------------------
+
+func TestSOCKS5ResolvesLocally(t *testing.T) {
+	t.Setenv("NO_PROXY", "")
+	backend := newBackend(t, false)
+	_, port, _ := net.SplitHostPort(backend.Listener.Addr().String())
+	p := startTestProxy(t, backend.Listener.Addr().String(), "u", "p@ss", handleSOCKS5)
+
+	body, err := fetch(t, staticSelector(mustProxyURL(t, "socks5://u:p%40ss@"+p.addr())), "http://localhost:"+port+"/", nil)
+	if err != nil || body != "ok" {
+		t.Fatalf("socks5 请求失败: %q %v", body, err)
+	}
+	seen := p.seen()
+	if len(seen) != 1 {
+		t.Fatalf("代理收到 %d 个 CONNECT，期望 1 个", len(seen))
+	}
+	host, _, _ := net.SplitHostPort(seen[0])
+	if net.ParseIP(host) == nil {
+		t.Errorf("socks5 应在本地解析域名，代理却收到 %s", seen[0])
+	}
+}
+
+func TestSOCKS5HResolvesRemotely(t *testing.T) {
+	t.Setenv("NO_PROXY", "")
+	backend := newBackend(t, false)
+	_, port, _ := net.SplitHostPort(backend.Listener.Addr().String())
+	p := startTestProxy(t, backend.Listener.Addr().String(), "", "", handleSOCKS5)
+
+	// .invalid 在本地无法解析，只有交给代理才能连通
+	target := "backend.invalid:" + port
+	body, err := fetch(t, staticSelector(mustProxyURL(t, "socks5h://"+p.addr())), "http://"+target+"/", nil)
+	if err != nil || body != "ok" {
+		t.Fatalf("socks5h 请求失败: %q %v", body, err)
+	}
+	if seen := p.seen(); len(seen) != 1 || seen[0] != target {
+		t.Errorf("socks5h 应把域名交给代理，代理收到 %v", seen)
+	}
+}
+
+func TestSOCKS5WrongPassword(t *testing.T) {
+	t.Setenv("NO_PROXY", "")
+	backend := newBackend(t, false)
+	p := startTestProxy(t, backend.Listener.Addr().String(), "u", "right", handleSOCKS5)
+
+	var failed *url.URL
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.DisableKeepAlives = true
+	configureProxy(tr, staticSelector(mustProxyURL(t, "socks5h://u:wrong@"+p.addr())), func(u *url.URL, err error) { failed = u })
+	cli := &http.Client{Transport: tr, Timeout: 5 * time.Second}
+	if _, err := cli.Get("http://backend.invalid/"); err == nil {
+		t.Fatal("密码错误时应返回错误")
+	}
+	if failed == nil || failed.Host != p.addr() {
+		t.Errorf("握手失败时应回调 onFail，得到 %v", failed)
+	}
+	if len(p.seen()) != 0 {
+		t.Errorf("认证失败不应发出 CONNECT: %v", p.seen())
+	}
+}
+
+// -------------------- HTTP CONNECT --------------------
+
+func TestHTTPConnectWithAuth(t *testing.T) {
+	t.Setenv("NO_PROXY", "")
+	backend := newBackend(t, true)
+	tlsCfg := backend.Client().Transport.(*http.Transport).TLSClientConfig
+	p := startTestProxy(t, backend.Listener.Addr().String(), "alice", "s3cret", handleCONNECT)
+
+	// httptest 的证书包含 example.com，代理把连接转发到本地 TLS 后端
+	body, err := fetch(t, staticSelector(mustProxyURL(t, "http://alice:s3cret@"+p.addr())), "https://example.com/", tlsCfg)
+	if err != nil || body != "ok" {
+		t.Fatalf("CONNECT 请求失败: %q %v", body, err)
+	}
+	if seen := p.seen(); len(seen) != 1 || seen[0] != "example.com:443" {
+		t.Errorf("CONNECT 目标应为 example.com:443，得到 %v", seen)
+	}
+
+	if _, err := fetch(t, staticSelector(mustProxyURL(t, "http://alice:wrong@"+p.addr())), "https://example.com/", tlsCfg); err == nil {
+		t.Error("Proxy-Authorization 错误时应返回错误")
+	}
+	if len(p.seen()) != 1 {
+		t.Errorf("认证失败的请求不应建立隧道: %v", p.seen())
+	}
+}
+
+func TestShortProxyFormIsSOCKS5(t *testing.T) {
+	u := mustProxyURL(t, "10.0.0.1:1080:user:pass")
+	if pass, _ := u.User.Password(); u.Scheme != "socks5" || u.Host != "10.0.0.1:1080" || pass != "pass" {
+		t.Errorf("host:port:user:pass 应视为 socks5，得到 %s", u.Redacted())
+	}
+	if _, err := parseProxyURL("ftp://10.0.0.1:21"); err == nil {
+		t.Error("不支持的协议应报错")
+	}
+	if _, err := parseProxyURL("socks5://10.0.0.1"); err == nil {
+		t.Error("缺少端口应报错")
+	}
+}
+
+// -------------------- NO_PROXY --------------------
+
+func TestNoProxyMatch(t *testing.T) {
+	cases := []struct {
+		host, port, rules string
+		want              bool
+	}{
+		{"api.example.com", "443", "*", true},
+		{"api.example.com", "443", "", false},
+		{"api.example.com", "443", "example.com", true},
+		{"api.example.com", "443", ".example.com", true},
+		{"api.example.com", "443", "*.example.com", true},
+		{"example.com", "443", ".example.com", true},
+		{"notexample.com", "443", "example.com", false},
+		{"API.Example.COM.", "443", "example.com", true},
+		{"example.com", "8443", "example.com:8443", true},
+		{"example.com", "443", "example.com:8443", false},
+		{"10.1.2.3", "443", "10.0.0.0/8", true},
+		{"11.0.0.1", "443", "10.0.0.0/8", false},
+		{"10.example.com", "443", "10.0.0.0/8", false},
+		{"192.168.1.5", "443", "192.168.1.5", true},
+		{"192.168.1.6", "443", "192.168.1.5", false},
+		{"::1", "443", "::1", true},
+		{"2600:1f18::5", "443", "2600:1f18::/32", true},
+		{"ec2.us-east-1.amazonaws.com", "443", "localhost, .amazonaws.com", true},
+	}
+	for _, c := range cases {
+		if got := noProxyMatch(c.host, c.port, c.rules); got != c.want {
+			t.Errorf("noProxyMatch(%q, %q, %q) = %v，期望 %v", c.host, c.port, c.rules, got, c.want)
+		}
+	}
+}
+
+func TestSelectorsHonorNoProxy(t *testing.T) {
+	t.Setenv("NO_PROXY", ".internal,10.0.0.0/8")
+	t.Setenv("HTTPS_PROXY", "http://proxy.example:3128")
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("ALL_PROXY", "")
+	p := mustProxyURL(t, "socks5h://127.0.0.1:1080")
+	env, err := envSelector()
+	if err != nil || env == nil {
+		t.Fatalf("envSelector: %v", err)
+	}
+	for _, sel := range []proxySelector{staticSelector(p), env} {
+		for target, direct := range map[string]bool{
+			"https://api.internal/":      true,
+			"https://10.2.3.4/":          true,
+			"https://ec2.amazonaws.com/": false,
+		} {
+			u, _ := url.Parse(target)
+			got, err := sel(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == nil) != direct {
+				t.Errorf("%s: 直连=%v，期望 %v", target, got == nil, direct)
+			}
+		}
+	}
+}
+
+// -------------------- 自检 --------------------
+
+func TestProxySelfTestProxyDown(t *testing.T) {
+	t.Setenv("NO_PROXY", "")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	for _, scheme := range []string{"socks5", "socks5h", "http"} {
+		ctx, cancel := context.WithTimeout(withProxy(context.Background(), scheme+"://"+addr), 10*time.Second)
+		err := proxySelfTest(ctx)
+		cancel()
+		if err == nil || !strings.Contains(err.Error(), "代理连通性检测失败") {
+			t.Errorf("%s 代理不可用时自检应失败，得到 %v", scheme, err)
+		}
+	}
+	if err := proxySelfTest(withProxy(context.Background(), "ftp://"+addr)); err == nil {
+		t.Error("代理地址无效时自检应报错")
+	}
+}
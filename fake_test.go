@@ -0,0 +1,2153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/account"
+	"github.com/aws/aws-sdk-go-v2/service/budgets"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2t "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdaTypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/lightsail"
+	lst "github.com/aws/aws-sdk-go-v2/service/lightsail/types"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdsTypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	sqTypes "github.com/aws/aws-sdk-go-v2/service/servicequotas/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	stsTypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/aws/smithy-go"
+	"golang.org/x/crypto/ssh"
+)
+
+// -------------------- 离线内存后端 (Fake AWS) --------------------
+
+// FakeBackend 在内存中模拟本工具用到的 AWS 操作，供离线测试使用，不会编入正式程序。
+// 每个区域拥有独立的 EC2 / Lightsail 状态；通过 FailNext 可为任意操作预置错误，
+// 用来复现容量不足、权限不足等难以在真实账户中触发的分支。
+type FakeBackend struct {
+	mu sync.Mutex
+
+	AccountID string
+	Regions   []RegionInfo
+	LSRegions []string
+	VCPUQuota float64
+
+	ec2       map[string]*fakeEC2State
+	ls        map[string]*fakeLSState
+	budgets   map[string]bool
+	roles     map[string]bool
+	functions map[string]bool
+	dbs       map[string]string
+	errs      map[string][]error
+	seq       int
+}
+
+type fakeEC2State struct {
+	Instances   []ec2t.Instance
+	Addresses   []ec2t.Address
+	Vpcs        []ec2t.Vpc
+	Subnets     []ec2t.Subnet
+	RouteTables []ec2t.RouteTable
+	Gateways    []ec2t.InternetGateway
+	EgressOnly  []ec2t.EgressOnlyInternetGateway
+	NatGateways []ec2t.NatGateway
+	Groups      []ec2t.SecurityGroup
+	Images      []ec2t.Image
+	Volumes     []ec2t.Volume
+	KeyPairs    []ec2t.KeyPairInfo
+}
+
+type fakeLSState struct {
+	Instances []lst.Instance
+	StaticIPs []lst.StaticIp
+	KeyPairs  []lst.KeyPair
+}
+
+// fakeAPIError 构造与 SDK 返回格式一致的 API 错误
+func fakeAPIError(code, msg string) error {
+	return &smithy.GenericAPIError{Code: code, Message: msg}
+}
+
+func newFakeBackend() *FakeBackend {
+	return &FakeBackend{
+		AccountID: "123456789012",
+		Regions: []RegionInfo{
+			{Name: "ap-east-1", Status: "not-opted-in"},
+			{Name: "ap-northeast-1", Status: "opt-in-not-required"},
+			{Name: "eu-west-1", Status: "opt-in-not-required"},
+			{Name: "us-east-1", Status: "opt-in-not-required"},
+			{Name: "us-west-2", Status: "opt-in-not-required"},
+		},
+		LSRegions: []string{"ap-northeast-1", "eu-west-1", "us-east-1"},
+		VCPUQuota: 32,
+		ec2:       map[string]*fakeEC2State{},
+		ls:        map[string]*fakeLSState{},
+		budgets:   map[string]bool{},
+		roles:     map[string]bool{},
+		functions: map[string]bool{},
+		dbs:       map[string]string{},
+		errs:      map[string][]error{},
+	}
+}
+
+// FailNext 让 op (形如 "ec2:RunInstances") 的下一次调用返回 err，可多次调用排队
+func (b *FakeBackend) FailNext(op string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.errs[op] = append(b.errs[op], err)
+}
+
+// begin 加锁并取出 op 预置的错误；调用方负责 defer b.mu.Unlock()
+func (b *FakeBackend) begin(op string) error {
+	b.mu.Lock()
+	if q := b.errs[op]; len(q) > 0 {
+		b.errs[op] = q[1:]
+		return q[0]
+	}
+	return nil
+}
+
+func (b *FakeBackend) id(prefix string) string {
+	b.seq++
+	return fmt.Sprintf("%s-%017x", prefix, b.seq)
+}
+
+func (b *FakeBackend) publicIP() string {
+	b.seq++
+	return fmt.Sprintf("3.%d.%d.%d", 80+b.seq/65536%100, b.seq/256%256, b.seq%256)
+}
+
+// ec2State 返回区域的 EC2 状态，首次访问时生成默认 VPC (无 IPv6)、子网、路由表、网关与镜像
+func (b *FakeBackend) ec2State(region string) *fakeEC2State {
+	if s, ok := b.ec2[region]; ok {
+		return s
+	}
+	vpcID, subnetID, igwID := b.id("vpc"), b.id("subnet"), b.id("igw")
+	s := &fakeEC2State{
+		Vpcs: []ec2t.Vpc{{VpcId: aws.String(vpcID), CidrBlock: aws.String("172.31.0.0/16"), IsDefault: aws.Bool(true)}},
+		Subnets: []ec2t.Subnet{{
+			SubnetId: aws.String(subnetID), VpcId: aws.String(vpcID), CidrBlock: aws.String("172.31.0.0/20"),
+			AvailabilityZone: aws.String(region + "a"), DefaultForAz: aws.Bool(true), MapPublicIpOnLaunch: aws.Bool(true),
+		}, {
+			SubnetId: aws.String(b.id("subnet")), VpcId: aws.String(vpcID), CidrBlock: aws.String("172.31.16.0/20"),
+			AvailabilityZone: aws.String(region + "b"), DefaultForAz: aws.Bool(true), MapPublicIpOnLaunch: aws.Bool(true),
+		}},
+		RouteTables: []ec2t.RouteTable{{
+			RouteTableId: aws.String(b.id("rtb")), VpcId: aws.String(vpcID),
+			Associations: []ec2t.RouteTableAssociation{{Main: aws.Bool(true), RouteTableAssociationId: aws.String(b.id("rtbassoc"))}},
+			Routes: []ec2t.Route{
+				{DestinationCidrBlock: aws.String("172.31.0.0/16"), GatewayId: aws.String("local")},
+				{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String(igwID)},
+			},
+		}},
+		Gateways: []ec2t.InternetGateway{{
+			InternetGatewayId: aws.String(igwID),
+			Attachments:       []ec2t.InternetGatewayAttachment{{VpcId: aws.String(vpcID), State: ec2t.AttachmentStatusAttached}},
+		}},
+	}
+	// 默认安全组：允许组内互访与全部出站
+	sgID := b.id("sg")
+	s.Groups = []ec2t.SecurityGroup{{
+		GroupId: aws.String(sgID), GroupName: aws.String("default"), Description: aws.String("default VPC security group"), VpcId: aws.String(vpcID),
+		IpPermissions:       []ec2t.IpPermission{{IpProtocol: aws.String("-1"), UserIdGroupPairs: []ec2t.UserIdGroupPair{{GroupId: aws.String(sgID)}}}},
+		IpPermissionsEgress: []ec2t.IpPermission{{IpProtocol: aws.String("-1"), IpRanges: []ec2t.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}}},
+	}}
+	for _, a := range amiList {
+		for _, arch := range []string{"x86_64", "arm64"} {
+			name := strings.ReplaceAll(a.Pattern, "*", "20250101-"+arch)
+			s.Images = append(s.Images, ec2t.Image{
+				ImageId: aws.String(b.id("ami")), Name: aws.String(name), OwnerId: aws.String(a.Owner),
+				Architecture: ec2t.ArchitectureValues(arch), CreationDate: aws.String("2025-01-01T00:00:00.000Z"),
+				RootDeviceName: aws.String("/dev/xvda"), VirtualizationType: ec2t.VirtualizationTypeHvm,
+			})
+		}
+	}
+	// 新手任务中写死的 AMI
+	s.Images = append(s.Images, ec2t.Image{
+		ImageId: aws.String("ami-051f7e7f6c2f40dc1"), Name: aws.String("al2023-ami-fixed"), OwnerId: aws.String("137112412989"),
+		Architecture: ec2t.ArchitectureValuesX8664, CreationDate: aws.String("2024-01-01T00:00:00.000Z"),
+		RootDeviceName: aws.String("/dev/xvda"), VirtualizationType: ec2t.VirtualizationTypeHvm,
+	})
+	b.ec2[region] = s
+	return s
+}
+
+func (b *FakeBackend) lsState(region string) *fakeLSState {
+	if s, ok := b.ls[region]; ok {
+		return s
+	}
+	s := &fakeLSState{}
+	b.ls[region] = s
+	return s
+}
+
+// useFakeBackend 把所有客户端构造函数切换到 b
+func useFakeBackend(b *FakeBackend) {
+	newEC2Client = func(cfg aws.Config) EC2API { return &fakeEC2{b, cfg.Region} }
+	newLightsailClient = func(cfg aws.Config) LightsailAPI { return &fakeLightsail{b, cfg.Region} }
+	newSTSClient = func(aws.Config) STSAPI { return &fakeSTS{b} }
+	newIAMClient = func(aws.Config) IAMAPI { return &fakeIAM{b} }
+	newLambdaClient = func(aws.Config) LambdaAPI { return &fakeLambda{b} }
+	newRDSClient = func(aws.Config) RDSAPI { return &fakeRDS{b} }
+	newBudgetsClient = func(aws.Config) BudgetsAPI { return &fakeBudgets{b} }
+	newAccountClient = func(aws.Config) AccountAPI { return &fakeAccount{b} }
+	newServiceQuotasClient = func(aws.Config) ServiceQuotasAPI { return &fakeServiceQuotas{b} }
+}
+
+// newTestBackend 为单个测试启用新的内存后端，测试结束后恢复客户端构造函数；
+// 本地密钥库与密码记录指向临时目录，避免写入用户目录
+func newTestBackend(t *testing.T) *FakeBackend {
+	t.Helper()
+	ec2c, lsc, stsc, iamc := newEC2Client, newLightsailClient, newSTSClient, newIAMClient
+	lambdac, rdsc, budgetsc, accountc, sqc := newLambdaClient, newRDSClient, newBudgetsClient, newAccountClient, newServiceQuotasClient
+	t.Cleanup(func() {
+		newEC2Client, newLightsailClient, newSTSClient, newIAMClient = ec2c, lsc, stsc, iamc
+		newLambdaClient, newRDSClient, newBudgetsClient, newAccountClient, newServiceQuotasClient = lambdac, rdsc, budgetsc, accountc, sqc
+	})
+	dir := t.TempDir()
+	t.Setenv("AWS_TOOL_KEYRING", filepath.Join(dir, "keys"))
+	t.Setenv("AWS_TOOL_VAULT", filepath.Join(dir, "vault.json"))
+	t.Setenv("AWS_TOOL_VAULT_PASS", "test-pass")
+	activeVault = nil
+	t.Cleanup(func() { activeVault = nil })
+	b := newFakeBackend()
+	useFakeBackend(b)
+	return b
+}
+
+// answer 把 s 作为交互输入的下一行；input 每次调用新建缓冲读取，所以一次只喂一行
+func answer(t *testing.T, s string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(s + "\n")
+	w.Close()
+	old := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = old
+		r.Close()
+	})
+}
+
+func filterValues(filters []ec2t.Filter, name string) []string {
+	for _, f := range filters {
+		if aws.ToString(f.Name) == name {
+			return f.Values
+		}
+	}
+	return nil
+}
+
+func matchAny(v string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if ok, _ := path.Match(a, v); ok || a == v {
+			return true
+		}
+	}
+	return false
+}
+
+// -------------------- Fake EC2 --------------------
+
+type fakeEC2 struct {
+	b      *FakeBackend
+	region string
+}
+
+func (c *fakeEC2) state() *fakeEC2State { return c.b.ec2State(c.region) }
+
+func (c *fakeEC2) instance(id string) *ec2t.Instance {
+	s := c.state()
+	for i := range s.Instances {
+		if aws.ToString(s.Instances[i].InstanceId) == id {
+			return &s.Instances[i]
+		}
+	}
+	return nil
+}
+
+func (c *fakeEC2) subnet(id string) *ec2t.Subnet {
+	s := c.state()
+	for i := range s.Subnets {
+		if aws.ToString(s.Subnets[i].SubnetId) == id {
+			return &s.Subnets[i]
+		}
+	}
+	return nil
+}
+
+// subnetIPv6 返回子网已关联的 IPv6 网段，未关联时返回无效前缀
+func subnetIPv6(sn *ec2t.Subnet) netip.Prefix {
+	for _, a := range sn.Ipv6CidrBlockAssociationSet {
+		if a.Ipv6CidrBlockState != nil && a.Ipv6CidrBlockState.State == ec2t.SubnetCidrBlockStateCodeAssociated {
+			p, _ := netip.ParsePrefix(aws.ToString(a.Ipv6CidrBlock))
+			return p
+		}
+	}
+	return netip.Prefix{}
+}
+
+func (c *fakeEC2) nextIPv6(p netip.Prefix) string {
+	c.b.seq++
+	a := p.Addr().As16()
+	a[14], a[15] = byte(c.b.seq>>8), byte(c.b.seq)
+	return netip.AddrFrom16(a).String()
+}
+
+func (c *fakeEC2) DescribeRegions(ctx context.Context, in *ec2.DescribeRegionsInput, _ ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error) {
+	if err := c.b.begin("ec2:DescribeRegions"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeRegionsOutput{}
+	for _, r := range c.b.Regions {
+		if matchAny(r.Name, in.RegionNames) {
+			out.Regions = append(out.Regions, ec2t.Region{RegionName: aws.String(r.Name), OptInStatus: aws.String(r.Status)})
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) DescribeInstances(ctx context.Context, in *ec2.DescribeInstancesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	if err := c.b.begin("ec2:DescribeInstances"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeInstancesOutput{}
+	groups := filterValues(in.Filters, "instance.group-id")
+	for _, ins := range c.state().Instances {
+		inGroup := len(groups) == 0
+		for _, g := range ins.SecurityGroups {
+			inGroup = inGroup || matchAny(aws.ToString(g.GroupId), groups)
+		}
+		if matchAny(aws.ToString(ins.InstanceId), in.InstanceIds) && inGroup {
+			out.Reservations = append(out.Reservations, ec2t.Reservation{Instances: []ec2t.Instance{ins}})
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) RunInstances(ctx context.Context, in *ec2.RunInstancesInput, _ ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error) {
+	if err := c.b.begin("ec2:RunInstances"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	s := c.state()
+	var img *ec2t.Image
+	for i := range s.Images {
+		if aws.ToString(s.Images[i].ImageId) == aws.ToString(in.ImageId) {
+			img = &s.Images[i]
+		}
+	}
+	if img == nil {
+		return nil, fakeAPIError("InvalidAMIID.NotFound", fmt.Sprintf("The image id '[%s]' does not exist", aws.ToString(in.ImageId)))
+	}
+	if in.KeyName != nil && c.keyPair(*in.KeyName) < 0 {
+		return nil, fakeAPIError("InvalidKeyPair.NotFound", fmt.Sprintf("The key pair '%s' does not exist", *in.KeyName))
+	}
+	subnetID := aws.ToString(in.SubnetId)
+	var groups []string
+	ipv6Count, publicIP := int32(0), true
+	if len(in.NetworkInterfaces) > 0 {
+		ni := in.NetworkInterfaces[0]
+		if ni.SubnetId != nil {
+			subnetID = *ni.SubnetId
+		}
+		groups = ni.Groups
+		ipv6Count = aws.ToInt32(ni.Ipv6AddressCount)
+		if ni.AssociatePublicIpAddress != nil {
+			publicIP = *ni.AssociatePublicIpAddress
+		}
+	}
+	if subnetID == "" {
+		subnetID = aws.ToString(s.Subnets[0].SubnetId)
+	}
+	sn := c.subnet(subnetID)
+	if sn == nil {
+		return nil, fakeAPIError("InvalidSubnetID.NotFound", fmt.Sprintf("The subnet ID '%s' does not exist", subnetID))
+	}
+	if !c.typeOfferedIn(string(in.InstanceType), aws.ToString(sn.AvailabilityZone)) {
+		return nil, fakeAPIError("Unsupported", fmt.Sprintf("Your requested instance type (%s) is not supported in your requested Availability Zone (%s).",
+			in.InstanceType, aws.ToString(sn.AvailabilityZone)))
+	}
+	spot := in.InstanceMarketOptions != nil && in.InstanceMarketOptions.MarketType == ec2t.MarketTypeSpot
+	if spot && in.InstanceMarketOptions.SpotOptions != nil && in.InstanceMarketOptions.SpotOptions.MaxPrice != nil {
+		maxPrice, _ := strconv.ParseFloat(*in.InstanceMarketOptions.SpotOptions.MaxPrice, 64)
+		if cur := fakeSpotPrice(string(in.InstanceType), aws.ToString(sn.AvailabilityZone), 0); maxPrice < cur {
+			return nil, fakeAPIError("SpotMaxPriceTooLow", fmt.Sprintf("Your Spot request price of %s is lower than the minimum required Spot request fulfillment price of %.4f.",
+				*in.InstanceMarketOptions.SpotOptions.MaxPrice, cur))
+		}
+	}
+	v6 := subnetIPv6(sn)
+	if ipv6Count > 0 && !v6.IsValid() {
+		return nil, fakeAPIError("InvalidParameterValue", "Subnet does not contain any IPv6 CIDR block ranges")
+	}
+	native := aws.ToBool(sn.Ipv6Native)
+	if native {
+		if len(in.NetworkInterfaces) > 0 && aws.ToBool(in.NetworkInterfaces[0].AssociatePublicIpAddress) {
+			return nil, fakeAPIError("InvalidParameterCombination", "Public IPv4 addresses cannot be assigned in an IPv6-only subnet")
+		}
+		if strings.HasPrefix(string(in.InstanceType), "t2.") {
+			return nil, fakeAPIError("InvalidParameterCombination", fmt.Sprintf("Instance type %s is not supported in IPv6-only subnets", in.InstanceType))
+		}
+		publicIP = false
+		ipv6Count = max(ipv6Count, 1)
+	}
+	size := int32(8)
+	for _, bd := range in.BlockDeviceMappings {
+		if bd.Ebs != nil && bd.Ebs.VolumeSize != nil {
+			size = *bd.Ebs.VolumeSize
+		}
+	}
+	out := &ec2.RunInstancesOutput{ReservationId: aws.String(c.b.id("r"))}
+	for n := int32(0); n < aws.ToInt32(in.MaxCount); n++ {
+		id, volID := c.b.id("i"), c.b.id("vol")
+		s.Volumes = append(s.Volumes, ec2t.Volume{VolumeId: aws.String(volID), Size: aws.Int32(size), VolumeType: ec2t.VolumeTypeGp3})
+		eni := ec2t.InstanceNetworkInterface{
+			NetworkInterfaceId: aws.String(c.b.id("eni")), SubnetId: sn.SubnetId, VpcId: sn.VpcId,
+			Attachment: &ec2t.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int32(0)},
+		}
+		for k := int32(0); k < ipv6Count; k++ {
+			eni.Ipv6Addresses = append(eni.Ipv6Addresses, ec2t.InstanceIpv6Address{Ipv6Address: aws.String(c.nextIPv6(v6))})
+		}
+		ins := ec2t.Instance{
+			InstanceId: aws.String(id), ImageId: in.ImageId, InstanceType: in.InstanceType, KeyName: in.KeyName,
+			State:     &ec2t.InstanceState{Name: ec2t.InstanceStateNameRunning, Code: aws.Int32(16)},
+			Placement: &ec2t.Placement{AvailabilityZone: sn.AvailabilityZone},
+			VpcId:     sn.VpcId, SubnetId: sn.SubnetId, LaunchTime: aws.Time(time.Now()),
+			Architecture:      img.Architecture,
+			NetworkInterfaces: []ec2t.InstanceNetworkInterface{eni},
+			BlockDeviceMappings: []ec2t.InstanceBlockDeviceMapping{{
+				DeviceName: img.RootDeviceName, Ebs: &ec2t.EbsInstanceBlockDevice{VolumeId: aws.String(volID)},
+			}},
+		}
+		if !native {
+			ins.PrivateIpAddress = aws.String(fmt.Sprintf("172.31.%d.%d", c.b.seq/256%16, c.b.seq%256))
+		}
+		if publicIP {
+			ins.PublicIpAddress = aws.String(c.b.publicIP())
+		}
+		if spot {
+			ins.InstanceLifecycle = ec2t.InstanceLifecycleTypeSpot
+			ins.SpotInstanceRequestId = aws.String(c.b.id("sir"))
+		}
+		ins.SecurityGroups = c.groupRefs(groups, aws.ToString(sn.VpcId))
+		ins.NetworkInterfaces[0].Groups = ins.SecurityGroups
+		for _, ts := range in.TagSpecifications {
+			if ts.ResourceType == ec2t.ResourceTypeInstance {
+				ins.Tags = append(ins.Tags, ts.Tags...)
+			}
+		}
+		s.Instances = append(s.Instances, ins)
+		out.Instances = append(out.Instances, ins)
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) typeOfferedIn(itype, az string) bool {
+	for _, t := range c.offeredTypes() {
+		if t.Type == itype {
+			return strings.Contains(t.AZs, strings.TrimPrefix(az, c.region))
+		}
+	}
+	return false
+}
+
+// setState 修改实例状态，找不到实例时返回与 AWS 相同的错误
+func (c *fakeEC2) setState(ids []string, st ec2t.InstanceStateName) ([]ec2t.InstanceStateChange, error) {
+	var changes []ec2t.InstanceStateChange
+	for _, id := range ids {
+		ins := c.instance(id)
+		if ins == nil {
+			return nil, fakeAPIError("InvalidInstanceID.NotFound", fmt.Sprintf("The instance ID '%s' does not exist", id))
+		}
+		prev := *ins.State
+		ins.State = &ec2t.InstanceState{Name: st}
+		if st == ec2t.InstanceStateNameTerminated || st == ec2t.InstanceStateNameStopped && !c.hasEIP(id) {
+			ins.PublicIpAddress = nil
+		}
+		changes = append(changes, ec2t.InstanceStateChange{InstanceId: aws.String(id), PreviousState: &prev, CurrentState: ins.State})
+	}
+	return changes, nil
+}
+
+func (c *fakeEC2) StartInstances(ctx context.Context, in *ec2.StartInstancesInput, _ ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
+	if err := c.b.begin("ec2:StartInstances"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	ch, err := c.setState(in.InstanceIds, ec2t.InstanceStateNameRunning)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range in.InstanceIds {
+		if ins := c.instance(id); ins.PublicIpAddress == nil {
+			ins.PublicIpAddress = aws.String(c.b.publicIP())
+		}
+	}
+	return &ec2.StartInstancesOutput{StartingInstances: ch}, nil
+}
+
+func (c *fakeEC2) StopInstances(ctx context.Context, in *ec2.StopInstancesInput, _ ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error) {
+	if err := c.b.begin("ec2:StopInstances"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	ch, err := c.setState(in.InstanceIds, ec2t.InstanceStateNameStopped)
+	if err != nil {
+		return nil, err
+	}
+	return &ec2.StopInstancesOutput{StoppingInstances: ch}, nil
+}
+
+func (c *fakeEC2) RebootInstances(ctx context.Context, in *ec2.RebootInstancesInput, _ ...func(*ec2.Options)) (*ec2.RebootInstancesOutput, error) {
+	if err := c.b.begin("ec2:RebootInstances"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	if _, err := c.setState(in.InstanceIds, ec2t.InstanceStateNameRunning); err != nil {
+		return nil, err
+	}
+	return &ec2.RebootInstancesOutput{}, nil
+}
+
+func (c *fakeEC2) TerminateInstances(ctx context.Context, in *ec2.TerminateInstancesInput, _ ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
+	if err := c.b.begin("ec2:TerminateInstances"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	ch, err := c.setState(in.InstanceIds, ec2t.InstanceStateNameTerminated)
+	if err != nil {
+		return nil, err
+	}
+	// 终止后 EIP 自动解除关联，但仍保留在账户中
+	s := c.state()
+	for i := range s.Addresses {
+		if matchAny(aws.ToString(s.Addresses[i].InstanceId), in.InstanceIds) {
+			s.Addresses[i].InstanceId, s.Addresses[i].AssociationId = nil, nil
+		}
+	}
+	return &ec2.TerminateInstancesOutput{TerminatingInstances: ch}, nil
+}
+
+func (c *fakeEC2) DescribeAddresses(ctx context.Context, in *ec2.DescribeAddressesInput, _ ...func(*ec2.Options)) (*ec2.DescribeAddressesOutput, error) {
+	if err := c.b.begin("ec2:DescribeAddresses"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeAddressesOutput{}
+	byInstance := filterValues(in.Filters, "instance-id")
+	for _, a := range c.state().Addresses {
+		if len(byInstance) > 0 && !matchAny(aws.ToString(a.InstanceId), byInstance) {
+			continue
+		}
+		if matchAny(aws.ToString(a.AllocationId), in.AllocationIds) {
+			out.Addresses = append(out.Addresses, a)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) AllocateAddress(ctx context.Context, in *ec2.AllocateAddressInput, _ ...func(*ec2.Options)) (*ec2.AllocateAddressOutput, error) {
+	if err := c.b.begin("ec2:AllocateAddress"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	a := ec2t.Address{AllocationId: aws.String(c.b.id("eipalloc")), PublicIp: aws.String(c.b.publicIP()), Domain: ec2t.DomainTypeVpc}
+	s := c.state()
+	s.Addresses = append(s.Addresses, a)
+	return &ec2.AllocateAddressOutput{AllocationId: a.AllocationId, PublicIp: a.PublicIp, Domain: a.Domain}, nil
+}
+
+func (c *fakeEC2) address(allocID string) *ec2t.Address {
+	s := c.state()
+	for i := range s.Addresses {
+		if aws.ToString(s.Addresses[i].AllocationId) == allocID {
+			return &s.Addresses[i]
+		}
+	}
+	return nil
+}
+
+func (c *fakeEC2) hasEIP(instanceID string) bool {
+	for _, a := range c.state().Addresses {
+		if aws.ToString(a.InstanceId) == instanceID {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *fakeEC2) AssociateAddress(ctx context.Context, in *ec2.AssociateAddressInput, _ ...func(*ec2.Options)) (*ec2.AssociateAddressOutput, error) {
+	if err := c.b.begin("ec2:AssociateAddress"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	a := c.address(aws.ToString(in.AllocationId))
+	if a == nil {
+		return nil, fakeAPIError("InvalidAllocationID.NotFound", "The allocation ID does not exist")
+	}
+	ins := c.instance(aws.ToString(in.InstanceId))
+	if ins == nil {
+		return nil, fakeAPIError("InvalidInstanceID.NotFound", "The instance ID does not exist")
+	}
+	// 实例原有的 EIP 被替换 (AllowReassociation)
+	s := c.state()
+	for i := range s.Addresses {
+		if aws.ToString(s.Addresses[i].InstanceId) == aws.ToString(in.InstanceId) {
+			s.Addresses[i].InstanceId, s.Addresses[i].AssociationId = nil, nil
+		}
+	}
+	a.InstanceId, a.AssociationId = in.InstanceId, aws.String(c.b.id("eipassoc"))
+	ins.PublicIpAddress = a.PublicIp
+	return &ec2.AssociateAddressOutput{AssociationId: a.AssociationId}, nil
+}
+
+func (c *fakeEC2) DisassociateAddress(ctx context.Context, in *ec2.DisassociateAddressInput, _ ...func(*ec2.Options)) (*ec2.DisassociateAddressOutput, error) {
+	if err := c.b.begin("ec2:DisassociateAddress"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	s := c.state()
+	for i := range s.Addresses {
+		a := &s.Addresses[i]
+		if aws.ToString(a.AssociationId) == aws.ToString(in.AssociationId) {
+			if ins := c.instance(aws.ToString(a.InstanceId)); ins != nil {
+				ins.PublicIpAddress = aws.String(c.b.publicIP())
+			}
+			a.InstanceId, a.AssociationId = nil, nil
+			return &ec2.DisassociateAddressOutput{}, nil
+		}
+	}
+	return nil, fakeAPIError("InvalidAssociationID.NotFound", "The association ID does not exist")
+}
+
+func (c *fakeEC2) ReleaseAddress(ctx context.Context, in *ec2.ReleaseAddressInput, _ ...func(*ec2.Options)) (*ec2.ReleaseAddressOutput, error) {
+	if err := c.b.begin("ec2:ReleaseAddress"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	s := c.state()
+	for i, a := range s.Addresses {
+		if aws.ToString(a.AllocationId) != aws.ToString(in.AllocationId) {
+			continue
+		}
+		if a.AssociationId != nil {
+			return nil, fakeAPIError("InvalidIPAddress.InUse", "Address is in use")
+		}
+		s.Addresses = append(s.Addresses[:i], s.Addresses[i+1:]...)
+		return &ec2.ReleaseAddressOutput{}, nil
+	}
+	return nil, fakeAPIError("InvalidAllocationID.NotFound", "The allocation ID does not exist")
+}
+
+func (c *fakeEC2) DescribeVpcs(ctx context.Context, in *ec2.DescribeVpcsInput, _ ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+	if err := c.b.begin("ec2:DescribeVpcs"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeVpcsOutput{}
+	isDefault := filterValues(in.Filters, "isDefault")
+	for _, v := range c.state().Vpcs {
+		if matchAny(aws.ToString(v.VpcId), in.VpcIds) && matchAny(fmt.Sprint(aws.ToBool(v.IsDefault)), isDefault) {
+			out.Vpcs = append(out.Vpcs, v)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) AssociateVpcCidrBlock(ctx context.Context, in *ec2.AssociateVpcCidrBlockInput, _ ...func(*ec2.Options)) (*ec2.AssociateVpcCidrBlockOutput, error) {
+	if err := c.b.begin("ec2:AssociateVpcCidrBlock"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	s := c.state()
+	for i := range s.Vpcs {
+		v := &s.Vpcs[i]
+		if aws.ToString(v.VpcId) != aws.ToString(in.VpcId) {
+			continue
+		}
+		c.b.seq++
+		assoc := ec2t.VpcIpv6CidrBlockAssociation{
+			AssociationId:      aws.String(c.b.id("vpc-cidr-assoc")),
+			Ipv6CidrBlock:      aws.String(fmt.Sprintf("2600:1f18:%x:%x00::/56", 0x4000+c.b.seq%0x1000, c.b.seq%256)),
+			Ipv6CidrBlockState: &ec2t.VpcCidrBlockState{State: ec2t.VpcCidrBlockStateCodeAssociated},
+		}
+		v.Ipv6CidrBlockAssociationSet = append(v.Ipv6CidrBlockAssociationSet, assoc)
+		return &ec2.AssociateVpcCidrBlockOutput{VpcId: v.VpcId, Ipv6CidrBlockAssociation: &assoc}, nil
+	}
+	return nil, fakeAPIError("InvalidVpcID.NotFound", "The vpc ID does not exist")
+}
+
+func (c *fakeEC2) DescribeSubnets(ctx context.Context, in *ec2.DescribeSubnetsInput, _ ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	if err := c.b.begin("ec2:DescribeSubnets"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeSubnetsOutput{}
+	vpc, def := filterValues(in.Filters, "vpc-id"), filterValues(in.Filters, "default-for-az")
+	for _, sn := range c.state().Subnets {
+		if matchAny(aws.ToString(sn.SubnetId), in.SubnetIds) && matchAny(aws.ToString(sn.VpcId), vpc) &&
+			matchAny(fmt.Sprint(aws.ToBool(sn.DefaultForAz)), def) {
+			out.Subnets = append(out.Subnets, sn)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) AssociateSubnetCidrBlock(ctx context.Context, in *ec2.AssociateSubnetCidrBlockInput, _ ...func(*ec2.Options)) (*ec2.AssociateSubnetCidrBlockOutput, error) {
+	if err := c.b.begin("ec2:AssociateSubnetCidrBlock"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	sn := c.subnet(aws.ToString(in.SubnetId))
+	if sn == nil {
+		return nil, fakeAPIError("InvalidSubnetID.NotFound", "The subnet ID does not exist")
+	}
+	want, err := netip.ParsePrefix(aws.ToString(in.Ipv6CidrBlock))
+	if err != nil || want.Bits() != 64 {
+		return nil, fakeAPIError("InvalidParameterValue", "The IPv6 CIDR block must be a /64")
+	}
+	for _, other := range c.state().Subnets {
+		if p := subnetIPv6(&other); p.IsValid() && p.Overlaps(want) {
+			return nil, fakeAPIError("InvalidSubnet.Conflict", fmt.Sprintf("The CIDR '%s' conflicts with another subnet", want))
+		}
+	}
+	assoc := ec2t.SubnetIpv6CidrBlockAssociation{
+		AssociationId:      aws.String(c.b.id("subnet-cidr-assoc")),
+		Ipv6CidrBlock:      aws.String(want.String()),
+		Ipv6CidrBlockState: &ec2t.SubnetCidrBlockState{State: ec2t.SubnetCidrBlockStateCodeAssociated},
+	}
+	sn.Ipv6CidrBlockAssociationSet = append(sn.Ipv6CidrBlockAssociationSet, assoc)
+	return &ec2.AssociateSubnetCidrBlockOutput{SubnetId: sn.SubnetId, Ipv6CidrBlockAssociation: &assoc}, nil
+}
+
+// CreateSubnet 只模拟 IPv6-only 子网 (IPv6 网段须在 VPC 的 /56 内且不与其他子网重叠)
+func (c *fakeEC2) CreateSubnet(ctx context.Context, in *ec2.CreateSubnetInput, _ ...func(*ec2.Options)) (*ec2.CreateSubnetOutput, error) {
+	if err := c.b.begin("ec2:CreateSubnet"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	s := c.state()
+	var vpc *ec2t.Vpc
+	for i := range s.Vpcs {
+		if aws.ToString(s.Vpcs[i].VpcId) == aws.ToString(in.VpcId) {
+			vpc = &s.Vpcs[i]
+		}
+	}
+	if vpc == nil {
+		return nil, fakeAPIError("InvalidVpcID.NotFound", "The vpc ID does not exist")
+	}
+	if !aws.ToBool(in.Ipv6Native) {
+		return nil, fakeAPIError("InvalidParameterValue", "only IPv6-only subnets are supported by the fake backend")
+	}
+	want, err := netip.ParsePrefix(aws.ToString(in.Ipv6CidrBlock))
+	if err != nil || want.Bits() != 64 {
+		return nil, fakeAPIError("InvalidParameterValue", "The IPv6 CIDR block must be a /64")
+	}
+	block, err := netip.ParsePrefix(vpcIPv6CIDR(*vpc))
+	if err != nil || !block.Contains(want.Addr()) {
+		return nil, fakeAPIError("InvalidSubnet.Range", fmt.Sprintf("The CIDR '%s' is invalid", want))
+	}
+	for _, other := range s.Subnets {
+		if p := subnetIPv6(&other); p.IsValid() && p.Overlaps(want) {
+			return nil, fakeAPIError("InvalidSubnet.Conflict", fmt.Sprintf("The CIDR '%s' conflicts with another subnet", want))
+		}
+	}
+	sn := ec2t.Subnet{
+		SubnetId: aws.String(c.b.id("subnet")), VpcId: in.VpcId, AvailabilityZone: in.AvailabilityZone,
+		Ipv6Native: aws.Bool(true), AssignIpv6AddressOnCreation: aws.Bool(true), DefaultForAz: aws.Bool(false),
+		MapPublicIpOnLaunch: aws.Bool(false), EnableDns64: aws.Bool(false),
+		Ipv6CidrBlockAssociationSet: []ec2t.SubnetIpv6CidrBlockAssociation{{
+			AssociationId:      aws.String(c.b.id("subnet-cidr-assoc")),
+			Ipv6CidrBlock:      aws.String(want.String()),
+			Ipv6CidrBlockState: &ec2t.SubnetCidrBlockState{State: ec2t.SubnetCidrBlockStateCodeAssociated},
+		}},
+	}
+	for _, ts := range in.TagSpecifications {
+		sn.Tags = append(sn.Tags, ts.Tags...)
+	}
+	s.Subnets = append(s.Subnets, sn)
+	return &ec2.CreateSubnetOutput{Subnet: &sn}, nil
+}
+
+func (c *fakeEC2) ModifySubnetAttribute(ctx context.Context, in *ec2.ModifySubnetAttributeInput, _ ...func(*ec2.Options)) (*ec2.ModifySubnetAttributeOutput, error) {
+	if err := c.b.begin("ec2:ModifySubnetAttribute"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	sn := c.subnet(aws.ToString(in.SubnetId))
+	if sn == nil {
+		return nil, fakeAPIError("InvalidSubnetID.NotFound", "The subnet ID does not exist")
+	}
+	if in.AssignIpv6AddressOnCreation != nil {
+		sn.AssignIpv6AddressOnCreation = in.AssignIpv6AddressOnCreation.Value
+	}
+	if in.MapPublicIpOnLaunch != nil {
+		sn.MapPublicIpOnLaunch = in.MapPublicIpOnLaunch.Value
+	}
+	if in.EnableDns64 != nil {
+		sn.EnableDns64 = in.EnableDns64.Value
+	}
+	return &ec2.ModifySubnetAttributeOutput{}, nil
+}
+
+func (c *fakeEC2) DescribeRouteTables(ctx context.Context, in *ec2.DescribeRouteTablesInput, _ ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+	if err := c.b.begin("ec2:DescribeRouteTables"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeRouteTablesOutput{}
+	vpc := filterValues(in.Filters, "vpc-id")
+	for _, rt := range c.state().RouteTables {
+		if matchAny(aws.ToString(rt.RouteTableId), in.RouteTableIds) && matchAny(aws.ToString(rt.VpcId), vpc) {
+			out.RouteTables = append(out.RouteTables, rt)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) CreateRoute(ctx context.Context, in *ec2.CreateRouteInput, _ ...func(*ec2.Options)) (*ec2.CreateRouteOutput, error) {
+	if err := c.b.begin("ec2:CreateRoute"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	s := c.state()
+	for i := range s.RouteTables {
+		rt := &s.RouteTables[i]
+		if aws.ToString(rt.RouteTableId) != aws.ToString(in.RouteTableId) {
+			continue
+		}
+		for _, r := range rt.Routes {
+			if aws.ToString(r.DestinationCidrBlock) != "" && aws.ToString(r.DestinationCidrBlock) == aws.ToString(in.DestinationCidrBlock) ||
+				aws.ToString(r.DestinationIpv6CidrBlock) != "" && aws.ToString(r.DestinationIpv6CidrBlock) == aws.ToString(in.DestinationIpv6CidrBlock) {
+				return nil, fakeAPIError("RouteAlreadyExists", "The route identified by the destination already exists")
+			}
+		}
+		rt.Routes = append(rt.Routes, ec2t.Route{
+			DestinationCidrBlock: in.DestinationCidrBlock, DestinationIpv6CidrBlock: in.DestinationIpv6CidrBlock, GatewayId: in.GatewayId,
+			EgressOnlyInternetGatewayId: in.EgressOnlyInternetGatewayId, NatGatewayId: in.NatGatewayId,
+		})
+		return &ec2.CreateRouteOutput{Return: aws.Bool(true)}, nil
+	}
+	return nil, fakeAPIError("InvalidRouteTableID.NotFound", "The routeTable ID does not exist")
+}
+
+func (c *fakeEC2) DescribeInternetGateways(ctx context.Context, in *ec2.DescribeInternetGatewaysInput, _ ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
+	if err := c.b.begin("ec2:DescribeInternetGateways"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeInternetGatewaysOutput{}
+	vpc := filterValues(in.Filters, "attachment.vpc-id")
+	for _, g := range c.state().Gateways {
+		attached := len(vpc) == 0
+		for _, a := range g.Attachments {
+			attached = attached || matchAny(aws.ToString(a.VpcId), vpc)
+		}
+		if attached {
+			out.InternetGateways = append(out.InternetGateways, g)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) DescribeNatGateways(ctx context.Context, in *ec2.DescribeNatGatewaysInput, _ ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+	if err := c.b.begin("ec2:DescribeNatGateways"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeNatGatewaysOutput{}
+	vpc, state := filterValues(in.Filter, "vpc-id"), filterValues(in.Filter, "state")
+	for _, g := range c.state().NatGateways {
+		if matchAny(aws.ToString(g.VpcId), vpc) && matchAny(string(g.State), state) {
+			out.NatGateways = append(out.NatGateways, g)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) DescribeEgressOnlyInternetGateways(ctx context.Context, in *ec2.DescribeEgressOnlyInternetGatewaysInput, _ ...func(*ec2.Options)) (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+	if err := c.b.begin("ec2:DescribeEgressOnlyInternetGateways"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeEgressOnlyInternetGatewaysOutput{}
+	for _, g := range c.state().EgressOnly {
+		if matchAny(aws.ToString(g.EgressOnlyInternetGatewayId), in.EgressOnlyInternetGatewayIds) {
+			out.EgressOnlyInternetGateways = append(out.EgressOnlyInternetGateways, g)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) CreateEgressOnlyInternetGateway(ctx context.Context, in *ec2.CreateEgressOnlyInternetGatewayInput, _ ...func(*ec2.Options)) (*ec2.CreateEgressOnlyInternetGatewayOutput, error) {
+	if err := c.b.begin("ec2:CreateEgressOnlyInternetGateway"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	s := c.state()
+	if !slices.ContainsFunc(s.Vpcs, func(v ec2t.Vpc) bool { return aws.ToString(v.VpcId) == aws.ToString(in.VpcId) }) {
+		return nil, fakeAPIError("InvalidVpcID.NotFound", "The vpc ID does not exist")
+	}
+	g := ec2t.EgressOnlyInternetGateway{
+		EgressOnlyInternetGatewayId: aws.String(c.b.id("eigw")),
+		Attachments:                 []ec2t.InternetGatewayAttachment{{VpcId: in.VpcId, State: ec2t.AttachmentStatusAttached}},
+	}
+	s.EgressOnly = append(s.EgressOnly, g)
+	return &ec2.CreateEgressOnlyInternetGatewayOutput{EgressOnlyInternetGateway: &g}, nil
+}
+
+func (c *fakeEC2) DescribeSecurityGroups(ctx context.Context, in *ec2.DescribeSecurityGroupsInput, _ ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	if err := c.b.begin("ec2:DescribeSecurityGroups"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeSecurityGroupsOutput{}
+	names, vpc := filterValues(in.Filters, "group-name"), filterValues(in.Filters, "vpc-id")
+	for _, g := range c.state().Groups {
+		if matchAny(aws.ToString(g.GroupId), in.GroupIds) && matchAny(aws.ToString(g.GroupName), names) && matchAny(aws.ToString(g.VpcId), vpc) {
+			out.SecurityGroups = append(out.SecurityGroups, g)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) CreateSecurityGroup(ctx context.Context, in *ec2.CreateSecurityGroupInput, _ ...func(*ec2.Options)) (*ec2.CreateSecurityGroupOutput, error) {
+	if err := c.b.begin("ec2:CreateSecurityGroup"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	s := c.state()
+	for _, g := range s.Groups {
+		if aws.ToString(g.GroupName) == aws.ToString(in.GroupName) && aws.ToString(g.VpcId) == aws.ToString(in.VpcId) {
+			return nil, fakeAPIError("InvalidGroup.Duplicate", fmt.Sprintf("The security group '%s' already exists", aws.ToString(in.GroupName)))
+		}
+	}
+	g := ec2t.SecurityGroup{GroupId: aws.String(c.b.id("sg")), GroupName: in.GroupName, Description: in.Description, VpcId: in.VpcId}
+	s.Groups = append(s.Groups, g)
+	return &ec2.CreateSecurityGroupOutput{GroupId: g.GroupId}, nil
+}
+
+func (c *fakeEC2) DeleteSecurityGroup(ctx context.Context, in *ec2.DeleteSecurityGroupInput, _ ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
+	if err := c.b.begin("ec2:DeleteSecurityGroup"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	s := c.state()
+	id := aws.ToString(in.GroupId)
+	i := slices.IndexFunc(s.Groups, func(g ec2t.SecurityGroup) bool { return aws.ToString(g.GroupId) == id })
+	if i < 0 {
+		return nil, fakeAPIError("InvalidGroup.NotFound", fmt.Sprintf("The security group '%s' does not exist", id))
+	}
+	for _, ins := range s.Instances {
+		for _, eni := range ins.NetworkInterfaces {
+			if slices.ContainsFunc(eni.Groups, func(g ec2t.GroupIdentifier) bool { return aws.ToString(g.GroupId) == id }) {
+				return nil, fakeAPIError("DependencyViolation", fmt.Sprintf("resource %s has a dependent object", id))
+			}
+		}
+	}
+	s.Groups = slices.Delete(s.Groups, i, i+1)
+	return &ec2.DeleteSecurityGroupOutput{Return: aws.Bool(true)}, nil
+}
+
+func (c *fakeEC2) AuthorizeSecurityGroupIngress(ctx context.Context, in *ec2.AuthorizeSecurityGroupIngressInput, _ ...func(*ec2.Options)) (*ec2.AuthorizeSecurityGroupIngressOutput, error) {
+	if err := c.b.begin("ec2:AuthorizeSecurityGroupIngress"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	g := c.group(aws.ToString(in.GroupId))
+	if g == nil {
+		return nil, fakeAPIError("InvalidGroup.NotFound", fmt.Sprintf("The security group '%s' does not exist", aws.ToString(in.GroupId)))
+	}
+	have := map[string]bool{}
+	for _, p := range g.IpPermissions {
+		for _, one := range splitPermission(p) {
+			have[permKey(one)] = true
+		}
+	}
+	for _, p := range in.IpPermissions {
+		for _, one := range splitPermission(p) {
+			if have[permKey(one)] {
+				src, _ := permSource(one)
+				return nil, fakeAPIError("InvalidPermission.Duplicate", fmt.Sprintf("the specified rule \"peer: %s, %s, ALLOW\" already exists", src, permPorts(one)))
+			}
+		}
+	}
+	g.IpPermissions = append(g.IpPermissions, in.IpPermissions...)
+	return &ec2.AuthorizeSecurityGroupIngressOutput{Return: aws.Bool(true)}, nil
+}
+
+func (c *fakeEC2) RevokeSecurityGroupIngress(ctx context.Context, in *ec2.RevokeSecurityGroupIngressInput, _ ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupIngressOutput, error) {
+	if err := c.b.begin("ec2:RevokeSecurityGroupIngress"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	g := c.group(aws.ToString(in.GroupId))
+	if g == nil {
+		return nil, fakeAPIError("InvalidGroup.NotFound", fmt.Sprintf("The security group '%s' does not exist", aws.ToString(in.GroupId)))
+	}
+	drop := map[string]bool{}
+	for _, p := range in.IpPermissions {
+		for _, one := range splitPermission(p) {
+			drop[permKey(one)] = true
+		}
+	}
+	var kept []ec2t.IpPermission
+	found := 0
+	for _, p := range g.IpPermissions {
+		for _, one := range splitPermission(p) {
+			if drop[permKey(one)] {
+				found++
+				continue
+			}
+			kept = append(kept, one)
+		}
+	}
+	if found < len(drop) {
+		return nil, fakeAPIError("InvalidPermission.NotFound", "The specified rule does not exist in this security group.")
+	}
+	g.IpPermissions = kept
+	return &ec2.RevokeSecurityGroupIngressOutput{Return: aws.Bool(true)}, nil
+}
+
+func (c *fakeEC2) ModifyNetworkInterfaceAttribute(ctx context.Context, in *ec2.ModifyNetworkInterfaceAttributeInput, _ ...func(*ec2.Options)) (*ec2.ModifyNetworkInterfaceAttributeOutput, error) {
+	if err := c.b.begin("ec2:ModifyNetworkInterfaceAttribute"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	s := c.state()
+	for i := range s.Instances {
+		ins := &s.Instances[i]
+		for j := range ins.NetworkInterfaces {
+			if aws.ToString(ins.NetworkInterfaces[j].NetworkInterfaceId) != aws.ToString(in.NetworkInterfaceId) {
+				continue
+			}
+			if len(in.Groups) == 0 {
+				return &ec2.ModifyNetworkInterfaceAttributeOutput{}, nil
+			}
+			for _, id := range in.Groups {
+				if g := c.group(id); g == nil || aws.ToString(g.VpcId) != aws.ToString(ins.VpcId) {
+					return nil, fakeAPIError("InvalidGroup.NotFound", fmt.Sprintf("The security group '%s' does not exist in VPC '%s'", id, aws.ToString(ins.VpcId)))
+				}
+			}
+			refs := c.groupRefs(in.Groups, aws.ToString(ins.VpcId))
+			ins.NetworkInterfaces[j].Groups = refs
+			if j == 0 {
+				ins.SecurityGroups = refs
+			}
+			return &ec2.ModifyNetworkInterfaceAttributeOutput{}, nil
+		}
+	}
+	return nil, fakeAPIError("InvalidNetworkInterfaceID.NotFound", fmt.Sprintf("The networkInterface ID '%s' does not exist", aws.ToString(in.NetworkInterfaceId)))
+}
+
+func (c *fakeEC2) group(id string) *ec2t.SecurityGroup {
+	s := c.state()
+	for i := range s.Groups {
+		if aws.ToString(s.Groups[i].GroupId) == id {
+			return &s.Groups[i]
+		}
+	}
+	return nil
+}
+
+// groupRefs 返回 ids 对应的安全组引用；ids 为空时使用 VPC 的默认安全组
+func (c *fakeEC2) groupRefs(ids []string, vpcID string) []ec2t.GroupIdentifier {
+	var refs []ec2t.GroupIdentifier
+	if len(ids) == 0 {
+		for _, g := range c.state().Groups {
+			if aws.ToString(g.GroupName) == "default" && aws.ToString(g.VpcId) == vpcID {
+				ids = []string{aws.ToString(g.GroupId)}
+			}
+		}
+	}
+	for _, id := range ids {
+		ref := ec2t.GroupIdentifier{GroupId: aws.String(id)}
+		if g := c.group(id); g != nil {
+			ref.GroupName = g.GroupName
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+func (c *fakeEC2) DescribeImages(ctx context.Context, in *ec2.DescribeImagesInput, _ ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error) {
+	if err := c.b.begin("ec2:DescribeImages"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeImagesOutput{}
+	names, arch := filterValues(in.Filters, "name"), filterValues(in.Filters, "architecture")
+	for _, img := range c.state().Images {
+		if matchAny(aws.ToString(img.ImageId), in.ImageIds) && matchAny(aws.ToString(img.OwnerId), in.Owners) &&
+			matchAny(aws.ToString(img.Name), names) && matchAny(string(img.Architecture), arch) {
+			out.Images = append(out.Images, img)
+		}
+	}
+	return out, nil
+}
+
+// fakeInstanceType 为模拟目录中的实例类型；AZs 为提供该类型的可用区后缀
+type fakeInstanceType struct {
+	Type     string
+	Arch     string
+	VCPU     int32
+	MemMiB   int64
+	Network  string
+	FreeTier bool
+	AZs      string
+}
+
+// fakeInstanceTypes 模拟新区域的情况：t2 只在老区域提供，c6i.large 只在 a 区提供
+var fakeInstanceTypes = []fakeInstanceType{
+	{"t2.nano", "x86_64", 1, 512, "Low to Moderate", false, "ab"},
+	{"t2.micro", "x86_64", 1, 1024, "Low to Moderate", true, "ab"},
+	{"t3.micro", "x86_64", 2, 1024, "Up to 5 Gigabit", true, "ab"},
+	{"t3.small", "x86_64", 2, 2048, "Up to 5 Gigabit", false, "ab"},
+	{"t3.medium", "x86_64", 2, 4096, "Up to 5 Gigabit", false, "ab"},
+	{"c6i.large", "x86_64", 2, 4096, "Up to 12.5 Gigabit", false, "a"},
+	{"m6i.xlarge", "x86_64", 4, 16384, "Up to 12.5 Gigabit", false, "ab"},
+	{"t4g.nano", "arm64", 2, 512, "Up to 5 Gigabit", false, "ab"},
+	{"t4g.micro", "arm64", 2, 1024, "Up to 5 Gigabit", true, "ab"},
+	{"t4g.small", "arm64", 2, 2048, "Up to 5 Gigabit", false, "ab"},
+	{"c7g.large", "arm64", 2, 4096, "Up to 12.5 Gigabit", false, "b"},
+}
+
+func (c *fakeEC2) offeredTypes() []fakeInstanceType {
+	var out []fakeInstanceType
+	for _, t := range fakeInstanceTypes {
+		if strings.HasPrefix(t.Type, "t2.") && c.region == "ap-east-1" {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+func (c *fakeEC2) DescribeInstanceTypes(ctx context.Context, in *ec2.DescribeInstanceTypesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
+	if err := c.b.begin("ec2:DescribeInstanceTypes"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeInstanceTypesOutput{}
+	arch := filterValues(in.Filters, "processor-info.supported-architecture")
+	var names []string
+	for _, n := range in.InstanceTypes {
+		names = append(names, string(n))
+	}
+	for _, t := range fakeInstanceTypes {
+		if !matchAny(t.Type, names) || !matchAny(t.Arch, arch) {
+			continue
+		}
+		hv := ec2t.InstanceTypeHypervisorNitro
+		if strings.HasPrefix(t.Type, "t2.") {
+			hv = ec2t.InstanceTypeHypervisorXen
+		}
+		out.InstanceTypes = append(out.InstanceTypes, ec2t.InstanceTypeInfo{
+			InstanceType:     ec2t.InstanceType(t.Type),
+			FreeTierEligible: aws.Bool(t.FreeTier),
+			ProcessorInfo:    &ec2t.ProcessorInfo{SupportedArchitectures: []ec2t.ArchitectureType{ec2t.ArchitectureType(t.Arch)}},
+			VCpuInfo:         &ec2t.VCpuInfo{DefaultVCpus: aws.Int32(t.VCPU)},
+			MemoryInfo:       &ec2t.MemoryInfo{SizeInMiB: aws.Int64(t.MemMiB)},
+			NetworkInfo:      &ec2t.NetworkInfo{NetworkPerformance: aws.String(t.Network), Ipv6Supported: aws.Bool(true)},
+			Hypervisor:       hv,
+		})
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) DescribeInstanceTypeOfferings(ctx context.Context, in *ec2.DescribeInstanceTypeOfferingsInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstanceTypeOfferingsOutput, error) {
+	if err := c.b.begin("ec2:DescribeInstanceTypeOfferings"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeInstanceTypeOfferingsOutput{}
+	types := filterValues(in.Filters, "instance-type")
+	for _, t := range c.offeredTypes() {
+		if !matchAny(t.Type, types) {
+			continue
+		}
+		for _, az := range t.AZs {
+			loc := c.region
+			if in.LocationType == ec2t.LocationTypeAvailabilityZone {
+				loc = c.region + string(az)
+			}
+			out.InstanceTypeOfferings = append(out.InstanceTypeOfferings, ec2t.InstanceTypeOffering{
+				InstanceType: ec2t.InstanceType(t.Type), LocationType: in.LocationType, Location: aws.String(loc),
+			})
+			if in.LocationType != ec2t.LocationTypeAvailabilityZone {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// fakeSpotPrice 为模拟的 Spot 价格：b 区比 a 区便宜，不同时段价格略有波动
+func fakeSpotPrice(itype, az string, slot int) float64 {
+	base, ok := onDemandRef[itype]
+	if !ok {
+		base = 0.05
+	}
+	factor := 0.38
+	if strings.HasSuffix(az, "b") {
+		factor = 0.31
+	}
+	return base * (factor + 0.01*float64(slot%3))
+}
+
+func (c *fakeEC2) DescribeSpotPriceHistory(ctx context.Context, in *ec2.DescribeSpotPriceHistoryInput, _ ...func(*ec2.Options)) (*ec2.DescribeSpotPriceHistoryOutput, error) {
+	if err := c.b.begin("ec2:DescribeSpotPriceHistory"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	var types []string
+	for _, t := range in.InstanceTypes {
+		types = append(types, string(t))
+	}
+	out := &ec2.DescribeSpotPriceHistoryOutput{}
+	now := time.Now()
+	for _, t := range c.offeredTypes() {
+		if !matchAny(t.Type, types) {
+			continue
+		}
+		for _, suffix := range t.AZs {
+			az := c.region + string(suffix)
+			for h := 0; h < 24; h += 6 {
+				out.SpotPriceHistory = append(out.SpotPriceHistory, ec2t.SpotPrice{
+					AvailabilityZone: aws.String(az), InstanceType: ec2t.InstanceType(t.Type),
+					ProductDescription: ec2t.RIProductDescription("Linux/UNIX"),
+					SpotPrice:          aws.String(strconv.FormatFloat(fakeSpotPrice(t.Type, az, h/6), 'f', 6, 64)),
+					Timestamp:          aws.Time(now.Add(-time.Duration(h) * time.Hour)),
+				})
+			}
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) keyPair(name string) int {
+	return slices.IndexFunc(c.state().KeyPairs, func(k ec2t.KeyPairInfo) bool { return aws.ToString(k.KeyName) == name })
+}
+
+// fakeFingerprint 解析 authorized_keys 格式的公钥并返回 SHA256 指纹
+func fakeFingerprint(pub string) (string, string, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pub))
+	if err != nil {
+		return "", "", err
+	}
+	return ssh.FingerprintSHA256(key), key.Type(), nil
+}
+
+func (c *fakeEC2) DescribeKeyPairs(ctx context.Context, in *ec2.DescribeKeyPairsInput, _ ...func(*ec2.Options)) (*ec2.DescribeKeyPairsOutput, error) {
+	if err := c.b.begin("ec2:DescribeKeyPairs"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeKeyPairsOutput{}
+	for _, k := range c.state().KeyPairs {
+		if matchAny(aws.ToString(k.KeyName), in.KeyNames) {
+			out.KeyPairs = append(out.KeyPairs, k)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) ImportKeyPair(ctx context.Context, in *ec2.ImportKeyPairInput, _ ...func(*ec2.Options)) (*ec2.ImportKeyPairOutput, error) {
+	if err := c.b.begin("ec2:ImportKeyPair"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	name := aws.ToString(in.KeyName)
+	if c.keyPair(name) >= 0 {
+		return nil, fakeAPIError("InvalidKeyPair.Duplicate", fmt.Sprintf("The keypair already exists: %s", name))
+	}
+	fp, typ, err := fakeFingerprint(string(in.PublicKeyMaterial))
+	if err != nil {
+		return nil, fakeAPIError("InvalidKey.Format", "Key is not in valid OpenSSH public key format")
+	}
+	kt := ec2t.KeyTypeRsa
+	if typ == ssh.KeyAlgoED25519 {
+		kt = ec2t.KeyTypeEd25519
+	}
+	id := c.b.id("key")
+	s := c.state()
+	s.KeyPairs = append(s.KeyPairs, ec2t.KeyPairInfo{KeyName: aws.String(name), KeyPairId: aws.String(id), KeyFingerprint: aws.String(fp), KeyType: kt})
+	return &ec2.ImportKeyPairOutput{KeyName: aws.String(name), KeyPairId: aws.String(id), KeyFingerprint: aws.String(fp)}, nil
+}
+
+func (c *fakeEC2) DeleteKeyPair(ctx context.Context, in *ec2.DeleteKeyPairInput, _ ...func(*ec2.Options)) (*ec2.DeleteKeyPairOutput, error) {
+	if err := c.b.begin("ec2:DeleteKeyPair"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	// 与 AWS 一致：删除不存在的密钥也返回成功
+	if i := c.keyPair(aws.ToString(in.KeyName)); i >= 0 {
+		s := c.state()
+		s.KeyPairs = slices.Delete(s.KeyPairs, i, i+1)
+	}
+	return &ec2.DeleteKeyPairOutput{Return: aws.Bool(true)}, nil
+}
+
+func (c *fakeEC2) DescribeVolumes(ctx context.Context, in *ec2.DescribeVolumesInput, _ ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	if err := c.b.begin("ec2:DescribeVolumes"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &ec2.DescribeVolumesOutput{}
+	for _, v := range c.state().Volumes {
+		if matchAny(aws.ToString(v.VolumeId), in.VolumeIds) {
+			out.Volumes = append(out.Volumes, v)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) eni(id string) *ec2t.InstanceNetworkInterface {
+	s := c.state()
+	for i := range s.Instances {
+		for j := range s.Instances[i].NetworkInterfaces {
+			if aws.ToString(s.Instances[i].NetworkInterfaces[j].NetworkInterfaceId) == id {
+				return &s.Instances[i].NetworkInterfaces[j]
+			}
+		}
+	}
+	return nil
+}
+
+func (c *fakeEC2) AssignIpv6Addresses(ctx context.Context, in *ec2.AssignIpv6AddressesInput, _ ...func(*ec2.Options)) (*ec2.AssignIpv6AddressesOutput, error) {
+	if err := c.b.begin("ec2:AssignIpv6Addresses"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	eni := c.eni(aws.ToString(in.NetworkInterfaceId))
+	if eni == nil {
+		return nil, fakeAPIError("InvalidNetworkInterfaceID.NotFound", "The networkInterface ID does not exist")
+	}
+	v6 := subnetIPv6(c.subnet(aws.ToString(eni.SubnetId)))
+	if !v6.IsValid() {
+		return nil, fakeAPIError("InvalidParameterValue", "Subnet does not contain any IPv6 CIDR block ranges")
+	}
+	out := &ec2.AssignIpv6AddressesOutput{NetworkInterfaceId: in.NetworkInterfaceId}
+	for _, a := range in.Ipv6Addresses {
+		eni.Ipv6Addresses = append(eni.Ipv6Addresses, ec2t.InstanceIpv6Address{Ipv6Address: aws.String(a)})
+		out.AssignedIpv6Addresses = append(out.AssignedIpv6Addresses, a)
+	}
+	for n := int32(0); n < aws.ToInt32(in.Ipv6AddressCount); n++ {
+		a := c.nextIPv6(v6)
+		eni.Ipv6Addresses = append(eni.Ipv6Addresses, ec2t.InstanceIpv6Address{Ipv6Address: aws.String(a)})
+		out.AssignedIpv6Addresses = append(out.AssignedIpv6Addresses, a)
+	}
+	return out, nil
+}
+
+func (c *fakeEC2) UnassignIpv6Addresses(ctx context.Context, in *ec2.UnassignIpv6AddressesInput, _ ...func(*ec2.Options)) (*ec2.UnassignIpv6AddressesOutput, error) {
+	if err := c.b.begin("ec2:UnassignIpv6Addresses"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	eni := c.eni(aws.ToString(in.NetworkInterfaceId))
+	if eni == nil {
+		return nil, fakeAPIError("InvalidNetworkInterfaceID.NotFound", "The networkInterface ID does not exist")
+	}
+	var keep []ec2t.InstanceIpv6Address
+	for _, a := range eni.Ipv6Addresses {
+		if !matchAny(aws.ToString(a.Ipv6Address), in.Ipv6Addresses) {
+			keep = append(keep, a)
+		}
+	}
+	eni.Ipv6Addresses = keep
+	return &ec2.UnassignIpv6AddressesOutput{NetworkInterfaceId: in.NetworkInterfaceId, UnassignedIpv6Addresses: in.Ipv6Addresses}, nil
+}
+
+// -------------------- Fake Lightsail --------------------
+
+type fakeLightsail struct {
+	b      *FakeBackend
+	region string
+}
+
+// fakeLSBundles 含对应的 IPv6-only 套餐 (无公网 IPv4，价格更低)
+var fakeLSBundles = []lst.Bundle{
+	{BundleId: aws.String("nano_3_0"), Price: aws.Float32(5), RamSizeInGb: aws.Float32(0.5), CpuCount: aws.Int32(2), IsActive: aws.Bool(true), SupportedPlatforms: []lst.InstancePlatform{lst.InstancePlatformLinuxUnix}, PublicIpv4AddressCount: aws.Int32(1)},
+	{BundleId: aws.String("micro_3_0"), Price: aws.Float32(7), RamSizeInGb: aws.Float32(1), CpuCount: aws.Int32(2), IsActive: aws.Bool(true), SupportedPlatforms: []lst.InstancePlatform{lst.InstancePlatformLinuxUnix}, PublicIpv4AddressCount: aws.Int32(1)},
+	{BundleId: aws.String("small_3_0"), Price: aws.Float32(12), RamSizeInGb: aws.Float32(2), CpuCount: aws.Int32(2), IsActive: aws.Bool(true), SupportedPlatforms: []lst.InstancePlatform{lst.InstancePlatformLinuxUnix}, PublicIpv4AddressCount: aws.Int32(1)},
+	{BundleId: aws.String("small_win_3_0"), Price: aws.Float32(20), RamSizeInGb: aws.Float32(2), CpuCount: aws.Int32(2), IsActive: aws.Bool(true), SupportedPlatforms: []lst.InstancePlatform{lst.InstancePlatformWindows}, PublicIpv4AddressCount: aws.Int32(1)},
+	{BundleId: aws.String("nano_ipv6_3_0"), Price: aws.Float32(3.5), RamSizeInGb: aws.Float32(0.5), CpuCount: aws.Int32(2), IsActive: aws.Bool(true), SupportedPlatforms: []lst.InstancePlatform{lst.InstancePlatformLinuxUnix}, PublicIpv4AddressCount: aws.Int32(0)},
+	{BundleId: aws.String("micro_ipv6_3_0"), Price: aws.Float32(5), RamSizeInGb: aws.Float32(1), CpuCount: aws.Int32(2), IsActive: aws.Bool(true), SupportedPlatforms: []lst.InstancePlatform{lst.InstancePlatformLinuxUnix}, PublicIpv4AddressCount: aws.Int32(0)},
+	{BundleId: aws.String("small_ipv6_3_0"), Price: aws.Float32(10), RamSizeInGb: aws.Float32(2), CpuCount: aws.Int32(2), IsActive: aws.Bool(true), SupportedPlatforms: []lst.InstancePlatform{lst.InstancePlatformLinuxUnix}, PublicIpv4AddressCount: aws.Int32(0)},
+}
+
+var fakeLSBlueprints = []lst.Blueprint{
+	{BlueprintId: aws.String("debian_12"), Platform: lst.InstancePlatformLinuxUnix},
+	{BlueprintId: aws.String("ubuntu_24_04"), Platform: lst.InstancePlatformLinuxUnix},
+	{BlueprintId: aws.String("amazon_linux_2023"), Platform: lst.InstancePlatformLinuxUnix},
+	{BlueprintId: aws.String("windows_server_2022"), Platform: lst.InstancePlatformWindows},
+}
+
+func (c *fakeLightsail) state() *fakeLSState { return c.b.lsState(c.region) }
+
+func (c *fakeLightsail) instance(name string) (*lst.Instance, error) {
+	s := c.state()
+	for i := range s.Instances {
+		if aws.ToString(s.Instances[i].Name) == name {
+			return &s.Instances[i], nil
+		}
+	}
+	return nil, fakeAPIError("NotFoundException", fmt.Sprintf("The Instance does not exist: %s", name))
+}
+
+func (c *fakeLightsail) staticIP(name string) (*lst.StaticIp, error) {
+	s := c.state()
+	for i := range s.StaticIPs {
+		if aws.ToString(s.StaticIPs[i].Name) == name {
+			return &s.StaticIPs[i], nil
+		}
+	}
+	return nil, fakeAPIError("NotFoundException", fmt.Sprintf("The StaticIp does not exist: %s", name))
+}
+
+func (c *fakeLightsail) op(kind string) []lst.Operation {
+	return []lst.Operation{{Id: aws.String(c.b.id("op")), OperationType: lst.OperationType(kind), Status: lst.OperationStatusSucceeded}}
+}
+
+func (c *fakeLightsail) GetRegions(ctx context.Context, in *lightsail.GetRegionsInput, _ ...func(*lightsail.Options)) (*lightsail.GetRegionsOutput, error) {
+	if err := c.b.begin("lightsail:GetRegions"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &lightsail.GetRegionsOutput{}
+	for _, r := range c.b.LSRegions {
+		out.Regions = append(out.Regions, lst.Region{Name: lst.RegionName(r)})
+	}
+	return out, nil
+}
+
+func (c *fakeLightsail) GetInstances(ctx context.Context, in *lightsail.GetInstancesInput, _ ...func(*lightsail.Options)) (*lightsail.GetInstancesOutput, error) {
+	if err := c.b.begin("lightsail:GetInstances"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	return &lightsail.GetInstancesOutput{Instances: append([]lst.Instance(nil), c.state().Instances...)}, nil
+}
+
+func (c *fakeLightsail) GetInstance(ctx context.Context, in *lightsail.GetInstanceInput, _ ...func(*lightsail.Options)) (*lightsail.GetInstanceOutput, error) {
+	if err := c.b.begin("lightsail:GetInstance"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	ins, err := c.instance(aws.ToString(in.InstanceName))
+	if err != nil {
+		return nil, err
+	}
+	cp := *ins
+	return &lightsail.GetInstanceOutput{Instance: &cp}, nil
+}
+
+func (c *fakeLightsail) CreateInstances(ctx context.Context, in *lightsail.CreateInstancesInput, _ ...func(*lightsail.Options)) (*lightsail.CreateInstancesOutput, error) {
+	if err := c.b.begin("lightsail:CreateInstances"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	var bundle *lst.Bundle
+	for i := range fakeLSBundles {
+		if aws.ToString(fakeLSBundles[i].BundleId) == aws.ToString(in.BundleId) {
+			bundle = &fakeLSBundles[i]
+		}
+	}
+	if bundle == nil {
+		return nil, fakeAPIError("InvalidInputException", fmt.Sprintf("The bundle ID is not valid: %s", aws.ToString(in.BundleId)))
+	}
+	ipType := in.IpAddressType
+	if ipType == "" {
+		ipType = lst.IpAddressTypeDualstack
+	}
+	if (ipType == lst.IpAddressTypeIpv6) != (aws.ToInt32(bundle.PublicIpv4AddressCount) == 0) {
+		return nil, fakeAPIError("InvalidInputException", fmt.Sprintf("The bundle %s does not support IP address type %s", aws.ToString(in.BundleId), ipType))
+	}
+	s := c.state()
+	keyName := "LightsailDefaultKeyPair"
+	if in.KeyPairName != nil {
+		if c.keyPair(*in.KeyPairName) < 0 {
+			return nil, fakeAPIError("NotFoundException", fmt.Sprintf("The KeyPair does not exist: %s", *in.KeyPairName))
+		}
+		keyName = *in.KeyPairName
+	}
+	out := &lightsail.CreateInstancesOutput{}
+	for _, name := range in.InstanceNames {
+		if _, err := c.instance(name); err == nil {
+			return nil, fakeAPIError("InvalidInputException", fmt.Sprintf("Some names are already in use: %s", name))
+		}
+		c.b.seq++
+		ins := lst.Instance{
+			Name:        aws.String(name),
+			Arn:         aws.String(fmt.Sprintf("arn:aws:lightsail:%s:%s:Instance/%s", c.region, c.b.AccountID, c.b.id("ls"))),
+			BlueprintId: in.BlueprintId, BundleId: in.BundleId,
+			Location:      &lst.ResourceLocation{AvailabilityZone: in.AvailabilityZone, RegionName: lst.RegionName(c.region)},
+			State:         &lst.InstanceState{Name: aws.String("running"), Code: aws.Int32(16)},
+			IsStaticIp:    aws.Bool(false),
+			IpAddressType: ipType,
+			SshKeyName:    aws.String(keyName),
+			Username:      aws.String(fakeLSUsername(aws.ToString(in.BlueprintId))),
+			Hardware:      &lst.InstanceHardware{CpuCount: bundle.CpuCount, RamSizeInGb: bundle.RamSizeInGb},
+			Networking: &lst.InstanceNetworking{Ports: []lst.InstancePortInfo{
+				fakeLSPort(lst.PortInfo{FromPort: 22, ToPort: 22, Protocol: lst.NetworkProtocolTcp}),
+				fakeLSPort(lst.PortInfo{FromPort: 80, ToPort: 80, Protocol: lst.NetworkProtocolTcp}),
+			}},
+			CreatedAt: aws.Time(time.Now()),
+		}
+		c.applyIPType(&ins)
+		s.Instances = append(s.Instances, ins)
+		out.Operations = append(out.Operations, c.op("CreateInstance")...)
+	}
+	return out, nil
+}
+
+func (c *fakeLightsail) setState(name, st string) ([]lst.Operation, error) {
+	ins, err := c.instance(name)
+	if err != nil {
+		return nil, err
+	}
+	ins.State = &lst.InstanceState{Name: aws.String(st)}
+	if st == "stopped" && !aws.ToBool(ins.IsStaticIp) {
+		ins.PublicIpAddress = nil
+	}
+	if st == "running" && ins.PublicIpAddress == nil && ins.IpAddressType != lst.IpAddressTypeIpv6 {
+		ins.PublicIpAddress = aws.String(c.b.publicIP())
+	}
+	return c.op(st), nil
+}
+
+func (c *fakeLightsail) StartInstance(ctx context.Context, in *lightsail.StartInstanceInput, _ ...func(*lightsail.Options)) (*lightsail.StartInstanceOutput, error) {
+	if err := c.b.begin("lightsail:StartInstance"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	ops, err := c.setState(aws.ToString(in.InstanceName), "running")
+	if err != nil {
+		return nil, err
+	}
+	return &lightsail.StartInstanceOutput{Operations: ops}, nil
+}
+
+func (c *fakeLightsail) StopInstance(ctx context.Context, in *lightsail.StopInstanceInput, _ ...func(*lightsail.Options)) (*lightsail.StopInstanceOutput, error) {
+	if err := c.b.begin("lightsail:StopInstance"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	ops, err := c.setState(aws.ToString(in.InstanceName), "stopped")
+	if err != nil {
+		return nil, err
+	}
+	return &lightsail.StopInstanceOutput{Operations: ops}, nil
+}
+
+func (c *fakeLightsail) RebootInstance(ctx context.Context, in *lightsail.RebootInstanceInput, _ ...func(*lightsail.Options)) (*lightsail.RebootInstanceOutput, error) {
+	if err := c.b.begin("lightsail:RebootInstance"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	ops, err := c.setState(aws.ToString(in.InstanceName), "running")
+	if err != nil {
+		return nil, err
+	}
+	return &lightsail.RebootInstanceOutput{Operations: ops}, nil
+}
+
+func (c *fakeLightsail) DeleteInstance(ctx context.Context, in *lightsail.DeleteInstanceInput, _ ...func(*lightsail.Options)) (*lightsail.DeleteInstanceOutput, error) {
+	if err := c.b.begin("lightsail:DeleteInstance"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	name := aws.ToString(in.InstanceName)
+	if _, err := c.instance(name); err != nil {
+		return nil, err
+	}
+	s := c.state()
+	for i := range s.Instances {
+		if aws.ToString(s.Instances[i].Name) == name {
+			s.Instances = append(s.Instances[:i], s.Instances[i+1:]...)
+			break
+		}
+	}
+	// 删除实例时固定 IP 自动解绑，但仍保留在账户中
+	for i := range s.StaticIPs {
+		if aws.ToString(s.StaticIPs[i].AttachedTo) == name {
+			s.StaticIPs[i].AttachedTo, s.StaticIPs[i].IsAttached = nil, aws.Bool(false)
+		}
+	}
+	return &lightsail.DeleteInstanceOutput{Operations: c.op("DeleteInstance")}, nil
+}
+
+// applyIPType 按 IP 地址类型分配或回收公网 IPv4 与 IPv6 地址
+func (c *fakeLightsail) applyIPType(ins *lst.Instance) {
+	if ins.IpAddressType == lst.IpAddressTypeIpv6 {
+		ins.PublicIpAddress = nil
+	} else if ins.PublicIpAddress == nil {
+		ins.PublicIpAddress = aws.String(c.b.publicIP())
+	}
+	if ins.IpAddressType == lst.IpAddressTypeIpv4 {
+		ins.Ipv6Addresses = nil
+	} else if len(ins.Ipv6Addresses) == 0 {
+		ins.Ipv6Addresses = []string{fmt.Sprintf("2600:1f18:%x::%x", 0x8000+c.b.seq%0x1000, c.b.seq)}
+	}
+}
+
+func (c *fakeLightsail) SetIpAddressType(ctx context.Context, in *lightsail.SetIpAddressTypeInput, _ ...func(*lightsail.Options)) (*lightsail.SetIpAddressTypeOutput, error) {
+	if err := c.b.begin("lightsail:SetIpAddressType"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	if in.ResourceType != lst.ResourceTypeInstance {
+		return nil, fakeAPIError("InvalidInputException", "only instances are supported by the fake backend")
+	}
+	ins, err := c.instance(aws.ToString(in.ResourceName))
+	if err != nil {
+		return nil, err
+	}
+	cur := ins.IpAddressType
+	if cur == "" {
+		cur = lst.IpAddressTypeDualstack
+	}
+	if cur == in.IpAddressType {
+		return &lightsail.SetIpAddressTypeOutput{Operations: c.op("SetIpAddressType")}, nil
+	}
+	bundle := aws.ToString(ins.BundleId)
+	switch {
+	case in.IpAddressType == lst.IpAddressTypeIpv6:
+		if aws.ToBool(ins.IsStaticIp) {
+			return nil, fakeAPIError("InvalidInputException", "Detach the static IP before switching to IPv6-only")
+		}
+		base, rest, _ := strings.Cut(bundle, "_")
+		bundle = base + "_ipv6_" + rest
+	case cur == lst.IpAddressTypeIpv6:
+		bundle = strings.Replace(bundle, "_ipv6_", "_", 1)
+	}
+	if bundle != aws.ToString(ins.BundleId) && !aws.ToBool(in.AcceptBundleUpdate) {
+		return nil, fakeAPIError("InvalidInputException", "You must accept the bundle update to change the IP address type")
+	}
+	ins.BundleId, ins.IpAddressType = aws.String(bundle), in.IpAddressType
+	c.b.seq++
+	c.applyIPType(ins)
+	return &lightsail.SetIpAddressTypeOutput{Operations: c.op("SetIpAddressType")}, nil
+}
+
+func (c *fakeLightsail) GetBundles(ctx context.Context, in *lightsail.GetBundlesInput, _ ...func(*lightsail.Options)) (*lightsail.GetBundlesOutput, error) {
+	if err := c.b.begin("lightsail:GetBundles"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	return &lightsail.GetBundlesOutput{Bundles: fakeLSBundles}, nil
+}
+
+func (c *fakeLightsail) GetBlueprints(ctx context.Context, in *lightsail.GetBlueprintsInput, _ ...func(*lightsail.Options)) (*lightsail.GetBlueprintsOutput, error) {
+	if err := c.b.begin("lightsail:GetBlueprints"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	return &lightsail.GetBlueprintsOutput{Blueprints: fakeLSBlueprints}, nil
+}
+
+func (c *fakeLightsail) PutInstancePublicPorts(ctx context.Context, in *lightsail.PutInstancePublicPortsInput, _ ...func(*lightsail.Options)) (*lightsail.PutInstancePublicPortsOutput, error) {
+	if err := c.b.begin("lightsail:PutInstancePublicPorts"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	ins, err := c.instance(aws.ToString(in.InstanceName))
+	if err != nil {
+		return nil, err
+	}
+	var ports []lst.InstancePortInfo
+	for _, p := range in.PortInfos {
+		ports = append(ports, fakeLSPort(p))
+	}
+	ins.Networking = &lst.InstanceNetworking{Ports: ports}
+	return &lightsail.PutInstancePublicPortsOutput{Operation: &c.op("PutInstancePublicPorts")[0]}, nil
+}
+
+// fakeLSPort 转换端口规则；未指定任何来源时与真实 API 一样对所有 IPv4 / IPv6 开放
+func fakeLSPort(p lst.PortInfo) lst.InstancePortInfo {
+	out := lst.InstancePortInfo{FromPort: p.FromPort, ToPort: p.ToPort, Protocol: p.Protocol, Cidrs: p.Cidrs, Ipv6Cidrs: p.Ipv6Cidrs, CidrListAliases: p.CidrListAliases}
+	if len(p.Cidrs)+len(p.Ipv6Cidrs)+len(p.CidrListAliases) == 0 {
+		out.Cidrs, out.Ipv6Cidrs = []string{"0.0.0.0/0"}, []string{"::/0"}
+	}
+	return out
+}
+
+func sameLSPort(a lst.InstancePortInfo, b *lst.PortInfo) bool {
+	return b != nil && a.Protocol == b.Protocol && a.FromPort == b.FromPort && a.ToPort == b.ToPort
+}
+
+func (c *fakeLightsail) OpenInstancePublicPorts(ctx context.Context, in *lightsail.OpenInstancePublicPortsInput, _ ...func(*lightsail.Options)) (*lightsail.OpenInstancePublicPortsOutput, error) {
+	if err := c.b.begin("lightsail:OpenInstancePublicPorts"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	ins, err := c.instance(aws.ToString(in.InstanceName))
+	if err != nil {
+		return nil, err
+	}
+	if in.PortInfo == nil {
+		return nil, fakeAPIError("InvalidInputException", "portInfo is required")
+	}
+	if ins.Networking == nil {
+		ins.Networking = &lst.InstanceNetworking{}
+	}
+	p := fakeLSPort(*in.PortInfo)
+	for i := range ins.Networking.Ports {
+		if sameLSPort(ins.Networking.Ports[i], in.PortInfo) {
+			ins.Networking.Ports[i] = p
+			return &lightsail.OpenInstancePublicPortsOutput{Operation: &c.op("OpenInstancePublicPorts")[0]}, nil
+		}
+	}
+	ins.Networking.Ports = append(ins.Networking.Ports, p)
+	return &lightsail.OpenInstancePublicPortsOutput{Operation: &c.op("OpenInstancePublicPorts")[0]}, nil
+}
+
+func (c *fakeLightsail) CloseInstancePublicPorts(ctx context.Context, in *lightsail.CloseInstancePublicPortsInput, _ ...func(*lightsail.Options)) (*lightsail.CloseInstancePublicPortsOutput, error) {
+	if err := c.b.begin("lightsail:CloseInstancePublicPorts"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	ins, err := c.instance(aws.ToString(in.InstanceName))
+	if err != nil {
+		return nil, err
+	}
+	if ins.Networking != nil {
+		ins.Networking.Ports = slices.DeleteFunc(ins.Networking.Ports, func(p lst.InstancePortInfo) bool { return sameLSPort(p, in.PortInfo) })
+	}
+	return &lightsail.CloseInstancePublicPortsOutput{Operation: &c.op("CloseInstancePublicPorts")[0]}, nil
+}
+
+func (c *fakeLightsail) GetStaticIps(ctx context.Context, in *lightsail.GetStaticIpsInput, _ ...func(*lightsail.Options)) (*lightsail.GetStaticIpsOutput, error) {
+	if err := c.b.begin("lightsail:GetStaticIps"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	return &lightsail.GetStaticIpsOutput{StaticIps: append([]lst.StaticIp(nil), c.state().StaticIPs...)}, nil
+}
+
+func (c *fakeLightsail) AllocateStaticIp(ctx context.Context, in *lightsail.AllocateStaticIpInput, _ ...func(*lightsail.Options)) (*lightsail.AllocateStaticIpOutput, error) {
+	if err := c.b.begin("lightsail:AllocateStaticIp"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	name := aws.ToString(in.StaticIpName)
+	if _, err := c.staticIP(name); err == nil {
+		return nil, fakeAPIError("InvalidInputException", fmt.Sprintf("The StaticIp name is already in use: %s", name))
+	}
+	s := c.state()
+	s.StaticIPs = append(s.StaticIPs, lst.StaticIp{
+		Name: aws.String(name), IpAddress: aws.String(c.b.publicIP()), IsAttached: aws.Bool(false),
+		Location: &lst.ResourceLocation{RegionName: lst.RegionName(c.region)},
+	})
+	return &lightsail.AllocateStaticIpOutput{Operations: c.op("AllocateStaticIp")}, nil
+}
+
+func (c *fakeLightsail) AttachStaticIp(ctx context.Context, in *lightsail.AttachStaticIpInput, _ ...func(*lightsail.Options)) (*lightsail.AttachStaticIpOutput, error) {
+	if err := c.b.begin("lightsail:AttachStaticIp"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	sip, err := c.staticIP(aws.ToString(in.StaticIpName))
+	if err != nil {
+		return nil, err
+	}
+	ins, err := c.instance(aws.ToString(in.InstanceName))
+	if err != nil {
+		return nil, err
+	}
+	if aws.ToBool(sip.IsAttached) {
+		return nil, fakeAPIError("InvalidInputException", "The StaticIp is already attached")
+	}
+	if ins.IpAddressType == lst.IpAddressTypeIpv6 {
+		return nil, fakeAPIError("InvalidInputException", "A static IP cannot be attached to an IPv6-only instance")
+	}
+	sip.AttachedTo, sip.IsAttached = ins.Name, aws.Bool(true)
+	ins.PublicIpAddress, ins.IsStaticIp = sip.IpAddress, aws.Bool(true)
+	return &lightsail.AttachStaticIpOutput{Operations: c.op("AttachStaticIp")}, nil
+}
+
+func (c *fakeLightsail) DetachStaticIp(ctx context.Context, in *lightsail.DetachStaticIpInput, _ ...func(*lightsail.Options)) (*lightsail.DetachStaticIpOutput, error) {
+	if err := c.b.begin("lightsail:DetachStaticIp"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	sip, err := c.staticIP(aws.ToString(in.StaticIpName))
+	if err != nil {
+		return nil, err
+	}
+	if ins, err := c.instance(aws.ToString(sip.AttachedTo)); err == nil {
+		ins.PublicIpAddress, ins.IsStaticIp = aws.String(c.b.publicIP()), aws.Bool(false)
+	}
+	sip.AttachedTo, sip.IsAttached = nil, aws.Bool(false)
+	return &lightsail.DetachStaticIpOutput{Operations: c.op("DetachStaticIp")}, nil
+}
+
+func (c *fakeLightsail) ReleaseStaticIp(ctx context.Context, in *lightsail.ReleaseStaticIpInput, _ ...func(*lightsail.Options)) (*lightsail.ReleaseStaticIpOutput, error) {
+	if err := c.b.begin("lightsail:ReleaseStaticIp"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	name := aws.ToString(in.StaticIpName)
+	sip, err := c.staticIP(name)
+	if err != nil {
+		return nil, err
+	}
+	if ins, err := c.instance(aws.ToString(sip.AttachedTo)); err == nil {
+		ins.PublicIpAddress, ins.IsStaticIp = aws.String(c.b.publicIP()), aws.Bool(false)
+	}
+	s := c.state()
+	for i := range s.StaticIPs {
+		if aws.ToString(s.StaticIPs[i].Name) == name {
+			s.StaticIPs = append(s.StaticIPs[:i], s.StaticIPs[i+1:]...)
+			break
+		}
+	}
+	return &lightsail.ReleaseStaticIpOutput{Operations: c.op("ReleaseStaticIp")}, nil
+}
+
+func fakeLSUsername(blueprint string) string {
+	switch {
+	case strings.HasPrefix(blueprint, "ubuntu"):
+		return "ubuntu"
+	case strings.HasPrefix(blueprint, "debian"):
+		return "admin"
+	case strings.HasPrefix(blueprint, "centos"):
+		return "centos"
+	}
+	return "ec2-user"
+}
+
+func (c *fakeLightsail) keyPair(name string) int {
+	return slices.IndexFunc(c.state().KeyPairs, func(k lst.KeyPair) bool { return aws.ToString(k.Name) == name })
+}
+
+func (c *fakeLightsail) GetKeyPairs(ctx context.Context, in *lightsail.GetKeyPairsInput, _ ...func(*lightsail.Options)) (*lightsail.GetKeyPairsOutput, error) {
+	if err := c.b.begin("lightsail:GetKeyPairs"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	return &lightsail.GetKeyPairsOutput{KeyPairs: append([]lst.KeyPair(nil), c.state().KeyPairs...)}, nil
+}
+
+func (c *fakeLightsail) ImportKeyPair(ctx context.Context, in *lightsail.ImportKeyPairInput, _ ...func(*lightsail.Options)) (*lightsail.ImportKeyPairOutput, error) {
+	if err := c.b.begin("lightsail:ImportKeyPair"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	name := aws.ToString(in.KeyPairName)
+	if c.keyPair(name) >= 0 {
+		return nil, fakeAPIError("InvalidInputException", fmt.Sprintf("The KeyPair name is already in use: %s", name))
+	}
+	fp, _, err := fakeFingerprint(aws.ToString(in.PublicKeyBase64))
+	if err != nil {
+		return nil, fakeAPIError("InvalidInputException", "The public key is not valid")
+	}
+	s := c.state()
+	s.KeyPairs = append(s.KeyPairs, lst.KeyPair{
+		Name: aws.String(name), Fingerprint: aws.String(fp), CreatedAt: aws.Time(time.Now()),
+		Location: &lst.ResourceLocation{RegionName: lst.RegionName(c.region)},
+	})
+	return &lightsail.ImportKeyPairOutput{Operation: &c.op("ImportKeyPair")[0]}, nil
+}
+
+func (c *fakeLightsail) DeleteKeyPair(ctx context.Context, in *lightsail.DeleteKeyPairInput, _ ...func(*lightsail.Options)) (*lightsail.DeleteKeyPairOutput, error) {
+	if err := c.b.begin("lightsail:DeleteKeyPair"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	i := c.keyPair(aws.ToString(in.KeyPairName))
+	if i < 0 {
+		return nil, fakeAPIError("NotFoundException", fmt.Sprintf("The KeyPair does not exist: %s", aws.ToString(in.KeyPairName)))
+	}
+	s := c.state()
+	s.KeyPairs = slices.Delete(s.KeyPairs, i, i+1)
+	return &lightsail.DeleteKeyPairOutput{Operation: &c.op("DeleteKeyPair")[0]}, nil
+}
+
+// -------------------- Fake STS / IAM / Lambda / RDS / Budgets / Account / Service Quotas --------------------
+
+type fakeSTS struct{ b *FakeBackend }
+
+func (c *fakeSTS) GetCallerIdentity(ctx context.Context, in *sts.GetCallerIdentityInput, _ ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	if err := c.b.begin("sts:GetCallerIdentity"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String(c.b.AccountID),
+		Arn:     aws.String("arn:aws:iam::" + c.b.AccountID + ":user/offline"),
+		UserId:  aws.String("AIDAOFFLINE"),
+	}, nil
+}
+
+func (c *fakeSTS) AssumeRole(ctx context.Context, in *sts.AssumeRoleInput, _ ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	if err := c.b.begin("sts:AssumeRole"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	exp := time.Now().Add(time.Hour)
+	return &sts.AssumeRoleOutput{
+		Credentials: &stsTypes.Credentials{
+			AccessKeyId: aws.String("ASIAOFFLINE"), SecretAccessKey: aws.String("offline"),
+			SessionToken: aws.String("offline"), Expiration: &exp,
+		},
+		AssumedRoleUser: &stsTypes.AssumedRoleUser{Arn: in.RoleArn, AssumedRoleId: aws.String("AROAOFFLINE:" + aws.ToString(in.RoleSessionName))},
+	}, nil
+}
+
+type fakeIAM struct{ b *FakeBackend }
+
+func (c *fakeIAM) CreateRole(ctx context.Context, in *iam.CreateRoleInput, _ ...func(*iam.Options)) (*iam.CreateRoleOutput, error) {
+	if err := c.b.begin("iam:CreateRole"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	name := aws.ToString(in.RoleName)
+	if c.b.roles[name] {
+		return nil, fakeAPIError("EntityAlreadyExists", "Role with name "+name+" already exists.")
+	}
+	c.b.roles[name] = true
+	return &iam.CreateRoleOutput{Role: &iamTypes.Role{
+		RoleName: in.RoleName, Arn: aws.String("arn:aws:iam::" + c.b.AccountID + ":role/" + name),
+		RoleId: aws.String(c.b.id("AROA")), Path: aws.String("/"), CreateDate: aws.Time(time.Now()),
+	}}, nil
+}
+
+func (c *fakeIAM) DeleteRole(ctx context.Context, in *iam.DeleteRoleInput, _ ...func(*iam.Options)) (*iam.DeleteRoleOutput, error) {
+	if err := c.b.begin("iam:DeleteRole"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	if !c.b.roles[aws.ToString(in.RoleName)] {
+		return nil, fakeAPIError("NoSuchEntity", "The role cannot be found")
+	}
+	delete(c.b.roles, aws.ToString(in.RoleName))
+	return &iam.DeleteRoleOutput{}, nil
+}
+
+type fakeLambda struct{ b *FakeBackend }
+
+func (c *fakeLambda) CreateFunction(ctx context.Context, in *lambda.CreateFunctionInput, _ ...func(*lambda.Options)) (*lambda.CreateFunctionOutput, error) {
+	if err := c.b.begin("lambda:CreateFunction"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	c.b.functions[aws.ToString(in.FunctionName)] = true
+	return &lambda.CreateFunctionOutput{FunctionName: in.FunctionName, State: lambdaTypes.StatePending}, nil
+}
+
+func (c *fakeLambda) GetFunction(ctx context.Context, in *lambda.GetFunctionInput, _ ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+	if err := c.b.begin("lambda:GetFunction"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	if !c.b.functions[aws.ToString(in.FunctionName)] {
+		return nil, fakeAPIError("ResourceNotFoundException", "Function not found")
+	}
+	return &lambda.GetFunctionOutput{Configuration: &lambdaTypes.FunctionConfiguration{FunctionName: in.FunctionName, State: lambdaTypes.StateActive}}, nil
+}
+
+func (c *fakeLambda) Invoke(ctx context.Context, in *lambda.InvokeInput, _ ...func(*lambda.Options)) (*lambda.InvokeOutput, error) {
+	if err := c.b.begin("lambda:Invoke"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	if !c.b.functions[aws.ToString(in.FunctionName)] {
+		return nil, fakeAPIError("ResourceNotFoundException", "Function not found")
+	}
+	return &lambda.InvokeOutput{StatusCode: 200, Payload: []byte(`"Hello AWS 80 USD"`)}, nil
+}
+
+func (c *fakeLambda) DeleteFunction(ctx context.Context, in *lambda.DeleteFunctionInput, _ ...func(*lambda.Options)) (*lambda.DeleteFunctionOutput, error) {
+	if err := c.b.begin("lambda:DeleteFunction"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	delete(c.b.functions, aws.ToString(in.FunctionName))
+	return &lambda.DeleteFunctionOutput{}, nil
+}
+
+type fakeRDS struct{ b *FakeBackend }
+
+func (c *fakeRDS) CreateDBInstance(ctx context.Context, in *rds.CreateDBInstanceInput, _ ...func(*rds.Options)) (*rds.CreateDBInstanceOutput, error) {
+	if err := c.b.begin("rds:CreateDBInstance"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	c.b.dbs[aws.ToString(in.DBInstanceIdentifier)] = "available"
+	return &rds.CreateDBInstanceOutput{DBInstance: &rdsTypes.DBInstance{DBInstanceIdentifier: in.DBInstanceIdentifier, DBInstanceStatus: aws.String("creating")}}, nil
+}
+
+func (c *fakeRDS) DescribeDBInstances(ctx context.Context, in *rds.DescribeDBInstancesInput, _ ...func(*rds.Options)) (*rds.DescribeDBInstancesOutput, error) {
+	if err := c.b.begin("rds:DescribeDBInstances"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	out := &rds.DescribeDBInstancesOutput{}
+	for id, st := range c.b.dbs {
+		if in.DBInstanceIdentifier == nil || *in.DBInstanceIdentifier == id {
+			out.DBInstances = append(out.DBInstances, rdsTypes.DBInstance{DBInstanceIdentifier: aws.String(id), DBInstanceStatus: aws.String(st)})
+		}
+	}
+	if in.DBInstanceIdentifier != nil && len(out.DBInstances) == 0 {
+		return nil, fakeAPIError("DBInstanceNotFound", "DBInstance "+*in.DBInstanceIdentifier+" not found.")
+	}
+	return out, nil
+}
+
+func (c *fakeRDS) DeleteDBInstance(ctx context.Context, in *rds.DeleteDBInstanceInput, _ ...func(*rds.Options)) (*rds.DeleteDBInstanceOutput, error) {
+	if err := c.b.begin("rds:DeleteDBInstance"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	delete(c.b.dbs, aws.ToString(in.DBInstanceIdentifier))
+	return &rds.DeleteDBInstanceOutput{}, nil
+}
+
+type fakeBudgets struct{ b *FakeBackend }
+
+func (c *fakeBudgets) CreateBudget(ctx context.Context, in *budgets.CreateBudgetInput, _ ...func(*budgets.Options)) (*budgets.CreateBudgetOutput, error) {
+	if err := c.b.begin("budgets:CreateBudget"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	name := ""
+	if in.Budget != nil {
+		name = aws.ToString(in.Budget.BudgetName)
+	}
+	if c.b.budgets[name] {
+		return nil, fakeAPIError("DuplicateRecordException", "the budget already exists")
+	}
+	c.b.budgets[name] = true
+	return &budgets.CreateBudgetOutput{}, nil
+}
+
+type fakeAccount struct{ b *FakeBackend }
+
+func (c *fakeAccount) EnableRegion(ctx context.Context, in *account.EnableRegionInput, _ ...func(*account.Options)) (*account.EnableRegionOutput, error) {
+	if err := c.b.begin("account:EnableRegion"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	for i := range c.b.Regions {
+		if c.b.Regions[i].Name == aws.ToString(in.RegionName) {
+			c.b.Regions[i].Status = "opted-in"
+			return &account.EnableRegionOutput{}, nil
+		}
+	}
+	return nil, fakeAPIError("ValidationException", "Invalid region")
+}
+
+type fakeServiceQuotas struct{ b *FakeBackend }
+
+func (c *fakeServiceQuotas) GetServiceQuota(ctx context.Context, in *servicequotas.GetServiceQuotaInput, _ ...func(*servicequotas.Options)) (*servicequotas.GetServiceQuotaOutput, error) {
+	if err := c.b.begin("servicequotas:GetServiceQuota"); err != nil {
+		c.b.mu.Unlock()
+		return nil, err
+	}
+	defer c.b.mu.Unlock()
+	return &servicequotas.GetServiceQuotaOutput{Quota: &sqTypes.ServiceQuota{
+		ServiceCode: in.ServiceCode, QuotaCode: in.QuotaCode, QuotaName: aws.String("Running On-Demand Standard instances"),
+		Value: aws.Float64(c.b.VCPUQuota),
+	}}, nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/lightsail"
+	"github.com/aws/smithy-go"
+)
+
+// -------------------- 账户健康检查 / 批量验 Key --------------------
+
+// 健康状态分类，机器可读输出使用英文代码，表格中显示中文说明
+const (
+	healthValid            = "valid"
+	healthInvalidSignature = "invalid-signature"
+	healthDeactivatedKey   = "deactivated-key"
+	healthSuspended        = "suspended"
+	healthNotSignedUp      = "not-signed-up"
+	healthRegionRestricted = "region-restricted"
+	healthSCPDenied        = "scp-denied"
+	healthExpired          = "expired-token"
+	healthMFARequired      = "mfa-required"
+	healthAccessDenied     = "access-denied"
+	healthError            = "error"
+)
+
+var healthLabels = map[string]string{
+	healthValid:            "✅ 正常",
+	healthInvalidSignature: "签名错误 (SK 不匹配)",
+	healthDeactivatedKey:   "密钥不存在或已停用",
+	healthSuspended:        "账户已暂停/封禁",
+	healthNotSignedUp:      "未开通服务",
+	healthRegionRestricted: "区域受限",
+	healthSCPDenied:        "被 SCP 拒绝",
+	healthExpired:          "凭证已过期",
+	healthMFARequired:      "需要 MFA",
+	healthAccessDenied:     "权限不足",
+	healthError:            "其他错误",
+}
+
+// healthFallbackRegion 用于判断 us-east-1 被拒绝时是否只是区域受限
+const healthFallbackRegion = "eu-west-1"
+
+// classifyHealth 先按 API 错误代码把账户级错误归入上面的分类；
+// AccessDenied 一类代码被 AWS 用于多种原因，只对它们再按消息中的完整短语细分
+func classifyHealth(err error) string {
+	if err == nil {
+		return healthValid
+	}
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return healthError
+	}
+	switch apiErr.ErrorCode() {
+	case "SignatureDoesNotMatch":
+		return healthInvalidSignature
+	case "InvalidClientTokenId", "InvalidAccessKeyId", "AuthFailure":
+		return healthDeactivatedKey
+	case "ExpiredToken", "ExpiredTokenException":
+		return healthExpired
+	case "Blocked":
+		return healthSuspended
+	case "OptInRequired", "SubscriptionRequiredException", "PendingVerification":
+		return healthNotSignedUp
+	case "AccessDenied", "AccessDeniedException", "UnauthorizedOperation":
+		return classifyAccessDenied(apiErr.ErrorMessage())
+	}
+	return healthError
+}
+
+// classifyAccessDenied 细分拒绝访问的原因：MFA 条件、SCP、账户暂停，其余为普通权限不足
+func classifyAccessDenied(msg string) string {
+	lower := strings.ToLower(msg)
+	switch {
+	case strings.Contains(lower, "multifactorauthentication"):
+		return healthMFARequired
+	case strings.Contains(lower, "service control policy"):
+		return healthSCPDenied
+	case strings.Contains(lower, "account is suspended") || strings.Contains(lower, "account has been suspended"):
+		return healthSuspended
+	}
+	return healthAccessDenied
+}
+
+// classifyAWSError 返回错误分类的中文说明，便于批量结果排查
+func classifyAWSError(err error) string {
+	return healthLabels[classifyHealth(err)]
+}
+
+type HealthRow struct {
+	Account      string   `json:"account" yaml:"account"`
+	Status       string   `json:"status" yaml:"status"`
+	AccountID    string   `json:"account_id" yaml:"account_id"`
+	ARN          string   `json:"arn" yaml:"arn"`
+	OptInRegions int      `json:"opt_in_regions" yaml:"opt_in_regions"`
+	VCPUQuota    *float64 `json:"vcpu_quota,omitempty" yaml:"vcpu_quota,omitempty"`
+	Lightsail    string   `json:"lightsail" yaml:"lightsail"`
+	Error        string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (h HealthRow) Healthy() bool { return h.Status == healthValid }
+
+// checkAccountHealth 依次检查 STS 身份、EC2 区域、vCPU 配额与 Lightsail；
+// STS 失败时直接返回，其余检查失败只影响对应字段
+func checkAccountHealth(ctx context.Context, a BatchAccount) HealthRow {
+	row := HealthRow{Account: a.Label, Status: healthValid, Lightsail: "-"}
+	id, err := stsIdentity(ctx, bootstrapRegion, a.Creds)
+	if err != nil {
+		row.Status, row.Error = classifyHealth(err), err.Error()
+		return row
+	}
+	row.AccountID, row.ARN = aws.ToString(id.Account), aws.ToString(id.Arn)
+
+	regions, err := getEC2RegionsWithStatus(ctx, a.Creds)
+	if err != nil {
+		row.Status, row.Error = classifyHealth(err), err.Error()
+		if row.Status == healthAccessDenied || row.Status == healthSCPDenied {
+			// us-east-1 被拒绝但其他区域可用，说明是按区域限制的策略
+			if cfg, cerr := mkCfg(ctx, healthFallbackRegion, a.Creds); cerr == nil {
+				if _, perr := newEC2Client(cfg).DescribeRegions(ctx, &ec2.DescribeRegionsInput{}); perr == nil {
+					row.Status = healthRegionRestricted
+				}
+			}
+		}
+	}
+	for _, r := range regions {
+		if r.Status != "not-opted-in" {
+			row.OptInRegions++
+		}
+	}
+
+	if v, err := getVCPUQuota(ctx, a.Creds); err == nil {
+		row.VCPUQuota = &v
+	}
+
+	if cfg, err := mkCfg(ctx, bootstrapRegion, a.Creds); err == nil {
+		_, lerr := newLightsailClient(cfg).GetInstances(ctx, &lightsail.GetInstancesInput{})
+		if lerr == nil {
+			row.Lightsail = "available"
+		} else {
+			row.Lightsail = classifyHealth(lerr)
+		}
+	}
+	return row
+}
+
+// runHealth 并发检查所有账户，结果保持账户输入顺序
+func runHealth(ctx context.Context, accounts []BatchAccount, concurrency int) []HealthRow {
+	rows := make([]HealthRow, len(accounts))
+	forEachAccount(ctx, accounts, concurrency, func(ctx context.Context, i int, a BatchAccount) {
+		rows[i] = checkAccountHealth(ctx, a)
+	})
+	return rows
+}
+
+func healthyLabels(rows []HealthRow) []string {
+	var out []string
+	for _, r := range rows {
+		if r.Healthy() {
+			out = append(out, r.Account)
+		}
+	}
+	return out
+}
+
+func healthReport(rows []HealthRow) Report {
+	r := Report{
+		Header:  "账户\t状态\t账户ID\tARN\t启用区域\tvCPU配额\tLightsail\t错误",
+		Columns: []string{"account", "status", "account_id", "arn", "opt_in_regions", "vcpu_quota", "lightsail", "error", "status_code"},
+		Cut:     map[int]int{3: 40, 7: 50},
+		Data:    rows,
+	}
+	if rows == nil {
+		r.Data = []HealthRow{}
+	}
+	for _, x := range rows {
+		quota := ""
+		if x.VCPUQuota != nil {
+			quota = strconv.FormatFloat(*x.VCPUQuota, 'f', -1, 64)
+		}
+		ls := x.Lightsail
+		if l, ok := healthLabels[ls]; ok {
+			ls = l
+		} else if ls == "available" {
+			ls = "可用"
+		}
+		r.Rows = append(r.Rows, []string{x.Account, healthLabels[x.Status], x.AccountID, x.ARN, strconv.Itoa(x.OptInRegions), quota, ls, x.Error, x.Status})
+	}
+	return r
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyHealth(t *testing.T) {
+	for _, c := range []struct {
+		err  error
+		want string
+	}{
+		{nil, healthValid},
+		{fakeAPIError("SignatureDoesNotMatch", "The request signature we calculated does not match"), healthInvalidSignature},
+		{fakeAPIError("InvalidClientTokenId", "The security token included in the request is invalid."), healthDeactivatedKey},
+		{fakeAPIError("AuthFailure", "AWS was not able to validate the provided access credentials"), healthDeactivatedKey},
+		{fakeAPIError("ExpiredToken", "The security token included in the request is expired"), healthExpired},
+		{fakeAPIError("OptInRequired", "You are not subscribed to this service."), healthNotSignedUp},
+		{fakeAPIError("Blocked", "This account is currently blocked"), healthSuspended},
+		{fakeAPIError("AccessDenied", "Access denied: aws:MultiFactorAuthenticationPresent is false"), healthMFARequired},
+		{fakeAPIError("AccessDenied", "User is not authorized to perform: sts:GetCallerIdentity with an explicit deny in a service control policy"), healthSCPDenied},
+		{fakeAPIError("UnauthorizedOperation", "Your account is suspended."), healthSuspended},
+		{fakeAPIError("UnauthorizedOperation", "You are not authorized to perform this operation."), healthAccessDenied},
+		// 消息中的单词不影响其他代码的分类
+		{fakeAPIError("InvalidParameterValue", "mfa device in a disabled region is blocked"), healthError},
+		{fakeAPIError("AccessDenied", "not authorized to access the mfa-logs bucket in a disabled region"), healthAccessDenied},
+		{errors.New("dial tcp: connection refused"), healthError},
+	} {
+		if got := classifyHealth(c.err); got != c.want {
+			t.Errorf("classifyHealth(%v) = %s，期望 %s", c.err, got, c.want)
+		}
+	}
+}
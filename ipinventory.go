@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/lightsail"
+)
+
+// -------------------- 弹性 IP / 固定 IP 清单 --------------------
+
+// 未绑定的弹性 IP 与 Lightsail 固定 IP 会持续计费，这里跨区域汇总并标出，便于批量释放或绑定。
+
+// PublicIPRow 为一个 EC2 弹性 IP 或 Lightsail 固定 IP
+type PublicIPRow struct {
+	Service    string `json:"service" yaml:"service"`
+	Region     string `json:"region" yaml:"region"`
+	ID         string `json:"id" yaml:"id"` // EC2 为分配 ID，Lightsail 为固定 IP 名称
+	Name       string `json:"name,omitempty" yaml:"name,omitempty"`
+	IP         string `json:"ip" yaml:"ip"`
+	AttachedTo string `json:"attached_to,omitempty" yaml:"attached_to,omitempty"` // 实例；EIP 只绑定在网卡上时为网卡 ID
+	Orphan     bool   `json:"orphan" yaml:"orphan"`
+}
+
+func (r PublicIPRow) matches(s string) bool { return s == r.IP || s == r.ID }
+
+func publicIPReport(rows []PublicIPRow) Report {
+	r := Report{
+		Header:  "#\t服务\t区域\tID / 名称\t标签\tIP\t绑定到\t状态",
+		Columns: []string{"index", "service", "region", "id", "name", "ip", "attached_to", "orphan"},
+		Cut:     map[int]int{4: 20},
+		Data:    rows,
+	}
+	if rows == nil {
+		r.Data = []PublicIPRow{}
+	}
+	for i, x := range rows {
+		st := "已绑定"
+		if x.Orphan {
+			st = "⚠️ 未绑定 (计费中)"
+		}
+		r.Rows = append(r.Rows, []string{strconv.Itoa(i + 1), x.Service, x.Region, x.ID, x.Name, x.IP, x.AttachedTo, st})
+	}
+	return r
+}
+
+func ec2PublicIPs(ctx context.Context, cli EC2API, region string) ([]PublicIPRow, error) {
+	out, err := cli.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{})
+	if err != nil {
+		return nil, err
+	}
+	var rows []PublicIPRow
+	for _, a := range out.Addresses {
+		row := PublicIPRow{
+			Service: "EC2", Region: region, ID: aws.ToString(a.AllocationId), IP: aws.ToString(a.PublicIp),
+			AttachedTo: orDefault(aws.ToString(a.InstanceId), aws.ToString(a.NetworkInterfaceId)),
+			Orphan:     a.AssociationId == nil,
+		}
+		for _, t := range a.Tags {
+			if aws.ToString(t.Key) == "Name" {
+				row.Name = aws.ToString(t.Value)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func lsPublicIPs(ctx context.Context, cli LightsailAPI, region string) ([]PublicIPRow, error) {
+	var rows []PublicIPRow
+	in := &lightsail.GetStaticIpsInput{}
+	for {
+		out, err := cli.GetStaticIps(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range out.StaticIps {
+			rows = append(rows, PublicIPRow{
+				Service: "Lightsail", Region: region, ID: aws.ToString(s.Name), IP: aws.ToString(s.IpAddress),
+				AttachedTo: aws.ToString(s.AttachedTo), Orphan: !aws.ToBool(s.IsAttached),
+			})
+		}
+		if aws.ToString(out.NextPageToken) == "" {
+			return rows, nil
+		}
+		in.PageToken = out.NextPageToken
+	}
+}
+
+// listPublicIPs 并发扫描各区域的弹性 IP 与固定 IP，未绑定的排在前面
+func listPublicIPs(ctx context.Context, ec2Regions, lsRegions []string, creds aws.CredentialsProvider) ([]PublicIPRow, []error) {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		rows []PublicIPRow
+		errs []error
+	)
+	fmt.Fprintf(os.Stderr, "正在并发扫描 %d 个 EC2 区域与 %d 个 Lightsail 区域...\n", len(ec2Regions), len(lsRegions))
+	scan := func(service, region string) {
+		defer wg.Done()
+		cfg, err := mkCfg(ctx, region, creds)
+		var local []PublicIPRow
+		if err == nil {
+			if service == "EC2" {
+				local, err = ec2PublicIPs(ctx, newEC2Client(cfg), region)
+			} else {
+				local, err = lsPublicIPs(ctx, newLightsailClient(cfg), region)
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %v", service, region, err))
+			return
+		}
+		rows = append(rows, local...)
+	}
+	for _, r := range ec2Regions {
+		wg.Add(1)
+		go scan("EC2", r)
+	}
+	for _, r := range lsRegions {
+		wg.Add(1)
+		go scan("Lightsail", r)
+	}
+	wg.Wait()
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Orphan != b.Orphan {
+			return a.Orphan
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.IP < b.IP
+	})
+	return rows, errs
+}
+
+// releasePublicIP 释放弹性 IP / 固定 IP；仍在绑定中的拒绝释放
+func releasePublicIP(ctx context.Context, row PublicIPRow, creds aws.CredentialsProvider) error {
+	if !row.Orphan {
+		return fmt.Errorf("%s 仍绑定在 %s 上，请先解绑", row.IP, row.AttachedTo)
+	}
+	cfg, err := mkCfg(ctx, row.Region, creds)
+	if err != nil {
+		return err
+	}
+	if row.Service == "EC2" {
+		_, err = newEC2Client(cfg).ReleaseAddress(ctx, &ec2.ReleaseAddressInput{AllocationId: aws.String(row.ID)})
+	} else {
+		_, err = newLightsailClient(cfg).ReleaseStaticIp(ctx, &lightsail.ReleaseStaticIpInput{StaticIpName: aws.String(row.ID)})
+	}
+	return err
+}
+
+// attachPublicIP 把未绑定的弹性 IP / 固定 IP 绑定到同区域的实例 (EC2 为实例 ID，Lightsail 为实例名称)
+func attachPublicIP(ctx context.Context, row PublicIPRow, instance string, creds aws.CredentialsProvider) error {
+	if !row.Orphan {
+		return fmt.Errorf("%s 已绑定在 %s 上", row.IP, row.AttachedTo)
+	}
+	cfg, err := mkCfg(ctx, row.Region, creds)
+	if err != nil {
+		return err
+	}
+	if row.Service == "EC2" {
+		_, err = newEC2Client(cfg).AssociateAddress(ctx, &ec2.AssociateAddressInput{AllocationId: aws.String(row.ID), InstanceId: aws.String(instance)})
+	} else {
+		_, err = newLightsailClient(cfg).AttachStaticIp(ctx, &lightsail.AttachStaticIpInput{StaticIpName: aws.String(row.ID), InstanceName: aws.String(instance)})
+	}
+	return err
+}
+
+// releasePublicIPs 逐个释放并打印结果，返回失败数
+func releasePublicIPs(ctx context.Context, rows []PublicIPRow, creds aws.CredentialsProvider) int {
+	failed := 0
+	for _, r := range rows {
+		if err := releasePublicIP(ctx, r, creds); err != nil {
+			fmt.Printf("❌ %s %s (%s): %v\n", r.Service, r.IP, r.Region, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✅ 已释放 %s %s (%s)\n", r.Service, r.IP, r.Region)
+	}
+	return failed
+}
+
+func orphanIPs(rows []PublicIPRow) []PublicIPRow {
+	var out []PublicIPRow
+	for _, r := range rows {
+		if r.Orphan {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// -------------------- 交互式清单 --------------------
+
+func publicIPMenu(ctx context.Context, ec2Regions, lsRegions []string, creds aws.CredentialsProvider) {
+	for {
+		rows, errs := listPublicIPs(ctx, ec2Regions, lsRegions, creds)
+		for _, e := range errs {
+			fmt.Println("⚠️", e)
+		}
+		fmt.Println("\n--- 🌐 弹性 IP / 固定 IP 清单 ---")
+		if len(rows) == 0 {
+			fmt.Println("(无)")
+			return
+		}
+		writeReport(os.Stdout, "table", publicIPReport(rows))
+		orphans := orphanIPs(rows)
+		if len(orphans) > 0 {
+			fmt.Printf("\n⚠️ %d 个 IP 未绑定任何实例，仍在持续计费\n", len(orphans))
+		}
+		fmt.Println("\n 1) 🗑️ 释放选中的 IP  2) 🧹 释放全部未绑定 IP  3) 🔗 绑定到实例  0) 返回")
+		switch input("选择: ", "0") {
+		case "1":
+			idx, err := parseSelection(input("要释放的序号 (如 1,3-4): ", ""), len(rows))
+			if err != nil || len(idx) == 0 {
+				fmt.Println("❌ 未选择 IP")
+				continue
+			}
+			var sel []PublicIPRow
+			for _, i := range idx {
+				sel = append(sel, rows[i])
+			}
+			if yes(input(fmt.Sprintf("⚠️ 释放后 IP 无法找回，确认释放 %d 个 IP? [y/N]: ", len(sel)), "n")) {
+				releasePublicIPs(ctx, sel, creds)
+			}
+		case "2":
+			if len(orphans) == 0 {
+				fmt.Println("✅ 没有未绑定的 IP")
+				continue
+			}
+			if yes(input(fmt.Sprintf("⚠️ 释放后 IP 无法找回，确认释放全部 %d 个未绑定 IP? [y/N]: ", len(orphans)), "n")) {
+				releasePublicIPs(ctx, orphans, creds)
+			}
+		case "3":
+			publicIPAttachInteractive(ctx, rows, creds)
+		default:
+			return
+		}
+	}
+}
+
+func publicIPAttachInteractive(ctx context.Context, rows []PublicIPRow, creds aws.CredentialsProvider) {
+	i := mustInt(input("要绑定的 IP 序号: ", "0"))
+	if i < 1 || i > len(rows) {
+		return
+	}
+	row := rows[i-1]
+	if !row.Orphan {
+		fmt.Printf("❌ %s 已绑定在 %s 上\n", row.IP, row.AttachedTo)
+		return
+	}
+	// 只能绑定同区域的实例
+	var names []string
+	if row.Service == "EC2" {
+		list, _ := ec2ListAll(ctx, []string{row.Region}, creds)
+		printEC2Rows(list)
+		for _, x := range list {
+			names = append(names, x.ID)
+		}
+	} else {
+		list, _ := lsListAll(ctx, []string{row.Region}, creds)
+		printLSRows(list)
+		for _, x := range list {
+			names = append(names, x.Name)
+		}
+	}
+	if len(names) == 0 {
+		fmt.Println("❌", row.Region, "中没有实例")
+		return
+	}
+	j := mustInt(input("绑定到实例序号: ", "0"))
+	if j < 1 || j > len(names) {
+		return
+	}
+	if err := attachPublicIP(ctx, row, names[j-1], creds); err != nil {
+		fmt.Println("❌ 绑定失败:", err)
+		return
+	}
+	fmt.Printf("✅ 已把 %s 绑定到 %s\n", row.IP, names[j-1])
+}
+
+// findPublicIPs 按 IP 或 ID 在清单中查找，任一未找到时报错
+func findPublicIPs(rows []PublicIPRow, keys []string) ([]PublicIPRow, error) {
+	var out []PublicIPRow
+	for _, k := range keys {
+		found := false
+		for _, r := range rows {
+			if r.matches(k) {
+				out = append(out, r)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("未找到 %s", k)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("未指定 IP")
+	}
+	return out, nil
+}
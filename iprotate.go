@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2t "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/lightsail"
+	lst "github.com/aws/aws-sdk-go-v2/service/lightsail/types"
+)
+
+// -------------------- 更换公网 IP --------------------
+
+// EC2 两种方式：
+//   restart  停止再启动，重新分配动态公网 IP (免费，但会中断服务；绑定了 EIP 时无效)
+//   eip      申请新 EIP 并绑定 (自动替换旧绑定)，随后释放旧 EIP
+// Lightsail：有固定 IP 时换成新的固定 IP；否则临时绑定一个固定 IP 再解绑释放，实例随之获得新的动态 IP。
+
+const (
+	rotateRestart = "restart"
+	rotateEIP     = "eip"
+)
+
+var (
+	rotatePollInterval = 5 * time.Second
+	rotateTimeout      = 5 * time.Minute
+	probeTimeout       = 90 * time.Second
+)
+
+// RotateResult 为一台实例更换公网 IP 的结果
+type RotateResult struct {
+	Service  string `json:"service" yaml:"service"`
+	Region   string `json:"region" yaml:"region"`
+	Instance string `json:"instance" yaml:"instance"`
+	Method   string `json:"method" yaml:"method"`
+	OldIP    string `json:"old_ip" yaml:"old_ip"`
+	NewIP    string `json:"new_ip" yaml:"new_ip"`
+	Probe    string `json:"probe,omitempty" yaml:"probe,omitempty"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func rotateReport(rows []RotateResult) Report {
+	r := Report{
+		Header:  "服务\t区域\t实例\t方式\t原 IP\t新 IP\t连通性\t错误",
+		Columns: []string{"service", "region", "instance", "method", "old_ip", "new_ip", "probe", "error"},
+		Data:    rows,
+	}
+	if rows == nil {
+		r.Data = []RotateResult{}
+	}
+	for _, x := range rows {
+		r.Rows = append(r.Rows, []string{x.Service, x.Region, x.Instance, x.Method, x.OldIP, x.NewIP, x.Probe, x.Error})
+	}
+	return r
+}
+
+// pollUntil 先检查一次 done，未完成时每隔 rotatePollInterval 重试，直到完成、出错或超时
+func pollUntil(ctx context.Context, timeout time.Duration, what string, done func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := done()
+		if err != nil || ok {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待%s超时 (%s)", what, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(rotatePollInterval):
+		}
+	}
+}
+
+// probeTCP 反复尝试连接 ip:port 直到成功或超时，返回描述；配置了 SOCKS5 代理时经代理连接
+func probeTCP(ctx context.Context, ip string, port int) string {
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
+	start := time.Now()
+	err := pollUntil(ctx, probeTimeout, " "+addr+" 连通", func() (bool, error) {
+		dctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		conn, err := sshNetDial(dctx, addr)
+		if err != nil {
+			return false, nil
+		}
+		conn.Close()
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Sprintf("❌ %d 不通", port)
+	}
+	return fmt.Sprintf("✅ %d 可达 (%ds)", port, int(time.Since(start).Seconds()))
+}
+
+// -------------------- EC2 --------------------
+
+func ec2Instance(ctx context.Context, cli EC2API, id string) (ec2t.Instance, error) {
+	out, err := cli.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{id}})
+	if err != nil {
+		return ec2t.Instance{}, err
+	}
+	if len(out.Reservations) == 0 || len(out.Reservations[0].Instances) == 0 {
+		return ec2t.Instance{}, fmt.Errorf("未找到实例 %s", id)
+	}
+	return out.Reservations[0].Instances[0], nil
+}
+
+func waitEC2State(ctx context.Context, cli EC2API, id string, want ec2t.InstanceStateName) (ec2t.Instance, error) {
+	var ins ec2t.Instance
+	err := pollUntil(ctx, rotateTimeout, "实例 "+string(want), func() (bool, error) {
+		var err error
+		ins, err = ec2Instance(ctx, cli, id)
+		return err == nil && ins.State != nil && ins.State.Name == want, err
+	})
+	return ins, err
+}
+
+func instanceEIPs(ctx context.Context, cli EC2API, id string) ([]ec2t.Address, error) {
+	out, err := cli.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{
+		Filters: []ec2t.Filter{{Name: aws.String("instance-id"), Values: []string{id}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Addresses, nil
+}
+
+// ec2RotateIP 按 method 为实例更换公网 IPv4，返回原 IP 与新 IP
+func ec2RotateIP(ctx context.Context, cli EC2API, id, method string) (string, string, error) {
+	ins, err := ec2Instance(ctx, cli, id)
+	if err != nil {
+		return "", "", err
+	}
+	oldIP := aws.ToString(ins.PublicIpAddress)
+	eips, err := instanceEIPs(ctx, cli, id)
+	if err != nil {
+		return oldIP, "", err
+	}
+	switch method {
+	case rotateRestart:
+		if len(eips) > 0 {
+			return oldIP, "", errors.New("实例绑定了弹性 IP，停止/启动不会更换，请使用 eip 方式")
+		}
+		if ins.State != nil && ins.State.Name != ec2t.InstanceStateNameStopped {
+			if _, err := cli.StopInstances(ctx, &ec2.StopInstancesInput{InstanceIds: []string{id}}); err != nil {
+				return oldIP, "", err
+			}
+			if _, err := waitEC2State(ctx, cli, id, ec2t.InstanceStateNameStopped); err != nil {
+				return oldIP, "", err
+			}
+		}
+		if _, err := cli.StartInstances(ctx, &ec2.StartInstancesInput{InstanceIds: []string{id}}); err != nil {
+			return oldIP, "", err
+		}
+		ins, err := waitEC2State(ctx, cli, id, ec2t.InstanceStateNameRunning)
+		if err != nil {
+			return oldIP, "", err
+		}
+		newIP := aws.ToString(ins.PublicIpAddress)
+		if newIP == "" {
+			return oldIP, "", errors.New("实例启动后没有公网 IP (子网未开启自动分配公网 IP)")
+		}
+		if newIP == oldIP {
+			return oldIP, newIP, fmt.Errorf("重启后公网 IP 仍为 %s，未更换", oldIP)
+		}
+		return oldIP, newIP, nil
+	case rotateEIP:
+		alloc, err := cli.AllocateAddress(ctx, &ec2.AllocateAddressInput{Domain: ec2t.DomainTypeVpc})
+		if err != nil {
+			return oldIP, "", fmt.Errorf("申请 EIP 失败: %v", err)
+		}
+		if _, err := cli.AssociateAddress(ctx, &ec2.AssociateAddressInput{
+			InstanceId: aws.String(id), AllocationId: alloc.AllocationId, AllowReassociation: aws.Bool(true),
+		}); err != nil {
+			cli.ReleaseAddress(ctx, &ec2.ReleaseAddressInput{AllocationId: alloc.AllocationId})
+			return oldIP, "", fmt.Errorf("绑定 EIP 失败 (已释放新 EIP): %v", err)
+		}
+		newIP := aws.ToString(alloc.PublicIp)
+		for _, a := range eips {
+			if err := releaseOldEIP(ctx, cli, a); err != nil {
+				return oldIP, newIP, err
+			}
+		}
+		return oldIP, newIP, nil
+	}
+	return oldIP, "", fmt.Errorf("未知方式: %s (可选 restart/eip)", method)
+}
+
+// releaseOldEIP 释放被新 EIP 替换下来的旧 EIP；仍绑定在其他地址上 (如辅助私有 IP) 时保留
+func releaseOldEIP(ctx context.Context, cli EC2API, old ec2t.Address) error {
+	out, err := cli.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{AllocationIds: []string{aws.ToString(old.AllocationId)}})
+	if err != nil {
+		return err
+	}
+	if len(out.Addresses) > 0 && out.Addresses[0].AssociationId != nil {
+		return fmt.Errorf("旧 EIP %s 仍绑定在 %s 上，未释放", aws.ToString(old.PublicIp), aws.ToString(out.Addresses[0].PrivateIpAddress))
+	}
+	if _, err := cli.ReleaseAddress(ctx, &ec2.ReleaseAddressInput{AllocationId: old.AllocationId}); err != nil {
+		return fmt.Errorf("释放旧 EIP %s 失败: %v", aws.ToString(old.PublicIp), err)
+	}
+	return nil
+}
+
+// -------------------- Lightsail --------------------
+
+var lsNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// lsRotateIP 为 Lightsail 实例更换公网 IPv4，返回方式、原 IP 与新 IP
+func lsRotateIP(ctx context.Context, cli LightsailAPI, name string) (string, string, string, error) {
+	out, err := cli.GetInstance(ctx, &lightsail.GetInstanceInput{InstanceName: aws.String(name)})
+	if err != nil {
+		return "", "", "", err
+	}
+	if lsIPTypeOf(*out.Instance) == lst.IpAddressTypeIpv6 {
+		return "", "", "", fmt.Errorf("%s 是 IPv6-only 实例，没有公网 IPv4 可更换", name)
+	}
+	oldIP := aws.ToString(out.Instance.PublicIpAddress)
+	var current string
+	if aws.ToBool(out.Instance.IsStaticIp) {
+		sips, err := cli.GetStaticIps(ctx, &lightsail.GetStaticIpsInput{})
+		if err != nil {
+			return "static", oldIP, "", err
+		}
+		for _, s := range sips.StaticIps {
+			if aws.ToString(s.AttachedTo) == name {
+				current = aws.ToString(s.Name)
+			}
+		}
+	}
+	newName := fmt.Sprintf("%s-ip-%d", lsNameUnsafe.ReplaceAllString(name, "-"), time.Now().Unix())
+	if _, err := cli.AllocateStaticIp(ctx, &lightsail.AllocateStaticIpInput{StaticIpName: aws.String(newName)}); err != nil {
+		return "", oldIP, "", fmt.Errorf("申请固定 IP 失败: %v", err)
+	}
+	if current != "" {
+		// 固定 IP → 新固定 IP
+		if _, err := cli.DetachStaticIp(ctx, &lightsail.DetachStaticIpInput{StaticIpName: aws.String(current)}); err != nil {
+			cli.ReleaseStaticIp(ctx, &lightsail.ReleaseStaticIpInput{StaticIpName: aws.String(newName)})
+			return "static", oldIP, "", fmt.Errorf("解绑原固定 IP 失败: %v", err)
+		}
+		if err := lsAttachAndWait(ctx, cli, name, newName); err != nil {
+			return "static", oldIP, "", lsRestoreStaticIP(ctx, cli, name, current, newName, err)
+		}
+		if _, err := cli.ReleaseStaticIp(ctx, &lightsail.ReleaseStaticIpInput{StaticIpName: aws.String(current)}); err != nil {
+			return "static", oldIP, lsPublicIP(ctx, cli, name), fmt.Errorf("释放原固定 IP %s 失败: %v", current, err)
+		}
+		return "static", oldIP, lsPublicIP(ctx, cli, name), nil
+	}
+	// 动态 IP：临时绑定固定 IP 再解绑释放，实例获得新的动态 IP
+	if err := lsAttachAndWait(ctx, cli, name, newName); err != nil {
+		cli.ReleaseStaticIp(ctx, &lightsail.ReleaseStaticIpInput{StaticIpName: aws.String(newName)})
+		return "dynamic", oldIP, "", fmt.Errorf("绑定临时固定 IP 失败: %v", err)
+	}
+	if _, err := cli.DetachStaticIp(ctx, &lightsail.DetachStaticIpInput{StaticIpName: aws.String(newName)}); err != nil {
+		return "dynamic", oldIP, "", fmt.Errorf("解绑临时固定 IP %s 失败: %v", newName, err)
+	}
+	var newIP string
+	err = pollUntil(ctx, rotateTimeout, "新的动态 IP", func() (bool, error) {
+		out, err := cli.GetInstance(ctx, &lightsail.GetInstanceInput{InstanceName: aws.String(name)})
+		if err != nil {
+			return false, err
+		}
+		newIP = aws.ToString(out.Instance.PublicIpAddress)
+		return !aws.ToBool(out.Instance.IsStaticIp) && newIP != "", nil
+	})
+	if _, rerr := cli.ReleaseStaticIp(ctx, &lightsail.ReleaseStaticIpInput{StaticIpName: aws.String(newName)}); rerr != nil && err == nil {
+		err = fmt.Errorf("释放临时固定 IP %s 失败: %v", newName, rerr)
+	}
+	if err == nil && newIP == oldIP {
+		err = fmt.Errorf("解绑临时固定 IP 后公网 IP 仍为 %s，未更换", oldIP)
+	}
+	return "dynamic", oldIP, newIP, err
+}
+
+// lsRestoreStaticIP 在新固定 IP 绑定失败后释放它并重新绑定原固定 IP，返回包含恢复结果的错误
+func lsRestoreStaticIP(ctx context.Context, cli LightsailAPI, name, current, newName string, cause error) error {
+	if _, err := cli.ReleaseStaticIp(ctx, &lightsail.ReleaseStaticIpInput{StaticIpName: aws.String(newName)}); err != nil {
+		cause = fmt.Errorf("%v；释放新固定 IP %s 失败: %v", cause, newName, err)
+	}
+	if err := lsAttachAndWait(ctx, cli, name, current); err != nil {
+		return fmt.Errorf("绑定新固定 IP 失败: %v；重新绑定原固定 IP %s 也失败 (已解绑未释放): %v", cause, current, err)
+	}
+	return fmt.Errorf("绑定新固定 IP 失败 (已恢复原固定 IP %s): %v", current, cause)
+}
+
+func lsAttachAndWait(ctx context.Context, cli LightsailAPI, name, ipName string) error {
+	if _, err := cli.AttachStaticIp(ctx, &lightsail.AttachStaticIpInput{InstanceName: aws.String(name), StaticIpName: aws.String(ipName)}); err != nil {
+		return err
+	}
+	return pollUntil(ctx, rotateTimeout, "固定 IP 生效", func() (bool, error) {
+		out, err := cli.GetInstance(ctx, &lightsail.GetInstanceInput{InstanceName: aws.String(name)})
+		return err == nil && aws.ToBool(out.Instance.IsStaticIp), err
+	})
+}
+
+func lsPublicIP(ctx context.Context, cli LightsailAPI, name string) string {
+	out, err := cli.GetInstance(ctx, &lightsail.GetInstanceInput{InstanceName: aws.String(name)})
+	if err != nil {
+		return ""
+	}
+	return aws.ToString(out.Instance.PublicIpAddress)
+}
+
+// -------------------- 批量 --------------------
+
+// RotateJob 为一台待更换 IP 的实例
+type RotateJob struct {
+	Service, Region, Instance string
+}
+
+// rotateAll 并发更换 jobs 的公网 IP；probePort > 0 时在更换后检测该 TCP 端口。
+// 逐台进度写到 stderr，stdout 只留给结果报告 (--output json/csv/yaml)
+func rotateAll(ctx context.Context, jobs []RotateJob, creds aws.CredentialsProvider, method string, probePort, concurrency int) []RotateResult {
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	results := make([]RotateResult, len(jobs))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i, j := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			res := RotateResult{Service: j.Service, Region: j.Region, Instance: j.Instance, Method: method}
+			cfg, err := mkCfg(ctx, j.Region, creds)
+			if err == nil {
+				if j.Service == "ec2" {
+					res.OldIP, res.NewIP, err = ec2RotateIP(ctx, newEC2Client(cfg), j.Instance, method)
+				} else {
+					res.Method, res.OldIP, res.NewIP, err = lsRotateIP(ctx, newLightsailClient(cfg), j.Instance)
+				}
+			}
+			if err != nil {
+				res.Error = err.Error()
+			}
+			if res.NewIP != "" && probePort > 0 {
+				res.Probe = probeTCP(ctx, res.NewIP, probePort)
+			}
+			mu.Lock()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[%s] ❌ %v\n", j.Instance, err)
+			} else {
+				fmt.Fprintf(os.Stderr, "[%s] ✅ %s → %s %s\n", j.Instance, orDefault(res.OldIP, "(无)"), res.NewIP, res.Probe)
+			}
+			mu.Unlock()
+			results[i] = res
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// promptRotate 询问 EC2 更换方式 (ls 为 true 时不询问) 与检测端口
+func promptRotate(ls bool) (method string, probePort int, ok bool) {
+	method = "static"
+	if !ls {
+		fmt.Println("更换方式:")
+		fmt.Println("  1) 停止再启动，换新的动态 IP (免费，会中断服务) [默认]")
+		fmt.Println("  2) 申请新弹性 IP 替换，并释放旧弹性 IP")
+		method = rotateRestart
+		if input("请选择 [1]: ", "1") == "2" {
+			method = rotateEIP
+		}
+	}
+	if p := input("更换后检测 TCP 端口连通性 (端口号，留空跳过): ", ""); p != "" {
+		if probePort = mustInt(p); probePort <= 0 || probePort > 65535 {
+			fmt.Println("❌ 端口无效")
+			return "", 0, false
+		}
+	}
+	return method, probePort, yes(input("⚠️ 原公网 IP 将被释放且无法找回，确认更换? [y/N]: ", "n"))
+}
+
+// rotateInteractive 在实例列表中选择多台实例并批量更换公网 IP
+func rotateInteractive(ctx context.Context, jobs []RotateJob, creds aws.CredentialsProvider) {
+	if len(jobs) == 0 {
+		fmt.Println("❌ 未选择实例")
+		return
+	}
+	method, port, ok := promptRotate(jobs[0].Service == "lightsail")
+	if !ok {
+		return
+	}
+	fmt.Printf("🔄 正在为 %d 台实例更换公网 IP...\n", len(jobs))
+	results := rotateAll(ctx, jobs, creds, method, port, 4)
+	fmt.Println()
+	writeReport(os.Stdout, "table", rotateReport(results))
+}
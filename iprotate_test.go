@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/lightsail"
+)
+
+// stickyEC2 模拟停止再启动后仍拿回原公网 IP 的情况
+type stickyEC2 struct {
+	EC2API
+	b *FakeBackend
+}
+
+func (c stickyEC2) StartInstances(ctx context.Context, in *ec2.StartInstancesInput, opts ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
+	out, err := c.EC2API.StartInstances(ctx, in, opts...)
+	s := c.b.ec2State("us-east-1")
+	for i := range s.Instances {
+		s.Instances[i].PublicIpAddress = aws.String("3.80.0.1")
+	}
+	return out, err
+}
+
+func TestEC2RotateRestart(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newEC2Client(aws.Config{Region: "us-east-1"})
+	ctx := context.Background()
+	out, err := ec2Launch(ctx, cli, "us-east-1", EC2LaunchOptions{AMI: "debian-12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := aws.ToString(out[0].InstanceId)
+	oldIP, newIP, err := ec2RotateIP(ctx, cli, id, rotateRestart)
+	if err != nil || newIP == "" || newIP == oldIP {
+		t.Fatalf("更换结果 %s → %s, %v", oldIP, newIP, err)
+	}
+
+	// 拿回同一个 IP 时不能报告成功
+	b.ec2State("us-east-1").Instances[0].PublicIpAddress = aws.String("3.80.0.1")
+	if _, _, err := ec2RotateIP(ctx, stickyEC2{cli, b}, id, rotateRestart); err == nil || !strings.Contains(err.Error(), "未更换") {
+		t.Fatalf("IP 未变化时应报错，得到 %v", err)
+	}
+}
+
+func TestLSRotateStatic(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newLightsailClient(aws.Config{Region: "us-east-1"})
+	ctx := context.Background()
+	if err := lsLaunch(ctx, cli, LSLaunchOptions{AZ: "us-east-1a", Name: "web", Bundle: "nano_3_0", Blueprint: "debian_12"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.AllocateStaticIp(ctx, &lightsail.AllocateStaticIpInput{StaticIpName: aws.String("ip-web")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lsAttachAndWait(ctx, cli, "web", "ip-web"); err != nil {
+		t.Fatal(err)
+	}
+	oldIP := aws.ToString(lsInstance(b, "web").PublicIpAddress)
+
+	// 新固定 IP 绑定失败：释放新 IP 并重新绑定原固定 IP
+	b.FailNext("lightsail:AttachStaticIp", fakeAPIError("ServiceException", "internal error"))
+	if _, _, _, err := lsRotateIP(ctx, cli, "web"); err == nil || !strings.Contains(err.Error(), "已恢复原固定 IP ip-web") {
+		t.Fatalf("应报告已恢复原固定 IP，得到 %v", err)
+	}
+	sips := b.lsState("us-east-1").StaticIPs
+	if len(sips) != 1 || aws.ToString(sips[0].AttachedTo) != "web" {
+		t.Fatalf("应只剩原固定 IP 且仍绑定在实例上: %+v", sips)
+	}
+	if ip := aws.ToString(lsInstance(b, "web").PublicIpAddress); ip != oldIP {
+		t.Fatalf("公网 IP 应恢复为 %s，得到 %s", oldIP, ip)
+	}
+
+	method, from, to, err := lsRotateIP(ctx, cli, "web")
+	if err != nil || method != "static" || from != oldIP || to == oldIP {
+		t.Fatalf("更换结果 %s %s → %s, %v", method, from, to, err)
+	}
+	if sips := b.lsState("us-east-1").StaticIPs; len(sips) != 1 || aws.ToString(sips[0].Name) == "ip-web" {
+		t.Fatalf("原固定 IP 应已释放: %+v", sips)
+	}
+}
+
+// stickyLightsail 模拟解绑临时固定 IP 后拿回原动态 IP 的情况
+type stickyLightsail struct {
+	LightsailAPI
+	b  *FakeBackend
+	ip string
+}
+
+func (c stickyLightsail) DetachStaticIp(ctx context.Context, in *lightsail.DetachStaticIpInput, opts ...func(*lightsail.Options)) (*lightsail.DetachStaticIpOutput, error) {
+	out, err := c.LightsailAPI.DetachStaticIp(ctx, in, opts...)
+	lsInstance(c.b, "web").PublicIpAddress = aws.String(c.ip)
+	return out, err
+}
+
+func TestLSRotateDynamic(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newLightsailClient(aws.Config{Region: "us-east-1"})
+	ctx := context.Background()
+	if err := lsLaunch(ctx, cli, LSLaunchOptions{AZ: "us-east-1a", Name: "web", Bundle: "nano_3_0", Blueprint: "debian_12"}); err != nil {
+		t.Fatal(err)
+	}
+	oldIP := aws.ToString(lsInstance(b, "web").PublicIpAddress)
+	method, from, to, err := lsRotateIP(ctx, cli, "web")
+	if err != nil || method != "dynamic" || from != oldIP || to == "" || to == oldIP {
+		t.Fatalf("更换结果 %s %s → %s, %v", method, from, to, err)
+	}
+	if sips := b.lsState("us-east-1").StaticIPs; len(sips) != 0 {
+		t.Fatalf("临时固定 IP 应已释放: %+v", sips)
+	}
+
+	if _, _, _, err := lsRotateIP(ctx, stickyLightsail{cli, b, to}, "web"); err == nil || !strings.Contains(err.Error(), "未更换") {
+		t.Fatalf("IP 未变化时应报错，得到 %v", err)
+	}
+	if sips := b.lsState("us-east-1").StaticIPs; len(sips) != 0 {
+		t.Fatalf("临时固定 IP 应已释放: %+v", sips)
+	}
+}
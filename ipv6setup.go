@@ -0,0 +1,750 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2t "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// -------------------- IPv6 自动配置 --------------------
+
+// 流程：VPC 没有 IPv6 时申请 Amazon 提供的 /56；每个子网从 VPC 网段中按顺序分配下一个未被占用的 /64，
+// 并开启自动分配 IPv6；子网所用路由表 (显式关联或主路由表) 缺少 ::/0 时指向互联网网关，
+// 或按需指向仅出口互联网网关。先生成计划并打印，再执行。
+// IPv6-only 模式只使用 IPv6 原生子网 (没有 IPv4 网段)，所选可用区没有时新建一个，
+// 并可开启 DNS64、把 64:ff9b::/96 路由到已有的 NAT 网关 (NAT64) 以访问纯 IPv4 站点。
+
+// IPv6Options 控制配置范围
+type IPv6Options struct {
+	AZ         string   // 只配置该可用区的子网，空为全部
+	SubnetIDs  []string // 只配置这些子网，优先于 AZ
+	EgressOnly bool     // ::/0 走仅出口互联网网关 (实例可主动访问外网，但不接受 IPv6 入站)
+	Native     bool     // 只配置 IPv6-only 子网，所选可用区没有时新建
+	DNS64      bool     // IPv6-only 子网开启 DNS64
+	NAT64      bool     // 64:ff9b::/96 路由到 VPC 中已有的 NAT 网关
+}
+
+// IPv6SubnetPlan 为一个子网的 IPv6 规划
+type IPv6SubnetPlan struct {
+	SubnetID, AZ string
+	CIDR         string // 已有或待分配的 /64；VPC 尚无 IPv6 时为空，申请后再计算
+	Exists       bool
+	AutoAssign   bool // 已开启自动分配 IPv6
+	Native       bool // IPv6-only 子网；SubnetID 为空表示待新建
+	DNS64        bool // 已开启 DNS64
+}
+
+// IPv6RoutePlan 为一张路由表的 ::/0 路由规划
+type IPv6RoutePlan struct {
+	RouteTableID string
+	Main         bool
+	Subnets      []string
+	Current      string // 已有 ::/0 路由的目标，空为没有
+	NAT64        string // 已有 64:ff9b::/96 路由的目标，空为没有
+}
+
+// IPv6Plan 为一个 VPC 的 IPv6 配置计划
+type IPv6Plan struct {
+	VpcID      string
+	VpcCIDR    string // 为空表示需要申请 Amazon 提供的 /56
+	Subnets    []IPv6SubnetPlan
+	Routes     []IPv6RoutePlan
+	IGW        string // VPC 已挂载的互联网网关
+	EIGW       string // VPC 已有的仅出口互联网网关
+	EgressOnly bool
+	DNS64      bool
+	NAT64      bool
+	NatGW      string // NAT64 使用的 NAT 网关，空为 VPC 中没有可用的
+}
+
+// nat64Prefix 为 DNS64 合成地址所用的知名前缀
+const nat64Prefix = "64:ff9b::/96"
+
+func subnetLabel(id string) string { return orDefault(id, "(新建)") }
+
+// routeTarget 返回新增 ::/0 路由的目标；仅出口网关尚未创建时返回 "(新建)"，无可用网关时返回空
+func (p *IPv6Plan) routeTarget() string {
+	if p.EgressOnly {
+		return orDefault(p.EIGW, "(新建仅出口网关)")
+	}
+	return p.IGW
+}
+
+// changes 返回计划中需要执行的变更数
+func (p *IPv6Plan) changes() int {
+	n := 0
+	if p.VpcCIDR == "" {
+		n++
+	}
+	for _, s := range p.Subnets {
+		if !s.Exists {
+			n++
+		}
+		if !s.AutoAssign {
+			n++
+		}
+		if p.DNS64 && !s.DNS64 {
+			n++
+		}
+	}
+	for _, r := range p.Routes {
+		if r.Current == "" && p.routeTarget() != "" {
+			n++
+		}
+		if r.NAT64 == "" && p.NAT64 && p.NatGW != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func printIPv6Plan(p *IPv6Plan) {
+	fmt.Printf("\n📋 IPv6 配置计划 (%s)\n", p.VpcID)
+	if p.VpcCIDR == "" {
+		fmt.Println(" VPC   : ➕ 申请 Amazon 提供的 IPv6 /56")
+	} else {
+		fmt.Printf(" VPC   : %s (已有)\n", p.VpcCIDR)
+	}
+	fmt.Println(" 子网  :")
+	for i, s := range p.Subnets {
+		cidr := s.CIDR
+		switch {
+		case s.Exists:
+			cidr += " (已有)"
+		case cidr == "":
+			cidr = fmt.Sprintf("➕ VPC 网段申请后分配第 %d 个空闲 /64", i+1)
+		default:
+			cidr = "➕ " + cidr
+		}
+		name := s.SubnetID
+		switch {
+		case name == "":
+			name = "➕ 新建 IPv6-only 子网"
+		case s.Native:
+			name += " [IPv6-only]"
+		}
+		extra := ""
+		if !s.AutoAssign {
+			extra += "，➕ 开启自动分配 IPv6"
+		}
+		if p.DNS64 && !s.DNS64 {
+			extra += "，➕ 开启 DNS64"
+		}
+		fmt.Printf("   %s (%s)  %s%s\n", name, s.AZ, cidr, extra)
+	}
+	fmt.Println(" 路由  :")
+	target := p.routeTarget()
+	for _, r := range p.Routes {
+		name := r.RouteTableID
+		if r.Main {
+			name += " (主路由表)"
+		}
+		switch {
+		case r.Current != "":
+			fmt.Printf("   %s  已有 ::/0 → %s，保持不变\n", name, r.Current)
+		case target == "":
+			fmt.Printf("   %s  ⚠️ VPC 未挂载互联网网关，跳过 ::/0 路由\n", name)
+		default:
+			fmt.Printf("   %s  ➕ ::/0 → %s\n", name, target)
+		}
+		if p.NAT64 {
+			switch {
+			case r.NAT64 != "":
+				fmt.Printf("     已有 %s → %s，保持不变\n", nat64Prefix, r.NAT64)
+			case p.NatGW == "":
+				fmt.Printf("     ⚠️ VPC 中没有可用的 NAT 网关，跳过 NAT64 (需先在公有子网创建 NAT 网关)\n")
+			default:
+				fmt.Printf("     ➕ %s → %s (NAT64)\n", nat64Prefix, p.NatGW)
+			}
+		}
+		var subs []string
+		for _, id := range r.Subnets {
+			subs = append(subs, subnetLabel(id))
+		}
+		fmt.Printf("     关联子网: %s\n", strings.Join(subs, ", "))
+	}
+	if p.changes() == 0 {
+		fmt.Println("✅ 无需变更")
+	}
+}
+
+// nthIPv6Subnet 返回 block 中第 n 个长度为 bits 的子网 (bits ≤ 64)
+func nthIPv6Subnet(block netip.Prefix, bits, n int) (netip.Prefix, error) {
+	if !block.Addr().Is6() || block.Bits() > bits || bits > 64 {
+		return netip.Prefix{}, fmt.Errorf("无法在 %s 中划分 /%d", block, bits)
+	}
+	if span := bits - block.Bits(); span < 63 && n >= 1<<span {
+		return netip.Prefix{}, fmt.Errorf("%s 中的 /%d 已用完", block, bits)
+	}
+	a := block.Masked().Addr().As16()
+	hi := binary.BigEndian.Uint64(a[:8]) | uint64(n)<<(64-bits)
+	binary.BigEndian.PutUint64(a[:8], hi)
+	return netip.PrefixFrom(netip.AddrFrom16(a), bits), nil
+}
+
+// nextFreeIPv6 返回 block 中第一个与 used 都不重叠的 /64
+func nextFreeIPv6(block netip.Prefix, used []netip.Prefix) (netip.Prefix, error) {
+	for n := 0; ; n++ {
+		p, err := nthIPv6Subnet(block, 64, n)
+		if err != nil {
+			return p, err
+		}
+		if !slices.ContainsFunc(used, p.Overlaps) {
+			return p, nil
+		}
+	}
+}
+
+func vpcIPv6CIDR(v ec2t.Vpc) string {
+	for _, a := range v.Ipv6CidrBlockAssociationSet {
+		if a.Ipv6CidrBlockState != nil && a.Ipv6CidrBlockState.State == ec2t.VpcCidrBlockStateCodeAssociated {
+			return aws.ToString(a.Ipv6CidrBlock)
+		}
+	}
+	return ""
+}
+
+func subnetIPv6CIDR(sn ec2t.Subnet) string {
+	for _, a := range sn.Ipv6CidrBlockAssociationSet {
+		if a.Ipv6CidrBlockState != nil && a.Ipv6CidrBlockState.State == ec2t.SubnetCidrBlockStateCodeAssociated {
+			return aws.ToString(a.Ipv6CidrBlock)
+		}
+	}
+	return ""
+}
+
+// usedIPv6 返回 VPC 中所有子网已占用的 IPv6 网段
+func usedIPv6(ctx context.Context, cli EC2API, vpcID string) ([]netip.Prefix, error) {
+	out, err := cli.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{Filters: []ec2t.Filter{{Name: aws.String("vpc-id"), Values: []string{vpcID}}}})
+	if err != nil {
+		return nil, err
+	}
+	var used []netip.Prefix
+	for _, sn := range out.Subnets {
+		if p, err := netip.ParsePrefix(subnetIPv6CIDR(sn)); err == nil {
+			used = append(used, p)
+		}
+	}
+	return used, nil
+}
+
+// egressOnlyGateway 返回挂载在 VPC 上的仅出口互联网网关
+func egressOnlyGateway(ctx context.Context, cli EC2API, vpcID string) (string, error) {
+	out, err := cli.DescribeEgressOnlyInternetGateways(ctx, &ec2.DescribeEgressOnlyInternetGatewaysInput{})
+	if err != nil {
+		return "", err
+	}
+	for _, g := range out.EgressOnlyInternetGateways {
+		for _, a := range g.Attachments {
+			if aws.ToString(a.VpcId) == vpcID && a.State == ec2t.AttachmentStatusAttached {
+				return aws.ToString(g.EgressOnlyInternetGatewayId), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// natGateway 返回 VPC 中第一个可用的 NAT 网关 (NAT64 需要它；NAT 网关按小时计费，这里不自动创建)
+func natGateway(ctx context.Context, cli EC2API, vpcID string) (string, error) {
+	out, err := cli.DescribeNatGateways(ctx, &ec2.DescribeNatGatewaysInput{Filter: []ec2t.Filter{
+		{Name: aws.String("vpc-id"), Values: []string{vpcID}},
+		{Name: aws.String("state"), Values: []string{"available"}},
+	}})
+	if err != nil {
+		return "", err
+	}
+	if len(out.NatGateways) == 0 {
+		return "", nil
+	}
+	return aws.ToString(out.NatGateways[0].NatGatewayId), nil
+}
+
+func routeTargetID(r ec2t.Route) string {
+	for _, s := range []*string{r.GatewayId, r.EgressOnlyInternetGatewayId, r.NatGatewayId, r.TransitGatewayId, r.NetworkInterfaceId, r.InstanceId, r.VpcPeeringConnectionId} {
+		if aws.ToString(s) != "" {
+			return aws.ToString(s)
+		}
+	}
+	return "(未知)"
+}
+
+// planIPv6 读取 VPC、子网、路由表与网关，生成配置计划 (不做任何修改)
+func planIPv6(ctx context.Context, cli EC2API, vpcID string, opt IPv6Options) (*IPv6Plan, error) {
+	vpcs, err := cli.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{VpcIds: []string{vpcID}})
+	if err != nil {
+		return nil, err
+	}
+	if len(vpcs.Vpcs) == 0 {
+		return nil, fmt.Errorf("未找到 VPC %s", vpcID)
+	}
+	p := &IPv6Plan{VpcID: vpcID, VpcCIDR: vpcIPv6CIDR(vpcs.Vpcs[0]), EgressOnly: opt.EgressOnly, DNS64: opt.DNS64, NAT64: opt.NAT64}
+
+	subs, err := cli.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{Filters: []ec2t.Filter{{Name: aws.String("vpc-id"), Values: []string{vpcID}}}})
+	if err != nil {
+		return nil, err
+	}
+	var used []netip.Prefix
+	var picked []ec2t.Subnet
+	var azs []string
+	for _, sn := range subs.Subnets {
+		if q, err := netip.ParsePrefix(subnetIPv6CIDR(sn)); err == nil {
+			used = append(used, q)
+		}
+		azs = append(azs, aws.ToString(sn.AvailabilityZone))
+		// 双栈与 IPv6-only 子网互不混用
+		if aws.ToBool(sn.Ipv6Native) != opt.Native {
+			continue
+		}
+		switch {
+		case len(opt.SubnetIDs) > 0:
+			if !slices.Contains(opt.SubnetIDs, aws.ToString(sn.SubnetId)) {
+				continue
+			}
+		case opt.AZ != "" && aws.ToString(sn.AvailabilityZone) != opt.AZ:
+			continue
+		}
+		picked = append(picked, sn)
+	}
+	if len(picked) == 0 && opt.Native {
+		az := opt.AZ
+		if az == "" && len(azs) > 0 {
+			az = slices.Min(azs)
+		}
+		if az == "" {
+			return nil, fmt.Errorf("%s 中没有子网，无法确定新建 IPv6-only 子网的可用区", vpcID)
+		}
+		picked = append(picked, ec2t.Subnet{AvailabilityZone: aws.String(az), Ipv6Native: aws.Bool(true)})
+	}
+	if len(picked) == 0 {
+		if opt.AZ != "" {
+			return nil, fmt.Errorf("%s 在 %s 中没有子网", vpcID, opt.AZ)
+		}
+		return nil, fmt.Errorf("%s 中没有子网", vpcID)
+	}
+	sort.Slice(picked, func(i, j int) bool {
+		a, b := picked[i], picked[j]
+		if aws.ToString(a.AvailabilityZone) != aws.ToString(b.AvailabilityZone) {
+			return aws.ToString(a.AvailabilityZone) < aws.ToString(b.AvailabilityZone)
+		}
+		return aws.ToString(a.SubnetId) < aws.ToString(b.SubnetId)
+	})
+	block, blockErr := netip.ParsePrefix(p.VpcCIDR)
+	for _, sn := range picked {
+		sp := IPv6SubnetPlan{
+			SubnetID: aws.ToString(sn.SubnetId), AZ: aws.ToString(sn.AvailabilityZone),
+			CIDR: subnetIPv6CIDR(sn), AutoAssign: aws.ToBool(sn.AssignIpv6AddressOnCreation),
+			Native: aws.ToBool(sn.Ipv6Native), DNS64: aws.ToBool(sn.EnableDns64),
+		}
+		sp.Exists = sp.CIDR != ""
+		if !sp.Exists && blockErr == nil {
+			next, err := nextFreeIPv6(block, used)
+			if err != nil {
+				return nil, err
+			}
+			sp.CIDR = next.String()
+			used = append(used, next)
+		}
+		p.Subnets = append(p.Subnets, sp)
+	}
+
+	// 路由表：子网显式关联的路由表，否则为主路由表
+	rts, err := cli.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{Filters: []ec2t.Filter{{Name: aws.String("vpc-id"), Values: []string{vpcID}}}})
+	if err != nil {
+		return nil, err
+	}
+	var mainRT *ec2t.RouteTable
+	explicit := map[string]*ec2t.RouteTable{}
+	for i := range rts.RouteTables {
+		rt := &rts.RouteTables[i]
+		for _, a := range rt.Associations {
+			if aws.ToBool(a.Main) {
+				mainRT = rt
+			}
+			if a.SubnetId != nil {
+				explicit[*a.SubnetId] = rt
+			}
+		}
+	}
+	for _, sp := range p.Subnets {
+		rt := explicit[sp.SubnetID]
+		if rt == nil {
+			rt = mainRT
+		}
+		if rt == nil {
+			continue
+		}
+		id := aws.ToString(rt.RouteTableId)
+		i := slices.IndexFunc(p.Routes, func(r IPv6RoutePlan) bool { return r.RouteTableID == id })
+		if i < 0 {
+			r := IPv6RoutePlan{RouteTableID: id, Main: rt == mainRT}
+			for _, route := range rt.Routes {
+				switch aws.ToString(route.DestinationIpv6CidrBlock) {
+				case "::/0":
+					r.Current = routeTargetID(route)
+				case nat64Prefix:
+					r.NAT64 = routeTargetID(route)
+				}
+			}
+			p.Routes = append(p.Routes, r)
+			i = len(p.Routes) - 1
+		}
+		p.Routes[i].Subnets = append(p.Routes[i].Subnets, sp.SubnetID)
+	}
+
+	igws, err := cli.DescribeInternetGateways(ctx, &ec2.DescribeInternetGatewaysInput{Filters: []ec2t.Filter{{Name: aws.String("attachment.vpc-id"), Values: []string{vpcID}}}})
+	if err != nil {
+		return nil, err
+	}
+	if len(igws.InternetGateways) > 0 {
+		p.IGW = aws.ToString(igws.InternetGateways[0].InternetGatewayId)
+	}
+	if opt.EgressOnly {
+		if p.EIGW, err = egressOnlyGateway(ctx, cli, vpcID); err != nil {
+			return nil, err
+		}
+	}
+	if opt.NAT64 {
+		if p.NatGW, err = natGateway(ctx, cli, vpcID); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// applyIPv6Plan 按计划执行，遇到错误立即返回 (已完成的步骤不回滚，重新执行会跳过)
+func applyIPv6Plan(ctx context.Context, cli EC2API, p *IPv6Plan) error {
+	if p.VpcCIDR == "" {
+		if _, err := cli.AssociateVpcCidrBlock(ctx, &ec2.AssociateVpcCidrBlockInput{
+			VpcId: aws.String(p.VpcID), AmazonProvidedIpv6CidrBlock: aws.Bool(true),
+		}); err != nil {
+			return fmt.Errorf("申请 VPC IPv6 失败: %v", err)
+		}
+		err := pollUntil(ctx, time.Minute, " VPC IPv6 网段", func() (bool, error) {
+			out, err := cli.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{VpcIds: []string{p.VpcID}})
+			if err != nil || len(out.Vpcs) == 0 {
+				return false, err
+			}
+			p.VpcCIDR = vpcIPv6CIDR(out.Vpcs[0])
+			return p.VpcCIDR != "", nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println("   -> VPC IPv6:", p.VpcCIDR)
+	}
+	block, err := netip.ParsePrefix(p.VpcCIDR)
+	if err != nil {
+		return fmt.Errorf("VPC IPv6 网段无效: %q", p.VpcCIDR)
+	}
+	for i := range p.Subnets {
+		s := &p.Subnets[i]
+		if !s.Exists {
+			created := s.SubnetID == ""
+			if err := associateSubnetIPv6(ctx, cli, p.VpcID, block, s); err != nil {
+				return err
+			}
+			if created {
+				p.renameNewSubnet(s.SubnetID)
+				fmt.Printf("   -> 已新建 IPv6-only 子网 %s (%s, %s)\n", s.SubnetID, s.AZ, s.CIDR)
+			} else {
+				fmt.Printf("   -> 子网 %s 关联 %s\n", s.SubnetID, s.CIDR)
+			}
+		}
+		if !s.AutoAssign {
+			if _, err := cli.ModifySubnetAttribute(ctx, &ec2.ModifySubnetAttributeInput{
+				SubnetId: aws.String(s.SubnetID), AssignIpv6AddressOnCreation: &ec2t.AttributeBooleanValue{Value: aws.Bool(true)},
+			}); err != nil {
+				return fmt.Errorf("子网 %s 开启自动分配 IPv6 失败: %v", s.SubnetID, err)
+			}
+			s.AutoAssign = true
+		}
+		if p.DNS64 && !s.DNS64 {
+			if _, err := cli.ModifySubnetAttribute(ctx, &ec2.ModifySubnetAttributeInput{
+				SubnetId: aws.String(s.SubnetID), EnableDns64: &ec2t.AttributeBooleanValue{Value: aws.Bool(true)},
+			}); err != nil {
+				return fmt.Errorf("子网 %s 开启 DNS64 失败: %v", s.SubnetID, err)
+			}
+			s.DNS64 = true
+		}
+	}
+	if err := applyDefaultRoutes(ctx, cli, p); err != nil {
+		return err
+	}
+	return applyNAT64Routes(ctx, cli, p)
+}
+
+// renameNewSubnet 把路由计划中待新建的子网替换为新建后的 ID
+func (p *IPv6Plan) renameNewSubnet(id string) {
+	for i := range p.Routes {
+		if j := slices.Index(p.Routes[i].Subnets, ""); j >= 0 {
+			p.Routes[i].Subnets[j] = id
+		}
+	}
+}
+
+// applyDefaultRoutes 为缺少 ::/0 的路由表添加默认路由，按需先创建仅出口互联网网关
+func applyDefaultRoutes(ctx context.Context, cli EC2API, p *IPv6Plan) error {
+	var pending []int
+	for i, r := range p.Routes {
+		if r.Current == "" {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	if p.EgressOnly && p.EIGW == "" {
+		out, err := cli.CreateEgressOnlyInternetGateway(ctx, &ec2.CreateEgressOnlyInternetGatewayInput{VpcId: aws.String(p.VpcID)})
+		if err != nil {
+			return fmt.Errorf("创建仅出口互联网网关失败: %v", err)
+		}
+		p.EIGW = aws.ToString(out.EgressOnlyInternetGateway.EgressOnlyInternetGatewayId)
+		fmt.Println("   -> 已创建仅出口互联网网关", p.EIGW)
+	}
+	target := p.routeTarget()
+	if target == "" {
+		fmt.Println("   ⚠️ VPC 未挂载互联网网关，未添加 ::/0 路由")
+		return nil
+	}
+	for _, i := range pending {
+		r := &p.Routes[i]
+		in := &ec2.CreateRouteInput{RouteTableId: aws.String(r.RouteTableID), DestinationIpv6CidrBlock: aws.String("::/0")}
+		if p.EgressOnly {
+			in.EgressOnlyInternetGatewayId = aws.String(target)
+		} else {
+			in.GatewayId = aws.String(target)
+		}
+		if _, err := cli.CreateRoute(ctx, in); err != nil {
+			return fmt.Errorf("路由表 %s 添加 ::/0 失败: %v", r.RouteTableID, err)
+		}
+		r.Current = target
+		fmt.Printf("   -> 路由表 %s: ::/0 → %s\n", r.RouteTableID, target)
+	}
+	return nil
+}
+
+// applyNAT64Routes 把 64:ff9b::/96 路由到 NAT 网关；没有可用的 NAT 网关时只提示
+func applyNAT64Routes(ctx context.Context, cli EC2API, p *IPv6Plan) error {
+	if !p.NAT64 {
+		return nil
+	}
+	for i := range p.Routes {
+		r := &p.Routes[i]
+		if r.NAT64 != "" {
+			continue
+		}
+		if p.NatGW == "" {
+			fmt.Println("   ⚠️ VPC 中没有可用的 NAT 网关，未添加 NAT64 路由")
+			return nil
+		}
+		if _, err := cli.CreateRoute(ctx, &ec2.CreateRouteInput{
+			RouteTableId: aws.String(r.RouteTableID), DestinationIpv6CidrBlock: aws.String(nat64Prefix), NatGatewayId: aws.String(p.NatGW),
+		}); err != nil {
+			return fmt.Errorf("路由表 %s 添加 %s 失败: %v", r.RouteTableID, nat64Prefix, err)
+		}
+		r.NAT64 = p.NatGW
+		fmt.Printf("   -> 路由表 %s: %s → %s\n", r.RouteTableID, nat64Prefix, p.NatGW)
+	}
+	return nil
+}
+
+// associateSubnetIPv6 为子网关联 /64 (待新建的 IPv6-only 子网直接以该网段创建)；
+// 网段在计划后被占用 (冲突) 时重新计算下一个空闲 /64
+func associateSubnetIPv6(ctx context.Context, cli EC2API, vpcID string, block netip.Prefix, s *IPv6SubnetPlan) error {
+	for attempt := 0; attempt < 3; attempt++ {
+		used, err := usedIPv6(ctx, cli, vpcID)
+		if err != nil {
+			return err
+		}
+		cur, err := netip.ParsePrefix(s.CIDR)
+		if err != nil || slices.ContainsFunc(used, cur.Overlaps) {
+			next, err := nextFreeIPv6(block, used)
+			if err != nil {
+				return err
+			}
+			s.CIDR = next.String()
+		}
+		if s.SubnetID == "" {
+			var out *ec2.CreateSubnetOutput
+			out, err = cli.CreateSubnet(ctx, &ec2.CreateSubnetInput{
+				VpcId: aws.String(vpcID), AvailabilityZone: aws.String(s.AZ), Ipv6Native: aws.Bool(true), Ipv6CidrBlock: aws.String(s.CIDR),
+				TagSpecifications: []ec2t.TagSpecification{{ResourceType: ec2t.ResourceTypeSubnet, Tags: []ec2t.Tag{{Key: aws.String("Name"), Value: aws.String("ipv6-only-" + s.AZ)}}}},
+			})
+			if err == nil {
+				s.SubnetID = aws.ToString(out.Subnet.SubnetId)
+				s.AutoAssign = aws.ToBool(out.Subnet.AssignIpv6AddressOnCreation)
+			}
+		} else {
+			_, err = cli.AssociateSubnetCidrBlock(ctx, &ec2.AssociateSubnetCidrBlockInput{SubnetId: aws.String(s.SubnetID), Ipv6CidrBlock: aws.String(s.CIDR)})
+		}
+		if err == nil {
+			s.Exists = true
+			return nil
+		}
+		if !strings.Contains(err.Error(), "Conflict") {
+			return fmt.Errorf("子网 %s 关联 %s 失败: %v", s.SubnetID, s.CIDR, err)
+		}
+	}
+	return fmt.Errorf("子网 %s 多次分配 IPv6 网段冲突", s.SubnetID)
+}
+
+// repairSubnetIPv6 只为实例所在的子网配置 IPv6 (及其路由表的 ::/0)，
+// 先打印计划并确认后执行；返回 false 表示用户取消
+func repairSubnetIPv6(ctx context.Context, cli EC2API, vpcID, subnetID string) (bool, error) {
+	p, err := planIPv6(ctx, cli, vpcID, IPv6Options{SubnetIDs: []string{subnetID}})
+	if err != nil {
+		return false, err
+	}
+	printIPv6Plan(p)
+	if p.changes() == 0 {
+		return true, nil
+	}
+	if !yes(input("\n按以上计划修复? [y/N]: ", "n")) {
+		return false, nil
+	}
+	return true, applyIPv6Plan(ctx, cli, p)
+}
+
+// defaultVpcID 返回区域的默认 VPC
+func defaultVpcID(ctx context.Context, cli EC2API) (string, error) {
+	out, err := cli.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{Filters: []ec2t.Filter{{Name: aws.String("isDefault"), Values: []string{"true"}}}})
+	if err != nil {
+		return "", err
+	}
+	if len(out.Vpcs) == 0 {
+		return "", errors.New("无默认VPC，请指定 VPC")
+	}
+	return aws.ToString(out.Vpcs[0].VpcId), nil
+}
+
+// ipv6SetupInteractive 交互式配置 VPC 的 IPv6：选择范围、预览计划、确认后执行
+func ipv6SetupInteractive(ctx context.Context, cli EC2API, vpcID string) {
+	opt := IPv6Options{AZ: input("只配置某个可用区 (如 us-east-1a，留空为全部子网): ", "")}
+	opt.EgressOnly = yes(input("::/0 使用仅出口互联网网关 (只出不进)? [y/N]: ", "n"))
+	p, err := planIPv6(ctx, cli, vpcID, opt)
+	if err != nil {
+		fmt.Println("❌", err)
+		return
+	}
+	printIPv6Plan(p)
+	if p.changes() == 0 || !yes(input("\n按以上计划执行? [y/N]: ", "n")) {
+		return
+	}
+	if err := applyIPv6Plan(ctx, cli, p); err != nil {
+		fmt.Println("❌", err)
+		return
+	}
+	fmt.Println("✅ IPv6 配置完成")
+}
+
+// -------------------- EC2 网络模式 --------------------
+
+// 创建实例时的网络模式。公网 IPv4 按小时计费，IPv6-only 实例不分配公网 IPv4，
+// 只能经 IPv6 访问 (需 Nitro 实例类型)
+const (
+	netIPv4 = "ipv4"
+	netDual = "dual"
+	netIPv6 = "ipv6"
+)
+
+func parseNetMode(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "", "ipv4":
+		return netIPv4, nil
+	case "dual", "dualstack":
+		return netDual, nil
+	case "ipv6", "ipv6-only":
+		return netIPv6, nil
+	}
+	return "", fmt.Errorf("网络模式只能是 ipv4|dual|ipv6: %s", s)
+}
+
+func netModeName(m string) string {
+	switch m {
+	case netDual:
+		return "双栈"
+	case netIPv6:
+		return "IPv6-only"
+	}
+	return "IPv4"
+}
+
+// instanceNetMode 按实例是否有内网 IPv4 / IPv6 地址判断网络模式
+func instanceNetMode(privIP, ipv6 string) string {
+	switch {
+	case privIP == "" && ipv6 != "":
+		return netIPv6
+	case ipv6 != "":
+		return netDual
+	}
+	return netIPv4
+}
+
+// promptEC2Net 交互式选择网络模式，IPv6-only 时再询问 DNS64 / NAT64，并确认实例类型是 Nitro
+func promptEC2Net(ctx context.Context, cli EC2API, o *EC2LaunchOptions) {
+	fmt.Println("\n网络模式:")
+	fmt.Println("  1) 仅 IPv4 [默认]")
+	fmt.Println("  2) 双栈 (IPv4 + IPv6)")
+	fmt.Println("  3) 仅 IPv6 (无公网 IPv4，不产生公网 IPv4 费用，需 Nitro 实例类型)")
+	switch input("请输入编号 [1]: ", "1") {
+	case "2":
+		o.Net = netDual
+	case "3":
+		o.Net = netIPv6
+		o.DNS64 = yes(input("子网开启 DNS64? [Y/n]: ", "y"))
+		o.NAT64 = yes(input("NAT64 (经 VPC 中已有的 NAT 网关访问纯 IPv4 站点)? [y/N]: ", "n"))
+		if _, err := nitroTypes(ctx, cli, append([]string{o.Type}, o.AltTypes...)); err != nil {
+			fmt.Println("⚠️", err)
+			def := nitroDefaultType(o.Arch)
+			o.Type = input(fmt.Sprintf("改用 Nitro 实例类型 [%s]: ", def), def)
+		}
+	default:
+		o.Net = netIPv4
+	}
+}
+
+// nitroDefaultType 为 IPv6-only 未指定类型时的默认类型 (默认的 t2 不是 Nitro)
+func nitroDefaultType(arch string) string {
+	if arch == "arm64" {
+		return "t4g.nano"
+	}
+	return "t3.micro"
+}
+
+// nitroTypes 去掉非 Nitro 的实例类型 (IPv6-only 子网只支持 Nitro)；无法查询时原样返回
+func nitroTypes(ctx context.Context, cli EC2API, types []string) ([]string, error) {
+	in := &ec2.DescribeInstanceTypesInput{}
+	for _, t := range types {
+		in.InstanceTypes = append(in.InstanceTypes, ec2t.InstanceType(t))
+	}
+	out, err := cli.DescribeInstanceTypes(ctx, in)
+	if err != nil {
+		return types, nil
+	}
+	nitro := map[string]bool{}
+	for _, it := range out.InstanceTypes {
+		nitro[string(it.InstanceType)] = it.Hypervisor == ec2t.InstanceTypeHypervisorNitro || aws.ToBool(it.BareMetal)
+	}
+	var keep []string
+	for _, t := range types {
+		if ok, known := nitro[t]; ok || !known {
+			keep = append(keep, t)
+			continue
+		}
+		fmt.Printf("⚠️ %s 不是 Nitro 实例类型，不支持 IPv6-only，已跳过\n", t)
+	}
+	if len(keep) == 0 {
+		return nil, fmt.Errorf("IPv6-only 需要 Nitro 实例类型 (如 t3、t4g、c6i)，%s 不支持", strings.Join(types, ", "))
+	}
+	return keep, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2t "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// ipv6TestVPC 返回默认 VPC 与两个子网；第二个子网显式关联一张自己的路由表
+func ipv6TestVPC(t *testing.T) (b *FakeBackend, cli EC2API, vpcID, subnetA, subnetB string) {
+	t.Helper()
+	b = newTestBackend(t)
+	cli = newEC2Client(aws.Config{Region: "us-east-1"})
+	s := b.ec2State("us-east-1")
+	vpcID = aws.ToString(s.Vpcs[0].VpcId)
+	subnetA, subnetB = aws.ToString(s.Subnets[0].SubnetId), aws.ToString(s.Subnets[1].SubnetId)
+	s.RouteTables = append(s.RouteTables, ec2t.RouteTable{
+		RouteTableId: aws.String(b.id("rtb")), VpcId: aws.String(vpcID),
+		Associations: []ec2t.RouteTableAssociation{{Main: aws.Bool(false), SubnetId: aws.String(subnetB), RouteTableAssociationId: aws.String(b.id("rtbassoc"))}},
+		Routes:       []ec2t.Route{{DestinationCidrBlock: aws.String("172.31.0.0/16"), GatewayId: aws.String("local")}},
+	})
+	return
+}
+
+func subnetHasIPv6(s *fakeEC2State, id string) bool {
+	for _, sn := range s.Subnets {
+		if aws.ToString(sn.SubnetId) == id {
+			return subnetIPv6CIDR(sn) != ""
+		}
+	}
+	return false
+}
+
+// defaultRoutes 返回各路由表是否有 ::/0 路由，主路由表的键为 "main"
+func defaultRoutes(s *fakeEC2State) map[string]bool {
+	m := map[string]bool{}
+	for _, rt := range s.RouteTables {
+		key := "main"
+		for _, a := range rt.Associations {
+			if !aws.ToBool(a.Main) {
+				key = aws.ToString(a.SubnetId)
+			}
+		}
+		for _, r := range rt.Routes {
+			if aws.ToString(r.DestinationIpv6CidrBlock) == "::/0" {
+				m[key] = true
+			}
+		}
+	}
+	return m
+}
+
+// -------------------- 单实例修复 --------------------
+
+func TestRepairSubnetIPv6Declined(t *testing.T) {
+	b, cli, vpcID, subnetA, _ := ipv6TestVPC(t)
+	answer(t, "n")
+	applied, err := repairSubnetIPv6(context.Background(), cli, vpcID, subnetA)
+	if err != nil || applied {
+		t.Fatalf("回答 n 时应取消: applied=%v err=%v", applied, err)
+	}
+	s := b.ec2State("us-east-1")
+	if vpcIPv6CIDR(s.Vpcs[0]) != "" || subnetHasIPv6(s, subnetA) || len(defaultRoutes(s)) != 0 {
+		t.Error("取消后不应有任何修改")
+	}
+}
+
+func TestRepairSubnetIPv6OnlyTarget(t *testing.T) {
+	b, cli, vpcID, subnetA, subnetB := ipv6TestVPC(t)
+	answer(t, "y")
+	applied, err := repairSubnetIPv6(context.Background(), cli, vpcID, subnetA)
+	if err != nil || !applied {
+		t.Fatalf("回答 y 时应执行: applied=%v err=%v", applied, err)
+	}
+	s := b.ec2State("us-east-1")
+	if vpcIPv6CIDR(s.Vpcs[0]) == "" {
+		t.Error("VPC 应已申请 IPv6 网段")
+	}
+	if !subnetHasIPv6(s, subnetA) {
+		t.Error("目标子网应已分配 IPv6")
+	}
+	if subnetHasIPv6(s, subnetB) {
+		t.Error("其他子网不应被修改")
+	}
+	routes := defaultRoutes(s)
+	if !routes["main"] {
+		t.Error("目标子网所用的主路由表应添加 ::/0")
+	}
+	if routes[subnetB] {
+		t.Error("其他子网显式关联的路由表不应被修改")
+	}
+
+	// 已配置完成时无需确认，直接返回
+	applied, err = repairSubnetIPv6(context.Background(), cli, vpcID, subnetA)
+	if err != nil || !applied {
+		t.Errorf("无需修改时应直接返回: applied=%v err=%v", applied, err)
+	}
+}
+
+// -------------------- 创建时自动配置 --------------------
+
+func TestAutoSetupIPv6(t *testing.T) {
+	b, cli, vpcID, subnetA, subnetB := ipv6TestVPC(t)
+	ctx := context.Background()
+	id, err := autoSetupIPv6(ctx, cli, "us-east-1", vpcID, subnetB, IPv6Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != subnetB {
+		t.Errorf("应返回指定的子网 %s，实际 %s", subnetB, id)
+	}
+	s := b.ec2State("us-east-1")
+	if !subnetHasIPv6(s, subnetA) || !subnetHasIPv6(s, subnetB) {
+		t.Error("双栈模式应为全部子网分配 IPv6")
+	}
+	if routes := defaultRoutes(s); !routes["main"] || !routes[subnetB] {
+		t.Errorf("各子网所用的路由表都应有 ::/0: %v", routes)
+	}
+
+	// IPv6-only：在目标子网的可用区新建原生子网
+	id, err = autoSetupIPv6(ctx, cli, "us-east-1", vpcID, subnetB, IPv6Options{Native: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var native *ec2t.Subnet
+	for i := range s.Subnets {
+		if aws.ToString(s.Subnets[i].SubnetId) == id {
+			native = &s.Subnets[i]
+		}
+	}
+	if native == nil || !aws.ToBool(native.Ipv6Native) || aws.ToString(native.AvailabilityZone) != "us-east-1b" {
+		t.Errorf("应在 us-east-1b 新建 IPv6-only 子网: %+v", native)
+	}
+}
+
+func TestAutoSetupIPv6APIError(t *testing.T) {
+	b, cli, vpcID, subnetA, _ := ipv6TestVPC(t)
+	b.FailNext("ec2:AssociateVpcCidrBlock", fakeAPIError("UnauthorizedOperation", "You are not authorized to perform this operation."))
+	_, err := autoSetupIPv6(context.Background(), cli, "us-east-1", vpcID, subnetA, IPv6Options{})
+	if err == nil || !strings.Contains(err.Error(), "UnauthorizedOperation") {
+		t.Fatalf("申请 VPC 网段失败应返回错误，得到 %v", err)
+	}
+	s := b.ec2State("us-east-1")
+	if subnetHasIPv6(s, subnetA) || len(defaultRoutes(s)) != 0 {
+		t.Error("失败时不应修改子网或路由")
+	}
+
+	// 创建实例时双栈配置失败降级为 IPv4，IPv6-only 直接报错
+	b.FailNext("ec2:AssociateVpcCidrBlock", fakeAPIError("UnauthorizedOperation", "You are not authorized to perform this operation."))
+	out, err := ec2Launch(context.Background(), cli, "us-east-1", EC2LaunchOptions{AMI: "debian-12", Net: "dual"})
+	if err != nil || len(out[0].NetworkInterfaces[0].Ipv6Addresses) != 0 || out[0].PublicIpAddress == nil {
+		t.Errorf("双栈配置失败应降级为 IPv4 启动: %v", err)
+	}
+	b.FailNext("ec2:AssociateVpcCidrBlock", fakeAPIError("UnauthorizedOperation", "You are not authorized to perform this operation."))
+	if _, err := ec2Launch(context.Background(), cli, "us-east-1", EC2LaunchOptions{AMI: "debian-12", Net: "ipv6", Type: "t3.micro"}); err == nil {
+		t.Error("IPv6-only 配置失败应报错")
+	}
+}
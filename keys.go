@@ -0,0 +1,525 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/lightsail"
+	"golang.org/x/crypto/ssh"
+)
+
+// -------------------- SSH 密钥对 --------------------
+
+// 本地密钥库默认位于 ~/.aws-tool/keys，可用环境变量 AWS_TOOL_KEYRING 覆盖。
+// 每个密钥保存为 <名称>.pem (OpenSSH 私钥，0600) 与 <名称>.pub。
+
+func keyringDir() string {
+	if p := os.Getenv("AWS_TOOL_KEYRING"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "keys"
+	}
+	return filepath.Join(home, ".aws-tool", "keys")
+}
+
+func keyPath(name string) string { return filepath.Join(keyringDir(), name+".pem") }
+
+var keyNameRe = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+func checkKeyName(name string) error {
+	if !keyNameRe.MatchString(name) {
+		return fmt.Errorf("密钥名称无效: %q (只能包含字母、数字、. _ -，最长 64)", name)
+	}
+	return nil
+}
+
+// generateKey 生成 ed25519 密钥并写入本地密钥库，返回 authorized_keys 格式的公钥
+func generateKey(name string) (string, error) {
+	if err := checkKeyName(name); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(keyPath(name)); err == nil {
+		return "", fmt.Errorf("本地密钥 %s 已存在: %s", name, keyPath(name))
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	block, err := ssh.MarshalPrivateKey(priv, name)
+	if err != nil {
+		return "", err
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	authorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))) + " " + name
+	if err := os.MkdirAll(keyringDir(), 0o700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(keyPath(name), pem.EncodeToMemory(block), 0o600); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(keyringDir(), name+".pub"), []byte(authorized+"\n"), 0o644); err != nil {
+		return "", err
+	}
+	return authorized, nil
+}
+
+// localPublicKey 读取本地密钥的公钥；.pub 丢失时从私钥推导
+func localPublicKey(name string) (string, error) {
+	if err := checkKeyName(name); err != nil {
+		return "", err
+	}
+	if b, err := os.ReadFile(filepath.Join(keyringDir(), name+".pub")); err == nil {
+		return strings.TrimSpace(string(b)), nil
+	}
+	b, err := os.ReadFile(keyPath(name))
+	if err != nil {
+		return "", fmt.Errorf("本地密钥库中没有 %s", name)
+	}
+	signer, err := ssh.ParsePrivateKey(b)
+	if err != nil {
+		return "", fmt.Errorf("无法解析私钥 %s: %v", keyPath(name), err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))) + " " + name, nil
+}
+
+// localKeys 返回本地密钥库中的密钥名称
+func localKeys() []string {
+	matches, _ := filepath.Glob(filepath.Join(keyringDir(), "*.pem"))
+	var names []string
+	for _, m := range matches {
+		names = append(names, strings.TrimSuffix(filepath.Base(m), ".pem"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasLocalKey 与 deleteLocalKey 的名称可能来自用户输入，先校验，避免 ../ 之类的路径逃出密钥库
+func hasLocalKey(name string) bool {
+	if checkKeyName(name) != nil {
+		return false
+	}
+	_, err := os.Stat(keyPath(name))
+	return err == nil
+}
+
+func deleteLocalKey(name string) error {
+	if err := checkKeyName(name); err != nil {
+		return err
+	}
+	if err := os.Remove(keyPath(name)); err != nil {
+		return err
+	}
+	os.Remove(filepath.Join(keyringDir(), name+".pub"))
+	return nil
+}
+
+// KeyPairRow 为云端的一个密钥对
+type KeyPairRow struct {
+	Service     string `json:"service" yaml:"service"`
+	Region      string `json:"region" yaml:"region"`
+	Name        string `json:"name" yaml:"name"`
+	Type        string `json:"type" yaml:"type"`
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+	Local       bool   `json:"local" yaml:"local"` // 本地密钥库中有私钥
+}
+
+// keyStore 抽象 EC2 与 Lightsail 的密钥对操作
+type keyStore interface {
+	Service() string
+	Region() string
+	List(ctx context.Context) ([]KeyPairRow, error)
+	Import(ctx context.Context, name, pub string) error
+	Delete(ctx context.Context, name string) error
+}
+
+type ec2KeyStore struct {
+	cli    EC2API
+	region string
+}
+
+func (k ec2KeyStore) Service() string { return "EC2" }
+func (k ec2KeyStore) Region() string  { return k.region }
+
+func (k ec2KeyStore) List(ctx context.Context) ([]KeyPairRow, error) {
+	out, err := k.cli.DescribeKeyPairs(ctx, &ec2.DescribeKeyPairsInput{})
+	if err != nil {
+		return nil, err
+	}
+	var rows []KeyPairRow
+	for _, kp := range out.KeyPairs {
+		name := aws.ToString(kp.KeyName)
+		rows = append(rows, KeyPairRow{
+			Service: "EC2", Region: k.region, Name: name, Type: string(kp.KeyType),
+			Fingerprint: aws.ToString(kp.KeyFingerprint), Local: hasLocalKey(name),
+		})
+	}
+	return rows, nil
+}
+
+func (k ec2KeyStore) Import(ctx context.Context, name, pub string) error {
+	_, err := k.cli.ImportKeyPair(ctx, &ec2.ImportKeyPairInput{KeyName: aws.String(name), PublicKeyMaterial: []byte(pub)})
+	return err
+}
+
+func (k ec2KeyStore) Delete(ctx context.Context, name string) error {
+	_, err := k.cli.DeleteKeyPair(ctx, &ec2.DeleteKeyPairInput{KeyName: aws.String(name)})
+	return err
+}
+
+type lsKeyStore struct {
+	cli    LightsailAPI
+	region string
+}
+
+func (k lsKeyStore) Service() string { return "Lightsail" }
+func (k lsKeyStore) Region() string  { return k.region }
+
+func (k lsKeyStore) List(ctx context.Context) ([]KeyPairRow, error) {
+	var rows []KeyPairRow
+	in := &lightsail.GetKeyPairsInput{}
+	for {
+		out, err := k.cli.GetKeyPairs(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		for _, kp := range out.KeyPairs {
+			name := aws.ToString(kp.Name)
+			rows = append(rows, KeyPairRow{
+				Service: "Lightsail", Region: k.region, Name: name, Type: "ssh",
+				Fingerprint: aws.ToString(kp.Fingerprint), Local: hasLocalKey(name),
+			})
+		}
+		if aws.ToString(out.NextPageToken) == "" {
+			return rows, nil
+		}
+		in.PageToken = out.NextPageToken
+	}
+}
+
+func (k lsKeyStore) Import(ctx context.Context, name, pub string) error {
+	_, err := k.cli.ImportKeyPair(ctx, &lightsail.ImportKeyPairInput{KeyPairName: aws.String(name), PublicKeyBase64: aws.String(pub)})
+	return err
+}
+
+func (k lsKeyStore) Delete(ctx context.Context, name string) error {
+	_, err := k.cli.DeleteKeyPair(ctx, &lightsail.DeleteKeyPairInput{KeyPairName: aws.String(name)})
+	return err
+}
+
+// ensureRemoteKey 确保密钥对已存在于云端；不存在时从本地密钥库导入。
+// 无法列出密钥时不阻止创建，交给创建接口报错
+func ensureRemoteKey(ctx context.Context, ks keyStore, name string) error {
+	rows, err := ks.List(ctx)
+	if err != nil {
+		return nil
+	}
+	for _, r := range rows {
+		if r.Name == name {
+			return nil
+		}
+	}
+	pub, err := localPublicKey(name)
+	if err != nil {
+		return fmt.Errorf("%s 密钥对 %s 不存在，且%v", ks.Service(), name, err)
+	}
+	if err := ks.Import(ctx, name, pub); err != nil {
+		return fmt.Errorf("导入密钥对 %s 失败: %v", name, err)
+	}
+	fmt.Printf("🔑 已把本地密钥 %s 导入 %s\n", name, ks.Service())
+	return nil
+}
+
+// pickKeyPair 交互式选择创建实例时使用的密钥对 (云端已有或本地密钥库，或新建)，返回空表示不使用
+func pickKeyPair(ctx context.Context, ks keyStore) string {
+	remote, _ := ks.List(ctx)
+	names := localKeys()
+	for _, r := range remote {
+		if !slices.Contains(names, r.Name) {
+			names = append(names, r.Name)
+		}
+	}
+	sort.Strings(names)
+	fmt.Println("\nSSH 密钥对:")
+	fmt.Println("  0) 不使用 [默认]")
+	for i, n := range names {
+		inRemote := slices.ContainsFunc(remote, func(r KeyPairRow) bool { return r.Name == n })
+		where := "本地 + 云端"
+		switch {
+		case !inRemote:
+			where = "仅本地，将自动导入"
+		case !hasLocalKey(n):
+			where = "仅云端，本地无私钥"
+		}
+		fmt.Printf("  %d) %s (%s)\n", i+1, n, where)
+	}
+	fmt.Println("  n) 新建 ed25519 密钥")
+	sel := input("请选择 [0]: ", "0")
+	name := ""
+	if sel == "n" || sel == "N" {
+		name = input("新密钥名称: ", "")
+		pub, err := generateKey(name)
+		if err != nil {
+			fmt.Println("❌", err)
+			return ""
+		}
+		fmt.Println("✅ 私钥已保存:", keyPath(name))
+		fmt.Println("   公钥:", pub)
+	} else if idx, err := strconv.Atoi(sel); err == nil && idx > 0 && idx <= len(names) {
+		name = names[idx-1]
+	}
+	if name == "" {
+		return ""
+	}
+	if err := ensureRemoteKey(ctx, ks, name); err != nil {
+		fmt.Println("❌", err)
+		return ""
+	}
+	return name
+}
+
+// ec2SSHUser 按镜像名称推断默认登录用户
+func ec2SSHUser(imageName string) string {
+	n := strings.ToLower(imageName)
+	switch {
+	case strings.Contains(n, "ubuntu"):
+		return "ubuntu"
+	case strings.Contains(n, "debian"):
+		return "admin"
+	case strings.Contains(n, "centos"):
+		return "centos"
+	case strings.Contains(n, "rocky"):
+		return "rocky"
+	case strings.Contains(n, "fedora"):
+		return "fedora"
+	}
+	return "ec2-user"
+}
+
+// sshCommand 返回可直接复制的登录命令；keyName 为空时只能用 root 密码登录
+func sshCommand(keyName, user, host string) string {
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	switch {
+	case keyName == "":
+		return "ssh root@" + host + "  (root 密码登录)"
+	case hasLocalKey(keyName):
+		return fmt.Sprintf("ssh -i %s %s@%s", keyPath(keyName), user, host)
+	}
+	return fmt.Sprintf("ssh -i <%s 的私钥> %s@%s", keyName, user, host)
+}
+
+func keyPairReport(rows []KeyPairRow) Report {
+	r := Report{
+		Header:  "服务\t区域\t名称\t类型\t指纹\t本地私钥",
+		Columns: []string{"service", "region", "name", "type", "fingerprint", "local"},
+		Cut:     map[int]int{4: 50},
+		Data:    rows,
+	}
+	if rows == nil {
+		r.Data = []KeyPairRow{}
+	}
+	for _, x := range rows {
+		local := ""
+		if x.Local {
+			local = "✅"
+		}
+		r.Rows = append(r.Rows, []string{x.Service, x.Region, x.Name, x.Type, x.Fingerprint, local})
+	}
+	return r
+}
+
+// listKeyPairs 并发列出多个区域的密钥对
+func listKeyPairs(ctx context.Context, stores []keyStore) ([]KeyPairRow, []error) {
+	type result struct {
+		rows []KeyPairRow
+		err  error
+	}
+	results := make([]result, len(stores))
+	done := make(chan struct{})
+	for i, ks := range stores {
+		go func() {
+			rows, err := ks.List(ctx)
+			results[i] = result{rows, err}
+			done <- struct{}{}
+		}()
+	}
+	for range stores {
+		<-done
+	}
+	var rows []KeyPairRow
+	var errs []error
+	for _, r := range results {
+		rows = append(rows, r.rows...)
+		if r.err != nil {
+			errs = append(errs, r.err)
+		}
+	}
+	return rows, errs
+}
+
+// keyStores 为 service (ec2|ls) 在各区域构造 keyStore
+func keyStores(ctx context.Context, service string, regions []string, creds aws.CredentialsProvider) ([]keyStore, error) {
+	var stores []keyStore
+	for _, r := range regions {
+		cfg, err := mkCfg(ctx, r, creds)
+		if err != nil {
+			return nil, err
+		}
+		switch service {
+		case "ec2":
+			stores = append(stores, ec2KeyStore{newEC2Client(cfg), r})
+		case "ls", "lightsail":
+			stores = append(stores, lsKeyStore{newLightsailClient(cfg), r})
+		default:
+			return nil, fmt.Errorf("未知服务: %s (可选 ec2|ls)", service)
+		}
+	}
+	return stores, nil
+}
+
+// importKeyToRegions 把本地密钥导入多个区域，已存在的区域跳过
+func importKeyToRegions(ctx context.Context, stores []keyStore, name string) error {
+	pub, err := localPublicKey(name)
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for _, ks := range stores {
+		region := ks.Region()
+		if err := ks.Import(ctx, name, pub); err != nil {
+			if strings.Contains(err.Error(), "Duplicate") || strings.Contains(err.Error(), "already") {
+				fmt.Printf("ℹ️ %s %s: 已存在\n", ks.Service(), region)
+				continue
+			}
+			fmt.Printf("❌ %s %s: %v\n", ks.Service(), region, err)
+			failed = append(failed, region)
+			continue
+		}
+		fmt.Printf("✅ %s %s: 已导入\n", ks.Service(), region)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d 个区域导入失败: %s", len(failed), strings.Join(failed, ","))
+	}
+	return nil
+}
+
+// keyMenu 交互式密钥管理
+func keyMenu(ctx context.Context, ec2Regions, lsRegions []string, creds aws.CredentialsProvider) {
+	pickService := func() (string, []string) {
+		if input("服务 1) EC2 [默认] 2) Lightsail: ", "1") == "2" {
+			return "ls", lsRegions
+		}
+		return "ec2", ec2Regions
+	}
+	pickRegions := func(all []string) []string {
+		sel := input("区域 (逗号分隔，all 为全部) ["+bootstrapRegion+"]: ", bootstrapRegion)
+		if sel == "all" {
+			return all
+		}
+		return splitList(sel)
+	}
+	for {
+		fmt.Println("\n--- 🗝️ SSH 密钥对 ---")
+		fmt.Println("本地密钥库:", keyringDir())
+		fmt.Println(" 1) 查看本地密钥")
+		fmt.Println(" 2) 生成 ed25519 密钥")
+		fmt.Println(" 3) 导入本地密钥到区域")
+		fmt.Println(" 4) 列出云端密钥对")
+		fmt.Println(" 5) 删除云端密钥对")
+		fmt.Println(" 6) 删除本地密钥")
+		fmt.Println(" 0) 返回")
+		switch input("选择: ", "0") {
+		case "1":
+			names := localKeys()
+			if len(names) == 0 {
+				fmt.Println("(空)")
+			}
+			for _, n := range names {
+				pub, _ := localPublicKey(n)
+				fmt.Printf(" • %s\n   %s\n", n, pub)
+			}
+		case "2":
+			name := input("密钥名称: ", "")
+			pub, err := generateKey(name)
+			if err != nil {
+				fmt.Println("❌", err)
+				continue
+			}
+			fmt.Println("✅ 私钥已保存:", keyPath(name))
+			fmt.Println("   公钥:", pub)
+		case "3":
+			name := input("本地密钥名称: ", "")
+			svc, all := pickService()
+			stores, err := keyStores(ctx, svc, pickRegions(all), creds)
+			if err == nil {
+				err = importKeyToRegions(ctx, stores, name)
+			}
+			if err != nil {
+				fmt.Println("❌", err)
+			}
+		case "4":
+			svc, all := pickService()
+			stores, err := keyStores(ctx, svc, pickRegions(all), creds)
+			if err != nil {
+				fmt.Println("❌", err)
+				continue
+			}
+			rows, errs := listKeyPairs(ctx, stores)
+			writeReport(os.Stdout, "table", keyPairReport(rows))
+			for _, e := range errs {
+				fmt.Println("⚠️", e)
+			}
+		case "5":
+			svc, _ := pickService()
+			region := input("区域 ["+bootstrapRegion+"]: ", bootstrapRegion)
+			name := input("密钥对名称: ", "")
+			stores, err := keyStores(ctx, svc, []string{region}, creds)
+			if err != nil || name == "" || !yes(input(fmt.Sprintf("⚠️ 确认删除 %s 中的密钥对 %s? [y/N]: ", region, name), "n")) {
+				continue
+			}
+			if err := stores[0].Delete(ctx, name); err != nil {
+				fmt.Println("❌ 删除失败:", err)
+				continue
+			}
+			fmt.Println("✅ 已删除")
+		case "6":
+			name := input("本地密钥名称: ", "")
+			if err := checkKeyName(name); err != nil {
+				fmt.Println("❌", err)
+				continue
+			}
+			if !hasLocalKey(name) {
+				fmt.Println("❌ 本地没有该密钥")
+				continue
+			}
+			if !yes(input("⚠️ 删除后将无法再用该私钥登录已有实例，确认? [y/N]: ", "n")) {
+				continue
+			}
+			if err := deleteLocalKey(name); err != nil {
+				fmt.Println("❌", err)
+				continue
+			}
+			fmt.Println("✅ 已删除")
+		case "0":
+			return
+		}
+	}
+}
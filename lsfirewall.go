@@ -0,0 +1,459 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lightsail"
+	lst "github.com/aws/aws-sdk-go-v2/service/lightsail/types"
+	"gopkg.in/yaml.v3"
+)
+
+// -------------------- Lightsail 防火墙 --------------------
+
+// Lightsail 的防火墙规则以 (协议, 起止端口) 为键，每条规则带 IPv4 / IPv6 来源列表。
+// 导出文件即 `ls firewall list --output yaml|json` 的输出，可直接导入到其他实例或区域。
+
+// lsConnectAlias 为 Lightsail 浏览器 SSH 的来源别名
+const lsConnectAlias = "lightsail-connect"
+
+// LSFirewallRule 为一条 Lightsail 防火墙规则；icmp 的 From/To 为 ICMP 类型与代码
+type LSFirewallRule struct {
+	Protocol  string   `json:"protocol" yaml:"protocol"`
+	From      int32    `json:"from" yaml:"from"`
+	To        int32    `json:"to" yaml:"to"`
+	Cidrs     []string `json:"cidrs,omitempty" yaml:"cidrs,omitempty"`
+	Ipv6Cidrs []string `json:"ipv6_cidrs,omitempty" yaml:"ipv6_cidrs,omitempty"`
+	Aliases   []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+}
+
+func (r LSFirewallRule) key() string {
+	return fmt.Sprintf("%s|%d|%d", r.Protocol, r.From, r.To)
+}
+
+func (r LSFirewallRule) ports() string {
+	switch {
+	case r.Protocol == "all" || (r.From == 0 && r.To == 65535):
+		return "全部"
+	case strings.HasPrefix(r.Protocol, "icmp"):
+		return fmt.Sprintf("type %d", r.From)
+	case r.From == r.To:
+		return strconv.Itoa(int(r.From))
+	}
+	return fmt.Sprintf("%d-%d", r.From, r.To)
+}
+
+// sources 返回规则的全部来源 (IPv4、IPv6、别名)
+func (r LSFirewallRule) sources() []string {
+	return slices.Concat(r.Cidrs, r.Ipv6Cidrs, r.Aliases)
+}
+
+func (r LSFirewallRule) summary() string {
+	s := r.ports() + "/" + r.Protocol
+	if src := r.sources(); len(src) > 0 && !slices.Equal(src, []string{"0.0.0.0/0", "::/0"}) {
+		s += " (" + strings.Join(src, ",") + ")"
+	}
+	return s
+}
+
+func (r LSFirewallRule) portInfo() lst.PortInfo {
+	return lst.PortInfo{
+		Protocol: lst.NetworkProtocol(r.Protocol), FromPort: r.From, ToPort: r.To,
+		Cidrs: r.Cidrs, Ipv6Cidrs: r.Ipv6Cidrs, CidrListAliases: r.Aliases,
+	}
+}
+
+func lsRuleFromInfo(p lst.InstancePortInfo) LSFirewallRule {
+	return LSFirewallRule{
+		Protocol: string(p.Protocol), From: p.FromPort, To: p.ToPort,
+		Cidrs: p.Cidrs, Ipv6Cidrs: p.Ipv6Cidrs, Aliases: p.CidrListAliases,
+	}
+}
+
+// validate 检查协议、端口与来源格式，并规范化 CIDR
+func (r *LSFirewallRule) validate() error {
+	switch r.Protocol {
+	case "tcp", "udp":
+		if r.From < 0 || r.To > 65535 || r.From > r.To {
+			return fmt.Errorf("端口范围无效: %d-%d", r.From, r.To)
+		}
+	case "all":
+		r.From, r.To = 0, 65535
+	case "icmp", "icmpv6":
+	default:
+		return fmt.Errorf("协议无效: %q (可选 tcp/udp/all/icmp/icmpv6)", r.Protocol)
+	}
+	for i, c := range r.Cidrs {
+		src, err := parseSGSource(c)
+		if err != nil || strings.Contains(src, ":") || strings.Contains(src, "-") {
+			return fmt.Errorf("IPv4 来源无效: %q", c)
+		}
+		r.Cidrs[i] = src
+	}
+	for i, c := range r.Ipv6Cidrs {
+		src, err := parseSGSource(c)
+		if err != nil || !strings.Contains(src, ":") {
+			return fmt.Errorf("IPv6 来源无效: %q", c)
+		}
+		r.Ipv6Cidrs[i] = src
+	}
+	for _, a := range r.Aliases {
+		if a != lsConnectAlias {
+			return fmt.Errorf("来源别名无效: %q (仅支持 %s)", a, lsConnectAlias)
+		}
+	}
+	if r.Protocol == "icmpv6" && len(r.Cidrs) > 0 {
+		return errors.New("icmpv6 规则只能使用 IPv6 来源")
+	}
+	if r.Protocol == "icmp" && len(r.Ipv6Cidrs) > 0 {
+		return errors.New("icmp 规则只能使用 IPv4 来源，IPv6 请使用 icmpv6")
+	}
+	if len(r.sources()) == 0 {
+		return fmt.Errorf("规则 %s 缺少来源", r.summary())
+	}
+	return nil
+}
+
+// withSources 返回替换了来源的规则副本；来源按 IPv4、IPv6 与 lightsail-connect 别名归类
+func (r LSFirewallRule) withSources(sources []string) (LSFirewallRule, error) {
+	r.Cidrs, r.Ipv6Cidrs, r.Aliases = nil, nil, nil
+	for _, s := range sources {
+		switch {
+		case s == lsConnectAlias:
+			r.Aliases = append(r.Aliases, s)
+		case strings.Contains(s, ":"):
+			r.Ipv6Cidrs = append(r.Ipv6Cidrs, s)
+		default:
+			r.Cidrs = append(r.Cidrs, s)
+		}
+	}
+	return r, r.validate()
+}
+
+// newLSRules 由端口规则与来源构造防火墙规则。
+// icmp 表示 ping：IPv4 来源生成 icmp 8，IPv6 来源生成 icmpv6 128
+func newLSRules(spec string, sources []string) ([]LSFirewallRule, error) {
+	if !strings.EqualFold(strings.TrimSpace(spec), "icmp") {
+		p, err := parsePortRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		r, err := LSFirewallRule{Protocol: p.Protocol, From: p.From, To: p.To}.withSources(sources)
+		if err != nil {
+			return nil, err
+		}
+		return []LSFirewallRule{r}, nil
+	}
+	var v4, v6 []string
+	for _, s := range sources {
+		if strings.Contains(s, ":") {
+			v6 = append(v6, s)
+		} else {
+			v4 = append(v4, s)
+		}
+	}
+	var rules []LSFirewallRule
+	for _, x := range []struct {
+		rule LSFirewallRule
+		src  []string
+	}{{LSFirewallRule{Protocol: "icmp", From: 8, To: -1}, v4}, {LSFirewallRule{Protocol: "icmpv6", From: 128, To: -1}, v6}} {
+		if len(x.src) == 0 {
+			continue
+		}
+		r, err := x.rule.withSources(x.src)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	if len(rules) == 0 {
+		return nil, errors.New("缺少来源")
+	}
+	return rules, nil
+}
+
+// mergeSources 返回合并去重后的来源列表
+func mergeSources(a, b []string) []string {
+	out := slices.Clone(a)
+	for _, s := range b {
+		if !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func lsFirewallRules(ctx context.Context, cli LightsailAPI, name string) ([]LSFirewallRule, error) {
+	out, err := cli.GetInstance(ctx, &lightsail.GetInstanceInput{InstanceName: aws.String(name)})
+	if err != nil {
+		return nil, err
+	}
+	var rules []LSFirewallRule
+	if out.Instance != nil && out.Instance.Networking != nil {
+		for _, p := range out.Instance.Networking.Ports {
+			rules = append(rules, lsRuleFromInfo(p))
+		}
+	}
+	return rules, nil
+}
+
+func lsFirewallReport(rules []LSFirewallRule) Report {
+	r := Report{
+		Header:  "#\t协议\t端口\tIPv4 来源\tIPv6 来源\t别名",
+		Columns: []string{"index", "protocol", "ports", "cidrs", "ipv6_cidrs", "aliases"},
+		Cut:     map[int]int{3: 40, 4: 40},
+		Data:    rules,
+	}
+	if rules == nil {
+		r.Data = []LSFirewallRule{}
+	}
+	for i, x := range rules {
+		r.Rows = append(r.Rows, []string{strconv.Itoa(i + 1), x.Protocol, x.ports(),
+			strings.Join(x.Cidrs, ","), strings.Join(x.Ipv6Cidrs, ","), strings.Join(x.Aliases, ",")})
+	}
+	return r
+}
+
+// lsOpenRules 逐条开放规则；与已有规则端口相同时合并来源
+func lsOpenRules(ctx context.Context, cli LightsailAPI, name string, cur, add []LSFirewallRule) error {
+	for _, r := range add {
+		for _, c := range cur {
+			if c.key() == r.key() {
+				r.Cidrs, r.Ipv6Cidrs, r.Aliases = mergeSources(c.Cidrs, r.Cidrs), mergeSources(c.Ipv6Cidrs, r.Ipv6Cidrs), mergeSources(c.Aliases, r.Aliases)
+			}
+		}
+		info := r.portInfo()
+		if _, err := cli.OpenInstancePublicPorts(ctx, &lightsail.OpenInstancePublicPortsInput{InstanceName: aws.String(name), PortInfo: &info}); err != nil {
+			return fmt.Errorf("%s: %v", r.summary(), err)
+		}
+	}
+	return nil
+}
+
+func lsCloseRule(ctx context.Context, cli LightsailAPI, name string, r LSFirewallRule) error {
+	info := r.portInfo()
+	_, err := cli.CloseInstancePublicPorts(ctx, &lightsail.CloseInstancePublicPortsInput{InstanceName: aws.String(name), PortInfo: &info})
+	return err
+}
+
+// lsPutRules 用 rules 整体替换实例的防火墙规则
+func lsPutRules(ctx context.Context, cli LightsailAPI, name string, rules []LSFirewallRule) error {
+	infos := make([]lst.PortInfo, 0, len(rules))
+	for _, r := range rules {
+		infos = append(infos, r.portInfo())
+	}
+	_, err := cli.PutInstancePublicPorts(ctx, &lightsail.PutInstancePublicPortsInput{InstanceName: aws.String(name), PortInfos: infos})
+	return err
+}
+
+// lsRemoveSources 从规则中删除部分来源；删空时关闭整条规则
+func lsRemoveSources(ctx context.Context, cli LightsailAPI, name string, cur []LSFirewallRule, idx int, sources []string) error {
+	r := cur[idx]
+	drop := func(list []string) []string {
+		return slices.DeleteFunc(slices.Clone(list), func(s string) bool {
+			if src, err := parseSGSource(s); err == nil {
+				s = src
+			}
+			for _, d := range sources {
+				if src, err := parseSGSource(d); err == nil {
+					d = src
+				}
+				if s == d {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	r.Cidrs, r.Ipv6Cidrs, r.Aliases = drop(r.Cidrs), drop(r.Ipv6Cidrs), drop(r.Aliases)
+	if len(r.sources()) == len(cur[idx].sources()) {
+		return fmt.Errorf("规则 %s 中没有这些来源: %s", cur[idx].summary(), strings.Join(sources, ","))
+	}
+	if len(r.sources()) == 0 {
+		return lsCloseRule(ctx, cli, name, cur[idx])
+	}
+	next := slices.Clone(cur)
+	next[idx] = r
+	return lsPutRules(ctx, cli, name, next)
+}
+
+// lsPresetRules 把安全组预设转换为 Lightsail 规则；仅当前 IP 的预设额外放行浏览器 SSH
+func lsPresetRules(ctx context.Context, p SGPreset) ([]LSFirewallRule, error) {
+	sources := []string{"0.0.0.0/0", "::/0"}
+	if p.MyIP {
+		ip, err := myPublicIP(ctx)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Println("🌐 当前公网 IP:", ip)
+		sources = []string{ip, lsConnectAlias}
+	}
+	var rules []LSFirewallRule
+	for _, port := range p.Ports {
+		rs, err := newLSRules(port, sources)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rs...)
+	}
+	return rules, nil
+}
+
+// loadLSFirewall 读取导出的规则文件 (YAML 或 JSON)
+func loadLSFirewall(path string) ([]LSFirewallRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []LSFirewallRule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", path, err)
+	}
+	for i := range rules {
+		if err := rules[i].validate(); err != nil {
+			return nil, fmt.Errorf("%s 第 %d 条规则: %v", path, i+1, err)
+		}
+	}
+	return rules, nil
+}
+
+// -------------------- 交互式防火墙管理 --------------------
+
+// lsFirewallMenu 管理实例 name 的防火墙；rows 为复制规则时可选的目标实例
+func lsFirewallMenu(ctx context.Context, cli LightsailAPI, sel LSInstanceRow, rows []LSInstanceRow, creds aws.CredentialsProvider) {
+	name := sel.Name
+	for {
+		rules, err := lsFirewallRules(ctx, cli, name)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		fmt.Printf("\n--- 🧱 防火墙 (%s) ---\n", name)
+		if len(rules) == 0 {
+			fmt.Println("(无规则，所有入站连接都会被拒绝)")
+		} else {
+			writeReport(os.Stdout, "table", lsFirewallReport(rules))
+		}
+		fmt.Println("\n 1) ➕ 添加规则/来源  2) ➖ 删除规则/来源  3) ✏️ 替换规则来源  4) 🧩 应用预设")
+		fmt.Println(" 5) 📤 导出到文件      6) 📥 从文件导入     7) 📋 复制到其他实例  0) 返回")
+		switch input("选择: ", "0") {
+		case "1":
+			spec := input("端口 (22、80/tcp、1000-2000/udp、icmp、all) [22]: ", "22")
+			sources, err := resolveSources(ctx, splitList(input("来源，逗号分隔 (CIDR/IP，my=当前公网 IP，lightsail-connect=浏览器 SSH) [0.0.0.0/0,::/0]: ", "0.0.0.0/0,::/0")))
+			if err == nil {
+				var add []LSFirewallRule
+				if add, err = newLSRules(spec, sources); err == nil {
+					err = lsOpenRules(ctx, cli, name, rules, add)
+				}
+			}
+			printResult(err, "✅ 规则已开放")
+		case "2":
+			idx, err := parseSelection(input("要删除的规则序号 (如 1,3-4): ", ""), len(rules))
+			if err != nil || len(idx) == 0 {
+				fmt.Println("❌ 未选择规则")
+				continue
+			}
+			if len(idx) == 1 {
+				if src := splitList(input("只删除部分来源 (逗号分隔，留空删除整条规则): ", "")); len(src) > 0 {
+					printResult(lsRemoveSources(ctx, cli, name, rules, idx[0], src), "✅ 已删除来源")
+					continue
+				}
+			}
+			for _, i := range idx {
+				printResult(lsCloseRule(ctx, cli, name, rules[i]), "✅ 已关闭 "+rules[i].summary())
+			}
+		case "3":
+			i := mustInt(input("要替换来源的规则序号: ", "0")) - 1
+			if i < 0 || i >= len(rules) {
+				continue
+			}
+			sources, err := resolveSources(ctx, splitList(input("新的来源，逗号分隔 (my=当前公网 IP): ", "")))
+			if err == nil {
+				var r LSFirewallRule
+				if r, err = rules[i].withSources(sources); err == nil {
+					next := slices.Clone(rules)
+					next[i] = r
+					err = lsPutRules(ctx, cli, name, next)
+				}
+			}
+			printResult(err, "✅ 来源已替换")
+		case "4":
+			for i, p := range sgPresets {
+				fmt.Printf(" %d) %s\n", i+1, p.Title)
+			}
+			i := mustInt(input("选择预设: ", "0"))
+			if i < 1 || i > len(sgPresets) || !yes(input("⚠️ 将替换全部现有规则，确认? [y/N]: ", "n")) {
+				continue
+			}
+			preset, err := lsPresetRules(ctx, sgPresets[i-1])
+			if err == nil {
+				err = lsPutRules(ctx, cli, name, preset)
+			}
+			printResult(err, "✅ 已应用预设「"+sgPresets[i-1].Title+"」")
+		case "5":
+			path := input(fmt.Sprintf("导出路径 (.yaml/.json) [%s-firewall.yaml]: ", name), name+"-firewall.yaml")
+			printResult(saveReport(path, lsFirewallReport(rules)), "✅ 已导出到 "+path)
+		case "6":
+			imported, err := loadLSFirewall(input("规则文件路径: ", ""))
+			if err == nil {
+				writeReport(os.Stdout, "table", lsFirewallReport(imported))
+				if !yes(input("⚠️ 将用以上规则替换全部现有规则，确认? [y/N]: ", "n")) {
+					continue
+				}
+				err = lsPutRules(ctx, cli, name, imported)
+			}
+			printResult(err, "✅ 已导入")
+		case "7":
+			lsCopyFirewallInteractive(ctx, sel, rules, rows, creds)
+		default:
+			return
+		}
+	}
+}
+
+func printResult(err error, ok string) {
+	if err != nil {
+		fmt.Println("❌", err)
+		return
+	}
+	fmt.Println(ok)
+}
+
+// lsCopyFirewallInteractive 把 rules 覆盖到选中的其他实例 (可跨区域)
+func lsCopyFirewallInteractive(ctx context.Context, src LSInstanceRow, rules []LSFirewallRule, rows []LSInstanceRow, creds aws.CredentialsProvider) {
+	var cands []LSInstanceRow
+	for _, r := range rows {
+		if r.Name != src.Name || r.Region != src.Region {
+			cands = append(cands, r)
+		}
+	}
+	if len(cands) == 0 {
+		fmt.Println("❌ 没有其他实例")
+		return
+	}
+	for i, r := range cands {
+		fmt.Printf(" %d) %-20s %s\n", i+1, r.Name, r.Region)
+	}
+	idx, err := parseSelection(input("目标实例序号 (如 1,3-4 或 all): ", ""), len(cands))
+	if err != nil || len(idx) == 0 {
+		fmt.Println("❌ 未选择实例")
+		return
+	}
+	if !yes(input(fmt.Sprintf("⚠️ 将覆盖 %d 台实例的全部防火墙规则，确认? [y/N]: ", len(idx)), "n")) {
+		return
+	}
+	for _, i := range idx {
+		t := cands[i]
+		cfg, err := mkCfg(ctx, t.Region, creds)
+		if err == nil {
+			err = lsPutRules(ctx, newLightsailClient(cfg), t.Name, rules)
+		}
+		printResult(err, fmt.Sprintf("✅ %s (%s) 已同步", t.Name, t.Region))
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lightsail"
+	lst "github.com/aws/aws-sdk-go-v2/service/lightsail/types"
+)
+
+// -------------------- Lightsail IPv6 / IP 地址类型 --------------------
+
+// Lightsail 实例有三种 IP 地址类型：dualstack (默认，IPv4 + IPv6)、ipv4 (关闭 IPv6)、
+// ipv6 (IPv6-only，没有公网 IPv4，只能使用更便宜的 IPv6 套餐)。切换到或离开 IPv6-only 时
+// Lightsail 会把套餐换成对应的 IPv6 / 普通套餐，并立即按新套餐计费。
+
+func parseLSIPType(s string) (lst.IpAddressType, error) {
+	switch strings.ToLower(s) {
+	case "", "dualstack", "dual":
+		return lst.IpAddressTypeDualstack, nil
+	case "ipv4":
+		return lst.IpAddressTypeIpv4, nil
+	case "ipv6", "ipv6-only":
+		return lst.IpAddressTypeIpv6, nil
+	}
+	return "", fmt.Errorf("IP 地址类型只能是 dualstack|ipv4|ipv6: %s", s)
+}
+
+// lsIPTypeOf 返回实例的 IP 地址类型，未返回时按 Lightsail 默认的双栈处理
+func lsIPTypeOf(ins lst.Instance) lst.IpAddressType {
+	if ins.IpAddressType == "" {
+		return lst.IpAddressTypeDualstack
+	}
+	return ins.IpAddressType
+}
+
+func lsIPTypeName(t lst.IpAddressType) string {
+	switch t {
+	case lst.IpAddressTypeIpv4:
+		return "仅 IPv4"
+	case lst.IpAddressTypeIpv6:
+		return "IPv6-only"
+	}
+	return "双栈"
+}
+
+// lsBundleIPv6Only 按套餐 ID 判断是否为 IPv6-only 套餐 (如 nano_ipv6_3_0)
+func lsBundleIPv6Only(id string) bool { return strings.Contains(id, "_ipv6_") }
+
+// bundleHasIPv4 返回套餐是否包含公网 IPv4
+func bundleHasIPv4(b lst.Bundle) bool {
+	if b.PublicIpv4AddressCount != nil {
+		return *b.PublicIpv4AddressCount > 0
+	}
+	return !lsBundleIPv6Only(aws.ToString(b.BundleId))
+}
+
+// lsIPTypeChangesBundle 返回切换是否会更换套餐 (进出 IPv6-only)
+func lsIPTypeChangesBundle(cur, want lst.IpAddressType) bool {
+	return cur != want && (cur == lst.IpAddressTypeIpv6 || want == lst.IpAddressTypeIpv6)
+}
+
+// setLSIPType 切换实例的 IP 地址类型；IPv6-only 不能绑定固定 IP，需先解绑
+func setLSIPType(ctx context.Context, cli LightsailAPI, ins lst.Instance, want lst.IpAddressType) error {
+	cur := lsIPTypeOf(ins)
+	if cur == want {
+		return fmt.Errorf("%s 已是%s", aws.ToString(ins.Name), lsIPTypeName(want))
+	}
+	if want == lst.IpAddressTypeIpv6 && aws.ToBool(ins.IsStaticIp) {
+		return fmt.Errorf("%s 绑定了固定 IP，IPv6-only 没有公网 IPv4，请先解绑固定 IP", aws.ToString(ins.Name))
+	}
+	in := &lightsail.SetIpAddressTypeInput{
+		ResourceType: lst.ResourceTypeInstance, ResourceName: ins.Name, IpAddressType: want,
+	}
+	if lsIPTypeChangesBundle(cur, want) {
+		in.AcceptBundleUpdate = aws.Bool(true)
+	}
+	_, err := cli.SetIpAddressType(ctx, in)
+	return err
+}
+
+// lsIPTypeMenu 实例详情页中的 IPv6 / IP 地址类型切换
+func lsIPTypeMenu(ctx context.Context, cli LightsailAPI, ins lst.Instance) {
+	cur := lsIPTypeOf(ins)
+	fmt.Printf("\n当前 IP 地址类型: %s\n", lsIPTypeName(cur))
+	fmt.Println(" 1) 双栈 (开启 IPv6)")
+	fmt.Println(" 2) 仅 IPv4 (关闭 IPv6)")
+	fmt.Println(" 3) IPv6-only (释放公网 IPv4，换成 IPv6 套餐)")
+	var want lst.IpAddressType
+	switch input("选择 (0 返回): ", "0") {
+	case "1":
+		want = lst.IpAddressTypeDualstack
+	case "2":
+		want = lst.IpAddressTypeIpv4
+	case "3":
+		want = lst.IpAddressTypeIpv6
+	default:
+		return
+	}
+	if cur == want {
+		fmt.Println("✅ 无需变更")
+		return
+	}
+	if lsIPTypeChangesBundle(cur, want) {
+		if want == lst.IpAddressTypeIpv6 {
+			fmt.Printf("⚠️ 套餐 %s 将换成对应的 IPv6 套餐并立即按新套餐计费；公网 IPv4 会被释放，之后只能通过 IPv6 连接\n", aws.ToString(ins.BundleId))
+		} else {
+			fmt.Printf("⚠️ 套餐 %s 将换成对应的含公网 IPv4 套餐，并立即按新套餐计费\n", aws.ToString(ins.BundleId))
+		}
+		if !yes(input("确认切换? [y/N]: ", "n")) {
+			return
+		}
+	}
+	if err := setLSIPType(ctx, cli, ins, want); err != nil {
+		fmt.Println("❌ 切换失败:", err)
+		return
+	}
+	fmt.Printf("✅ 已切换为%s\n", lsIPTypeName(want))
+}
+
+func (r LSInstanceRow) ipTypeName() string { return lsIPTypeName(lst.IpAddressType(r.IPType)) }
+
+// cliLSSetIPType 命令行切换 IP 地址类型；会更换套餐时必须显式确认
+func cliLSSetIPType(ctx context.Context, cli LightsailAPI, name string, want lst.IpAddressType, force bool) error {
+	out, err := cli.GetInstance(ctx, &lightsail.GetInstanceInput{InstanceName: aws.String(name)})
+	if err != nil {
+		return err
+	}
+	if lsIPTypeChangesBundle(lsIPTypeOf(*out.Instance), want) && !force {
+		return fmt.Errorf("进出 IPv6-only 会更换套餐 %s 并立即按新套餐计费，需要加 --yes 确认", aws.ToString(out.Instance.BundleId))
+	}
+	return setLSIPType(ctx, cli, *out.Instance, want)
+}
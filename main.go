@@ -9,9 +9,9 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
-	"net/http"
 	"net/url"
 	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -21,7 +21,6 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/account"
 	"github.com/aws/aws-sdk-go-v2/service/budgets"
 	budgetsTypes "github.com/aws/aws-sdk-go-v2/service/budgets/types"
@@ -47,35 +46,41 @@ const bootstrapRegion = "us-east-1"
 
 var GlobalProxy string
 
+// userProxy 为启动时手动选择的代理；切换到未绑定代理的保险库账户时恢复为它
+var userProxy string
+
 // --- 数据结构 ---
 
 type LSInstanceRow struct {
-	Idx    int
-	Region string
-	Name   string
-	State  string
-	IP     string
-	IPv6   string
-	AZ     string
-	Bundle string
+	Idx    int    `json:"idx" yaml:"idx"`
+	Region string `json:"region" yaml:"region"`
+	Name   string `json:"name" yaml:"name"`
+	State  string `json:"state" yaml:"state"`
+	IP     string `json:"ipv4" yaml:"ipv4"`
+	IPv6   string `json:"ipv6" yaml:"ipv6"`
+	AZ     string `json:"az" yaml:"az"`
+	Bundle string `json:"bundle" yaml:"bundle"`
+	IPType string `json:"ip_type" yaml:"ip_type"` // dualstack|ipv4|ipv6
 }
 
 type EC2InstanceRow struct {
-	Idx    int
-	Region string
-	AZ     string
-	ID     string
-	State  string
-	Name   string
-	Type   string
-	PubIP  string
-	PrivIP string
-	IPv6   string
+	Idx    int    `json:"idx" yaml:"idx"`
+	Region string `json:"region" yaml:"region"`
+	AZ     string `json:"az" yaml:"az"`
+	ID     string `json:"id" yaml:"id"`
+	State  string `json:"state" yaml:"state"`
+	Name   string `json:"name" yaml:"name"`
+	Type   string `json:"type" yaml:"type"`
+	PubIP  string `json:"public_ip" yaml:"public_ip"`
+	PrivIP string `json:"private_ip" yaml:"private_ip"`
+	IPv6   string `json:"ipv6" yaml:"ipv6"`
+	Net    string `json:"net" yaml:"net"` // ipv4|dual|ipv6
+	Spot   bool   `json:"spot" yaml:"spot"`
 }
 
 type RegionInfo struct {
-	Name   string
-	Status string
+	Name   string `json:"name" yaml:"name"`
+	Status string `json:"status" yaml:"status"`
 }
 
 type AMIOption struct {
@@ -203,27 +208,28 @@ func mkCfg(ctx context.Context, region string, creds aws.CredentialsProvider) (a
 		config.WithRegion(region),
 		config.WithCredentialsProvider(creds),
 	}
-	if GlobalProxy != "" {
-		proxyURL, err := url.Parse(GlobalProxy)
-		if err == nil {
-			httpClient := &http.Client{
-				Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)},
-				Timeout:   30 * time.Second,
-			}
-			opts = append(opts, config.WithHTTPClient(httpClient))
-		}
+	hc, err := proxyHTTPClient(ctx)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	if hc != nil {
+		opts = append(opts, config.WithHTTPClient(hc))
 	}
 	return config.LoadDefaultConfig(ctx, opts...)
 }
 
 func stsCheck(ctx context.Context, region string, creds aws.CredentialsProvider) error {
+	_, err := stsIdentity(ctx, region, creds)
+	return err
+}
+
+func stsIdentity(ctx context.Context, region string, creds aws.CredentialsProvider) (*sts.GetCallerIdentityOutput, error) {
 	cfg, err := mkCfg(ctx, region, creds)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	cli := sts.NewFromConfig(cfg)
-	_, err = cli.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
-	return err
+	cli := newSTSClient(cfg)
+	return cli.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 }
 
 func pickRegion(title string, items []RegionInfo, def string) (RegionInfo, error) {
@@ -287,9 +293,12 @@ func printTable(header string, rowsFunc func(*tabwriter.Writer)) {
 
 // -------------------- 2. 自动化 $80 任务逻辑 --------------------
 
+// taskSleep 为新手任务中等待资源就绪的间隔，测试中替换为不等待
+var taskSleep = time.Sleep
+
 func taskSetBudget(ctx context.Context, cfg aws.Config, acctID string) {
 	fmt.Println("\n[任务 1/4] 正在设置 AWS Cost Budget (成本预算)...")
-	cli := budgets.NewFromConfig(cfg)
+	cli := newBudgetsClient(cfg)
 	budgetName := fmt.Sprintf("AutoBudget-%s", randStr(6))
 	email := fmt.Sprintf("alert-%s@gmail.com", randStr(4))
 	_, err := cli.CreateBudget(ctx, &budgets.CreateBudgetInput{
@@ -324,7 +333,7 @@ func taskSetBudget(ctx context.Context, cfg aws.Config, acctID string) {
 
 func taskRunEC2(ctx context.Context, cfg aws.Config) {
 	fmt.Println("\n[任务 2/4] 正在启动 EC2 实例...")
-	cli := ec2.NewFromConfig(cfg)
+	cli := newEC2Client(cfg)
 	ami := "ami-051f7e7f6c2f40dc1"
 	runOut, err := cli.RunInstances(ctx, &ec2.RunInstancesInput{
 		ImageId:      aws.String(ami),
@@ -339,7 +348,7 @@ func taskRunEC2(ctx context.Context, cfg aws.Config) {
 	id := *runOut.Instances[0].InstanceId
 	fmt.Printf(" ⏳ 实例 %s 启动中，等待 Running...\n", id)
 	for i := 0; i < 40; i++ {
-		time.Sleep(3 * time.Second)
+		taskSleep(3 * time.Second)
 		desc, _ := cli.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{id}})
 		if len(desc.Reservations) > 0 && desc.Reservations[0].Instances[0].State.Name == ec2t.InstanceStateNameRunning {
 			fmt.Println(" ✅ 状态: Running (任务达成)")
@@ -354,7 +363,7 @@ func taskRunEC2(ctx context.Context, cfg aws.Config) {
 
 func taskRunLambda(ctx context.Context, cfg aws.Config) {
 	fmt.Println("\n[任务 3/4] 正在创建并调用 Lambda 函数...")
-	iamCli := iam.NewFromConfig(cfg)
+	iamCli := newIAMClient(cfg)
 	roleName := fmt.Sprintf("AutoLambdaRole-%s", randStr(5))
 	assumeRolePolicy := `{"Version": "2012-10-17","Statement": [{"Effect": "Allow","Principal": {"Service": "lambda.amazonaws.com"},"Action": "sts:AssumeRole"}]}`
 	fmt.Printf(" -> 创建临时 IAM 角色: %s\n", roleName)
@@ -368,7 +377,7 @@ func taskRunLambda(ctx context.Context, cfg aws.Config) {
 	}
 	roleArn := *roleOut.Role.Arn
 	fmt.Print(" ⏳ 等待 IAM 角色生效 (约10秒)...")
-	time.Sleep(10 * time.Second)
+	taskSleep(10 * time.Second)
 	fmt.Println("")
 
 	code := `def lambda_handler(event, context): return "Hello AWS 80 USD"`
@@ -378,7 +387,7 @@ func taskRunLambda(ctx context.Context, cfg aws.Config) {
 	f.Write([]byte(code))
 	zipWriter.Close()
 
-	lambdaCli := lambda.NewFromConfig(cfg)
+	lambdaCli := newLambdaClient(cfg)
 	funcName := fmt.Sprintf("AutoFunc-%s", randStr(5))
 	_, err = lambdaCli.CreateFunction(ctx, &lambda.CreateFunctionInput{
 		FunctionName: aws.String(funcName),
@@ -388,7 +397,7 @@ func taskRunLambda(ctx context.Context, cfg aws.Config) {
 		Code:         &lambdaTypes.FunctionCode{ZipFile: buf.Bytes()},
 	})
 	if err != nil {
-		time.Sleep(5 * time.Second)
+		taskSleep(5 * time.Second)
 		_, err = lambdaCli.CreateFunction(ctx, &lambda.CreateFunctionInput{
 			FunctionName: aws.String(funcName),
 			Runtime:      lambdaTypes.RuntimePython39,
@@ -410,7 +419,7 @@ func taskRunLambda(ctx context.Context, cfg aws.Config) {
 			fmt.Println(" ✅ 就绪")
 			break
 		}
-		time.Sleep(2 * time.Second)
+		taskSleep(2 * time.Second)
 		fmt.Print(".")
 	}
 	_, err = lambdaCli.Invoke(ctx, &lambda.InvokeInput{FunctionName: aws.String(funcName)})
@@ -427,10 +436,10 @@ func taskRunLambda(ctx context.Context, cfg aws.Config) {
 func taskRunRDS(ctx context.Context, cfg aws.Config) {
 	fmt.Println("\n[任务 4/4] 正在创建 RDS 数据库 (MySQL Free Tier)...")
 	fmt.Println("⚠️ 警告：RDS 创建非常慢 (5-10 分钟)，请耐心等待。")
-	rdsCli := rds.NewFromConfig(cfg)
+	rdsCli := newRDSClient(cfg)
 	dbName := fmt.Sprintf("db-%s", randStr(6))
 	masterUser := "admin"
-	masterPass := "Password123456"
+	masterPass := generatePassword(rdsPasswordPolicy)
 	_, err := rdsCli.CreateDBInstance(ctx, &rds.CreateDBInstanceInput{
 		DBInstanceIdentifier:  aws.String(dbName),
 		DBInstanceClass:       aws.String("db.t3.micro"),
@@ -444,13 +453,12 @@ func taskRunRDS(ctx context.Context, cfg aws.Config) {
 		fmt.Printf(" ❌ 创建请求失败: %v\n", err)
 		return
 	}
-	fmt.Printf(" ⏳ 数据库 %s 正在创建...\n", dbName)
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
+	fmt.Printf(" ⏳ 数据库 %s 正在创建... (主用户 %s，密码 %s)\n", dbName, masterUser, masterPass)
+	recordCredentials(CredentialRecord{Service: "rds", Region: cfg.Region, Resource: dbName, User: masterUser, Password: masterPass})
 	maxWait := 30
 	created := false
 	for i := 0; i < maxWait; i++ {
-		<-ticker.C
+		taskSleep(30 * time.Second)
 		out, err := rdsCli.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{
 			DBInstanceIdentifier: aws.String(dbName),
 		})
@@ -496,7 +504,7 @@ func autoClaimCredits(ctx context.Context, creds aws.CredentialsProvider) {
 		fmt.Println("初始化配置失败:", err)
 		return
 	}
-	stsCli := sts.NewFromConfig(cfg)
+	stsCli := newSTSClient(cfg)
 	idOut, err := stsCli.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
 		fmt.Println("获取账户 ID 失败:", err)
@@ -547,7 +555,7 @@ func getEC2RegionsWithStatus(ctx context.Context, creds aws.CredentialsProvider)
 	if err != nil {
 		return nil, err
 	}
-	cli := ec2.NewFromConfig(cfg)
+	cli := newEC2Client(cfg)
 	out, err := cli.DescribeRegions(ctx, &ec2.DescribeRegionsInput{AllRegions: aws.Bool(true)})
 	if err != nil {
 		return nil, err
@@ -567,7 +575,7 @@ func getLightsailRegions(ctx context.Context, creds aws.CredentialsProvider) ([]
 	if err != nil {
 		return nil, err
 	}
-	cli := lightsail.NewFromConfig(cfg)
+	cli := newLightsailClient(cfg)
 	out, err := cli.GetRegions(ctx, &lightsail.GetRegionsInput{})
 	if err != nil {
 		return nil, err
@@ -583,6 +591,9 @@ func getLightsailRegions(ctx context.Context, creds aws.CredentialsProvider) ([]
 	return rs, nil
 }
 
+// regionOptInPoll 为启用区域后轮询状态的间隔
+var regionOptInPoll = 15 * time.Second
+
 func ensureRegionOptIn(ctx context.Context, regionName, currentStatus string, creds aws.CredentialsProvider) error {
 	if currentStatus == "opt-in-not-required" || currentStatus == "opted-in" {
 		return nil
@@ -595,7 +606,7 @@ func ensureRegionOptIn(ctx context.Context, regionName, currentStatus string, cr
 	if err != nil {
 		return err
 	}
-	acctCli := account.NewFromConfig(cfg)
+	acctCli := newAccountClient(cfg)
 	_, err = acctCli.EnableRegion(ctx, &account.EnableRegionInput{RegionName: aws.String(regionName)})
 	if err != nil {
 		if !strings.Contains(err.Error(), "ResourceAlreadyExists") && !strings.Contains(err.Error(), "Region is enabled") {
@@ -603,8 +614,8 @@ func ensureRegionOptIn(ctx context.Context, regionName, currentStatus string, cr
 		}
 	}
 	fmt.Println("⏳ 请求已发送...")
-	ec2Cli := ec2.NewFromConfig(cfg)
-	ticker := time.NewTicker(15 * time.Second)
+	ec2Cli := newEC2Client(cfg)
+	ticker := time.NewTicker(regionOptInPoll)
 	defer ticker.Stop()
 	for {
 		<-ticker.C
@@ -627,151 +638,61 @@ func ensureRegionOptIn(ctx context.Context, regionName, currentStatus string, cr
 	}
 }
 
-func checkQuotas(ctx context.Context, creds aws.CredentialsProvider) {
+// vcpuQuotaCode 为 EC2 "Running On-Demand Standard instances" 的 vCPU 配额代码
+const vcpuQuotaCode = "L-1216C47A"
+
+func getVCPUQuota(ctx context.Context, creds aws.CredentialsProvider) (float64, error) {
 	cfg, err := mkCfg(ctx, "us-east-1", creds)
 	if err != nil {
-		fmt.Println("❌ 失败:", err)
-		return
+		return 0, err
 	}
+	sqCli := newServiceQuotasClient(cfg)
+	qOut, err := sqCli.GetServiceQuota(ctx, &servicequotas.GetServiceQuotaInput{ServiceCode: aws.String("ec2"), QuotaCode: aws.String(vcpuQuotaCode)})
+	if err != nil {
+		return 0, err
+	}
+	return aws.ToFloat64(qOut.Quota.Value), nil
+}
+
+func checkQuotas(ctx context.Context, creds aws.CredentialsProvider) {
 	fmt.Println("\n正在查询.....")
-	sqCli := servicequotas.NewFromConfig(cfg)
-	vcpuCode := "L-1216C47A"
-	svcCode := "ec2"
-	qOut, err := sqCli.GetServiceQuota(ctx, &servicequotas.GetServiceQuotaInput{ServiceCode: &svcCode, QuotaCode: &vcpuCode})
+	v, err := getVCPUQuota(ctx, creds)
 	if err != nil {
 		fmt.Printf("EC2配额: 失败\n")
 	} else {
-		fmt.Printf("EC2配额: %.0f vCPU\n", *qOut.Quota.Value)
+		fmt.Printf("EC2配额: %.0f vCPU\n", v)
 	}
 	input("\n按回车返回...", "")
 }
 
-func autoSetupIPv6(ctx context.Context, cli *ec2.Client, region, vpcID, targetSubnetID string) (string, error) {
+// autoSetupIPv6 为 VPC 内所有子网配置 IPv6 (见 planIPv6)，返回用于启动实例的子网；
+// opt.Native 时改为查找或新建 IPv6-only 子网 (与 targetSubnetID 同一可用区)
+func autoSetupIPv6(ctx context.Context, cli EC2API, region, vpcID, targetSubnetID string, opt IPv6Options) (string, error) {
 	fmt.Println("🔍 配置 IPv6 (VPC/子网)...")
-	vpcOut, err := cli.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{VpcIds: []string{vpcID}})
+	if opt.Native && targetSubnetID != "" {
+		out, err := cli.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{SubnetIds: []string{targetSubnetID}})
+		if err == nil && len(out.Subnets) > 0 {
+			opt.AZ = aws.ToString(out.Subnets[0].AvailabilityZone)
+		}
+		targetSubnetID = ""
+	}
+	p, err := planIPv6(ctx, cli, vpcID, opt)
 	if err != nil {
 		return "", err
 	}
-	hasVpcIPv6 := false
-	var vpcCidrBlock string
-	for _, assoc := range vpcOut.Vpcs[0].Ipv6CidrBlockAssociationSet {
-		if assoc.Ipv6CidrBlockState.State == ec2t.VpcCidrBlockStateCodeAssociated {
-			hasVpcIPv6 = true
-			vpcCidrBlock = *assoc.Ipv6CidrBlock
-			break
-		}
-	}
-	if !hasVpcIPv6 {
-		_, err := cli.AssociateVpcCidrBlock(ctx, &ec2.AssociateVpcCidrBlockInput{
-			VpcId: aws.String(vpcID), AmazonProvidedIpv6CidrBlock: aws.Bool(true),
-		})
-		if err != nil {
+	if p.changes() > 0 {
+		printIPv6Plan(p)
+		if err := applyIPv6Plan(ctx, cli, p); err != nil {
 			return "", err
 		}
-		fmt.Println("   -> 申请 VPC IPv6 成功")
-		for i := 0; i < 10; i++ {
-			time.Sleep(3 * time.Second)
-			v, _ := cli.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{VpcIds: []string{vpcID}})
-			for _, a := range v.Vpcs[0].Ipv6CidrBlockAssociationSet {
-				if a.Ipv6CidrBlockState.State == ec2t.VpcCidrBlockStateCodeAssociated {
-					vpcCidrBlock = *a.Ipv6CidrBlock
-					goto VPC_READY
-				}
-			}
-		}
-		return "", fmt.Errorf("超时")
 	}
-VPC_READY:
-	// 获取子网
-	var subOut *ec2.DescribeSubnetsOutput
 	if targetSubnetID != "" {
-		subOut, err = cli.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{SubnetIds: []string{targetSubnetID}})
-	} else {
-		subOut, err = cli.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{Filters: []ec2t.Filter{{Name: aws.String("vpc-id"), Values: []string{vpcID}}}})
-	}
-
-	if err != nil || len(subOut.Subnets) == 0 {
-		return "", fmt.Errorf("无子网")
-	}
-
-	subnet := subOut.Subnets[0]
-	subnetID := *subnet.SubnetId
-	
-	// 检查子网是否已有 IPv6
-	hasSubnetIPv6 := false
-	for _, assoc := range subnet.Ipv6CidrBlockAssociationSet {
-		if assoc.Ipv6CidrBlockState.State == ec2t.SubnetCidrBlockStateCodeAssociated {
-			hasSubnetIPv6 = true
-			break
-		}
-	}
-
-	if !hasSubnetIPv6 {
-		newSubnetCidr := strings.Replace(vpcCidrBlock, "/56", "/64", 1)
-		_, err = cli.AssociateSubnetCidrBlock(ctx, &ec2.AssociateSubnetCidrBlockInput{SubnetId: aws.String(subnetID), Ipv6CidrBlock: aws.String(newSubnetCidr)})
-		if err != nil {
-			if strings.Contains(err.Error(), "Conflict") {
-				return "", fmt.Errorf("IPv6网段分配冲突，请手动在控制台配置子网 CIDR")
-			}
-			return "", err
-		}
-		fmt.Println("   -> 子网关联 IPv6 CIDR 成功")
+		return targetSubnetID, nil
 	}
-
-	cli.ModifySubnetAttribute(ctx, &ec2.ModifySubnetAttributeInput{
-		SubnetId: aws.String(subnetID), AssignIpv6AddressOnCreation: &ec2t.AttributeBooleanValue{Value: aws.Bool(true)},
-	})
-
-	// Route
-	rtOut, err := cli.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{Filters: []ec2t.Filter{{Name: aws.String("vpc-id"), Values: []string{vpcID}}}})
-	if err == nil && len(rtOut.RouteTables) > 0 {
-		// 找到关联该子网的路由表，或者主路由表
-		var targetRT *ec2t.RouteTable
-		for _, rt := range rtOut.RouteTables {
-			for _, assoc := range rt.Associations {
-				if assoc.SubnetId != nil && *assoc.SubnetId == subnetID {
-					targetRT = &rt
-					goto FOUND_RT
-				}
-			}
-		}
-		if targetRT == nil {
-			for _, rt := range rtOut.RouteTables {
-				for _, assoc := range rt.Associations {
-					if assoc.Main != nil && *assoc.Main {
-						targetRT = &rt
-						goto FOUND_RT
-					}
-				}
-			}
-		}
-
-	FOUND_RT:
-		if targetRT != nil {
-			hasRoute := false
-			var igwID string
-			igwOut, _ := cli.DescribeInternetGateways(ctx, &ec2.DescribeInternetGatewaysInput{Filters: []ec2t.Filter{{Name: aws.String("attachment.vpc-id"), Values: []string{vpcID}}}})
-			if len(igwOut.InternetGateways) > 0 {
-				igwID = *igwOut.InternetGateways[0].InternetGatewayId
-			}
-			for _, r := range targetRT.Routes {
-				if aws.ToString(r.DestinationIpv6CidrBlock) == "::/0" {
-					hasRoute = true
-					break
-				}
-			}
-			if !hasRoute && igwID != "" {
-				cli.CreateRoute(ctx, &ec2.CreateRouteInput{
-					RouteTableId: targetRT.RouteTableId, DestinationIpv6CidrBlock: aws.String("::/0"), GatewayId: aws.String(igwID),
-				})
-				fmt.Println("   -> 路由表更新成功 (::/0 -> IGW)")
-			}
-		}
-	}
-	return subnetID, nil
+	return p.Subnets[0].SubnetID, nil
 }
 
-func ensureOpenAllSG(ctx context.Context, cli *ec2.Client, region string) (string, string, error) {
+func ensureOpenAllSG(ctx context.Context, cli EC2API, region string) (string, string, error) {
 	vpcs, err := cli.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{Filters: []ec2t.Filter{{Name: aws.String("isDefault"), Values: []string{"true"}}}})
 	if err != nil || len(vpcs.Vpcs) == 0 {
 		return "", "", fmt.Errorf("无默认VPC")
@@ -798,7 +719,7 @@ func ensureOpenAllSG(ctx context.Context, cli *ec2.Client, region string) (strin
 	return *res.GroupId, vpcID, nil
 }
 
-func getLatestAMI(ctx context.Context, cli *ec2.Client, owner, namePattern string) string {
+func getLatestAMI(ctx context.Context, cli EC2API, owner, namePattern string) string {
 	out, err := cli.DescribeImages(ctx, &ec2.DescribeImagesInput{
 		Owners: []string{owner},
 		Filters: []ec2t.Filter{
@@ -814,7 +735,7 @@ func getLatestAMI(ctx context.Context, cli *ec2.Client, owner, namePattern strin
 	return *out.Images[0].ImageId
 }
 
-func getLatestAMIWithArch(ctx context.Context, cli *ec2.Client, owner, namePattern, arch string) string {
+func getLatestAMIWithArch(ctx context.Context, cli EC2API, owner, namePattern, arch string) string {
 	out, err := cli.DescribeImages(ctx, &ec2.DescribeImagesInput{
 		Owners: []string{owner},
 		Filters: []ec2t.Filter{
@@ -830,7 +751,244 @@ func getLatestAMIWithArch(ctx context.Context, cli *ec2.Client, owner, namePatte
 	return *out.Images[0].ImageId
 }
 
+// amiList 为内置的常用系统镜像，CLI 中用名称别名引用 (如 debian-12、ubuntu-24.04)
+var amiList = []AMIOption{
+	{"Debian 12", "136693071363", "debian-12-*"},
+	{"Debian 11", "136693071363", "debian-11-*"},
+	{"Ubuntu 24.04", "099720109477", "ubuntu/images/hvm-ssd-gp3/ubuntu-noble-24.04-*"},
+	{"Ubuntu 22.04", "099720109477", "ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-*"},
+	{"Amazon Linux 2023", "137112412989", "al2023-ami-2023.*"},
+	{"Amazon Linux 2", "137112412989", "amzn2-ami-hvm-*"},
+}
+
+func amiAlias(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), " ", "-")
+}
+
+// resolveAMI 接受 AMI ID 或内置镜像别名，返回该架构下的最新 AMI ID
+func resolveAMI(ctx context.Context, cli EC2API, sel, arch string) (string, error) {
+	if strings.HasPrefix(sel, "ami-") {
+		return sel, nil
+	}
+	for _, a := range amiList {
+		if amiAlias(a.Name) == strings.ToLower(sel) {
+			fmt.Printf("🔍 正在搜索 %s (%s) 的最新镜像...\n", a.Name, arch)
+			if ami := getLatestAMIWithArch(ctx, cli, a.Owner, a.Pattern, arch); ami != "" {
+				return ami, nil
+			}
+			return "", fmt.Errorf("未找到 AMI")
+		}
+	}
+	return "", fmt.Errorf("未知镜像: %s", sel)
+}
+
+func defaultEC2Types(arch string) []TypeOption {
+	if arch == "arm64" {
+		return []TypeOption{{"t4g.nano", "2 vCPU, 0.5 GiB"}, {"t4g.micro", "2 vCPU, 1.0 GiB"}}
+	}
+	return []TypeOption{{"t2.nano", "1 vCPU, 0.5 GiB"}, {"t2.micro", "1 vCPU, 1.0 GiB"}, {"t3.micro", "2 vCPU, 1.0 GiB"}}
+}
+
+// EC2LaunchOptions 汇总一次 EC2 创建所需的全部参数，交互菜单与 CLI 共用
+type EC2LaunchOptions struct {
+	Arch         string
+	AMI          string
+	Type         string
+	Count        int32
+	VolSize      int32
+	Net          string // 网络模式 ipv4|dual|ipv6，空为 ipv4
+	DNS64        bool   // IPv6-only 子网开启 DNS64
+	NAT64        bool   // IPv6-only 子网经已有 NAT 网关访问 IPv4
+	RootPwd      string
+	GenPwd       bool // RootPwd 为空时按密码策略生成
+	PlainPwd     bool // user-data 中明文写入密码 (默认只写哈希)
+	OpenAll      bool
+	Ports        []PortRule // 未全开时只放行这些端口
+	UserData     string
+	Snippets     []string          // 启动脚本片段，如 docker、swap:SIZE=2G
+	UserDataVars map[string]string // 片段与 UserData 中 {{VAR}} 的取值
+	KeyName      string
+	Tags         map[string]string
+	Spot         *SpotOptions // nil 为按需实例
+	AltTypes     []string     // 容量不足时依次尝试的备选类型
+}
+
+// buildEC2UserData 组合 root 密码、片段与自定义脚本，并校验大小
+func buildEC2UserData(o EC2LaunchOptions) (string, error) {
+	specs, vars := withRootPassword(o.RootPwd, o.PlainPwd, o.Snippets, o.UserDataVars)
+	parts, err := renderUserData(specs, o.UserData, vars)
+	if err != nil {
+		return "", err
+	}
+	return ec2UserDataDoc(parts)
+}
+
+func ec2Launch(ctx context.Context, cli EC2API, region string, o EC2LaunchOptions) ([]ec2t.Instance, error) {
+	if o.Arch == "" {
+		o.Arch = "x86_64"
+	}
+	if o.Count < 1 {
+		o.Count = 1
+	}
+	o.RootPwd, o.PlainPwd = resolveRootPassword(o.RootPwd, o.GenPwd, o.PlainPwd)
+	userData, err := buildEC2UserData(o)
+	if err != nil {
+		return nil, err
+	}
+	ami, err := resolveAMI(ctx, cli, o.AMI, o.Arch)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := parseNetMode(o.Net)
+	if err != nil {
+		return nil, err
+	}
+	if o.Type == "" {
+		o.Type = defaultEC2Types(o.Arch)[0].Type
+		if mode == netIPv6 {
+			o.Type = nitroDefaultType(o.Arch)
+		}
+	}
+	if o.Spot != nil {
+		if err := o.Spot.normalize(); err != nil {
+			return nil, err
+		}
+	}
+	types := launchTypes(ctx, cli, region, o.Arch, o.Type, o.AltTypes)
+	if mode == netIPv6 {
+		if types, err = nitroTypes(ctx, cli, types); err != nil {
+			return nil, err
+		}
+	}
+	azs, err := checkTypeOffered(ctx, cli, region, o.Type)
+	if err != nil {
+		if len(types) == 1 {
+			return nil, err
+		}
+		fmt.Printf("⚠️ %v，将尝试备选类型\n", err)
+	}
+	// 类型只在部分可用区提供时，固定到这些可用区的默认子网，避免 Unsupported 错误
+	typeSubnetID := subnetForTypeAZs(ctx, cli, azs)
+
+	// 只有显式要求时才放行端口；双栈 / IPv6-only 未指定端口时沿用 VPC 默认安全组
+	var sgID, vpcID string
+	var sgErr error
+	switch {
+	case o.OpenAll:
+		sgID, vpcID, sgErr = ensureOpenAllSG(ctx, cli, region)
+	case len(o.Ports) > 0:
+		sgID, vpcID, sgErr = ensurePortsSG(ctx, cli, o.Ports)
+	case mode != netIPv4:
+		vpcID, sgErr = defaultVpcID(ctx, cli)
+	}
+	if sgErr != nil {
+		return nil, fmt.Errorf("网络错误: %v", sgErr)
+	}
+
+	var targetSubnetID string
+	if mode != netIPv4 {
+		opt := IPv6Options{Native: mode == netIPv6, DNS64: o.DNS64, NAT64: o.NAT64}
+		sID, err := autoSetupIPv6(ctx, cli, region, vpcID, typeSubnetID, opt)
+		switch {
+		case err != nil && mode == netIPv6:
+			return nil, fmt.Errorf("IPv6-only 子网配置失败: %v", err)
+		case err != nil:
+			fmt.Println("⚠️ IPv6 配置失败:", err)
+			mode = netIPv4
+		default:
+			targetSubnetID = sID
+		}
+	}
+
+	runIn := &ec2.RunInstancesInput{
+		ImageId:      aws.String(ami),
+		InstanceType: ec2t.InstanceType(o.Type),
+		MinCount:     aws.Int32(o.Count),
+		MaxCount:     aws.Int32(o.Count),
+	}
+	// 这里使用了 base64
+	if userData != "" {
+		runIn.UserData = aws.String(base64.StdEncoding.EncodeToString([]byte(userData)))
+	}
+	if o.KeyName != "" {
+		if err := ensureRemoteKey(ctx, ec2KeyStore{cli, region}, o.KeyName); err != nil {
+			return nil, err
+		}
+		runIn.KeyName = aws.String(o.KeyName)
+	}
+	if len(o.Tags) > 0 {
+		var tags []ec2t.Tag
+		for _, k := range sortedKeys(o.Tags) {
+			tags = append(tags, ec2t.Tag{Key: aws.String(k), Value: aws.String(o.Tags[k])})
+		}
+		runIn.TagSpecifications = []ec2t.TagSpecification{
+			{ResourceType: ec2t.ResourceTypeInstance, Tags: tags},
+			{ResourceType: ec2t.ResourceTypeVolume, Tags: tags},
+		}
+	}
+	if mode != netIPv4 || sgID != "" {
+		// IPv6-only 子网不能分配公网 IPv4
+		netIf := ec2t.InstanceNetworkInterfaceSpecification{DeviceIndex: aws.Int32(0), AssociatePublicIpAddress: aws.Bool(mode != netIPv6)}
+		if sgID != "" {
+			netIf.Groups = []string{sgID}
+		}
+		if mode != netIPv4 {
+			netIf.Ipv6AddressCount = aws.Int32(1)
+			netIf.SubnetId = aws.String(targetSubnetID)
+		}
+		if netIf.SubnetId == nil && typeSubnetID != "" {
+			netIf.SubnetId = aws.String(typeSubnetID)
+		}
+		runIn.NetworkInterfaces = []ec2t.InstanceNetworkInterfaceSpecification{netIf}
+	} else if typeSubnetID != "" {
+		runIn.SubnetId = aws.String(typeSubnetID)
+	}
+	if mode == netIPv6 {
+		// IPv6-only 实例的主机名只能基于实例 ID，并为其添加 AAAA 记录
+		runIn.PrivateDnsNameOptions = &ec2t.PrivateDnsNameOptionsRequest{
+			HostnameType: ec2t.HostnameTypeResourceName, EnableResourceNameDnsAAAARecord: aws.Bool(true),
+		}
+	}
+	if o.VolSize > 0 {
+		imgOut, _ := cli.DescribeImages(ctx, &ec2.DescribeImagesInput{ImageIds: []string{ami}})
+		if len(imgOut.Images) > 0 {
+			runIn.BlockDeviceMappings = []ec2t.BlockDeviceMapping{{
+				DeviceName: imgOut.Images[0].RootDeviceName,
+				Ebs:        &ec2t.EbsBlockDevice{VolumeSize: aws.Int32(o.VolSize), VolumeType: ec2t.VolumeTypeGp3},
+			}}
+		}
+	}
+
+	if o.Spot != nil {
+		fmt.Printf("\n🚀 正在启动 %d 台 (%s, %s)...\n", o.Count, o.Spot, netModeName(mode))
+	} else {
+		fmt.Printf("\n🚀 正在启动 %d 台 (%s)...\n", o.Count, netModeName(mode))
+	}
+	// IPv6 实例只在已配置 IPv6 的子网间换可用区
+	var subnets map[string]string
+	if mode != netIPv4 {
+		subnets = ipv6SubnetsByAZ(ctx, cli, vpcID, targetSubnetID, mode == netIPv6)
+	}
+	out, attempts, err := runWithFallback(ctx, cli, runIn, types, o.Spot, subnets)
+	printLaunchAttempts(attempts, err)
+	if err != nil {
+		return nil, err
+	}
+	if o.RootPwd != "" {
+		var recs []CredentialRecord
+		for _, ins := range out.Instances {
+			recs = append(recs, CredentialRecord{Service: "ec2", Region: region, Resource: aws.ToString(ins.InstanceId), User: "root", Password: o.RootPwd})
+		}
+		recordCredentials(recs...)
+	}
+	return out.Instances, nil
+}
+
 func ec2Create(ctx context.Context, regions []RegionInfo, creds aws.CredentialsProvider) {
+	if name, t := pickEC2Template(); t != nil {
+		ec2CreateFromTemplate(ctx, regions, creds, name, t)
+		return
+	}
 	fmt.Println("\n请选择 CPU 架构:")
 	fmt.Println("  1) x86_64 (Intel/AMD) [默认]")
 	fmt.Println("  2) arm64 (Graviton)")
@@ -850,17 +1008,7 @@ func ec2Create(ctx context.Context, regions []RegionInfo, creds aws.CredentialsP
 	}
 	region := regionInfo.Name
 	cfg, _ := mkCfg(ctx, region, creds)
-	cli := ec2.NewFromConfig(cfg)
-
-	// AMI List
-	amiList := []AMIOption{
-		{"Debian 12", "136693071363", "debian-12-*"},
-		{"Debian 11", "136693071363", "debian-11-*"},
-		{"Ubuntu 24.04", "099720109477", "ubuntu/images/hvm-ssd-gp3/ubuntu-noble-24.04-*"},
-		{"Ubuntu 22.04", "099720109477", "ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-*"},
-		{"Amazon Linux 2023", "137112412989", "al2023-ami-2023.*"},
-		{"Amazon Linux 2", "137112412989", "amzn2-ami-hvm-*"},
-	}
+	cli := newEC2Client(cfg)
 
 	fmt.Printf("\n请选择操作系统 (%s):\n", targetArch)
 	for i, a := range amiList {
@@ -875,9 +1023,7 @@ func ec2Create(ctx context.Context, regions []RegionInfo, creds aws.CredentialsP
 	} else {
 		idx := mustInt(sel)
 		if idx > 0 && idx <= len(amiList) {
-			target := amiList[idx-1]
-			fmt.Printf("🔍 正在搜索 %s (%s) 的最新镜像...\n", target.Name, targetArch)
-			ami = getLatestAMIWithArch(ctx, cli, target.Owner, target.Pattern, targetArch)
+			ami, _ = resolveAMI(ctx, cli, amiAlias(amiList[idx-1].Name), targetArch)
 		} else {
 			fmt.Println("❌ 编号无效")
 			return
@@ -889,109 +1035,62 @@ func ec2Create(ctx context.Context, regions []RegionInfo, creds aws.CredentialsP
 	}
 	fmt.Println("✅ 选中 AMI:", ami)
 
-	// Type
-	var typeList []TypeOption
-	if targetArch == "x86_64" {
-		typeList = []TypeOption{{"t2.nano", "1 vCPU, 0.5 GiB"}, {"t2.micro", "1 vCPU, 1.0 GiB"}, {"t3.micro", "2 vCPU, 1.0 GiB"}}
-	} else {
-		typeList = []TypeOption{{"t4g.nano", "2 vCPU, 0.5 GiB"}, {"t4g.micro", "2 vCPU, 1.0 GiB"}}
-	}
-	fmt.Printf("\n请选择实例类型:\n")
-	for i, t := range typeList {
-		fmt.Printf("  %2d) %s\n", i+1, t.Type)
-	}
-	var itype string
-	tSel := input("编号 [1]: ", "1")
-	idx := mustInt(tSel)
-	if idx > 0 && idx <= len(typeList) {
-		itype = typeList[idx-1].Type
-	} else {
-		itype = typeList[0].Type
-	}
+	itype := pickInstanceType(ctx, cli, region, targetArch)
 
-	count := int32(mustInt(input("启动数量 [1]: ", "1")))
-	if count < 1 {
-		count = 1
-	}
-	volSize := int32(mustInt(input("磁盘大小(GB) [默认]: ", "0")))
-	enableIPv6 := yes(input("自动分配 IPv6? [y/N]: ", "n"))
-	rootPwd := input("设置 SSH root 密码 (留空跳过): ", "")
-	openAll := yes(input("全开端口 (安全组)? [y/N]: ", "n"))
-
-	rawUD, empty := collectUserData("\n可选：EC2 启动脚本")
-	userData := ""
-	if rootPwd != "" {
-		userData = fmt.Sprintf("#!/bin/bash\necho \"root:%s\" | chpasswd\n", rootPwd)
-		userData += "sed -i 's/^#PermitRootLogin.*/PermitRootLogin yes/' /etc/ssh/sshd_config\n"
-		userData += "sed -i 's/^#PasswordAuthentication.*/PasswordAuthentication yes/' /etc/ssh/sshd_config\n"
-		userData += "service sshd restart\n"
-		if !empty {
-			userData += "\n" + rawUD
-		}
-	} else if !empty {
-		userData = rawUD
-	}
+	opts := EC2LaunchOptions{Arch: targetArch, AMI: ami, Type: itype}
+	opts.AltTypes = splitList(input("备选实例类型 (逗号分隔，容量不足时依次尝试，留空跳过): ", ""))
+	opts.Spot = promptSpot(ctx, cli, region, itype)
+	opts.Count = int32(mustInt(input("启动数量 [1]: ", "1")))
+	opts.VolSize = int32(mustInt(input("磁盘大小(GB) [默认]: ", "0")))
+	promptEC2Net(ctx, cli, &opts)
+	opts.KeyName = pickKeyPair(ctx, ec2KeyStore{cli, region})
+	opts.RootPwd = promptRootPassword(currentPasswordPolicy())
+	opts.OpenAll = yes(input("全开端口 (安全组)? [y/N]: ", "n"))
+	opts.Snippets, opts.UserData, opts.UserDataVars = promptUserData("\n可选：EC2 启动脚本", "root-password", "root-password-plain")
 
-	var sgID, vpcID string
-	if openAll || enableIPv6 {
-		s, v, err := ensureOpenAllSG(ctx, cli, region)
-		if err != nil {
-			fmt.Println("❌ 网络错误:", err)
-			return
-		}
-		sgID = s
-		vpcID = v
+	out, err := ec2Launch(ctx, cli, region, opts)
+	if err != nil {
+		fmt.Println("❌ 失败:", err)
+		return
 	}
-
-	var targetSubnetID string
-	if enableIPv6 {
-		sID, err := autoSetupIPv6(ctx, cli, region, vpcID, "")
-		if err != nil {
-			fmt.Println("⚠️ IPv6 配置失败:", err)
-			enableIPv6 = false
-		} else {
-			targetSubnetID = sID
-		}
+	for _, ins := range out {
+		fmt.Println("✅ 成功:", *ins.InstanceId)
 	}
+}
 
-	runIn := &ec2.RunInstancesInput{
-		ImageId:      aws.String(ami),
-		InstanceType: ec2t.InstanceType(itype),
-		MinCount:     aws.Int32(count),
-		MaxCount:     aws.Int32(count),
+// ec2CreateFromTemplate 按模板创建；模板未指定区域时再询问
+func ec2CreateFromTemplate(ctx context.Context, regions []RegionInfo, creds aws.CredentialsProvider, name string, t *EC2Template) {
+	opts, err := t.launchOptions()
+	if err != nil {
+		fmt.Println("❌ 模板无效:", err)
+		return
 	}
-	// 这里使用了 base64
-	if userData != "" {
-		runIn.UserData = aws.String(base64.StdEncoding.EncodeToString([]byte(userData)))
+	var regionInfo RegionInfo
+	for _, r := range regions {
+		if r.Name == t.Region {
+			regionInfo = r
+		}
 	}
-	if enableIPv6 || sgID != "" {
-		netIf := ec2t.InstanceNetworkInterfaceSpecification{DeviceIndex: aws.Int32(0), AssociatePublicIpAddress: aws.Bool(true)}
-		if sgID != "" {
-			netIf.Groups = []string{sgID}
+	if regionInfo.Name == "" {
+		if t.Region != "" {
+			fmt.Printf("⚠️ 模板区域 %s 不在可用列表中\n", t.Region)
 		}
-		if enableIPv6 {
-			netIf.Ipv6AddressCount = aws.Int32(1)
-			netIf.SubnetId = aws.String(targetSubnetID)
+		if regionInfo, err = pickRegion("\n选择 EC2 Region：", regions, "us-east-1"); err != nil {
+			return
 		}
-		runIn.NetworkInterfaces = []ec2t.InstanceNetworkInterfaceSpecification{netIf}
 	}
-	if volSize > 0 {
-		imgOut, _ := cli.DescribeImages(ctx, &ec2.DescribeImagesInput{ImageIds: []string{ami}})
-		if len(imgOut.Images) > 0 {
-			runIn.BlockDeviceMappings = []ec2t.BlockDeviceMapping{{
-				DeviceName: imgOut.Images[0].RootDeviceName,
-				Ebs:        &ec2t.EbsBlockDevice{VolumeSize: aws.Int32(volSize), VolumeType: ec2t.VolumeTypeGp3},
-			}}
-		}
+	if err := ensureRegionOptIn(ctx, regionInfo.Name, regionInfo.Status, creds); err != nil {
+		fmt.Println("❌ 区域不可用:", err)
+		return
 	}
-
-	fmt.Printf("\n🚀 正在启动 %d 台...\n", count)
-	out, err := cli.RunInstances(ctx, runIn)
+	cfg, _ := mkCfg(ctx, regionInfo.Name, creds)
+	fmt.Printf("📄 使用模板 %s: %s\n", name, t.summary())
+	out, err := ec2Launch(ctx, newEC2Client(cfg), regionInfo.Name, opts)
 	if err != nil {
 		fmt.Println("❌ 失败:", err)
 		return
 	}
-	for _, ins := range out.Instances {
+	for _, ins := range out {
 		fmt.Println("✅ 成功:", *ins.InstanceId)
 	}
 }
@@ -1002,7 +1101,7 @@ func lsListAll(ctx context.Context, regions []string, creds aws.CredentialsProvi
 		rows = make([]LSInstanceRow, 0, 8)
 		wg   sync.WaitGroup
 	)
-	fmt.Printf("正在并发扫描 %d 个 Lightsail 区域...\n", len(regions))
+	fmt.Fprintf(os.Stderr, "正在并发扫描 %d 个 Lightsail 区域...\n", len(regions))
 	for _, rg := range regions {
 		wg.Add(1)
 		go func(region string) {
@@ -1011,7 +1110,7 @@ func lsListAll(ctx context.Context, regions []string, creds aws.CredentialsProvi
 			if err != nil {
 				return
 			}
-			cli := lightsail.NewFromConfig(cfg)
+			cli := newLightsailClient(cfg)
 			out, err := cli.GetInstances(ctx, &lightsail.GetInstancesInput{})
 			if err != nil || len(out.Instances) == 0 {
 				return
@@ -1040,6 +1139,7 @@ func lsListAll(ctx context.Context, regions []string, creds aws.CredentialsProvi
 				}
 				localRows = append(localRows, LSInstanceRow{
 					Region: region, Name: aws.ToString(ins.Name), State: state, IP: ip, IPv6: ipv6, AZ: az, Bundle: bundle,
+					IPType: string(lsIPTypeOf(ins)),
 				})
 			}
 			mu.Lock()
@@ -1056,12 +1156,16 @@ func lsListAll(ctx context.Context, regions []string, creds aws.CredentialsProvi
 }
 
 func lsCreate(ctx context.Context, regions []string, creds aws.CredentialsProvider) {
+	if name, t := pickLSTemplate(); t != nil {
+		lsCreateFromTemplate(ctx, regions, creds, name, t)
+		return
+	}
 	region, err := pickFromList("\n选择 Lightsail Region：", regions, "us-east-1")
 	if err != nil {
 		return
 	}
 	cfg, _ := mkCfg(ctx, region, creds)
-	cli := lightsail.NewFromConfig(cfg)
+	cli := newLightsailClient(cfg)
 	az := input("可用区 (默认自动): ", region+"a")
 	name := input("实例名称 [LS-1]: ", "LS-1")
 	bOut, _ := cli.GetBundles(ctx, &lightsail.GetBundlesInput{})
@@ -1070,6 +1174,7 @@ func lsCreate(ctx context.Context, regions []string, creds aws.CredentialsProvid
 		Price float64
 		Ram   float64
 		Cpu   int32
+		IPv4  bool
 	}
 	var brs []bRow
 	defBundle := "nano_3_0"
@@ -1081,9 +1186,15 @@ func lsCreate(ctx context.Context, regions []string, creds aws.CredentialsProvid
 		if b.SupportedPlatforms != nil && len(b.SupportedPlatforms) > 0 && b.SupportedPlatforms[0] == lst.InstancePlatformWindows {
 			continue
 		}
-		brs = append(brs, bRow{ID: *b.BundleId, Price: float64(*b.Price), Ram: float64(*b.RamSizeInGb), Cpu: *b.CpuCount})
+		brs = append(brs, bRow{ID: *b.BundleId, Price: float64(*b.Price), Ram: float64(*b.RamSizeInGb), Cpu: *b.CpuCount, IPv4: bundleHasIPv4(b)})
 	}
-	sort.Slice(brs, func(i, j int) bool { return brs[i].Price < brs[j].Price })
+	// 含公网 IPv4 的套餐在前，IPv6-only 套餐单独排在后面
+	sort.Slice(brs, func(i, j int) bool {
+		if brs[i].IPv4 != brs[j].IPv4 {
+			return brs[i].IPv4
+		}
+		return brs[i].Price < brs[j].Price
+	})
 	for i, b := range brs {
 		if b.ID == defBundle {
 			defIdx = i + 1
@@ -1091,16 +1202,22 @@ func lsCreate(ctx context.Context, regions []string, creds aws.CredentialsProvid
 		}
 	}
 	fmt.Println("--- 套餐列表 ---")
-	printTable("NO.\tID\tPrice\tRAM\tCPU", func(w *tabwriter.Writer) {
+	printTable("NO.\tID\tPrice\tRAM\tCPU\t公网IPv4", func(w *tabwriter.Writer) {
 		for i, b := range brs {
 			mk := ""; if i+1 == defIdx { mk = " <-- 默认" }
-			fmt.Fprintf(w, "[%d]\t%s\t$%.2f\t%.1f G\t%d vCPU%s\n", i+1, b.ID, b.Price, b.Ram, b.Cpu, mk)
+			v4 := "✅"; if !b.IPv4 { v4 = "— (IPv6-only)" }
+			fmt.Fprintf(w, "[%d]\t%s\t$%.2f\t%.1f G\t%d vCPU\t%s%s\n", i+1, b.ID, b.Price, b.Ram, b.Cpu, v4, mk)
 		}
 	})
 	bIn := input(fmt.Sprintf("输入套餐序号 (默认 %d): ", defIdx), "")
-	finalBundle := brs[defIdx-1].ID
+	finalBundle := brs[defIdx-1]
 	if idx, err := strconv.Atoi(bIn); err == nil && idx > 0 && idx <= len(brs) {
-		finalBundle = brs[idx-1].ID
+		finalBundle = brs[idx-1]
+	}
+	ipType := lst.IpAddressTypeDualstack
+	if !finalBundle.IPv4 {
+		ipType = lst.IpAddressTypeIpv6
+		fmt.Println("🌐 IPv6-only 套餐：实例没有公网 IPv4，只能通过 IPv6 访问")
 	}
 	pOut, _ := cli.GetBlueprints(ctx, &lightsail.GetBlueprintsInput{})
 	var osList []string
@@ -1121,24 +1238,117 @@ func lsCreate(ctx context.Context, regions []string, creds aws.CredentialsProvid
 	if idx, err := strconv.Atoi(oIn); err == nil && idx > 0 && idx <= len(osList) {
 		finalOS = osList[idx-1]
 	}
-	openAll := yes(input("是否全开防火墙端口 (TCP+UDP 0-65535)? [y/N]: ", "n"))
-	ud, _ := collectUserData("\n可选：UserData 脚本")
-	fmt.Println("🚀 创建中...")
-	_, err = cli.CreateInstances(ctx, &lightsail.CreateInstancesInput{
-		AvailabilityZone: aws.String(az), BlueprintId: aws.String(finalOS), BundleId: aws.String(finalBundle),
-		InstanceNames: []string{name}, UserData: aws.String(ud),
-	})
-	if err != nil {
+	opts := LSLaunchOptions{AZ: az, Name: name, Bundle: finalBundle.ID, Blueprint: finalOS, IPType: string(ipType)}
+	opts.OpenAll = yes(input("是否全开防火墙端口 (TCP+UDP 0-65535)? [y/N]: ", "n"))
+	opts.KeyPair = pickKeyPair(ctx, lsKeyStore{cli, region})
+	opts.RootPwd = promptRootPassword(currentPasswordPolicy())
+	opts.Snippets, opts.UserData, opts.UserDataVars = promptUserData("\n可选：UserData 脚本", "root-password", "root-password-plain")
+	if err := lsLaunch(ctx, cli, opts); err != nil {
 		fmt.Println("❌ 失败:", err)
+	}
+}
+
+// lsCreateFromTemplate 按模板创建，只需再输入实例名称
+func lsCreateFromTemplate(ctx context.Context, regions []string, creds aws.CredentialsProvider, name string, t *LSTemplate) {
+	region := t.Region
+	if region == "" || !slices.Contains(regions, region) {
+		if region != "" {
+			fmt.Printf("⚠️ 模板区域 %s 不在可用列表中\n", region)
+		}
+		var err error
+		if region, err = pickFromList("\n选择 Lightsail Region：", regions, "us-east-1"); err != nil {
+			return
+		}
+	}
+	opts, err := t.launchOptions(region, input("实例名称 [LS-1]: ", "LS-1"))
+	if err != nil {
+		fmt.Println("❌ 模板无效:", err)
 		return
 	}
+	cfg, _ := mkCfg(ctx, region, creds)
+	fmt.Printf("📄 使用模板 %s: %s\n", name, t.summary())
+	if err := lsLaunch(ctx, newLightsailClient(cfg), opts); err != nil {
+		fmt.Println("❌ 失败:", err)
+	}
+}
+
+// LSLaunchOptions 汇总一次 Lightsail 创建所需的全部参数，交互菜单与 CLI 共用
+type LSLaunchOptions struct {
+	AZ           string
+	Name         string
+	Bundle       string
+	Blueprint    string
+	IPType       string // dualstack|ipv4|ipv6，空时按套餐决定 (IPv6 套餐为 ipv6，其余为 dualstack)
+	OpenAll      bool
+	Ports        []PortRule // 未全开时用这些端口替换默认防火墙规则
+	RootPwd      string
+	GenPwd       bool
+	PlainPwd     bool
+	UserData     string
+	Snippets     []string
+	UserDataVars map[string]string
+	KeyPair      string
+	Tags         map[string]string
+}
+
+func lsLaunch(ctx context.Context, cli LightsailAPI, o LSLaunchOptions) error {
+	o.RootPwd, o.PlainPwd = resolveRootPassword(o.RootPwd, o.GenPwd, o.PlainPwd)
+	specs, vars := withRootPassword(o.RootPwd, o.PlainPwd, o.Snippets, o.UserDataVars)
+	parts, err := renderUserData(specs, o.UserData, vars)
+	if err != nil {
+		return err
+	}
+	userData, err := lsUserDataDoc(parts)
+	if err != nil {
+		return err
+	}
+	ipType, err := parseLSIPType(o.IPType)
+	if err != nil {
+		return err
+	}
+	if o.IPType == "" && lsBundleIPv6Only(o.Bundle) {
+		ipType = lst.IpAddressTypeIpv6
+	}
+	if (ipType == lst.IpAddressTypeIpv6) != lsBundleIPv6Only(o.Bundle) {
+		return fmt.Errorf("IP 地址类型 %s 与套餐 %s 不匹配 (IPv6-only 须使用 *_ipv6_* 套餐)", ipType, o.Bundle)
+	}
+	fmt.Println("🚀 创建中...")
+	in := &lightsail.CreateInstancesInput{
+		AvailabilityZone: aws.String(o.AZ), BlueprintId: aws.String(o.Blueprint), BundleId: aws.String(o.Bundle),
+		InstanceNames: []string{o.Name}, UserData: aws.String(userData), IpAddressType: ipType,
+	}
+	if o.KeyPair != "" {
+		if err := ensureRemoteKey(ctx, lsKeyStore{cli, strings.TrimRight(o.AZ, "abcdef")}, o.KeyPair); err != nil {
+			return err
+		}
+		in.KeyPairName = aws.String(o.KeyPair)
+	}
+	for _, k := range sortedKeys(o.Tags) {
+		in.Tags = append(in.Tags, lst.Tag{Key: aws.String(k), Value: aws.String(o.Tags[k])})
+	}
+	if _, err := cli.CreateInstances(ctx, in); err != nil {
+		return err
+	}
 	fmt.Println("✅ 实例创建指令已提交")
-	if openAll {
+	if o.RootPwd != "" {
+		recordCredentials(CredentialRecord{Service: "lightsail", Region: strings.TrimRight(o.AZ, "abcdef"), Resource: o.Name, User: "root", Password: o.RootPwd})
+	}
+	portInfos := []lst.PortInfo{
+		{FromPort: 0, ToPort: 65535, Protocol: lst.NetworkProtocolTcp},
+		{FromPort: 0, ToPort: 65535, Protocol: lst.NetworkProtocolUdp},
+	}
+	if !o.OpenAll {
+		portInfos = nil
+		for _, r := range o.Ports {
+			portInfos = append(portInfos, r.lsPortInfo())
+		}
+	}
+	if len(portInfos) > 0 {
 		fmt.Println("⏳ 正在等待实例就绪以配置防火墙 (最多等待 60 秒)...")
 		ready := false
 		for i := 0; i < 30; i++ {
 			time.Sleep(2 * time.Second)
-			insOut, err := cli.GetInstance(ctx, &lightsail.GetInstanceInput{InstanceName: aws.String(name)})
+			insOut, err := cli.GetInstance(ctx, &lightsail.GetInstanceInput{InstanceName: aws.String(o.Name)})
 			if err == nil && insOut.Instance != nil && insOut.Instance.State != nil {
 				if aws.ToString(insOut.Instance.State.Name) == "running" {
 					ready = true
@@ -1149,18 +1359,67 @@ func lsCreate(ctx context.Context, regions []string, creds aws.CredentialsProvid
 		}
 		if ready {
 			fmt.Println("\n✅ 实例已就绪，正在开启端口...")
-			cli.PutInstancePublicPorts(ctx, &lightsail.PutInstancePublicPortsInput{
-				InstanceName: aws.String(name),
-				PortInfos: []lst.PortInfo{
-					{FromPort: 0, ToPort: 65535, Protocol: lst.NetworkProtocolTcp},
-					{FromPort: 0, ToPort: 65535, Protocol: lst.NetworkProtocolUdp},
-				},
-			})
-			fmt.Println("✅ 防火墙规则已更新 (全开)")
+			if _, err := cli.PutInstancePublicPorts(ctx, &lightsail.PutInstancePublicPortsInput{
+				InstanceName: aws.String(o.Name), PortInfos: portInfos,
+			}); err != nil {
+				return fmt.Errorf("防火墙配置失败: %v", err)
+			}
+			if o.OpenAll {
+				fmt.Println("✅ 防火墙规则已更新 (全开)")
+			} else {
+				fmt.Println("✅ 防火墙规则已更新")
+			}
 		} else {
 			fmt.Println("\n⚠️ 等待超时，请稍后手动配置防火墙。")
 		}
 	}
+	return nil
+}
+
+// lsAction 执行单个实例的电源/删除操作，action 取值 start|stop|reboot|delete
+func lsAction(ctx context.Context, cli LightsailAPI, name, action string) error {
+	var err error
+	switch action {
+	case "start":
+		_, err = cli.StartInstance(ctx, &lightsail.StartInstanceInput{InstanceName: &name})
+	case "stop":
+		_, err = cli.StopInstance(ctx, &lightsail.StopInstanceInput{InstanceName: &name})
+	case "reboot":
+		_, err = cli.RebootInstance(ctx, &lightsail.RebootInstanceInput{InstanceName: &name})
+	case "delete":
+		fmt.Println("🔍 检查固定 IP...")
+		allSip, err := cli.GetStaticIps(ctx, &lightsail.GetStaticIpsInput{})
+		if err == nil {
+			for _, s := range allSip.StaticIps {
+				if s.AttachedTo != nil && *s.AttachedTo == name {
+					fmt.Printf("⚠️ 释放关联 IP (%s)...\n", *s.Name)
+					cli.ReleaseStaticIp(ctx, &lightsail.ReleaseStaticIpInput{StaticIpName: s.Name})
+					break
+				}
+			}
+		}
+		_, err = cli.DeleteInstance(ctx, &lightsail.DeleteInstanceInput{InstanceName: &name})
+		return err
+	default:
+		return fmt.Errorf("未知操作: %s", action)
+	}
+	return err
+}
+
+func lsDoAction(ctx context.Context, cli LightsailAPI, name, action string) {
+	if err := lsAction(ctx, cli, name, action); err != nil {
+		fmt.Println("❌ 失败:", err)
+		return
+	}
+	fmt.Println(actionDoneMsg[action])
+}
+
+var actionDoneMsg = map[string]string{
+	"start":     "✅ 启动中",
+	"stop":      "✅ 停止中",
+	"reboot":    "✅ 重启中",
+	"delete":    "🗑️ 删除指令已发送",
+	"terminate": "🗑️ 正在终止...",
 }
 
 func lsControl(ctx context.Context, regions []string, creds aws.CredentialsProvider) {
@@ -1169,38 +1428,57 @@ func lsControl(ctx context.Context, regions []string, creds aws.CredentialsProvi
 		fmt.Println("❌ 无实例")
 		return
 	}
-	printTable("序号\t区域\t名称\t状态\t配置\tIPv4\tIPv6", func(w *tabwriter.Writer) {
-		for _, r := range rows {
-			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Idx, r.Region, r.Name, r.State, cut(r.Bundle, 10), r.IP, r.IPv6)
+	printLSRows(rows)
+	choice := input("\n输入序号操作，r 在多台实例上执行命令，i 批量更换公网 IP (0 返回): ", "0")
+	if choice == "r" || choice == "R" {
+		if targets, err := lsSSHTargets(ctx, rows, creds); err != nil {
+			fmt.Println("❌", err)
+		} else {
+			sshBatchInteractive(ctx, targets)
+		}
+		return
+	}
+	if choice == "i" || choice == "I" {
+		sel, err := parseSelection(input("选择实例序号 (如 1,3-5 或 all): ", ""), len(rows))
+		if err != nil {
+			fmt.Println("❌", err)
+			return
 		}
-	})
-	idx := mustInt(input("\n输入序号操作 (0 返回): ", "0"))
+		var jobs []RotateJob
+		for _, i := range sel {
+			jobs = append(jobs, RotateJob{Service: "lightsail", Region: rows[i].Region, Instance: rows[i].Name})
+		}
+		rotateInteractive(ctx, jobs, creds)
+		return
+	}
+	idx := mustInt(choice)
 	if idx <= 0 || idx > len(rows) {
 		return
 	}
 	sel := rows[idx-1]
 	cfg, _ := mkCfg(ctx, sel.Region, creds)
-	cli := lightsail.NewFromConfig(cfg)
+	cli := newLightsailClient(cfg)
 	fmt.Printf("\n🔍 正在获取 Lightsail 实例 %s 的详细指标...\n", sel.Name)
 	insOut, err := cli.GetInstance(ctx, &lightsail.GetInstanceInput{InstanceName: &sel.Name})
 	var isStaticIP bool
+	var sshTarget SSHTarget
+	var detail *lst.Instance
 	if err == nil && insOut.Instance != nil {
+		detail = insOut.Instance
 		ins := insOut.Instance
 		isStaticIP = *ins.IsStaticIp
 		var ports []string
 		for _, p := range ins.Networking.Ports {
-			if (p.FromPort == 0 && p.ToPort == 65535) || (p.FromPort == 0 && (p.Protocol == "all" || p.Protocol == "-1")) {
-				ports = append(ports, fmt.Sprintf("全部允许 (%s)", p.Protocol))
-			} else {
-				ports = append(ports, fmt.Sprintf("%d/%s", p.FromPort, p.Protocol))
-			}
+			ports = append(ports, lsRuleFromInfo(p).summary())
 		}
 		fmt.Println("================================================================")
 		fmt.Printf(" 实例名称  : %s\n", *ins.Name)
 		fmt.Printf(" 所在区域  : %s (%s)\n", sel.Region, *ins.Location.AvailabilityZone)
 		fmt.Printf(" 套餐类型  : %s (%d vCPU, %.1f GB RAM)\n", *ins.BundleId, *ins.Hardware.CpuCount, *ins.Hardware.RamSizeInGb)
 		fmt.Printf(" 运行状态  : %s\n", *ins.State.Name)
-		fmt.Printf(" 公网 IPv4 : %s\n", sel.IP)
+		fmt.Printf(" 公网 IPv4 : %s\n", orDefault(sel.IP, "(无)"))
+		fmt.Printf(" IPv6 地址 : %s\n", orDefault(strings.Join(ins.Ipv6Addresses, ", "), "(未开启)"))
+		fmt.Printf(" 地址类型  : %s\n", lsIPTypeName(lsIPTypeOf(*ins)))
 		fmt.Printf(" IP 类型   : %v\n", func() string {
 			if isStaticIP {
 				return "[固定IP/Static] ✅"
@@ -1208,34 +1486,35 @@ func lsControl(ctx context.Context, regions []string, creds aws.CredentialsProvi
 			return "[动态IP/Dynamic]"
 		}())
 		fmt.Printf(" 开放端口  : %s\n", strings.Join(ports, ", "))
+		if sshTarget = lsSSHTarget(*ins); sshTarget.Host != "" {
+			fmt.Printf(" SSH 密钥  : %s\n", aws.ToString(ins.SshKeyName))
+			fmt.Printf(" SSH 登录  : %s\n", sshCommand(aws.ToString(ins.SshKeyName), aws.ToString(ins.Username), sshTarget.Host))
+		}
 		fmt.Println("================================================================")
 	}
-	fmt.Printf("\n操作: %s\n1) 启动 2) 停止 3) 重启 4) 删除 5) 管理固定 IP\n", sel.Name)
+	fmt.Printf("\n操作: %s\n1) 启动 2) 停止 3) 重启 4) 删除 5) 管理固定 IP 6) 🖥️ SSH 连接 7) 🧱 防火墙 8) 🔄 更换公网 IP 9) 🌐 IPv6 / 地址类型\n", sel.Name)
 	switch input("选择: ", "0") {
+	case "9":
+		if detail == nil {
+			fmt.Println("❌ 获取实例详情失败:", err)
+			return
+		}
+		lsIPTypeMenu(ctx, cli, *detail)
+	case "6":
+		sshConnectInteractive(ctx, sshTarget)
+	case "7":
+		lsFirewallMenu(ctx, cli, sel, rows, creds)
+	case "8":
+		rotateInteractive(ctx, []RotateJob{{Service: "lightsail", Region: sel.Region, Instance: sel.Name}}, creds)
 	case "1":
-		cli.StartInstance(ctx, &lightsail.StartInstanceInput{InstanceName: &sel.Name})
-		fmt.Println("✅ 启动中")
+		lsDoAction(ctx, cli, sel.Name, "start")
 	case "2":
-		cli.StopInstance(ctx, &lightsail.StopInstanceInput{InstanceName: &sel.Name})
-		fmt.Println("✅ 停止中")
+		lsDoAction(ctx, cli, sel.Name, "stop")
 	case "3":
-		cli.RebootInstance(ctx, &lightsail.RebootInstanceInput{InstanceName: &sel.Name})
-		fmt.Println("✅ 重启中")
+		lsDoAction(ctx, cli, sel.Name, "reboot")
 	case "4":
 		if yes(input("⚠️ 确认删除实例 (删除)? [y/N]: ", "n")) {
-			fmt.Println("🔍 检查固定 IP...")
-			allSip, err := cli.GetStaticIps(ctx, &lightsail.GetStaticIpsInput{})
-			if err == nil {
-				for _, s := range allSip.StaticIps {
-					if s.AttachedTo != nil && *s.AttachedTo == sel.Name {
-						fmt.Printf("⚠️ 释放关联 IP (%s)...\n", *s.Name)
-						cli.ReleaseStaticIp(ctx, &lightsail.ReleaseStaticIpInput{StaticIpName: s.Name})
-						break
-					}
-				}
-			}
-			cli.DeleteInstance(ctx, &lightsail.DeleteInstanceInput{InstanceName: &sel.Name})
-			fmt.Println("🗑️ 删除指令已发送")
+			lsDoAction(ctx, cli, sel.Name, "delete")
 		}
 	case "5":
 		if isStaticIP {
@@ -1267,7 +1546,7 @@ func ec2ListAll(ctx context.Context, regions []string, creds aws.CredentialsProv
 	var mu sync.Mutex
 	var rows []EC2InstanceRow
 	var wg sync.WaitGroup
-	fmt.Printf("正在并发扫描 %d 个 EC2 区域...\n", len(regions))
+	fmt.Fprintf(os.Stderr, "正在并发扫描 %d 个 EC2 区域...\n", len(regions))
 	for _, rg := range regions {
 		wg.Add(1)
 		go func(region string) {
@@ -1276,7 +1555,7 @@ func ec2ListAll(ctx context.Context, regions []string, creds aws.CredentialsProv
 			if err != nil {
 				return
 			}
-			cli := ec2.NewFromConfig(cfg)
+			cli := newEC2Client(cfg)
 			out, err := cli.DescribeInstances(ctx, &ec2.DescribeInstancesInput{})
 			if err != nil {
 				return
@@ -1305,9 +1584,14 @@ func ec2ListAll(ctx context.Context, regions []string, creds aws.CredentialsProv
 					if len(ins.NetworkInterfaces) > 0 && len(ins.NetworkInterfaces[0].Ipv6Addresses) > 0 {
 						ipv6 = *ins.NetworkInterfaces[0].Ipv6Addresses[0].Ipv6Address
 					}
+					az := ""
+					if ins.Placement != nil {
+						az = aws.ToString(ins.Placement.AvailabilityZone)
+					}
 					local = append(local, EC2InstanceRow{
-						Region: region, ID: *ins.InstanceId, State: string(ins.State.Name),
-						Name: name, Type: string(ins.InstanceType), PubIP: pub, PrivIP: priv, IPv6: ipv6,
+						Region: region, AZ: az, ID: *ins.InstanceId, State: string(ins.State.Name),
+						Name: name, Type: string(ins.InstanceType), PubIP: pub, PrivIP: priv, IPv6: ipv6, Net: instanceNetMode(priv, ipv6),
+						Spot: ins.InstanceLifecycle == ec2t.InstanceLifecycleTypeSpot,
 					})
 				}
 			}
@@ -1324,25 +1608,86 @@ func ec2ListAll(ctx context.Context, regions []string, creds aws.CredentialsProv
 	return rows, nil
 }
 
+// ec2Action 执行单个实例的电源/终止操作，action 取值 start|stop|reboot|terminate
+func ec2Action(ctx context.Context, cli EC2API, id, action string) error {
+	var err error
+	switch action {
+	case "start":
+		_, err = cli.StartInstances(ctx, &ec2.StartInstancesInput{InstanceIds: []string{id}})
+	case "stop":
+		_, err = cli.StopInstances(ctx, &ec2.StopInstancesInput{InstanceIds: []string{id}})
+	case "reboot":
+		_, err = cli.RebootInstances(ctx, &ec2.RebootInstancesInput{InstanceIds: []string{id}})
+	case "terminate":
+		fmt.Println("🔍 检查关联EIP...")
+		eipOut, err := cli.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{
+			Filters: []ec2t.Filter{{Name: aws.String("instance-id"), Values: []string{id}}},
+		})
+		if err == nil && len(eipOut.Addresses) > 0 {
+			for _, addr := range eipOut.Addresses {
+				// 绑定中的 EIP 无法直接释放，先解绑
+				if addr.AssociationId != nil {
+					cli.DisassociateAddress(ctx, &ec2.DisassociateAddressInput{AssociationId: addr.AssociationId})
+				}
+				if _, rerr := cli.ReleaseAddress(ctx, &ec2.ReleaseAddressInput{AllocationId: addr.AllocationId}); rerr != nil {
+					fmt.Printf("   ⚠️ 释放 IP %s 失败: %v (可在主菜单「弹性 IP / 固定 IP 清单」中清理)\n", *addr.PublicIp, rerr)
+					continue
+				}
+				fmt.Printf("   ✅ 已释放 IP: %s\n", *addr.PublicIp)
+			}
+		}
+		_, err = cli.TerminateInstances(ctx, &ec2.TerminateInstancesInput{InstanceIds: []string{id}})
+		return err
+	default:
+		return fmt.Errorf("未知操作: %s", action)
+	}
+	return err
+}
+
+func ec2DoAction(ctx context.Context, cli EC2API, id, action string) {
+	if err := ec2Action(ctx, cli, id, action); err != nil {
+		fmt.Println("❌ 失败:", err)
+		return
+	}
+	fmt.Println(actionDoneMsg[action])
+}
+
 func ec2Control(ctx context.Context, regions []string, creds aws.CredentialsProvider) {
 	rows, _ := ec2ListAll(ctx, regions, creds)
 	if len(rows) == 0 {
 		fmt.Println("❌ 无实例")
 		return
 	}
-	printTable("序号\t区域\tID\t名称\t状态\t配置\t公网IP\t内网IP\tIPv6", func(w *tabwriter.Writer) {
-		for _, r := range rows {
-			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-				r.Idx, r.Region, r.ID, cut(r.Name, 10), r.State, r.Type, r.PubIP, r.PrivIP, r.IPv6)
+	printEC2Rows(rows)
+	choice := input("\n输入序号操作，r 在多台实例上执行命令，i 批量更换公网 IP (0 返回): ", "0")
+	if choice == "r" || choice == "R" {
+		if targets, err := ec2SSHTargets(ctx, rows, creds); err != nil {
+			fmt.Println("❌", err)
+		} else {
+			sshBatchInteractive(ctx, targets)
 		}
-	})
-	idx := mustInt(input("\n输入序号操作 (0 返回): ", "0"))
+		return
+	}
+	if choice == "i" || choice == "I" {
+		sel, err := parseSelection(input("选择实例序号 (如 1,3-5 或 all): ", ""), len(rows))
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		var jobs []RotateJob
+		for _, i := range sel {
+			jobs = append(jobs, RotateJob{Service: "ec2", Region: rows[i].Region, Instance: rows[i].ID})
+		}
+		rotateInteractive(ctx, jobs, creds)
+		return
+	}
+	idx := mustInt(choice)
 	if idx <= 0 || idx > len(rows) {
 		return
 	}
 	sel := rows[idx-1]
 	cfg, _ := mkCfg(ctx, sel.Region, creds)
-	cli := ec2.NewFromConfig(cfg)
+	cli := newEC2Client(cfg)
 	fmt.Printf("\n🔍 正在获取实例 %s 的详细指标 (磁盘/网络/密钥)...\n", sel.ID)
 	desc, err := cli.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{sel.ID}})
 	
@@ -1350,6 +1695,7 @@ func ec2Control(ctx context.Context, regions []string, creds aws.CredentialsProv
 	var eniID string
 	var currentIPv6s []string
 	var vpcID, subnetID string
+	var sshTarget SSHTarget
 
 	if err == nil && len(desc.Reservations) > 0 {
 		ins := desc.Reservations[0].Instances[0]
@@ -1392,34 +1738,31 @@ func ec2Control(ctx context.Context, regions []string, creds aws.CredentialsProv
 			fmt.Printf(" SSH 密钥  : %s\n", *ins.KeyName)
 		}
 		fmt.Printf(" 磁盘挂载  : %s\n", strings.Join(diskInfo, ", "))
+		imageName := ec2ImageName(ctx, cli, aws.ToString(ins.ImageId))
+		sshTarget = ec2SSHTarget(ins, imageName)
+		if sshTarget.Host != "" {
+			fmt.Printf(" SSH 登录  : %s\n", sshCommand(aws.ToString(ins.KeyName), ec2SSHUser(imageName), sshTarget.Host))
+		}
 		fmt.Println("================================================================")
 	}
 
-	fmt.Printf("\n操作: %s\n1) 启动 2) 停止 3) 重启 4) 终止 5) 🔧 网络管理 (IP)\n", sel.ID)
+	fmt.Printf("\n操作: %s\n1) 启动 2) 停止 3) 重启 4) 终止 5) 🔧 网络管理 (IP) 6) 🖥️ SSH 连接 7) 🛡️ 安全组 8) 🔄 更换公网 IP\n", sel.ID)
 	switch input("选择: ", "0") {
+	case "6":
+		sshConnectInteractive(ctx, sshTarget)
+	case "7":
+		sgMenu(ctx, cli, sel.ID)
+	case "8":
+		rotateInteractive(ctx, []RotateJob{{Service: "ec2", Region: sel.Region, Instance: sel.ID}}, creds)
 	case "1":
-		cli.StartInstances(ctx, &ec2.StartInstancesInput{InstanceIds: []string{sel.ID}})
-		fmt.Println("✅ 启动中")
+		ec2DoAction(ctx, cli, sel.ID, "start")
 	case "2":
-		cli.StopInstances(ctx, &ec2.StopInstancesInput{InstanceIds: []string{sel.ID}})
-		fmt.Println("✅ 停止中")
+		ec2DoAction(ctx, cli, sel.ID, "stop")
 	case "3":
-		cli.RebootInstances(ctx, &ec2.RebootInstancesInput{InstanceIds: []string{sel.ID}})
-		fmt.Println("✅ 重启中")
+		ec2DoAction(ctx, cli, sel.ID, "reboot")
 	case "4":
 		if yes(input("⚠️ 确认终止实例 (删除)? [y/N]: ", "n")) {
-			fmt.Println("🔍 检查关联EIP...")
-			eipOut, err := cli.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{
-				Filters: []ec2t.Filter{{Name: aws.String("instance-id"), Values: []string{sel.ID}}},
-			})
-			if err == nil && len(eipOut.Addresses) > 0 {
-				for _, addr := range eipOut.Addresses {
-					cli.ReleaseAddress(ctx, &ec2.ReleaseAddressInput{AllocationId: addr.AllocationId})
-					fmt.Printf("   ✅ 已释放 IP: %s\n", *addr.PublicIp)
-				}
-			}
-			cli.TerminateInstances(ctx, &ec2.TerminateInstancesInput{InstanceIds: []string{sel.ID}})
-			fmt.Println("🗑️ 正在终止...")
+			ec2DoAction(ctx, cli, sel.ID, "terminate")
 		}
 	case "5":
 		if eniID == "" {
@@ -1429,6 +1772,7 @@ func ec2Control(ctx context.Context, regions []string, creds aws.CredentialsProv
 		fmt.Println("\n--- 网络/IP 管理 ---")
 		fmt.Println(" 1) IPv6 管理 (分配/删除)")
 		fmt.Println(" 2) IPv4 公网/弹性IP 管理 (绑定/释放)")
+		fmt.Println(" 3) 🌐 VPC IPv6 配置 (子网 /64、::/0 路由)")
 		netSel := input("选择: ", "0")
 
 		if netSel == "1" {
@@ -1445,10 +1789,12 @@ func ec2Control(ctx context.Context, regions []string, creds aws.CredentialsProv
 				if err != nil {
 					// 自动修复逻辑：检查是否是网段缺失
 					if strings.Contains(err.Error(), "Subnet does not contain any IPv6 CIDR block ranges") {
-						fmt.Println("\n⚠️  检测到子网未配置 IPv6，正在自动修复 (VPC/子网/路由)...")
-						_, errFix := autoSetupIPv6(ctx, cli, sel.Region, vpcID, subnetID)
+						fmt.Printf("\n⚠️  检测到子网 %s 未配置 IPv6，只为该子网生成修复计划:\n", subnetID)
+						applied, errFix := repairSubnetIPv6(ctx, cli, vpcID, subnetID)
 						if errFix != nil {
 							fmt.Printf("❌ 修复失败: %v\n", errFix)
+						} else if !applied {
+							fmt.Println("已取消，未做任何修改")
 						} else {
 							fmt.Println("✅ 网络配置已修复，正在重试分配 IP...")
 							time.Sleep(2 * time.Second)
@@ -1580,6 +1926,8 @@ func ec2Control(ctx context.Context, regions []string, creds aws.CredentialsProv
 					}
 				}
 			}
+		} else if netSel == "3" {
+			ipv6SetupInteractive(ctx, cli, vpcID)
 		}
 	}
 }
@@ -1589,61 +1937,119 @@ func ec2Control(ctx context.Context, regions []string, creds aws.CredentialsProv
 func main() {
 	rand.Seed(time.Now().UnixNano())
 	ctx := context.Background()
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(ctx, os.Args[1:]))
+	}
 	fmt.Println("=== AWS 管理工具 (Win) ===")
 
 	// 代理选择菜单
 	fmt.Println("\n请选择连接方式:")
 	fmt.Println(" 1) 直连 (Direct Connection) [默认]")
 	fmt.Println(" 2) 代理 (Use Proxy)")
+	fmt.Println(" 3) 代理池 (从文件加载，按账户分配并自动切换)")
 	connType := input("选择 [1]: ", "1")
 
-	if connType == "2" {
-		rawProxy := input("请输入代理地址 (host:port:user:pass 或 socks5://...): ", "")
+	if connType == "3" {
+		if err := setupProxyPool(ctx, input("代理列表文件路径: ", "proxies.txt")); err != nil {
+			fmt.Println("❌ 代理池不可用:", err)
+			return
+		}
+		fmt.Printf("🔄 代理池已启用，可用 %d 个\n", activePool.aliveCount())
+	} else if connType == "2" {
+		rawProxy := input("请输入代理地址 (host:port:user:pass、http(s)://、socks5(h)://...): ", "")
+		if _, err := parseProxyURL(rawProxy); rawProxy != "" && err != nil {
+			fmt.Println("❌", err)
+			return
+		}
 		GlobalProxy = parseProxyString(rawProxy)
+		userProxy = GlobalProxy
 		if GlobalProxy != "" {
-			fmt.Println("🔄 使用代理:", GlobalProxy)
+			fmt.Println("🔄 使用代理:", maskProxy(GlobalProxy))
 		}
 	} else {
 		fmt.Println("🌐 使用直连模式")
 	}
-
-	ak := input("AWS Access Key ID: ", "")
-	sk := inputSecret("AWS Secret Access Key: ")
-	if ak == "" || sk == "" {
-		return
+	if activePool == nil {
+		// 手动代理或环境变量中的代理：验证凭证前先确认代理可用
+		if err := proxySelfTest(ctx); err != nil {
+			fmt.Println("❌", err)
+			if !yes(input("仍然继续? [y/N]: ", "n")) {
+				return
+			}
+		}
 	}
-	creds := credentials.NewStaticCredentialsProvider(ak, sk, "")
 
-	fmt.Printf("\n🔍 验证凭证...\n")
-	if err := stsCheck(ctx, bootstrapRegion, creds); err != nil {
-		fmt.Println("❌ 失败:", err)
+	var (
+		creds      aws.CredentialsProvider
+		acctLabel  string
+		baseCreds  aws.CredentialsProvider // 扮演角色前的基础凭证
+		baseLabel  string
+		ec2Regions []RegionInfo
+		lsRegions  []string
+	)
+	useCreds := func(c aws.CredentialsProvider, label string) bool {
+		fmt.Printf("\n🔍 验证凭证...\n")
+		if err := stsCheck(withAccount(ctx, label), bootstrapRegion, c); err != nil {
+			fmt.Printf("❌ 失败 [%s]: %v\n", classifyAWSError(err), err)
+			return false
+		}
+		fmt.Println("✅ 成功")
+		sessionAccount = label
+		if activeVault != nil && strings.HasPrefix(label, vaultLabelPrefix) {
+			if err := activeVault.MarkVerified(strings.TrimPrefix(label, vaultLabelPrefix)); err != nil {
+				fmt.Println("⚠️ 保险库更新失败:", err)
+			}
+		}
+		creds, acctLabel = c, label
+		fmt.Println("🌍 获取区域列表...")
+		ec2Regions, _ = getEC2RegionsWithStatus(ctx, creds)
+		lsRegions, _ = getLightsailRegions(ctx, creds)
+		return true
+	}
+	useBase := func(c aws.CredentialsProvider, label string) bool {
+		if !useCreds(c, label) {
+			return false
+		}
+		baseCreds, baseLabel = c, label
+		return true
+	}
+	login := func() bool {
+		c, label, err := pickCredentials(ctx)
+		if err != nil {
+			fmt.Println("❌ 失败:", err)
+			return false
+		}
+		return useBase(c, label)
+	}
+	if !login() {
 		return
 	}
-	fmt.Println("✅ 成功")
-
-	fmt.Println("🌍 获取区域列表...")
-	ec2Regions, _ := getEC2RegionsWithStatus(ctx, creds)
-	lsRegions, _ := getLightsailRegions(ctx, creds)
 
 	for {
 		fmt.Println("\n====== 主菜单 ======")
+		fmt.Println("当前账户:", acctLabel)
 		fmt.Println("1) EC2：创建 (自动AMI/IPv6/磁盘)")
 		fmt.Println("2) EC2：管理 (全球扫描)")
 		fmt.Println("3) Lightsail：创建")
 		fmt.Println("4) Lightsail：管理")
 		fmt.Println("5) 查询配额")
 		fmt.Println("6) 💰 自动完成新手任务 (赚 $80)")
+		fmt.Println("7) 🔑 切换凭证 / Profile")
+		fmt.Println("8) 🔐 账户保险库 (切换/添加/删除)")
+		fmt.Println("9) 📦 多账户批量操作")
+		fmt.Println("10) 🎭 扮演 IAM 角色 (AssumeRole / 角色链)")
+		if activePool != nil {
+			fmt.Println("11) 🌐 代理池状态 (重新检测)")
+		}
+		fmt.Println("12) 🗝️ SSH 密钥对管理")
+		fmt.Println("13) 🌐 弹性 IP / 固定 IP 清单 (未绑定清理)")
 		fmt.Println("0) 退出")
 
 		switch input("选择: ", "0") {
 		case "1":
 			ec2Create(ctx, ec2Regions, creds)
 		case "2":
-			var plainRegions []string
-			for _, r := range ec2Regions {
-				plainRegions = append(plainRegions, r.Name)
-			}
-			ec2Control(ctx, plainRegions, creds)
+			ec2Control(ctx, regionNames(ec2Regions), creds)
 		case "3":
 			lsCreate(ctx, lsRegions, creds)
 		case "4":
@@ -1652,6 +2058,52 @@ func main() {
 			checkQuotas(ctx, creds)
 		case "6":
 			autoClaimCredits(ctx, creds)
+		case "7":
+			login()
+		case "8":
+			a, err := vaultMenu()
+			if err != nil {
+				fmt.Println("❌ 失败:", err)
+			} else if a != nil {
+				useBase(vaultAccountCreds(a))
+			}
+		case "9":
+			batchMenu(ctx)
+		case "10":
+			if acctLabel != baseLabel && yes(input("当前已在扮演角色，是否先恢复基础凭证 "+baseLabel+"? [y/N]: ", "n")) {
+				useCreds(baseCreds, baseLabel)
+				continue
+			}
+			spec := pickRole()
+			if spec == nil {
+				continue
+			}
+			// 以当前凭证为基础扮演，重复执行即为角色链
+			c, err := assumeRoleCreds(ctx, creds, *spec)
+			if err != nil {
+				fmt.Printf("❌ 失败 [%s]: %v\n", classifyAWSError(err), err)
+				continue
+			}
+			roleLabel := acctLabel + " → " + roleShortName(spec.ARN)
+			if activePool != nil {
+				// 角色会话沿用基础账户的出口 IP
+				activePool.Pin(roleLabel, activePool.Bind(acctLabel))
+			}
+			useCreds(c, roleLabel)
+		case "11":
+			if activePool == nil {
+				continue
+			}
+			fmt.Println("\n🩺 检测代理...")
+			activePool.CheckAll(ctx)
+			writeReport(os.Stdout, "table", proxyPoolReport(activePool))
+			fmt.Println()
+			printProxyBindings(activePool)
+			input("\n按回车返回...", "")
+		case "12":
+			keyMenu(ctx, regionNames(ec2Regions), lsRegions, creds)
+		case "13":
+			publicIPMenu(ctx, regionNames(ec2Regions), lsRegions, creds)
 		case "0":
 			return
 		}
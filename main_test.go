@@ -0,0 +1,601 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2t "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/lightsail"
+	lst "github.com/aws/aws-sdk-go-v2/service/lightsail/types"
+)
+
+// captureStdout 返回 f 执行期间写到标准输出的内容
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- string(b)
+	}()
+	defer func() { os.Stdout = old }()
+	f()
+	w.Close()
+	return <-done
+}
+
+// instanceGroups 返回实例主网卡挂载的安全组名称
+func instanceGroups(ins ec2t.Instance) []string {
+	var names []string
+	for _, g := range ins.NetworkInterfaces[0].Groups {
+		names = append(names, aws.ToString(g.GroupName))
+	}
+	return names
+}
+
+func hasGroupNamed(s *fakeEC2State, prefix string) bool {
+	return slices.ContainsFunc(s.Groups, func(g ec2t.SecurityGroup) bool { return strings.HasPrefix(aws.ToString(g.GroupName), prefix) })
+}
+
+// -------------------- EC2 创建 --------------------
+
+func TestEC2LaunchDefault(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newEC2Client(aws.Config{Region: "us-east-1"})
+	out, err := ec2Launch(context.Background(), cli, "us-east-1", EC2LaunchOptions{AMI: "debian-12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || out[0].InstanceType != "t2.nano" || out[0].PublicIpAddress == nil {
+		t.Fatalf("默认应启动 1 台带公网 IPv4 的 t2.nano: %+v", out)
+	}
+	if g := instanceGroups(out[0]); !slices.Equal(g, []string{"default"}) {
+		t.Errorf("未要求放行端口时应只挂默认安全组，实际 %v", g)
+	}
+	if hasGroupNamed(b.ec2State("us-east-1"), "open-all-ports") {
+		t.Error("未指定 OpenAll 时不应创建 open-all-ports")
+	}
+}
+
+func TestEC2LaunchIPv6KeepsDefaultSG(t *testing.T) {
+	for _, o := range []EC2LaunchOptions{
+		{AMI: "debian-12", Net: "dual"},
+		{AMI: "debian-12", Net: "ipv6", Type: "t3.micro"},
+	} {
+		t.Run(o.Net, func(t *testing.T) {
+			b := newTestBackend(t)
+			cli := newEC2Client(aws.Config{Region: "us-east-1"})
+			out, err := ec2Launch(context.Background(), cli, "us-east-1", o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ins := out[0]
+			if len(ins.NetworkInterfaces[0].Ipv6Addresses) == 0 {
+				t.Error("实例应分配 IPv6 地址")
+			}
+			if (ins.PublicIpAddress != nil) != (o.Net == "dual") {
+				t.Errorf("%s 模式公网 IPv4 = %v", o.Net, aws.ToString(ins.PublicIpAddress))
+			}
+			if g := instanceGroups(ins); !slices.Equal(g, []string{"default"}) {
+				t.Errorf("未要求放行端口时应沿用默认安全组，实际 %v", g)
+			}
+			if hasGroupNamed(b.ec2State("us-east-1"), "open-all-ports") {
+				t.Error("未指定 OpenAll 时不应创建 open-all-ports")
+			}
+		})
+	}
+}
+
+func TestEC2LaunchOpenPorts(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newEC2Client(aws.Config{Region: "us-east-1"})
+	ctx := context.Background()
+
+	out, err := ec2Launch(ctx, cli, "us-east-1", EC2LaunchOptions{AMI: "debian-12", OpenAll: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := instanceGroups(out[0]); !slices.Equal(g, []string{"open-all-ports"}) {
+		t.Errorf("OpenAll 应挂 open-all-ports，实际 %v", g)
+	}
+
+	rules, err := parsePortRules([]string{"22,443"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = ec2Launch(ctx, cli, "us-east-1", EC2LaunchOptions{AMI: "debian-12", Ports: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := instanceGroups(out[0])
+	if len(g) != 1 || !strings.HasPrefix(g[0], "ports-") {
+		t.Fatalf("指定端口时应挂 ports-* 安全组，实际 %v", g)
+	}
+	var ports []int32
+	for _, sg := range b.ec2State("us-east-1").Groups {
+		if aws.ToString(sg.GroupName) == g[0] {
+			for _, p := range sg.IpPermissions {
+				ports = append(ports, aws.ToInt32(p.FromPort))
+			}
+		}
+	}
+	slices.Sort(ports)
+	if ports = slices.Compact(ports); !slices.Equal(ports, []int32{22, 443}) {
+		t.Errorf("安全组应只放行 22 与 443，实际 %v", ports)
+	}
+}
+
+func TestEC2LaunchCapacityFallback(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newEC2Client(aws.Config{Region: "us-east-1"})
+	ctx := context.Background()
+	noCap := fakeAPIError("InsufficientInstanceCapacity", "We currently do not have sufficient capacity in the Availability Zone you requested.")
+
+	// 首个可用区容量不足，换到另一个可用区
+	b.FailNext("ec2:RunInstances", noCap)
+	out, err := ec2Launch(ctx, cli, "us-east-1", EC2LaunchOptions{AMI: "debian-12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if az := aws.ToString(out[0].Placement.AvailabilityZone); az != "us-east-1b" {
+		t.Errorf("应换到 us-east-1b，实际 %s", az)
+	}
+
+	// 首选类型在两个可用区都不足，换到备选类型
+	b.FailNext("ec2:RunInstances", noCap)
+	b.FailNext("ec2:RunInstances", noCap)
+	out, err = ec2Launch(ctx, cli, "us-east-1", EC2LaunchOptions{AMI: "debian-12", Type: "t3.micro", AltTypes: []string{"t3.small"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out[0].InstanceType != "t3.small" {
+		t.Errorf("应换到备选类型 t3.small，实际 %s", out[0].InstanceType)
+	}
+
+	// 全部失败时返回汇总错误
+	for range 2 {
+		b.FailNext("ec2:RunInstances", noCap)
+	}
+	if _, err := ec2Launch(ctx, cli, "us-east-1", EC2LaunchOptions{AMI: "debian-12"}); err == nil || !strings.Contains(err.Error(), "InsufficientInstanceCapacity") {
+		t.Errorf("所有可用区都不足时应报错，得到 %v", err)
+	}
+}
+
+func TestEC2LaunchDualStackCapacityFallback(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newEC2Client(aws.Config{Region: "us-east-1"})
+	b.FailNext("ec2:RunInstances", fakeAPIError("InsufficientInstanceCapacity", "We currently do not have sufficient capacity in the Availability Zone you requested."))
+	out, err := ec2Launch(context.Background(), cli, "us-east-1", EC2LaunchOptions{AMI: "debian-12", Net: "dual"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ins := out[0]
+	if az := aws.ToString(ins.Placement.AvailabilityZone); az != "us-east-1b" {
+		t.Errorf("双栈实例容量不足时也应换可用区，实际 %s", az)
+	}
+	if len(ins.NetworkInterfaces[0].Ipv6Addresses) == 0 {
+		t.Error("换到的子网应已配置 IPv6")
+	}
+}
+
+func TestEC2LaunchPermissionError(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newEC2Client(aws.Config{Region: "us-east-1"})
+	b.FailNext("ec2:RunInstances", fakeAPIError("UnauthorizedOperation", "You are not authorized to perform this operation."))
+	_, err := ec2Launch(context.Background(), cli, "us-east-1", EC2LaunchOptions{AMI: "debian-12"})
+	if errorCode(err) != "UnauthorizedOperation" {
+		t.Fatalf("权限不足应直接返回，不换区重试，得到 %v", err)
+	}
+	if n := len(b.ec2State("us-east-1").Instances); n != 0 {
+		t.Errorf("不应创建实例，实际 %d 台", n)
+	}
+	if len(b.errs["ec2:RunInstances"]) != 0 {
+		t.Error("预置错误应被消耗")
+	}
+}
+
+// -------------------- EC2 操作 --------------------
+
+func TestEC2Actions(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newEC2Client(aws.Config{Region: "us-east-1"})
+	ctx := context.Background()
+	out, err := ec2Launch(ctx, cli, "us-east-1", EC2LaunchOptions{AMI: "debian-12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := aws.ToString(out[0].InstanceId)
+	state := func() ec2t.InstanceStateName {
+		for _, ins := range b.ec2State("us-east-1").Instances {
+			if aws.ToString(ins.InstanceId) == id {
+				return ins.State.Name
+			}
+		}
+		return ""
+	}
+
+	for _, c := range []struct {
+		action string
+		want   ec2t.InstanceStateName
+	}{
+		{"stop", ec2t.InstanceStateNameStopped},
+		{"start", ec2t.InstanceStateNameRunning},
+		{"reboot", ec2t.InstanceStateNameRunning},
+	} {
+		if err := ec2Action(ctx, cli, id, c.action); err != nil {
+			t.Fatalf("%s: %v", c.action, err)
+		}
+		if st := state(); st != c.want {
+			t.Errorf("%s 后状态为 %s，期望 %s", c.action, st, c.want)
+		}
+	}
+	if err := ec2Action(ctx, cli, id, "bogus"); err == nil {
+		t.Error("未知操作应报错")
+	}
+
+	// 权限不足时 ec2DoAction 输出失败信息，状态不变
+	b.FailNext("ec2:StopInstances", fakeAPIError("UnauthorizedOperation", "You are not authorized to perform this operation."))
+	if s := captureStdout(t, func() { ec2DoAction(ctx, cli, id, "stop") }); !strings.Contains(s, "❌ 失败") || !strings.Contains(s, "UnauthorizedOperation") {
+		t.Errorf("应输出失败原因，得到 %q", s)
+	}
+	if st := state(); st != ec2t.InstanceStateNameRunning {
+		t.Errorf("操作失败后状态应不变，实际 %s", st)
+	}
+
+	// 终止时释放绑定的 EIP
+	alloc, err := cli.AllocateAddress(ctx, &ec2.AllocateAddressInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.AssociateAddress(ctx, &ec2.AssociateAddressInput{AllocationId: alloc.AllocationId, InstanceId: aws.String(id)}); err != nil {
+		t.Fatal(err)
+	}
+	if s := captureStdout(t, func() { ec2DoAction(ctx, cli, id, "terminate") }); !strings.Contains(s, actionDoneMsg["terminate"]) {
+		t.Errorf("终止应输出完成信息，得到 %q", s)
+	}
+	if st := state(); st != ec2t.InstanceStateNameTerminated {
+		t.Errorf("终止后状态为 %s", st)
+	}
+	if n := len(b.ec2State("us-east-1").Addresses); n != 0 {
+		t.Errorf("终止后 EIP 应被释放，剩余 %d 个", n)
+	}
+}
+
+// -------------------- Lightsail --------------------
+
+func lsInstance(b *FakeBackend, name string) *lst.Instance {
+	s := b.lsState("us-east-1")
+	for i := range s.Instances {
+		if aws.ToString(s.Instances[i].Name) == name {
+			return &s.Instances[i]
+		}
+	}
+	return nil
+}
+
+func TestLSLaunch(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newLightsailClient(aws.Config{Region: "us-east-1"})
+	ctx := context.Background()
+	base := LSLaunchOptions{AZ: "us-east-1a", Bundle: "nano_3_0", Blueprint: "debian_12"}
+
+	o := base
+	o.Name = "web"
+	if err := lsLaunch(ctx, cli, o); err != nil {
+		t.Fatal(err)
+	}
+	ins := lsInstance(b, "web")
+	if ins == nil || ins.IpAddressType != lst.IpAddressTypeDualstack || len(ins.Networking.Ports) != 2 {
+		t.Fatalf("应创建双栈实例并保留默认防火墙规则: %+v", ins)
+	}
+
+	// IPv6 套餐未指定 IP 类型时自动使用 ipv6
+	o = base
+	o.Name, o.Bundle = "v6", "nano_ipv6_3_0"
+	if err := lsLaunch(ctx, cli, o); err != nil {
+		t.Fatal(err)
+	}
+	if ins := lsInstance(b, "v6"); ins == nil || ins.IpAddressType != lst.IpAddressTypeIpv6 {
+		t.Errorf("IPv6 套餐应创建 IPv6-only 实例: %+v", ins)
+	}
+
+	// IP 类型与套餐不符时不调用 CreateInstances
+	o = base
+	o.Name, o.IPType = "bad", "ipv6"
+	if err := lsLaunch(ctx, cli, o); err == nil || !strings.Contains(err.Error(), "不匹配") {
+		t.Errorf("类型不符应报错，得到 %v", err)
+	}
+	if lsInstance(b, "bad") != nil {
+		t.Error("类型不符时不应创建实例")
+	}
+
+	// 指定端口时替换默认防火墙规则
+	o = base
+	o.Name, o.Ports = "ports", []PortRule{{Protocol: "udp", From: 8443, To: 8443}}
+	if err := lsLaunch(ctx, cli, o); err != nil {
+		t.Fatal(err)
+	}
+	ports := lsInstance(b, "ports").Networking.Ports
+	if len(ports) != 1 || ports[0].FromPort != 8443 || ports[0].Protocol != lst.NetworkProtocolUdp {
+		t.Errorf("防火墙应只放行 8443/udp，实际 %+v", ports)
+	}
+}
+
+func TestLSLaunchAPIError(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newLightsailClient(aws.Config{Region: "us-east-1"})
+	b.FailNext("lightsail:CreateInstances", fakeAPIError("AccessDeniedException", "User is not authorized to perform: lightsail:CreateInstances"))
+	err := lsLaunch(context.Background(), cli, LSLaunchOptions{AZ: "us-east-1a", Name: "x", Bundle: "nano_3_0", Blueprint: "debian_12"})
+	if errorCode(err) != "AccessDeniedException" {
+		t.Fatalf("应返回 API 错误，得到 %v", err)
+	}
+	if lsInstance(b, "x") != nil {
+		t.Error("失败时不应有实例")
+	}
+}
+
+func TestLSActions(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newLightsailClient(aws.Config{Region: "us-east-1"})
+	ctx := context.Background()
+	if err := lsLaunch(ctx, cli, LSLaunchOptions{AZ: "us-east-1a", Name: "web", Bundle: "nano_3_0", Blueprint: "debian_12"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ action, want string }{
+		{"stop", "stopped"},
+		{"start", "running"},
+		{"reboot", "running"},
+	} {
+		if err := lsAction(ctx, cli, "web", c.action); err != nil {
+			t.Fatalf("%s: %v", c.action, err)
+		}
+		if st := aws.ToString(lsInstance(b, "web").State.Name); st != c.want {
+			t.Errorf("%s 后状态为 %s，期望 %s", c.action, st, c.want)
+		}
+	}
+	if err := lsAction(ctx, cli, "web", "terminate"); err == nil {
+		t.Error("未知操作应报错")
+	}
+
+	b.FailNext("lightsail:StopInstance", fakeAPIError("AccessDeniedException", "User is not authorized to perform: lightsail:StopInstance"))
+	if s := captureStdout(t, func() { lsDoAction(ctx, cli, "web", "stop") }); !strings.Contains(s, "❌ 失败") {
+		t.Errorf("应输出失败信息，得到 %q", s)
+	}
+
+	// 删除时释放绑定的固定 IP
+	if _, err := cli.AllocateStaticIp(ctx, &lightsail.AllocateStaticIpInput{StaticIpName: aws.String("ip-web")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.AttachStaticIp(ctx, &lightsail.AttachStaticIpInput{StaticIpName: aws.String("ip-web"), InstanceName: aws.String("web")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lsAction(ctx, cli, "web", "delete"); err != nil {
+		t.Fatal(err)
+	}
+	if lsInstance(b, "web") != nil {
+		t.Error("实例应已删除")
+	}
+	if n := len(b.lsState("us-east-1").StaticIPs); n != 0 {
+		t.Errorf("删除后固定 IP 应被释放，剩余 %d 个", n)
+	}
+}
+
+// -------------------- 新手任务 --------------------
+
+// creditTest 启用内存后端并去掉任务中的等待
+func creditTest(t *testing.T) (*FakeBackend, aws.Config) {
+	t.Helper()
+	b := newTestBackend(t)
+	old := taskSleep
+	taskSleep = func(time.Duration) {}
+	t.Cleanup(func() { taskSleep = old })
+	return b, aws.Config{Region: "us-east-1"}
+}
+
+func TestTaskSetBudget(t *testing.T) {
+	b, cfg := creditTest(t)
+	ctx := context.Background()
+	if s := captureStdout(t, func() { taskSetBudget(ctx, cfg, b.AccountID) }); !strings.Contains(s, "创建成功") || len(b.budgets) != 1 {
+		t.Errorf("应创建预算: %q", s)
+	}
+	b.FailNext("budgets:CreateBudget", fakeAPIError("DuplicateRecordException", "Error creating budget: Duplicate budget name"))
+	if s := captureStdout(t, func() { taskSetBudget(ctx, cfg, b.AccountID) }); !strings.Contains(s, "已存在") {
+		t.Errorf("重复预算应跳过: %q", s)
+	}
+	b.FailNext("budgets:CreateBudget", fakeAPIError("AccessDeniedException", "User is not authorized to perform: budgets:ModifyBudget"))
+	if s := captureStdout(t, func() { taskSetBudget(ctx, cfg, b.AccountID) }); !strings.Contains(s, "❌ 失败") || !strings.Contains(s, "AccessDeniedException") {
+		t.Errorf("权限不足应输出失败原因: %q", s)
+	}
+	if len(b.budgets) != 1 {
+		t.Errorf("失败时不应新增预算，现有 %d 个", len(b.budgets))
+	}
+}
+
+func TestTaskRunEC2(t *testing.T) {
+	b, cfg := creditTest(t)
+	ctx := context.Background()
+	if s := captureStdout(t, func() { taskRunEC2(ctx, cfg) }); !strings.Contains(s, "任务达成") {
+		t.Errorf("实例应进入 Running: %q", s)
+	}
+	ins := b.ec2State("us-east-1").Instances
+	if len(ins) != 1 || ins[0].InstanceType != ec2t.InstanceTypeT3Micro || ins[0].State.Name != ec2t.InstanceStateNameTerminated {
+		t.Fatalf("应启动一台 t3.micro 并在完成后终止: %+v", ins)
+	}
+
+	b.FailNext("ec2:RunInstances", fakeAPIError("VcpuLimitExceeded", "You have requested more vCPU capacity than your current vCPU limit of 0 allows."))
+	if s := captureStdout(t, func() { taskRunEC2(ctx, cfg) }); !strings.Contains(s, "❌ 启动失败") || !strings.Contains(s, "VcpuLimitExceeded") {
+		t.Errorf("启动失败应输出原因: %q", s)
+	}
+	if n := len(b.ec2State("us-east-1").Instances); n != 1 {
+		t.Errorf("启动失败时不应新增实例，现有 %d 台", n)
+	}
+}
+
+func TestTaskRunLambda(t *testing.T) {
+	b, cfg := creditTest(t)
+	ctx := context.Background()
+	if s := captureStdout(t, func() { taskRunLambda(ctx, cfg) }); !strings.Contains(s, "调用成功") {
+		t.Errorf("函数应调用成功: %q", s)
+	}
+	if len(b.roles) != 0 || len(b.functions) != 0 {
+		t.Errorf("完成后应清理角色与函数: roles=%v functions=%v", b.roles, b.functions)
+	}
+
+	// 角色尚未生效时首次创建失败，重试成功
+	b.FailNext("lambda:CreateFunction", fakeAPIError("InvalidParameterValueException", "The role defined for the function cannot be assumed by Lambda."))
+	if s := captureStdout(t, func() { taskRunLambda(ctx, cfg) }); !strings.Contains(s, "调用成功") {
+		t.Errorf("重试后应调用成功: %q", s)
+	}
+
+	// 两次都失败时删除临时角色
+	for range 2 {
+		b.FailNext("lambda:CreateFunction", fakeAPIError("InvalidParameterValueException", "The role defined for the function cannot be assumed by Lambda."))
+	}
+	if s := captureStdout(t, func() { taskRunLambda(ctx, cfg) }); !strings.Contains(s, "❌ 函数创建失败") {
+		t.Errorf("应输出函数创建失败: %q", s)
+	}
+	if len(b.roles) != 0 || len(b.functions) != 0 {
+		t.Errorf("失败后应删除临时角色: roles=%v functions=%v", b.roles, b.functions)
+	}
+
+	b.FailNext("iam:CreateRole", fakeAPIError("AccessDenied", "User is not authorized to perform: iam:CreateRole"))
+	if s := captureStdout(t, func() { taskRunLambda(ctx, cfg) }); !strings.Contains(s, "❌ IAM 角色创建失败") {
+		t.Errorf("应输出角色创建失败: %q", s)
+	}
+	if len(b.functions) != 0 {
+		t.Error("角色创建失败时不应创建函数")
+	}
+}
+
+func TestTaskRunRDS(t *testing.T) {
+	b, cfg := creditTest(t)
+	ctx := context.Background()
+	s := captureStdout(t, func() { taskRunRDS(ctx, cfg) })
+	if !strings.Contains(s, "任务达成") || !strings.Contains(s, "删除指令已发送") {
+		t.Errorf("数据库就绪后应删除: %q", s)
+	}
+	if len(b.dbs) != 0 {
+		t.Errorf("完成后应删除数据库: %v", b.dbs)
+	}
+
+	// 生成的主密码符合 RDS 要求并加密记录到保险库
+	recs, err := loadCredentials()
+	if err != nil || len(recs) != 1 {
+		t.Fatalf("应记录一条 RDS 凭证: %v %v", recs, err)
+	}
+	r := recs[0]
+	if r.Service != "rds" || r.Region != "us-east-1" || r.User != "admin" || !strings.Contains(s, r.Password) {
+		t.Errorf("凭证记录不符: %+v", r)
+	}
+	if len(r.Password) != rdsPasswordPolicy.Length || strings.ContainsAny(r.Password, "/@\" ") {
+		t.Errorf("RDS 主密码不符合要求: %q", r.Password)
+	}
+	if raw, err := os.ReadFile(vaultPath()); err != nil || strings.Contains(string(raw), r.Password) {
+		t.Errorf("保险库文件中不应出现明文密码 (%v)", err)
+	}
+
+	// 创建失败时不记录凭证
+	b.FailNext("rds:CreateDBInstance", fakeAPIError("StorageQuotaExceeded", "Cannot create more than 0 GB of storage."))
+	if s := captureStdout(t, func() { taskRunRDS(ctx, cfg) }); !strings.Contains(s, "❌ 创建请求失败") {
+		t.Errorf("应输出创建失败: %q", s)
+	}
+	if recs, _ := loadCredentials(); len(recs) != 1 {
+		t.Errorf("创建失败时不应记录凭证，现有 %d 条", len(recs))
+	}
+
+	// 删除失败时保留数据库并提示
+	b.FailNext("rds:DeleteDBInstance", fakeAPIError("InvalidDBInstanceState", "Instance is not in available state."))
+	if s := captureStdout(t, func() { taskRunRDS(ctx, cfg) }); !strings.Contains(s, "❌ 删除失败") {
+		t.Errorf("应输出删除失败: %q", s)
+	}
+	if len(b.dbs) != 1 {
+		t.Errorf("删除失败时数据库应仍在: %v", b.dbs)
+	}
+}
+
+// -------------------- 账户 (STS / 配额 / 区域) --------------------
+
+var testCreds = credentials.NewStaticCredentialsProvider("AKIAEXAMPLE", "secret", "")
+
+func TestSTSIdentityAndQuota(t *testing.T) {
+	b := newTestBackend(t)
+	ctx := context.Background()
+	id, err := stsIdentity(ctx, "us-east-1", testCreds)
+	if err != nil || aws.ToString(id.Account) != b.AccountID {
+		t.Fatalf("应返回账户 ID: %+v %v", id, err)
+	}
+	b.FailNext("sts:GetCallerIdentity", fakeAPIError("InvalidClientTokenId", "The security token included in the request is invalid."))
+	if err := stsCheck(ctx, "us-east-1", testCreds); errorCode(err) != "InvalidClientTokenId" {
+		t.Errorf("无效密钥应返回错误，得到 %v", err)
+	}
+
+	if v, err := getVCPUQuota(ctx, testCreds); err != nil || v != b.VCPUQuota {
+		t.Errorf("配额应为 %.0f，得到 %v %v", b.VCPUQuota, v, err)
+	}
+	b.FailNext("servicequotas:GetServiceQuota", fakeAPIError("AccessDeniedException", "User is not authorized to perform: servicequotas:GetServiceQuota"))
+	if _, err := getVCPUQuota(ctx, testCreds); err == nil {
+		t.Error("无权限查询配额时应报错")
+	}
+}
+
+func TestAssumeRoleChain(t *testing.T) {
+	b := newTestBackend(t)
+	ctx := context.Background()
+	specs := []RoleSpec{{ARN: "arn:aws:iam::111111111111:role/hub"}, {ARN: "arn:aws:iam::222222222222:role/target", ExternalID: "x"}}
+	creds, err := assumeRoleChain(ctx, testCreds, specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := creds.Retrieve(ctx); err != nil || c.AccessKeyID != "ASIAOFFLINE" || c.SessionToken == "" {
+		t.Errorf("应得到临时凭证: %+v %v", c, err)
+	}
+
+	b.FailNext("sts:AssumeRole", fakeAPIError("AccessDenied", "User is not authorized to perform: sts:AssumeRole"))
+	_, err = assumeRoleChain(ctx, testCreds, specs)
+	if err == nil || !strings.Contains(err.Error(), "第 1 跳") || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("扮演失败应指出第几跳，得到 %v", err)
+	}
+}
+
+func TestEnsureRegionOptIn(t *testing.T) {
+	b := newTestBackend(t)
+	ctx := context.Background()
+	old := regionOptInPoll
+	regionOptInPoll = time.Millisecond
+	t.Cleanup(func() { regionOptInPoll = old })
+
+	if err := ensureRegionOptIn(ctx, "us-east-1", "opt-in-not-required", testCreds); err != nil {
+		t.Errorf("无需启用的区域应直接返回: %v", err)
+	}
+	answer(t, "n")
+	if err := ensureRegionOptIn(ctx, "ap-east-1", "not-opted-in", testCreds); err == nil {
+		t.Error("拒绝启用时应返回错误")
+	}
+
+	answer(t, "y")
+	b.FailNext("account:EnableRegion", fakeAPIError("AccessDeniedException", "User is not authorized to perform: account:EnableRegion"))
+	if err := ensureRegionOptIn(ctx, "ap-east-1", "not-opted-in", testCreds); err == nil || !strings.Contains(err.Error(), "AccessDeniedException") {
+		t.Errorf("无权限启用时应报错，得到 %v", err)
+	}
+
+	answer(t, "y")
+	if err := ensureRegionOptIn(ctx, "ap-east-1", "not-opted-in", testCreds); err != nil {
+		t.Fatal(err)
+	}
+	if b.Regions[0].Name != "ap-east-1" || b.Regions[0].Status != "opted-in" {
+		t.Errorf("区域应已启用: %+v", b.Regions[0])
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// -------------------- 输出格式 (table / json / csv / yaml) --------------------
+
+var outputFormats = []string{"table", "json", "csv", "yaml"}
+
+// Report 是一份可按多种格式输出的结果:
+// table 使用中文表头 Header，csv 使用英文列名 Columns，json/yaml 直接序列化 Data
+type Report struct {
+	Header  string
+	Columns []string
+	Rows    [][]string
+	Cut     map[int]int // table 模式下按列截断显示宽度
+	Data    any
+}
+
+type QuotaRow struct {
+	Service string  `json:"service" yaml:"service"`
+	Code    string  `json:"code" yaml:"code"`
+	Name    string  `json:"name" yaml:"name"`
+	Value   float64 `json:"value" yaml:"value"`
+}
+
+func checkOutputFormat(f string) error {
+	for _, v := range outputFormats {
+		if f == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("不支持的输出格式: %s (可选 %s)", f, strings.Join(outputFormats, "|"))
+}
+
+func writeReport(w io.Writer, format string, r Report) error {
+	switch format {
+	case "", "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, r.Header)
+		ncol := strings.Count(r.Header, "\t") + 1
+		for _, row := range r.Rows {
+			if len(row) > ncol {
+				row = row[:ncol] // 多出的列只在 csv/json/yaml 中输出
+			}
+			cells := make([]string, len(row))
+			for i, c := range row {
+				if n, ok := r.Cut[i]; ok {
+					c = cut(c, n)
+				}
+				cells[i] = c
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+		return tw.Flush()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r.Data)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(r.Data); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(r.Columns)
+		cw.WriteAll(r.Rows)
+		return cw.Error()
+	}
+	return checkOutputFormat(format)
+}
+
+// saveReport 把报告写入文件，格式按扩展名判断，未知扩展名按 table 输出
+func saveReport(path string, r Report) error {
+	format := "table"
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = "json"
+	case ".yaml", ".yml":
+		format = "yaml"
+	case ".csv":
+		format = "csv"
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeReport(f, format, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func printEC2Rows(rows []EC2InstanceRow) {
+	writeReport(os.Stdout, "table", ec2Report(rows))
+}
+
+func printLSRows(rows []LSInstanceRow) {
+	writeReport(os.Stdout, "table", lsReport(rows))
+}
+
+func ec2Report(rows []EC2InstanceRow) Report {
+	r := Report{
+		Header:  "序号\t区域\tID\t名称\t状态\t配置\t公网IP\t内网IP\tIPv6\t网络",
+		Columns: []string{"idx", "region", "id", "name", "state", "type", "public_ip", "private_ip", "ipv6", "net", "az", "spot"},
+		Cut:     map[int]int{3: 10},
+		Data:    rows,
+	}
+	if rows == nil {
+		r.Data = []EC2InstanceRow{}
+	}
+	for _, x := range rows {
+		itype := x.Type
+		if x.Spot {
+			itype += " (spot)"
+		}
+		r.Rows = append(r.Rows, []string{strconv.Itoa(x.Idx), x.Region, x.ID, x.Name, x.State, itype, x.PubIP, x.PrivIP, x.IPv6, netModeName(x.Net), x.AZ, strconv.FormatBool(x.Spot)})
+	}
+	return r
+}
+
+func lsReport(rows []LSInstanceRow) Report {
+	r := Report{
+		Header:  "序号\t区域\t名称\t状态\t配置\tIPv4\tIPv6\t网络",
+		Columns: []string{"idx", "region", "name", "state", "bundle", "ipv4", "ipv6", "ip_type", "az"},
+		Cut:     map[int]int{4: 10},
+		Data:    rows,
+	}
+	if rows == nil {
+		r.Data = []LSInstanceRow{}
+	}
+	for _, x := range rows {
+		r.Rows = append(r.Rows, []string{strconv.Itoa(x.Idx), x.Region, x.Name, x.State, x.Bundle, x.IP, x.IPv6, x.ipTypeName(), x.AZ})
+	}
+	return r
+}
+
+func regionReport(rows []RegionInfo) Report {
+	r := Report{
+		Header:  "区域\t位置\t状态",
+		Columns: []string{"name", "location", "status"},
+		Data:    rows,
+	}
+	if rows == nil {
+		r.Data = []RegionInfo{}
+	}
+	for _, x := range rows {
+		r.Rows = append(r.Rows, []string{x.Name, regionCN(x.Name), x.Status})
+	}
+	return r
+}
+
+func quotaReport(rows []QuotaRow) Report {
+	r := Report{
+		Header:  "服务\t配额代码\t名称\t数值",
+		Columns: []string{"service", "code", "name", "value"},
+		Data:    rows,
+	}
+	for _, x := range rows {
+		r.Rows = append(r.Rows, []string{x.Service, x.Code, x.Name, strconv.FormatFloat(x.Value, 'f', -1, 64)})
+	}
+	return r
+}
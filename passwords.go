@@ -0,0 +1,434 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// -------------------- 密码策略与生成 --------------------
+
+// PasswordPolicy 为生成与校验 root / 系统密码的规则。
+// 默认值可用 ~/.aws-tool/password-policy.yaml (或 AWS_TOOL_PASSWORD_POLICY) 覆盖
+type PasswordPolicy struct {
+	Length           int    `yaml:"length"`            // 生成密码的长度
+	MinLength        int    `yaml:"min_length"`        // 手动输入密码的最短长度
+	MinClasses       int    `yaml:"min_classes"`       // 手动输入密码至少包含几类字符 (小写/大写/数字/符号)
+	Symbols          string `yaml:"symbols"`           // 生成时使用的符号，留空则不含符号
+	ExcludeAmbiguous bool   `yaml:"exclude_ambiguous"` // 生成时去掉 0O1lI 等易混淆字符
+	HashUserData     bool   `yaml:"hash_userdata"`     // user-data 中只写 SHA-512 crypt 哈希
+}
+
+// 符号避开 shell 与 URL 中需要转义的字符，方便复制粘贴
+var defaultPasswordPolicy = PasswordPolicy{
+	Length: 20, MinLength: 12, MinClasses: 3, Symbols: "!#%+-=_~^", ExcludeAmbiguous: true, HashUserData: true,
+}
+
+// rdsPasswordPolicy 为 RDS 主密码：不能包含 / @ " 与空格
+var rdsPasswordPolicy = PasswordPolicy{Length: 24, Symbols: "!#%+-=_~^", ExcludeAmbiguous: true}
+
+func passwordPolicyPath() string {
+	if p := os.Getenv("AWS_TOOL_PASSWORD_POLICY"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "password-policy.yaml"
+	}
+	return filepath.Join(home, ".aws-tool", "password-policy.yaml")
+}
+
+// loadPasswordPolicy 读取策略文件，未填写的字段沿用默认值；文件不存在时返回默认策略
+func loadPasswordPolicy() (PasswordPolicy, error) {
+	p := defaultPasswordPolicy
+	b, err := os.ReadFile(passwordPolicyPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	if err := yaml.Unmarshal(b, &p); err != nil {
+		return defaultPasswordPolicy, fmt.Errorf("密码策略文件无效: %v", err)
+	}
+	if p.Length < 8 || p.Length > 128 {
+		return defaultPasswordPolicy, fmt.Errorf("密码策略 length 应在 8-128 之间: %d", p.Length)
+	}
+	return p, nil
+}
+
+// currentPasswordPolicy 为交互与 CLI 使用的策略，策略文件无效时提示并使用默认值
+func currentPasswordPolicy() PasswordPolicy {
+	p, err := loadPasswordPolicy()
+	if err != nil {
+		fmt.Printf("⚠️ %v，使用默认密码策略\n", err)
+	}
+	return p
+}
+
+const (
+	pwLower = "abcdefghijklmnopqrstuvwxyz"
+	pwUpper = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	pwDigit = "0123456789"
+)
+
+func randIndex(n int) int {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		panic(err)
+	}
+	return int(v.Int64())
+}
+
+// generatePassword 用 crypto/rand 生成密码，保证每类字符至少出现一次
+func generatePassword(p PasswordPolicy) string {
+	strip := func(s string) string {
+		if !p.ExcludeAmbiguous {
+			return s
+		}
+		return strings.Map(func(r rune) rune {
+			if strings.ContainsRune("0O1lI", r) {
+				return -1
+			}
+			return r
+		}, s)
+	}
+	classes := []string{strip(pwLower), strip(pwUpper), strip(pwDigit)}
+	if p.Symbols != "" {
+		classes = append(classes, p.Symbols)
+	}
+	all := strings.Join(classes, "")
+	length := max(p.Length, len(classes))
+	b := make([]byte, 0, length)
+	for _, c := range classes {
+		b = append(b, c[randIndex(len(c))])
+	}
+	for len(b) < length {
+		b = append(b, all[randIndex(len(all))])
+	}
+	for i := len(b) - 1; i > 0; i-- {
+		j := randIndex(i + 1)
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// checkPassword 按策略检查手动输入的密码
+func checkPassword(p PasswordPolicy, pwd string) error {
+	if len(pwd) < p.MinLength {
+		return fmt.Errorf("密码至少 %d 位", p.MinLength)
+	}
+	var lower, upper, digit, symbol bool
+	for _, r := range pwd {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsSpace(r) || r == ':':
+			return errors.New("密码不能包含空白或冒号")
+		default:
+			symbol = true
+		}
+	}
+	n := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			n++
+		}
+	}
+	if n < p.MinClasses {
+		return fmt.Errorf("密码至少包含 %d 类字符 (小写、大写、数字、符号)，当前 %d 类", p.MinClasses, n)
+	}
+	return nil
+}
+
+// promptRootPassword 询问 root 密码：g 自动生成，不符合策略时确认是否仍然使用
+func promptRootPassword(p PasswordPolicy) string {
+	for {
+		pwd := input("设置 SSH root 密码 (留空跳过，g 自动生成): ", "")
+		switch {
+		case pwd == "":
+			return ""
+		case pwd == "g" || pwd == "G":
+			pwd = generatePassword(p)
+			fmt.Println("🔑 已生成 root 密码:", pwd)
+			return pwd
+		}
+		err := checkPassword(p, pwd)
+		if err == nil {
+			return pwd
+		}
+		fmt.Println("⚠️", err)
+		if yes(input("仍然使用该密码? [y/N]: ", "n")) {
+			return pwd
+		}
+	}
+}
+
+// resolveRootPassword 按需生成密码，并按密码策略决定 user-data 中是否明文写入
+func resolveRootPassword(pwd string, gen, plain bool) (string, bool) {
+	p := currentPasswordPolicy()
+	if pwd == "" && gen {
+		pwd = generatePassword(p)
+		fmt.Println("🔑 已生成 root 密码:", pwd)
+	}
+	return pwd, plain || !p.HashUserData
+}
+
+// -------------------- SHA-512 crypt ($6$) --------------------
+
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// cryptSalt 生成 16 位随机盐
+func cryptSalt() string {
+	b := make([]byte, 16)
+	for i := range b {
+		b[i] = cryptAlphabet[randIndex(len(cryptAlphabet))]
+	}
+	return string(b)
+}
+
+// isCryptHash 判断是否已是 crypt(3) 格式的哈希 ($6$ / $5$ / $y$ ...)，此时不再重复哈希
+func isCryptHash(s string) bool {
+	for _, prefix := range []string{"$1$", "$5$", "$6$", "$y$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// cryptPassword 把明文转换为 SHA-512 crypt 哈希，可直接用于 chpasswd -e
+func cryptPassword(pwd string) string {
+	if isCryptHash(pwd) {
+		return pwd
+	}
+	return sha512Crypt(pwd, cryptSalt(), 0)
+}
+
+// sha512Crypt 实现 glibc 的 SHA-512 crypt (Ulrich Drepper 规范)；rounds 为 0 时使用默认 5000 轮
+func sha512Crypt(key, salt string, rounds int) string {
+	const defaultRounds = 5000
+	custom := rounds != 0
+	if !custom {
+		rounds = defaultRounds
+	}
+	rounds = min(max(rounds, 1000), 999999999)
+	if len(salt) > 16 {
+		salt = salt[:16]
+	}
+	k, s := []byte(key), []byte(salt)
+
+	alt := sha512.New()
+	alt.Write(k)
+	alt.Write(s)
+	alt.Write(k)
+	altSum := alt.Sum(nil)
+
+	a := sha512.New()
+	a.Write(k)
+	a.Write(s)
+	i := len(k)
+	for ; i > 64; i -= 64 {
+		a.Write(altSum)
+	}
+	a.Write(altSum[:i])
+	for i = len(k); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			a.Write(altSum)
+		} else {
+			a.Write(k)
+		}
+	}
+	sum := a.Sum(nil)
+
+	dp := sha512.New()
+	for range k {
+		dp.Write(k)
+	}
+	p := repeatBytes(dp.Sum(nil), len(k))
+
+	ds := sha512.New()
+	for range 16 + int(sum[0]) {
+		ds.Write(s)
+	}
+	sb := repeatBytes(ds.Sum(nil), len(s))
+
+	for r := 0; r < rounds; r++ {
+		c := sha512.New()
+		if r&1 != 0 {
+			c.Write(p)
+		} else {
+			c.Write(sum)
+		}
+		if r%3 != 0 {
+			c.Write(sb)
+		}
+		if r%7 != 0 {
+			c.Write(p)
+		}
+		if r&1 != 0 {
+			c.Write(sum)
+		} else {
+			c.Write(p)
+		}
+		sum = c.Sum(nil)
+	}
+
+	var out strings.Builder
+	out.WriteString("$6$")
+	if custom {
+		out.WriteString("rounds=" + strconv.Itoa(rounds) + "$")
+	}
+	out.WriteString(salt + "$")
+	enc := func(b2, b1, b0 byte, n int) {
+		w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+		for ; n > 0; n-- {
+			out.WriteByte(cryptAlphabet[w&0x3f])
+			w >>= 6
+		}
+	}
+	// 输出时按规范打乱字节顺序：(0,21,42) (22,43,1) (44,2,23) (3,24,45) ...
+	for j := 0; j < 21; j++ {
+		x, y, z := sum[j], sum[j+21], sum[j+42]
+		switch j % 3 {
+		case 0:
+			enc(x, y, z, 4)
+		case 1:
+			enc(y, z, x, 4)
+		case 2:
+			enc(z, x, y, 4)
+		}
+	}
+	enc(0, 0, sum[63], 2)
+	return out.String()
+}
+
+func repeatBytes(src []byte, n int) []byte {
+	b := make([]byte, 0, n)
+	for len(b) < n {
+		b = append(b, src[:min(len(src), n-len(b))]...)
+	}
+	return b
+}
+
+// -------------------- 本地凭证记录 --------------------
+
+// 工具设置过的 root / 数据库密码保存在本地加密保险库 (vault.go) 中，不落地明文；
+// 记录时保险库未解锁会先要求输入口令
+
+// CredentialRecord 为一个实例或数据库的登录凭证
+type CredentialRecord struct {
+	Service  string    `json:"service" yaml:"service"`
+	Region   string    `json:"region" yaml:"region"`
+	Resource string    `json:"resource" yaml:"resource"` // 实例 ID、实例名称或数据库标识
+	User     string    `json:"user" yaml:"user"`
+	Password string    `json:"password" yaml:"password"`
+	Created  time.Time `json:"created" yaml:"created"`
+}
+
+var credentialsMu sync.Mutex
+
+// loadCredentials 返回保险库中的凭证记录；保险库尚未创建时没有记录，不提示新建
+func loadCredentials() ([]CredentialRecord, error) {
+	if activeVault == nil {
+		if _, err := os.Stat(vaultPath()); errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+	}
+	v, err := unlockVault()
+	if err != nil {
+		return nil, err
+	}
+	return v.Credentials, nil
+}
+
+// saveCredentials 把记录写入保险库；同一资源与用户的旧记录被替换
+func saveCredentials(recs ...CredentialRecord) error {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	v, err := unlockVault()
+	if err != nil {
+		return err
+	}
+	for _, r := range recs {
+		if r.Created.IsZero() {
+			r.Created = time.Now()
+		}
+		v.Credentials = slices.DeleteFunc(v.Credentials, func(x CredentialRecord) bool {
+			return x.Service == r.Service && x.Resource == r.Resource && x.User == r.User
+		})
+		v.Credentials = append(v.Credentials, r)
+	}
+	return v.Save()
+}
+
+// recordCredentials 保存记录并提示，失败时只警告
+func recordCredentials(recs ...CredentialRecord) {
+	if len(recs) == 0 {
+		return
+	}
+	if activeVault == nil {
+		fmt.Println("\n🔐 密码将加密记录到本地保险库，需要解锁")
+	}
+	if err := saveCredentials(recs...); err != nil {
+		fmt.Println("⚠️ 保存密码记录失败，请自行记下密码:", err)
+		return
+	}
+	fmt.Printf("🔐 密码已记录到保险库 %s\n", vaultPath())
+}
+
+// lookupCredential 返回资源的已记录密码，没有时返回空字符串
+func lookupCredential(service, resource, user string) string {
+	recs, _ := loadCredentials()
+	for i := len(recs) - 1; i >= 0; i-- {
+		r := recs[i]
+		if r.Service == service && r.Resource == resource && r.User == user {
+			return r.Password
+		}
+	}
+	return ""
+}
+
+// credentialReport 生成凭证列表，show 为 false 时隐藏密码
+func credentialReport(recs []CredentialRecord, show bool) Report {
+	r := Report{
+		Header:  "服务\t区域\t资源\t用户\t密码\t记录时间",
+		Columns: []string{"service", "region", "resource", "user", "password", "created"},
+		Data:    recs,
+	}
+	if recs == nil {
+		r.Data = []CredentialRecord{}
+	}
+	if !show {
+		masked := make([]CredentialRecord, len(recs))
+		for i, x := range recs {
+			x.Password = "******"
+			masked[i] = x
+		}
+		r.Data = masked
+	}
+	for _, x := range recs {
+		pwd := x.Password
+		if !show {
+			pwd = "******"
+		}
+		r.Rows = append(r.Rows, []string{x.Service, x.Region, x.Resource, x.User, pwd, x.Created.Local().Format("2006-01-02 15:04")})
+	}
+	return r
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// -------------------- 代理 (单代理 / 代理池 / 故障切换) --------------------
+
+type proxyCtxKey struct{}
+
+type accountCtxKey struct{}
+
+// withProxy 为单个账户的调用链绑定代理 (批量模式下各账户并发，不能共用 GlobalProxy)
+func withProxy(ctx context.Context, proxy string) context.Context {
+	return context.WithValue(ctx, proxyCtxKey{}, proxy)
+}
+
+// withAccount 标记调用链所属账户，代理池据此为账户分配并固定代理
+func withAccount(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, accountCtxKey{}, label)
+}
+
+// sessionAccount 为交互模式 / 单账户命令当前使用的账户标签
+var sessionAccount string
+
+func accountOf(ctx context.Context) string {
+	if l, ok := ctx.Value(accountCtxKey{}).(string); ok {
+		return l
+	}
+	return sessionAccount
+}
+
+// accountCtx 为批量任务中的账户构造调用上下文：
+// 代理池启用时账户自带的代理作为首选固定到池中，否则直接绑定到 ctx
+func accountCtx(ctx context.Context, label, proxy string) context.Context {
+	ctx = withAccount(ctx, label)
+	if proxy == "" {
+		return ctx
+	}
+	proxy = parseProxyString(proxy)
+	if activePool != nil {
+		activePool.Pin(label, proxy)
+		return ctx
+	}
+	return withProxy(ctx, proxy)
+}
+
+// activeProxy 返回当前调用应使用的代理: ctx 上显式绑定的代理 > 代理池为账户分配的代理 > GlobalProxy
+func activeProxy(ctx context.Context) string {
+	if p, ok := ctx.Value(proxyCtxKey{}).(string); ok {
+		return p
+	}
+	if activePool != nil {
+		return activePool.Bind(accountOf(ctx))
+	}
+	return GlobalProxy
+}
+
+// proxyHTTPClient 按当前生效的代理构造 HTTP 客户端，未设置任何代理 (含环境变量) 时返回 nil。
+// 代理池启用时每个请求都重新读取账户当前的代理，连接代理失败会标记失效并切换到其他代理，
+// 由 SDK 的重试机制在新代理上重发请求
+func proxyHTTPClient(ctx context.Context) (*awshttp.BuildableClient, error) {
+	var (
+		sel    proxySelector
+		onFail func(*url.URL, error)
+		err    error
+	)
+	_, explicit := ctx.Value(proxyCtxKey{}).(string)
+	if activePool != nil && !explicit {
+		pool, label := activePool, accountOf(ctx)
+		noProxy := getenvAny("NO_PROXY", "no_proxy")
+		sel = func(target *url.URL) (*url.URL, error) {
+			if noProxyMatch(target.Hostname(), targetPort(target), noProxy) {
+				return nil, nil
+			}
+			p := pool.Bind(label)
+			if p == "" {
+				return nil, errNoPoolProxy
+			}
+			return parseProxyURL(p)
+		}
+		onFail = func(p *url.URL, err error) { pool.markFailed(p.Host, err) }
+	} else if sel, err = currentSelector(ctx); err != nil {
+		return nil, err
+	}
+	if sel == nil {
+		return nil, nil
+	}
+	return awshttp.NewBuildableClient().WithTimeout(30 * time.Second).WithTransportOptions(func(tr *http.Transport) {
+		configureProxy(tr, sel, onFail)
+	}), nil
+}
+
+// -------------------- 代理池 --------------------
+
+// proxyCheckURL 返回请求方的公网 IP，用于测量延迟与出口 IP
+const proxyCheckURL = "https://checkip.amazonaws.com"
+
+type ProxyEntry struct {
+	URL       string    `json:"url" yaml:"url"`
+	Alive     bool      `json:"alive" yaml:"alive"`
+	LatencyMS int64     `json:"latency_ms" yaml:"latency_ms"`
+	ExitIP    string    `json:"exit_ip" yaml:"exit_ip"`
+	Error     string    `json:"error,omitempty" yaml:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at" yaml:"checked_at"`
+}
+
+// ProxyPool 管理一组代理与账户的绑定关系；账户首次使用时分配负载最少的存活代理，
+// 之后固定使用，直到该代理失效才切换
+type ProxyPool struct {
+	mu      sync.Mutex
+	Entries []*ProxyEntry
+	bind    map[string]*ProxyEntry
+}
+
+// activePool 为本次会话加载的代理池，未加载时为 nil
+var activePool *ProxyPool
+
+func newProxyPool(urls []string) *ProxyPool {
+	p := &ProxyPool{bind: map[string]*ProxyEntry{}}
+	for _, u := range urls {
+		p.add(u)
+	}
+	return p
+}
+
+func (p *ProxyPool) add(u string) *ProxyEntry {
+	for _, e := range p.Entries {
+		if e.URL == u {
+			return e
+		}
+	}
+	e := &ProxyEntry{URL: u, Alive: true}
+	p.Entries = append(p.Entries, e)
+	return e
+}
+
+// loadProxyPool 从文件读取代理，每行一个，格式同 parseProxyString；空行与 # 开头的行忽略
+func loadProxyPool(path string) (*ProxyPool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var urls []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, parseProxyString(line))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(urls) == 0 {
+		return nil, errors.New("代理文件中没有代理")
+	}
+	return newProxyPool(urls), nil
+}
+
+// Pin 把账户固定到指定代理 (不在池中时加入池)
+func (p *ProxyPool) Pin(label, proxy string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bind[label] = p.add(proxy)
+}
+
+// Bind 返回账户当前绑定的代理；未绑定或已失效时重新分配，池中无存活代理时返回空
+func (p *ProxyPool) Bind(label string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.bind[label]; ok && e.Alive {
+		return e.URL
+	}
+	load := map[*ProxyEntry]int{}
+	for _, e := range p.bind {
+		load[e]++
+	}
+	var best *ProxyEntry
+	for _, e := range p.Entries {
+		if e.Alive && (best == nil || load[e] < load[best]) {
+			best = e
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	if old, ok := p.bind[label]; ok {
+		fmt.Fprintf(os.Stderr, "🔁 [%s] 代理 %s 失效，切换到 %s\n", label, old.URL, best.URL)
+	}
+	p.bind[label] = best
+	return best.URL
+}
+
+// markFailed 把连接失败的代理标记为失效，绑定到它的账户下次请求时会切换到其他代理
+func (p *ProxyPool) markFailed(host string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range p.Entries {
+		if u, perr := url.Parse(e.URL); perr == nil && u.Host == host {
+			e.Alive, e.Error = false, err.Error()
+		}
+	}
+}
+
+// checkProxy 通过代理请求 proxyCheckURL，记录延迟、出口 IP 与存活状态
+func checkProxy(ctx context.Context, e *ProxyEntry) {
+	e.CheckedAt = time.Now()
+	u, err := parseProxyURL(e.URL)
+	if err != nil {
+		e.Alive, e.Error = false, err.Error()
+		return
+	}
+	ip, d, err := probeExitIP(ctx, func(*url.URL) (*url.URL, error) { return u, nil })
+	if err != nil {
+		e.Alive, e.Error = false, err.Error()
+		return
+	}
+	e.Alive, e.Error = true, ""
+	e.LatencyMS, e.ExitIP = d.Milliseconds(), ip
+}
+
+// CheckAll 并发检测池中全部代理
+func (p *ProxyPool) CheckAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for _, e := range p.Entries {
+		wg.Add(1)
+		go func(e *ProxyEntry) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			res := &ProxyEntry{URL: e.URL}
+			checkProxy(ctx, res)
+			p.mu.Lock()
+			*e = *res
+			p.mu.Unlock()
+		}(e)
+	}
+	wg.Wait()
+}
+
+func (p *ProxyPool) aliveCount() int {
+	n := 0
+	for _, e := range p.Entries {
+		if e.Alive {
+			n++
+		}
+	}
+	return n
+}
+
+// maskProxy 隐藏代理 URL 中的密码
+func maskProxy(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.Redacted()
+}
+
+func proxyPoolReport(p *ProxyPool) Report {
+	r := Report{
+		Header:  "序号\t代理\t状态\t延迟\t出口IP\t错误",
+		Columns: []string{"idx", "url", "alive", "latency_ms", "exit_ip", "error"},
+		Cut:     map[int]int{1: 45, 5: 50},
+		Data:    p.Entries,
+	}
+	for i, e := range p.Entries {
+		state, latency := "❌ 失效", "-"
+		if e.Alive {
+			state, latency = "✅ 可用", fmt.Sprintf("%dms", e.LatencyMS)
+		}
+		r.Rows = append(r.Rows, []string{fmt.Sprint(i + 1), maskProxy(e.URL), state, latency, e.ExitIP, e.Error})
+	}
+	return r
+}
+
+// printProxyBindings 显示账户与代理的当前绑定关系
+func printProxyBindings(p *ProxyPool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.bind) == 0 {
+		return
+	}
+	printTable("账户\t代理", func(w *tabwriter.Writer) {
+		for label, e := range p.bind {
+			fmt.Fprintf(w, "%s\t%s\n", label, maskProxy(e.URL))
+		}
+	})
+}
+
+// setupProxyPool 加载并检测代理池，至少有一个可用代理时启用
+func setupProxyPool(ctx context.Context, path string) error {
+	pool, err := loadProxyPool(path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "🩺 检测 %d 个代理...\n", len(pool.Entries))
+	pool.CheckAll(ctx)
+	writeReport(os.Stderr, "table", proxyPoolReport(pool))
+	if pool.aliveCount() == 0 {
+		return errors.New("代理池中没有可用代理")
+	}
+	activePool = pool
+	return nil
+}
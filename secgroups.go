@@ -0,0 +1,709 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2t "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// -------------------- 安全组管理 --------------------
+
+// 安全组挂载在网卡上，这里只管理实例的主网卡 (设备号 0)。
+// 规则按来源拆成单行展示，删除时也只撤销该来源，不影响同一条权限中的其他 CIDR。
+
+// SGRuleRow 为安全组中的一条规则 (一个来源一行)
+type SGRuleRow struct {
+	GroupID     string `json:"group_id" yaml:"group_id"`
+	GroupName   string `json:"group_name" yaml:"group_name"`
+	Direction   string `json:"direction" yaml:"direction"`
+	Protocol    string `json:"protocol" yaml:"protocol"`
+	Ports       string `json:"ports" yaml:"ports"`
+	Source      string `json:"source" yaml:"source"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	perm ec2t.IpPermission // 仅含本行来源，用于撤销
+}
+
+// InstanceSGs 为实例主网卡及其当前挂载的安全组
+type InstanceSGs struct {
+	InstanceID, ENI, VpcID string
+	Groups                 []ec2t.SecurityGroup
+}
+
+func (s InstanceSGs) groupIDs() []string {
+	var ids []string
+	for _, g := range s.Groups {
+		ids = append(ids, aws.ToString(g.GroupId))
+	}
+	return ids
+}
+
+// instanceSGs 查询实例主网卡上挂载的安全组及其规则
+func instanceSGs(ctx context.Context, cli EC2API, instanceID string) (InstanceSGs, error) {
+	out, err := cli.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}})
+	if err != nil {
+		return InstanceSGs{}, err
+	}
+	if len(out.Reservations) == 0 || len(out.Reservations[0].Instances) == 0 {
+		return InstanceSGs{}, fmt.Errorf("未找到实例 %s", instanceID)
+	}
+	ins := out.Reservations[0].Instances[0]
+	res := InstanceSGs{InstanceID: instanceID, VpcID: aws.ToString(ins.VpcId)}
+	refs := ins.SecurityGroups
+	for _, ni := range ins.NetworkInterfaces {
+		if ni.Attachment == nil || aws.ToInt32(ni.Attachment.DeviceIndex) == 0 {
+			res.ENI = aws.ToString(ni.NetworkInterfaceId)
+			if len(ni.Groups) > 0 {
+				refs = ni.Groups
+			}
+			break
+		}
+	}
+	if res.ENI == "" {
+		return res, errors.New("无法找到网络接口 (ENI)")
+	}
+	var ids []string
+	for _, g := range refs {
+		ids = append(ids, aws.ToString(g.GroupId))
+	}
+	if len(ids) == 0 {
+		return res, nil
+	}
+	sgs, err := cli.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{GroupIds: ids})
+	if err != nil {
+		return res, err
+	}
+	// 保持网卡上的挂载顺序
+	for _, id := range ids {
+		for _, g := range sgs.SecurityGroups {
+			if aws.ToString(g.GroupId) == id {
+				res.Groups = append(res.Groups, g)
+			}
+		}
+	}
+	return res, nil
+}
+
+func protocolName(p string) string {
+	switch p {
+	case "-1":
+		return "all"
+	case "6":
+		return "tcp"
+	case "17":
+		return "udp"
+	case "1":
+		return "icmp"
+	case "58":
+		return "icmpv6"
+	}
+	return p
+}
+
+func permPorts(p ec2t.IpPermission) string {
+	if aws.ToString(p.IpProtocol) == "-1" || p.FromPort == nil || aws.ToInt32(p.FromPort) == -1 {
+		return "全部"
+	}
+	from, to := aws.ToInt32(p.FromPort), aws.ToInt32(p.ToPort)
+	if from == to || strings.HasPrefix(protocolName(aws.ToString(p.IpProtocol)), "icmp") {
+		return strconv.Itoa(int(from))
+	}
+	return fmt.Sprintf("%d-%d", from, to)
+}
+
+// splitPermission 把一条权限按来源拆分，每个结果只含一个 CIDR/安全组/前缀列表
+func splitPermission(p ec2t.IpPermission) []ec2t.IpPermission {
+	base := ec2t.IpPermission{IpProtocol: p.IpProtocol, FromPort: p.FromPort, ToPort: p.ToPort}
+	var out []ec2t.IpPermission
+	for _, r := range p.IpRanges {
+		x := base
+		x.IpRanges = []ec2t.IpRange{r}
+		out = append(out, x)
+	}
+	for _, r := range p.Ipv6Ranges {
+		x := base
+		x.Ipv6Ranges = []ec2t.Ipv6Range{r}
+		out = append(out, x)
+	}
+	for _, r := range p.UserIdGroupPairs {
+		x := base
+		x.UserIdGroupPairs = []ec2t.UserIdGroupPair{r}
+		out = append(out, x)
+	}
+	for _, r := range p.PrefixListIds {
+		x := base
+		x.PrefixListIds = []ec2t.PrefixListId{r}
+		out = append(out, x)
+	}
+	return out
+}
+
+// permSource 返回单来源权限的来源与描述
+func permSource(p ec2t.IpPermission) (string, string) {
+	switch {
+	case len(p.IpRanges) > 0:
+		return aws.ToString(p.IpRanges[0].CidrIp), aws.ToString(p.IpRanges[0].Description)
+	case len(p.Ipv6Ranges) > 0:
+		return aws.ToString(p.Ipv6Ranges[0].CidrIpv6), aws.ToString(p.Ipv6Ranges[0].Description)
+	case len(p.UserIdGroupPairs) > 0:
+		return aws.ToString(p.UserIdGroupPairs[0].GroupId), aws.ToString(p.UserIdGroupPairs[0].Description)
+	case len(p.PrefixListIds) > 0:
+		return aws.ToString(p.PrefixListIds[0].PrefixListId), aws.ToString(p.PrefixListIds[0].Description)
+	}
+	return "", ""
+}
+
+// permKey 为单来源权限生成比较用的键 (忽略描述)
+func permKey(p ec2t.IpPermission) string {
+	src, _ := permSource(p)
+	return protocolName(aws.ToString(p.IpProtocol)) + "|" + permPorts(p) + "|" + src
+}
+
+// sgRules 展开安全组的规则；egress 为 true 时展开出站规则
+func sgRules(groups []ec2t.SecurityGroup, egress bool) []SGRuleRow {
+	var rows []SGRuleRow
+	dir := "入站"
+	if egress {
+		dir = "出站"
+	}
+	for _, g := range groups {
+		perms := g.IpPermissions
+		if egress {
+			perms = g.IpPermissionsEgress
+		}
+		for _, p := range perms {
+			for _, one := range splitPermission(p) {
+				src, desc := permSource(one)
+				rows = append(rows, SGRuleRow{
+					GroupID: aws.ToString(g.GroupId), GroupName: aws.ToString(g.GroupName), Direction: dir,
+					Protocol: protocolName(aws.ToString(one.IpProtocol)), Ports: permPorts(one), Source: src, Description: desc,
+					perm: one,
+				})
+			}
+		}
+	}
+	return rows
+}
+
+func sgRuleReport(rows []SGRuleRow) Report {
+	r := Report{
+		Header:  "#\t安全组\t名称\t方向\t协议\t端口\t来源\t描述",
+		Columns: []string{"index", "group_id", "group_name", "direction", "protocol", "ports", "source", "description"},
+		Cut:     map[int]int{2: 24, 7: 30},
+		Data:    rows,
+	}
+	if rows == nil {
+		r.Data = []SGRuleRow{}
+	}
+	for i, x := range rows {
+		r.Rows = append(r.Rows, []string{strconv.Itoa(i + 1), x.GroupID, x.GroupName, x.Direction, x.Protocol, x.Ports, x.Source, x.Description})
+	}
+	return r
+}
+
+// parseSGSource 解析规则来源：CIDR、单个 IP (转为 /32 或 /128)、sg-xxx 或 pl-xxx
+func parseSGSource(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "sg-") || strings.HasPrefix(s, "pl-") {
+		return s, nil
+	}
+	if a, err := netip.ParseAddr(s); err == nil {
+		return netip.PrefixFrom(a, a.BitLen()).String(), nil
+	}
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return "", fmt.Errorf("来源无效: %q (应为 CIDR、IP、sg-xxx 或 pl-xxx)", s)
+	}
+	return p.Masked().String(), nil
+}
+
+// sgPermissions 由端口规则 (parsePortRule 格式，另支持 icmp) 与来源列表构造安全组权限；
+// icmp 对 IPv4 与 IPv6 来源分别生成 icmp / icmpv6 两条权限
+func sgPermissions(rule string, sources []string, desc string) ([]ec2t.IpPermission, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("缺少来源")
+	}
+	var base ec2t.IpPermission
+	icmp := strings.EqualFold(strings.TrimSpace(rule), "icmp")
+	if icmp {
+		base = ec2t.IpPermission{IpProtocol: aws.String("icmp"), FromPort: aws.Int32(-1), ToPort: aws.Int32(-1)}
+	} else {
+		r, err := parsePortRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		base = r.ec2Permission()
+		base.IpRanges, base.Ipv6Ranges = nil, nil
+	}
+	v4, v6 := base, base
+	if icmp {
+		v6.IpProtocol = aws.String("icmpv6")
+	}
+	var d *string
+	if desc != "" {
+		d = aws.String(desc)
+	}
+	for _, s := range sources {
+		src, err := parseSGSource(s)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case strings.HasPrefix(src, "sg-"):
+			v4.UserIdGroupPairs = append(v4.UserIdGroupPairs, ec2t.UserIdGroupPair{GroupId: aws.String(src), Description: d})
+		case strings.HasPrefix(src, "pl-"):
+			v4.PrefixListIds = append(v4.PrefixListIds, ec2t.PrefixListId{PrefixListId: aws.String(src), Description: d})
+		case strings.Contains(src, ":"):
+			v6.Ipv6Ranges = append(v6.Ipv6Ranges, ec2t.Ipv6Range{CidrIpv6: aws.String(src), Description: d})
+		default:
+			v4.IpRanges = append(v4.IpRanges, ec2t.IpRange{CidrIp: aws.String(src), Description: d})
+		}
+	}
+	if !icmp {
+		v4.Ipv6Ranges = v6.Ipv6Ranges
+		return []ec2t.IpPermission{v4}, nil
+	}
+	var out []ec2t.IpPermission
+	if len(v4.IpRanges)+len(v4.UserIdGroupPairs)+len(v4.PrefixListIds) > 0 {
+		out = append(out, v4)
+	}
+	if len(v6.Ipv6Ranges) > 0 {
+		out = append(out, v6)
+	}
+	return out, nil
+}
+
+// myPublicIP 返回本机访问公网时的出口 IP；配置了 SOCKS5 代理时与 SSH 一样经代理出口
+func myPublicIP(ctx context.Context) (string, error) {
+	sel := proxySelector(func(*url.URL) (*url.URL, error) { return nil, nil })
+	if cur, err := currentSelector(ctx); err == nil && cur != nil {
+		sel = func(u *url.URL) (*url.URL, error) {
+			p, err := cur(u)
+			if err != nil || !isSOCKS(p) {
+				return nil, err
+			}
+			return p, nil
+		}
+	}
+	ip, _, err := probeExitIP(ctx, sel)
+	if err != nil {
+		return "", fmt.Errorf("获取当前公网 IP 失败: %v", err)
+	}
+	if _, err := netip.ParseAddr(ip); err != nil {
+		return "", fmt.Errorf("获取当前公网 IP 失败: 响应无效 %q", ip)
+	}
+	return ip, nil
+}
+
+// resolveSources 把来源列表中的 "my" 替换为当前公网 IP
+func resolveSources(ctx context.Context, sources []string) ([]string, error) {
+	out := make([]string, 0, len(sources))
+	for _, s := range sources {
+		if strings.EqualFold(s, "my") {
+			ip, err := myPublicIP(ctx)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Println("🌐 当前公网 IP:", ip)
+			s = ip
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func addSGRules(ctx context.Context, cli EC2API, groupID string, perms []ec2t.IpPermission) error {
+	_, err := cli.AuthorizeSecurityGroupIngress(ctx, &ec2.AuthorizeSecurityGroupIngressInput{GroupId: aws.String(groupID), IpPermissions: perms})
+	return err
+}
+
+func revokeSGRules(ctx context.Context, cli EC2API, groupID string, perms []ec2t.IpPermission) error {
+	_, err := cli.RevokeSecurityGroupIngress(ctx, &ec2.RevokeSecurityGroupIngressInput{GroupId: aws.String(groupID), IpPermissions: perms})
+	return err
+}
+
+func revokeSGRule(ctx context.Context, cli EC2API, row SGRuleRow) error {
+	return revokeSGRules(ctx, cli, row.GroupID, []ec2t.IpPermission{row.perm})
+}
+
+// setENIGroups 用 groupIDs 整体替换网卡上的安全组
+func setENIGroups(ctx context.Context, cli EC2API, eniID string, groupIDs []string) error {
+	if len(groupIDs) == 0 {
+		return errors.New("网卡至少需要保留一个安全组")
+	}
+	_, err := cli.ModifyNetworkInterfaceAttribute(ctx, &ec2.ModifyNetworkInterfaceAttributeInput{
+		NetworkInterfaceId: aws.String(eniID), Groups: groupIDs,
+	})
+	return err
+}
+
+// sgUsers 返回挂载了 groupID 的实例 ID
+func sgUsers(ctx context.Context, cli EC2API, groupID string) []string {
+	out, err := cli.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: []ec2t.Filter{{Name: aws.String("instance.group-id"), Values: []string{groupID}}},
+	})
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, r := range out.Reservations {
+		for _, ins := range r.Instances {
+			if ins.State != nil && ins.State.Name == ec2t.InstanceStateNameTerminated {
+				continue
+			}
+			ids = append(ids, aws.ToString(ins.InstanceId))
+		}
+	}
+	return ids
+}
+
+// warnShared 在修改被其他实例共用的安全组前给出提示
+func warnShared(ctx context.Context, cli EC2API, groupID, instanceID string) {
+	n := 0
+	for _, id := range sgUsers(ctx, cli, groupID) {
+		if id != instanceID {
+			n++
+		}
+	}
+	if n > 0 {
+		fmt.Printf("⚠️ 安全组 %s 同时挂载在另外 %d 台实例上，修改会一并生效\n", groupID, n)
+	}
+}
+
+// -------------------- 安全组预设 --------------------
+
+// SGPreset 为常用的入站规则组合；MyIP 为 true 时只放行当前公网 IP
+type SGPreset struct {
+	Name, Title string
+	Ports       []string
+	MyIP        bool
+}
+
+var sgPresets = []SGPreset{
+	{Name: "ssh", Title: "仅 SSH (22)", Ports: []string{"22"}},
+	{Name: "web", Title: "Web (80/443)", Ports: []string{"80", "443"}},
+	{Name: "ssh-web", Title: "SSH + Web (22/80/443)", Ports: []string{"22", "80", "443"}},
+	{Name: "ssh-myip", Title: "仅当前 IP 可 SSH (22)", Ports: []string{"22"}, MyIP: true},
+}
+
+func findSGPreset(name string) (SGPreset, error) {
+	var names []string
+	for _, p := range sgPresets {
+		if p.Name == name {
+			return p, nil
+		}
+		names = append(names, p.Name)
+	}
+	return SGPreset{}, fmt.Errorf("未知预设: %s (可选 %s)", name, strings.Join(names, "/"))
+}
+
+// ensurePresetSG 在 vpcID 中查找或创建预设对应的安全组。
+// 组名固定为 preset-<名称>，当前 IP 预设为 preset-<名称>-<IP>，同一 VPC 内的实例共用
+func ensurePresetSG(ctx context.Context, cli EC2API, vpcID string, p SGPreset) (string, error) {
+	name, sources := "preset-"+p.Name, []string{"0.0.0.0/0", "::/0"}
+	if p.MyIP {
+		ip, err := myPublicIP(ctx)
+		if err != nil {
+			return "", err
+		}
+		fmt.Println("🌐 当前公网 IP:", ip)
+		name, sources = name+"-"+ip, []string{ip}
+	}
+	var perms []ec2t.IpPermission
+	for _, port := range p.Ports {
+		ps, err := sgPermissions(port, sources, "preset "+p.Name)
+		if err != nil {
+			return "", err
+		}
+		perms = append(perms, ps...)
+	}
+	return ensureRuleSG(ctx, cli, vpcID, name, "Preset "+p.Name, perms)
+}
+
+// ensureRuleSG 按名称查找或创建入站规则恰为 perms 的安全组。
+// 新建后授权失败时删除该组，避免留下同名空组被下次复用；复用已有组前核对规则，
+// 缺少的补齐，多出预期以外的规则 (被手动改过) 时报错，不挂载内容未知的组
+func ensureRuleSG(ctx context.Context, cli EC2API, vpcID, name, desc string, perms []ec2t.IpPermission) (string, error) {
+	sgs, err := cli.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []ec2t.Filter{{Name: aws.String("group-name"), Values: []string{name}}, {Name: aws.String("vpc-id"), Values: []string{vpcID}}},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(sgs.SecurityGroups) > 0 {
+		sg := sgs.SecurityGroups[0]
+		id := aws.ToString(sg.GroupId)
+		have, want := map[string]bool{}, map[string]bool{}
+		var missing []ec2t.IpPermission
+		for _, p := range sg.IpPermissions {
+			for _, one := range splitPermission(p) {
+				have[permKey(one)] = true
+			}
+		}
+		for _, p := range perms {
+			for _, one := range splitPermission(p) {
+				want[permKey(one)] = true
+				if !have[permKey(one)] {
+					missing = append(missing, one)
+				}
+			}
+		}
+		for _, p := range sg.IpPermissions {
+			for _, one := range splitPermission(p) {
+				if !want[permKey(one)] {
+					src, _ := permSource(one)
+					return "", fmt.Errorf("安全组 %s (%s) 含有预期以外的规则 %s %s ← %s，请检查或删除该组后重试",
+						name, id, protocolName(aws.ToString(one.IpProtocol)), permPorts(one), src)
+				}
+			}
+		}
+		if len(missing) > 0 {
+			fmt.Printf("⚠️ 安全组 %s 缺少 %d 条规则，正在补齐\n", name, len(missing))
+			if err := addSGRules(ctx, cli, id, missing); err != nil {
+				return "", fmt.Errorf("补齐安全组 %s 规则失败: %v", name, err)
+			}
+		}
+		return id, nil
+	}
+	res, err := cli.CreateSecurityGroup(ctx, &ec2.CreateSecurityGroupInput{
+		GroupName: aws.String(name), Description: aws.String(desc), VpcId: aws.String(vpcID),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := addSGRules(ctx, cli, *res.GroupId, perms); err != nil {
+		if _, derr := cli.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{GroupId: res.GroupId}); derr != nil {
+			return "", fmt.Errorf("%v (清理空安全组 %s 失败: %v)", err, name, derr)
+		}
+		return "", err
+	}
+	return *res.GroupId, nil
+}
+
+// applySGPreset 把预设安全组挂到实例主网卡；replace 为 true 时卸下其他安全组
+func applySGPreset(ctx context.Context, cli EC2API, cur InstanceSGs, p SGPreset, replace bool) (string, error) {
+	id, err := ensurePresetSG(ctx, cli, cur.VpcID, p)
+	if err != nil {
+		return "", err
+	}
+	groups := []string{id}
+	if !replace {
+		groups = cur.groupIDs()
+		if !slices.Contains(groups, id) {
+			groups = append(groups, id)
+		}
+	}
+	return id, setENIGroups(ctx, cli, cur.ENI, groups)
+}
+
+// -------------------- 交互式安全组管理 --------------------
+
+func sgMenu(ctx context.Context, cli EC2API, instanceID string) {
+	for {
+		cur, err := instanceSGs(ctx, cli, instanceID)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		fmt.Printf("\n--- 🛡️ 安全组管理 (%s, 网卡 %s) ---\n", instanceID, cur.ENI)
+		for i, g := range cur.Groups {
+			fmt.Printf(" [%d] %s  %s  (%s)\n", i+1, aws.ToString(g.GroupId), aws.ToString(g.GroupName), aws.ToString(g.Description))
+		}
+		rules := sgRules(cur.Groups, false)
+		fmt.Println()
+		if len(rules) == 0 {
+			fmt.Println("(无入站规则，所有入站连接都会被拒绝)")
+		} else {
+			writeReport(os.Stdout, "table", sgRuleReport(rules))
+		}
+		fmt.Println("\n 1) ➕ 添加入站规则  2) ➖ 删除入站规则  3) 🔗 挂载安全组  4) ✂️ 卸载安全组")
+		fmt.Println(" 5) 🧩 应用预设      6) 📤 查看出站规则  0) 返回")
+		switch input("选择: ", "0") {
+		case "1":
+			sgAddInteractive(ctx, cli, cur)
+		case "2":
+			sgRemoveInteractive(ctx, cli, cur, rules)
+		case "3":
+			sgAttachInteractive(ctx, cli, cur)
+		case "4":
+			sgDetachInteractive(ctx, cli, cur)
+		case "5":
+			sgPresetInteractive(ctx, cli, cur)
+		case "6":
+			out := sgRules(cur.Groups, true)
+			if len(out) == 0 {
+				fmt.Println("(无出站规则)")
+			} else {
+				writeReport(os.Stdout, "table", sgRuleReport(out))
+			}
+		default:
+			return
+		}
+	}
+}
+
+// pickGroup 在已挂载的安全组中选择一个，只有一个时直接返回
+func pickGroup(cur InstanceSGs) (ec2t.SecurityGroup, bool) {
+	switch len(cur.Groups) {
+	case 0:
+		fmt.Println("❌ 实例未挂载任何安全组")
+		return ec2t.SecurityGroup{}, false
+	case 1:
+		return cur.Groups[0], true
+	}
+	idx := mustInt(input("选择安全组序号 [1]: ", "1"))
+	if idx < 1 || idx > len(cur.Groups) {
+		fmt.Println("❌ 序号无效")
+		return ec2t.SecurityGroup{}, false
+	}
+	return cur.Groups[idx-1], true
+}
+
+func sgAddInteractive(ctx context.Context, cli EC2API, cur InstanceSGs) {
+	g, ok := pickGroup(cur)
+	if !ok {
+		return
+	}
+	id := aws.ToString(g.GroupId)
+	warnShared(ctx, cli, id, cur.InstanceID)
+	rule := input("端口 (22、80/tcp、1000-2000/udp、icmp、all) [22]: ", "22")
+	sources := splitList(input("来源，逗号分隔 (CIDR/IP/sg-xxx，my=当前公网 IP) [0.0.0.0/0,::/0]: ", "0.0.0.0/0,::/0"))
+	desc := input("描述 (可留空): ", "")
+	sources, err := resolveSources(ctx, sources)
+	if err != nil {
+		fmt.Println("❌", err)
+		return
+	}
+	perms, err := sgPermissions(rule, sources, desc)
+	if err != nil {
+		fmt.Println("❌", err)
+		return
+	}
+	if err := addSGRules(ctx, cli, id, perms); err != nil {
+		fmt.Println("❌ 添加失败:", err)
+		return
+	}
+	fmt.Printf("✅ 已在 %s 中放行 %s ← %s\n", id, rule, strings.Join(sources, ", "))
+}
+
+func sgRemoveInteractive(ctx context.Context, cli EC2API, cur InstanceSGs, rules []SGRuleRow) {
+	if len(rules) == 0 {
+		fmt.Println("❌ 没有可删除的规则")
+		return
+	}
+	idx, err := parseSelection(input("要删除的规则序号 (如 1,3-4): ", ""), len(rules))
+	if err != nil || len(idx) == 0 {
+		fmt.Println("❌ 未选择规则")
+		return
+	}
+	warned := map[string]bool{}
+	for _, i := range idx {
+		if !warned[rules[i].GroupID] {
+			warned[rules[i].GroupID] = true
+			warnShared(ctx, cli, rules[i].GroupID, cur.InstanceID)
+		}
+	}
+	if !yes(input(fmt.Sprintf("确认删除 %d 条规则? [y/N]: ", len(idx)), "n")) {
+		return
+	}
+	for _, i := range idx {
+		r := rules[i]
+		if err := revokeSGRule(ctx, cli, r); err != nil {
+			fmt.Printf("❌ %s %s/%s ← %s: %v\n", r.GroupID, r.Ports, r.Protocol, r.Source, err)
+			continue
+		}
+		fmt.Printf("✅ 已删除 %s %s/%s ← %s\n", r.GroupID, r.Ports, r.Protocol, r.Source)
+	}
+}
+
+func sgAttachInteractive(ctx context.Context, cli EC2API, cur InstanceSGs) {
+	out, err := cli.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []ec2t.Filter{{Name: aws.String("vpc-id"), Values: []string{cur.VpcID}}},
+	})
+	if err != nil {
+		fmt.Println("❌", err)
+		return
+	}
+	attached := cur.groupIDs()
+	var cands []ec2t.SecurityGroup
+	for _, g := range out.SecurityGroups {
+		if !slices.Contains(attached, aws.ToString(g.GroupId)) {
+			cands = append(cands, g)
+		}
+	}
+	if len(cands) == 0 {
+		fmt.Println("❌ VPC 中没有其他可挂载的安全组")
+		return
+	}
+	for i, g := range cands {
+		fmt.Printf(" %d) %s  %s  (%s)\n", i+1, aws.ToString(g.GroupId), aws.ToString(g.GroupName), aws.ToString(g.Description))
+	}
+	idx, err := parseSelection(input("要挂载的序号 (如 1,3): ", ""), len(cands))
+	if err != nil || len(idx) == 0 {
+		fmt.Println("❌ 未选择安全组")
+		return
+	}
+	for _, i := range idx {
+		attached = append(attached, aws.ToString(cands[i].GroupId))
+	}
+	if err := setENIGroups(ctx, cli, cur.ENI, attached); err != nil {
+		fmt.Println("❌ 挂载失败:", err)
+		return
+	}
+	fmt.Println("✅ 已挂载，当前安全组:", strings.Join(attached, ", "))
+}
+
+func sgDetachInteractive(ctx context.Context, cli EC2API, cur InstanceSGs) {
+	if len(cur.Groups) <= 1 {
+		fmt.Println("❌ 网卡至少需要保留一个安全组，请先挂载其他安全组")
+		return
+	}
+	idx, err := parseSelection(input("要卸载的安全组序号 (如 1,2): ", ""), len(cur.Groups))
+	if err != nil || len(idx) == 0 {
+		fmt.Println("❌ 未选择安全组")
+		return
+	}
+	drop := map[int]bool{}
+	for _, i := range idx {
+		drop[i] = true
+	}
+	var keep []string
+	for i, id := range cur.groupIDs() {
+		if !drop[i] {
+			keep = append(keep, id)
+		}
+	}
+	if err := setENIGroups(ctx, cli, cur.ENI, keep); err != nil {
+		fmt.Println("❌ 卸载失败:", err)
+		return
+	}
+	fmt.Println("✅ 已卸载，当前安全组:", strings.Join(keep, ", "))
+}
+
+func sgPresetInteractive(ctx context.Context, cli EC2API, cur InstanceSGs) {
+	for i, p := range sgPresets {
+		fmt.Printf(" %d) %s\n", i+1, p.Title)
+	}
+	idx := mustInt(input("选择预设: ", "0"))
+	if idx < 1 || idx > len(sgPresets) {
+		return
+	}
+	p := sgPresets[idx-1]
+	replace := yes(input("同时卸下当前其他安全组 (只保留预设)? [Y/n]: ", "y"))
+	id, err := applySGPreset(ctx, cli, cur, p, replace)
+	if err != nil {
+		fmt.Println("❌ 应用预设失败:", err)
+		return
+	}
+	fmt.Printf("✅ 已应用预设「%s」(%s)\n", p.Title, id)
+}
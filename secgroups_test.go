@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2t "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func findGroup(s *fakeEC2State, name string) *ec2t.SecurityGroup {
+	for i := range s.Groups {
+		if aws.ToString(s.Groups[i].GroupName) == name {
+			return &s.Groups[i]
+		}
+	}
+	return nil
+}
+
+func TestEnsurePresetSGCleansUpOnAuthorizeFailure(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newEC2Client(aws.Config{Region: "us-east-1"})
+	ctx := context.Background()
+	vpcID, _ := defaultVpcID(ctx, cli)
+	web, _ := findSGPreset("web")
+
+	b.FailNext("ec2:AuthorizeSecurityGroupIngress", fakeAPIError("RulesPerSecurityGroupLimitExceeded", "The maximum number of rules per security group has been reached."))
+	if _, err := ensurePresetSG(ctx, cli, vpcID, web); err == nil {
+		t.Fatal("授权失败应报错")
+	}
+	if findGroup(b.ec2State("us-east-1"), "preset-web") != nil {
+		t.Fatal("授权失败后应删除新建的空安全组")
+	}
+
+	// 再次调用时重新创建并授权
+	id, err := ensurePresetSG(ctx, cli, vpcID, web)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg := findGroup(b.ec2State("us-east-1"), "preset-web")
+	if sg == nil || aws.ToString(sg.GroupId) != id || len(sg.IpPermissions) == 0 {
+		t.Errorf("应创建带规则的 preset-web: %+v", sg)
+	}
+}
+
+func TestEnsurePresetSGVerifiesReusedGroup(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newEC2Client(aws.Config{Region: "us-east-1"})
+	ctx := context.Background()
+	vpcID, _ := defaultVpcID(ctx, cli)
+	web, _ := findSGPreset("web")
+	id, err := ensurePresetSG(ctx, cli, vpcID, web)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 缺少的规则在复用时补齐
+	if _, err := cli.RevokeSecurityGroupIngress(ctx, &ec2.RevokeSecurityGroupIngressInput{
+		GroupId: aws.String(id),
+		IpPermissions: []ec2t.IpPermission{{
+			IpProtocol: aws.String("tcp"), FromPort: aws.Int32(443), ToPort: aws.Int32(443),
+			IpRanges: []ec2t.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ensurePresetSG(ctx, cli, vpcID, web); err != nil || got != id {
+		t.Fatalf("应复用并补齐 %s，得到 %s %v", id, got, err)
+	}
+	if _, err := ensurePresetSG(ctx, cli, vpcID, web); err != nil {
+		t.Fatalf("补齐后再次复用不应报错: %v", err)
+	}
+
+	// 被手动加了预期以外的规则时拒绝复用
+	if _, err := cli.AuthorizeSecurityGroupIngress(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId: aws.String(id),
+		IpPermissions: []ec2t.IpPermission{{
+			IpProtocol: aws.String("tcp"), FromPort: aws.Int32(3306), ToPort: aws.Int32(3306),
+			IpRanges: []ec2t.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ensurePresetSG(ctx, cli, vpcID, web); err == nil || !strings.Contains(err.Error(), "预期以外") {
+		t.Errorf("含有多余规则时应报错，得到 %v", err)
+	}
+	if n := len(b.ec2State("us-east-1").Groups); n != 2 {
+		t.Errorf("不应新建其他安全组，实际 %d 个", n)
+	}
+}
+
+func TestEnsurePortsSG(t *testing.T) {
+	b := newTestBackend(t)
+	cli := newEC2Client(aws.Config{Region: "us-east-1"})
+	ctx := context.Background()
+	rules, err := parsePortRules([]string{"22", "60000-61000/udp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.FailNext("ec2:AuthorizeSecurityGroupIngress", fakeAPIError("UnauthorizedOperation", "You are not authorized to perform this operation."))
+	if _, _, err := ensurePortsSG(ctx, cli, rules); err == nil {
+		t.Fatal("授权失败应报错")
+	}
+	if n := len(b.ec2State("us-east-1").Groups); n != 1 {
+		t.Fatalf("授权失败后应删除新建的安全组，现有 %d 个", n)
+	}
+
+	id, _, err := ensurePortsSG(ctx, cli, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 规则顺序不同时复用同一个组
+	rev := []PortRule{rules[1], rules[0]}
+	if again, _, err := ensurePortsSG(ctx, cli, rev); err != nil || again != id {
+		t.Errorf("相同规则应复用 %s，得到 %s %v", id, again, err)
+	}
+}
+
+func TestParsePortRuleRejectsAllSuffix(t *testing.T) {
+	if r, err := parsePortRule("all"); err != nil || r.Protocol != "all" {
+		t.Errorf("all 应放行全部: %+v %v", r, err)
+	}
+	for _, s := range []string{"22/all", "1000-2000/ALL"} {
+		if _, err := parsePortRule(s); err == nil {
+			t.Errorf("%q 应被拒绝", s)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2t "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// -------------------- Spot 实例 --------------------
+
+// SpotOptions 为 Spot 购买参数，模板中写在 spot 段
+type SpotOptions struct {
+	MaxPrice         string `yaml:"max_price,omitempty"`    // 每小时最高价 (USD)，留空为按需价
+	Persistent       bool   `yaml:"persistent,omitempty"`   // 持久请求：中断后自动重新拉起
+	Interruption     string `yaml:"interruption,omitempty"` // terminate|stop|hibernate
+	FallbackOnDemand bool   `yaml:"fallback_on_demand,omitempty"`
+}
+
+var spotInterruptions = []string{"terminate", "stop", "hibernate"}
+
+// normalize 校验参数；stop/hibernate 只能用于持久请求，此时自动切换为持久
+func (s *SpotOptions) normalize() error {
+	if s.Interruption == "" {
+		s.Interruption = "terminate"
+	}
+	valid := false
+	for _, v := range spotInterruptions {
+		valid = valid || s.Interruption == v
+	}
+	if !valid {
+		return fmt.Errorf("中断行为无效: %s (可选 %s)", s.Interruption, strings.Join(spotInterruptions, "|"))
+	}
+	if s.MaxPrice != "" {
+		if p, err := strconv.ParseFloat(s.MaxPrice, 64); err != nil || p <= 0 {
+			return fmt.Errorf("最高价无效: %s", s.MaxPrice)
+		}
+	}
+	if s.Interruption != "terminate" && !s.Persistent {
+		fmt.Printf("ℹ️ 中断行为 %s 需要持久请求，已自动切换为 persistent\n", s.Interruption)
+		s.Persistent = true
+	}
+	return nil
+}
+
+func (s *SpotOptions) marketOptions() *ec2t.InstanceMarketOptionsRequest {
+	o := &ec2t.SpotMarketOptions{
+		SpotInstanceType:             ec2t.SpotInstanceTypeOneTime,
+		InstanceInterruptionBehavior: ec2t.InstanceInterruptionBehavior(s.Interruption),
+	}
+	if s.Persistent {
+		o.SpotInstanceType = ec2t.SpotInstanceTypePersistent
+	}
+	if s.MaxPrice != "" {
+		o.MaxPrice = aws.String(s.MaxPrice)
+	}
+	return &ec2t.InstanceMarketOptionsRequest{MarketType: ec2t.MarketTypeSpot, SpotOptions: o}
+}
+
+func (s *SpotOptions) String() string {
+	kind := "一次性"
+	if s.Persistent {
+		kind = "持久"
+	}
+	price := "按需价封顶"
+	if s.MaxPrice != "" {
+		price = "$" + s.MaxPrice + "/h"
+	}
+	return fmt.Sprintf("Spot %s / %s / 中断时 %s", kind, price, s.Interruption)
+}
+
+// onDemandRefRegion 为 onDemandRef 价格表所属的区域，只有该区域的节省比例有意义
+const onDemandRefRegion = "us-east-1"
+
+// onDemandRef 为 us-east-1 Linux 按需参考价 (USD/小时)，仅用于对比展示，其他区域价格不同
+var onDemandRef = map[string]float64{
+	"t2.nano": 0.0058, "t2.micro": 0.0116, "t2.small": 0.023, "t2.medium": 0.0464,
+	"t3.nano": 0.0052, "t3.micro": 0.0104, "t3.small": 0.0208, "t3.medium": 0.0416, "t3.large": 0.0832,
+	"t3a.nano": 0.0047, "t3a.micro": 0.0094, "t3a.small": 0.0188, "t3a.medium": 0.0376,
+	"t4g.nano": 0.0042, "t4g.micro": 0.0084, "t4g.small": 0.0168, "t4g.medium": 0.0336, "t4g.large": 0.0672,
+	"c6i.large": 0.085, "c7i.large": 0.08925, "c6g.large": 0.068, "c7g.large": 0.0725,
+	"m6i.large": 0.096, "m6i.xlarge": 0.192, "m7i.large": 0.1008, "m6g.large": 0.077, "m7g.large": 0.0816,
+	"r6i.large": 0.126, "r6g.large": 0.1008,
+}
+
+// SpotPriceRow 为某可用区的 Spot 价格统计
+type SpotPriceRow struct {
+	AZ       string  `json:"az" yaml:"az"`
+	Current  float64 `json:"current" yaml:"current"`
+	Min      float64 `json:"min" yaml:"min"`
+	Max      float64 `json:"max" yaml:"max"`
+	Avg      float64 `json:"avg" yaml:"avg"`
+	OnDemand float64 `json:"on_demand_ref,omitempty" yaml:"on_demand_ref,omitempty"`
+}
+
+// spotPriceHistory 汇总 itype 在各可用区最近 window 内的 Spot 价格，按当前价升序
+func spotPriceHistory(ctx context.Context, cli EC2API, itype string, window time.Duration) ([]SpotPriceRow, error) {
+	type acc struct {
+		row    SpotPriceRow
+		latest time.Time
+		sum    float64
+		n      int
+	}
+	byAZ := map[string]*acc{}
+	p := ec2.NewDescribeSpotPriceHistoryPaginator(cli, &ec2.DescribeSpotPriceHistoryInput{
+		InstanceTypes:       []ec2t.InstanceType{ec2t.InstanceType(itype)},
+		ProductDescriptions: []string{"Linux/UNIX"},
+		StartTime:           aws.Time(time.Now().Add(-window)),
+	})
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range out.SpotPriceHistory {
+			price, err := strconv.ParseFloat(aws.ToString(h.SpotPrice), 64)
+			if err != nil {
+				continue
+			}
+			az := aws.ToString(h.AvailabilityZone)
+			a, ok := byAZ[az]
+			if !ok {
+				a = &acc{row: SpotPriceRow{AZ: az, Min: price, Max: price}}
+				byAZ[az] = a
+			}
+			if ts := aws.ToTime(h.Timestamp); !ts.Before(a.latest) {
+				a.latest, a.row.Current = ts, price
+			}
+			a.row.Min, a.row.Max = min(a.row.Min, price), max(a.row.Max, price)
+			a.sum += price
+			a.n++
+		}
+	}
+	var rows []SpotPriceRow
+	for _, a := range byAZ {
+		a.row.Avg = a.sum / float64(a.n)
+		a.row.OnDemand = onDemandRef[itype]
+		rows = append(rows, a.row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Current != rows[j].Current {
+			return rows[i].Current < rows[j].Current
+		}
+		return rows[i].AZ < rows[j].AZ
+	})
+	return rows, nil
+}
+
+func usd(v float64) string {
+	if v == 0 {
+		return "-"
+	}
+	return "$" + strconv.FormatFloat(v, 'f', 4, 64)
+}
+
+// spotPriceReport 生成 Spot 价格表；按需参考价来自 us-east-1 价格表，其他区域不计算节省比例
+func spotPriceReport(rows []SpotPriceRow, region string) Report {
+	r := Report{
+		Header:  "可用区\t当前价\t最低\t最高\t均价\t按需参考价(" + onDemandRefRegion + ")\t节省",
+		Columns: []string{"az", "current", "min", "max", "avg", "on_demand_ref", "saving"},
+		Data:    rows,
+	}
+	if rows == nil {
+		r.Data = []SpotPriceRow{}
+	}
+	for _, x := range rows {
+		saving := "-"
+		if x.OnDemand > 0 && region == onDemandRefRegion {
+			saving = fmt.Sprintf("%.0f%%", (1-x.Current/x.OnDemand)*100)
+		}
+		r.Rows = append(r.Rows, []string{x.AZ, usd(x.Current), usd(x.Min), usd(x.Max), usd(x.Avg), usd(x.OnDemand), saving})
+	}
+	return r
+}
+
+// promptSpot 交互式询问购买方式，选择按需时返回 nil
+func promptSpot(ctx context.Context, cli EC2API, region, itype string) *SpotOptions {
+	fmt.Println("\n购买方式:")
+	fmt.Println("  1) 按需 On-Demand [默认]")
+	fmt.Println("  2) Spot (价格低，可能被回收)")
+	if input("请输入编号 [1]: ", "1") != "2" {
+		return nil
+	}
+	if rows, err := spotPriceHistory(ctx, cli, itype, 24*time.Hour); err != nil {
+		fmt.Println("⚠️ 无法获取 Spot 价格:", err)
+	} else if len(rows) == 0 {
+		fmt.Println("⚠️ 该类型在此区域没有 Spot 报价")
+	} else {
+		fmt.Printf("\n%s Spot 价格 (USD/小时):\n", itype)
+		writeReport(os.Stdout, "table", spotPriceReport(rows, region))
+	}
+	s := &SpotOptions{}
+	s.MaxPrice = input("最高价 USD/小时 (留空为按需价): ", "")
+	s.Persistent = input("请求类型 1) 一次性 [默认] 2) 持久: ", "1") == "2"
+	s.Interruption = input("中断行为 terminate/stop/hibernate [terminate]: ", "terminate")
+	s.FallbackOnDemand = yes(input("容量不足时回退为按需实例? [Y/n]: ", "y"))
+	if err := s.normalize(); err != nil {
+		fmt.Printf("❌ %v，改用按需实例\n", err)
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,13 @@
+package main
+
+import "testing"
+
+func TestSpotPriceReportSavingOnlyInRefRegion(t *testing.T) {
+	rows := []SpotPriceRow{{AZ: "x-1a", Current: 0.0026, OnDemand: onDemandRef["t3.nano"]}}
+	if got := spotPriceReport(rows, onDemandRefRegion).Rows[0][6]; got != "50%" {
+		t.Fatalf("us-east-1 节省 = %q, 想要 50%%", got)
+	}
+	if got := spotPriceReport(rows, "ap-northeast-1").Rows[0][6]; got != "-" {
+		t.Fatalf("其他区域节省 = %q, 想要 -", got)
+	}
+}
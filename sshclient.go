@@ -0,0 +1,625 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2t "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/lightsail"
+	lst "github.com/aws/aws-sdk-go-v2/service/lightsail/types"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/term"
+)
+
+// -------------------- 内置 SSH 客户端 --------------------
+
+// SSHTarget 为一台可登录的主机；KeyName 为本地密钥库中的密钥，为空时使用 Password
+type SSHTarget struct {
+	Label    string
+	Host     string
+	Port     int
+	User     string
+	KeyName  string
+	Password string
+}
+
+func (t SSHTarget) addr() string {
+	port := t.Port
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(t.Host, strconv.Itoa(port))
+}
+
+// ec2SSHTarget 根据实例信息生成登录目标：有本地私钥时用镜像默认用户，否则用 root 密码 (取本地记录)
+func ec2SSHTarget(ins ec2t.Instance, imageName string) SSHTarget {
+	t := SSHTarget{Label: aws.ToString(ins.InstanceId), Host: aws.ToString(ins.PublicIpAddress), User: "root"}
+	if t.Host == "" && len(ins.NetworkInterfaces) > 0 && len(ins.NetworkInterfaces[0].Ipv6Addresses) > 0 {
+		t.Host = aws.ToString(ins.NetworkInterfaces[0].Ipv6Addresses[0].Ipv6Address)
+	}
+	if name := aws.ToString(ins.KeyName); hasLocalKey(name) {
+		t.KeyName, t.User = name, ec2SSHUser(imageName)
+	} else {
+		t.Password = lookupCredential("ec2", t.Label, "root")
+	}
+	return t
+}
+
+func lsSSHTarget(ins lst.Instance) SSHTarget {
+	t := SSHTarget{Label: aws.ToString(ins.Name), Host: aws.ToString(ins.PublicIpAddress), User: "root"}
+	if t.Host == "" && len(ins.Ipv6Addresses) > 0 {
+		t.Host = ins.Ipv6Addresses[0]
+	}
+	if name := aws.ToString(ins.SshKeyName); hasLocalKey(name) {
+		t.KeyName, t.User = name, aws.ToString(ins.Username)
+	} else {
+		t.Password = lookupCredential("lightsail", t.Label, "root")
+	}
+	return t
+}
+
+func ec2ImageName(ctx context.Context, cli EC2API, imageID string) string {
+	out, err := cli.DescribeImages(ctx, &ec2.DescribeImagesInput{ImageIds: []string{imageID}})
+	if err != nil || len(out.Images) == 0 {
+		return ""
+	}
+	return aws.ToString(out.Images[0].Name)
+}
+
+// ec2SSHTargetsByID 查询实例的密钥与镜像后生成登录目标；ids 为空时取区域内全部运行中实例
+func ec2SSHTargetsByID(ctx context.Context, cli EC2API, ids []string) ([]SSHTarget, error) {
+	in := &ec2.DescribeInstancesInput{InstanceIds: ids}
+	if len(ids) == 0 {
+		in.Filters = []ec2t.Filter{{Name: aws.String("instance-state-name"), Values: []string{"running"}}}
+	}
+	out, err := cli.DescribeInstances(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	images := map[string]string{}
+	var targets []SSHTarget
+	for _, r := range out.Reservations {
+		for _, ins := range r.Instances {
+			id := aws.ToString(ins.ImageId)
+			if _, ok := images[id]; !ok {
+				images[id] = ec2ImageName(ctx, cli, id)
+			}
+			if t := ec2SSHTarget(ins, images[id]); t.Host != "" {
+				targets = append(targets, t)
+			}
+		}
+	}
+	return targets, nil
+}
+
+// lsSSHTargetsByName 同上；names 为空时取区域内全部运行中实例
+func lsSSHTargetsByName(ctx context.Context, cli LightsailAPI, names []string) ([]SSHTarget, error) {
+	var instances []lst.Instance
+	if len(names) == 0 {
+		out, err := cli.GetInstances(ctx, &lightsail.GetInstancesInput{})
+		if err != nil {
+			return nil, err
+		}
+		for _, ins := range out.Instances {
+			if ins.State != nil && aws.ToString(ins.State.Name) == "running" {
+				instances = append(instances, ins)
+			}
+		}
+	}
+	for _, n := range names {
+		out, err := cli.GetInstance(ctx, &lightsail.GetInstanceInput{InstanceName: aws.String(n)})
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, *out.Instance)
+	}
+	var targets []SSHTarget
+	for _, ins := range instances {
+		if t := lsSSHTarget(ins); t.Host != "" {
+			targets = append(targets, t)
+		}
+	}
+	return targets, nil
+}
+
+// ec2SSHTargets 让用户从列表中选择实例 (如 1,3-5 或 all)，按区域生成登录目标
+func ec2SSHTargets(ctx context.Context, rows []EC2InstanceRow, creds aws.CredentialsProvider) ([]SSHTarget, error) {
+	idx, err := parseSelection(input("选择实例 (如 1,3-5 或 all): ", ""), len(rows))
+	if err != nil {
+		return nil, err
+	}
+	byRegion := map[string][]string{}
+	for _, i := range idx {
+		if rows[i].State == "running" {
+			byRegion[rows[i].Region] = append(byRegion[rows[i].Region], rows[i].ID)
+		}
+	}
+	var targets []SSHTarget
+	for _, region := range sortedKeys(byRegion) {
+		cfg, err := mkCfg(ctx, region, creds)
+		if err != nil {
+			return nil, err
+		}
+		t, err := ec2SSHTargetsByID(ctx, newEC2Client(cfg), byRegion[region])
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, t...)
+	}
+	return targets, nil
+}
+
+func lsSSHTargets(ctx context.Context, rows []LSInstanceRow, creds aws.CredentialsProvider) ([]SSHTarget, error) {
+	idx, err := parseSelection(input("选择实例 (如 1,3-5 或 all): ", ""), len(rows))
+	if err != nil {
+		return nil, err
+	}
+	byRegion := map[string][]string{}
+	for _, i := range idx {
+		if rows[i].State == "running" {
+			byRegion[rows[i].Region] = append(byRegion[rows[i].Region], rows[i].Name)
+		}
+	}
+	var targets []SSHTarget
+	for _, region := range sortedKeys(byRegion) {
+		cfg, err := mkCfg(ctx, region, creds)
+		if err != nil {
+			return nil, err
+		}
+		t, err := lsSSHTargetsByName(ctx, newLightsailClient(cfg), byRegion[region])
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, t...)
+	}
+	return targets, nil
+}
+
+// cliSSHTargets 为命令行解析 --targets (逗号分隔或 all)
+func cliSSHTargets(ctx context.Context, creds aws.CredentialsProvider, service, region, sel string) ([]SSHTarget, error) {
+	var names []string
+	if sel != "all" {
+		names = splitList(sel)
+	}
+	cfg, err := mkCfg(ctx, region, creds)
+	if err != nil {
+		return nil, err
+	}
+	switch service {
+	case "ec2":
+		return ec2SSHTargetsByID(ctx, newEC2Client(cfg), names)
+	case "ls", "lightsail":
+		return lsSSHTargetsByName(ctx, newLightsailClient(cfg), names)
+	}
+	return nil, fmt.Errorf("未知服务: %s (可选 ec2|ls)", service)
+}
+
+// 主机密钥记录默认位于 ~/.aws-tool/known_hosts，可用 AWS_TOOL_KNOWN_HOSTS 覆盖
+func knownHostsPath() string {
+	if p := os.Getenv("AWS_TOOL_KNOWN_HOSTS"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "known_hosts"
+	}
+	return filepath.Join(home, ".aws-tool", "known_hosts")
+}
+
+var knownHostsMu sync.Mutex
+
+// errHostKeyChanged 表示主机密钥与记录不一致
+var errHostKeyChanged = errors.New("主机密钥与记录不一致")
+
+// pinnedHostKeys 返回固定主机密钥的校验函数：已记录的主机必须匹配；
+// 首次连接时由 confirm 决定是否信任 (nil 表示自动信任) 并写入记录
+func pinnedHostKeys(path string, confirm func(host string, key ssh.PublicKey) bool) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		knownHostsMu.Lock()
+		defer knownHostsMu.Unlock()
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		check, err := knownhosts.New(path)
+		if err != nil {
+			return err
+		}
+		err = check(hostname, remote, key)
+		var ke *knownhosts.KeyError
+		if !errors.As(err, &ke) {
+			return err
+		}
+		if len(ke.Want) > 0 {
+			return fmt.Errorf("%w: %s (指纹 %s)。可能遭到中间人攻击，也可能是实例重建或 IP 被复用，确认安全后可删除记录重新信任",
+				errHostKeyChanged, hostname, ssh.FingerprintSHA256(key))
+		}
+		if confirm != nil && !confirm(hostname, key) {
+			return fmt.Errorf("未信任 %s 的主机密钥", hostname)
+		}
+		_, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key))
+		return err
+	}
+}
+
+// forgetHostKey 从记录中删除 host (host:port 或 IP) 的主机密钥
+func forgetHostKey(path, host string) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	want := knownhosts.Normalize(host)
+	var keep []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if f := strings.Fields(line); len(f) > 0 && f[0] == want {
+			continue
+		}
+		keep = append(keep, line)
+	}
+	return os.WriteFile(path, []byte(strings.Join(keep, "\n")), 0o600)
+}
+
+// confirmHostKey 在交互模式下询问是否信任首次连接的主机
+func confirmHostKey(host string, key ssh.PublicKey) bool {
+	fmt.Printf("🔐 首次连接 %s，主机密钥指纹: %s\n", host, ssh.FingerprintSHA256(key))
+	return yes(input("是否信任并记录? [y/N]: ", "n"))
+}
+
+func sshAuth(t SSHTarget) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+	if t.KeyName != "" {
+		b, err := os.ReadFile(keyPath(t.KeyName))
+		if err != nil {
+			return nil, fmt.Errorf("本地密钥库中没有 %s 的私钥", t.KeyName)
+		}
+		signer, err := ssh.ParsePrivateKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("无法解析私钥 %s: %v", keyPath(t.KeyName), err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	if t.Password != "" {
+		methods = append(methods, ssh.Password(t.Password),
+			ssh.KeyboardInteractive(func(_, _ string, questions []string, _ []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = t.Password
+				}
+				return answers, nil
+			}))
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("没有可用的登录方式 (本地无私钥且未提供密码)")
+	}
+	return methods, nil
+}
+
+// sshNetDial 建立到 addr 的 TCP 连接；当前配置了 SOCKS5 代理时经代理连接
+var sshNetDial = func(ctx context.Context, addr string) (net.Conn, error) {
+	if sel, err := currentSelector(ctx); err == nil && sel != nil {
+		if p, err := sel(&url.URL{Scheme: "https", Host: addr}); err == nil && p != nil && isSOCKS(p) {
+			return socksDial(ctx, p, "tcp", addr)
+		}
+	}
+	return proxyDialer.DialContext(ctx, "tcp", addr)
+}
+
+func sshDial(ctx context.Context, t SSHTarget, hostKey ssh.HostKeyCallback) (*ssh.Client, error) {
+	if t.Host == "" {
+		return nil, errors.New("实例没有公网 IP")
+	}
+	auth, err := sshAuth(t)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	conn, err := sshNetDial(ctx, t.addr())
+	if err != nil {
+		return nil, err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl)
+	}
+	cfg := &ssh.ClientConfig{User: t.User, Auth: auth, HostKeyCallback: hostKey}
+	c, chans, reqs, err := ssh.NewClientConn(conn, t.addr(), cfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// sshShell 打开交互式会话；本地为终端时切换到原始模式并申请伪终端
+func sshShell(ctx context.Context, t SSHTarget, hostKey ssh.HostKeyCallback) error {
+	client, err := sshDial(ctx, t, hostKey)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	sess, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		w, h, err := term.GetSize(fd)
+		if err != nil {
+			w, h = 80, 24
+		}
+		termType := os.Getenv("TERM")
+		if termType == "" {
+			termType = "xterm-256color"
+		}
+		if err := sess.RequestPty(termType, h, w, ssh.TerminalModes{ssh.ECHO: 1}); err != nil {
+			return err
+		}
+		old, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer term.Restore(fd, old)
+	}
+	stdin, err := sess.StdinPipe()
+	if err != nil {
+		return err
+	}
+	sess.Stdout, sess.Stderr = os.Stdout, os.Stderr
+	stdinDone := make(chan struct{})
+	go func() {
+		io.Copy(stdin, os.Stdin)
+		close(stdinDone)
+	}()
+	if err := sess.Shell(); err != nil {
+		return err
+	}
+	err = sess.Wait()
+	stdin.Close()
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) || errors.Is(err, io.EOF) {
+		err = nil
+	}
+	select {
+	case <-stdinDone:
+	default:
+		// 转发 stdin 的协程还阻塞在读取上，需要再读一次才会退出，否则会吞掉菜单的下一次输入
+		fmt.Print("\r\n🔚 会话已结束，按回车返回菜单...")
+		<-stdinDone
+	}
+	return err
+}
+
+// prefixWriter 按行为输出加前缀，多台主机共用 mu 保证行不交错
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		p.mu.Lock()
+		fmt.Fprintf(p.w, "%s%s\n", p.prefix, bytes.TrimRight(p.buf[:i], "\r"))
+		p.mu.Unlock()
+		p.buf = p.buf[i+1:]
+	}
+}
+
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.Write([]byte("\n"))
+	}
+}
+
+// SSHResult 为一台主机上的执行结果
+type SSHResult struct {
+	Label    string  `json:"label" yaml:"label"`
+	Host     string  `json:"host" yaml:"host"`
+	ExitCode int     `json:"exit_code" yaml:"exit_code"`
+	Error    string  `json:"error,omitempty" yaml:"error,omitempty"`
+	Seconds  float64 `json:"seconds" yaml:"seconds"`
+}
+
+// sshRun 在单台主机上执行 cmd，stdout/stderr 写入 out
+func sshRun(ctx context.Context, t SSHTarget, cmd string, hostKey ssh.HostKeyCallback, out io.Writer) (int, error) {
+	client, err := sshDial(ctx, t, hostKey)
+	if err != nil {
+		return -1, err
+	}
+	defer client.Close()
+	sess, err := client.NewSession()
+	if err != nil {
+		return -1, err
+	}
+	defer sess.Close()
+	sess.Stdout, sess.Stderr = out, out
+	stop := context.AfterFunc(ctx, func() { client.Close() })
+	defer stop()
+	err = sess.Run(cmd)
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return 0, nil
+}
+
+// sshRunAll 在多台主机上并发执行 cmd，输出实时打印到 w，每行带 [标签] 前缀
+func sshRunAll(ctx context.Context, targets []SSHTarget, cmd string, concurrency int, hostKey ssh.HostKeyCallback, w io.Writer) []SSHResult {
+	if concurrency <= 0 {
+		concurrency = 8
+	}
+	width := 0
+	for _, t := range targets {
+		width = max(width, len(t.Label))
+	}
+	results := make([]SSHResult, len(targets))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			start := time.Now()
+			pw := &prefixWriter{mu: &mu, w: w, prefix: fmt.Sprintf("[%-*s] ", width, t.Label)}
+			code, err := sshRun(ctx, t, cmd, hostKey, pw)
+			pw.Flush()
+			results[i] = SSHResult{Label: t.Label, Host: t.Host, ExitCode: code, Seconds: time.Since(start).Seconds()}
+			if err != nil {
+				results[i].Error = err.Error()
+				mu.Lock()
+				fmt.Fprintf(w, "%s❌ %v\n", pw.prefix, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func sshResultReport(rows []SSHResult) Report {
+	r := Report{
+		Header:  "主机\t地址\t退出码\t耗时\t错误",
+		Columns: []string{"label", "host", "exit_code", "seconds", "error"},
+		Cut:     map[int]int{4: 60},
+		Data:    rows,
+	}
+	for _, x := range rows {
+		code := strconv.Itoa(x.ExitCode)
+		if x.Error != "" {
+			code = "-"
+		}
+		r.Rows = append(r.Rows, []string{x.Label, x.Host, code, fmt.Sprintf("%.1fs", x.Seconds), x.Error})
+	}
+	return r
+}
+
+// sshTargetsNeedPassword 为没有本地私钥的目标补上密码，留空时去掉这些目标
+func sshTargetsNeedPassword(targets []SSHTarget) []SSHTarget {
+	var need int
+	for _, t := range targets {
+		if t.KeyName == "" && t.Password == "" {
+			need++
+		}
+	}
+	if need == 0 {
+		return targets
+	}
+	pwd := inputSecret(fmt.Sprintf("%d 台实例没有本地私钥，输入 root 密码 (留空跳过这些实例): ", need))
+	var out []SSHTarget
+	for _, t := range targets {
+		if t.KeyName == "" && t.Password == "" {
+			if pwd == "" {
+				continue
+			}
+			t.Password = pwd
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// sshConnectInteractive 为详情页的「SSH 连接」操作：必要时询问密码，主机密钥变化时可选择重新信任
+func sshConnectInteractive(ctx context.Context, t SSHTarget) {
+	if t.KeyName == "" && t.Password == "" {
+		if t.Password = inputSecret(fmt.Sprintf("%s@%s 的密码: ", t.User, t.Host)); t.Password == "" {
+			return
+		}
+	}
+	fmt.Printf("🔗 正在连接 %s@%s ...\n", t.User, t.Host)
+	hostKey := pinnedHostKeys(knownHostsPath(), confirmHostKey)
+	err := sshShell(ctx, t, hostKey)
+	if errors.Is(err, errHostKeyChanged) {
+		fmt.Println("⚠️", err)
+		if !yes(input("是否删除旧记录并重新信任? [y/N]: ", "n")) {
+			return
+		}
+		if err := forgetHostKey(knownHostsPath(), t.addr()); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		err = sshShell(ctx, t, hostKey)
+	}
+	if err != nil {
+		fmt.Println("❌ SSH 失败:", err)
+	}
+}
+
+// sshBatchInteractive 询问命令并在 targets 上执行
+func sshBatchInteractive(ctx context.Context, targets []SSHTarget) {
+	targets = sshTargetsNeedPassword(targets)
+	if len(targets) == 0 {
+		fmt.Println("❌ 没有可登录的实例")
+		return
+	}
+	cmd := input("要执行的命令: ", "")
+	if cmd == "" {
+		return
+	}
+	fmt.Printf("\n▶ 在 %d 台实例上执行: %s\n", len(targets), cmd)
+	// 批量模式下首次连接的主机自动信任，已记录但不一致的主机拒绝连接
+	results := sshRunAll(ctx, targets, cmd, 8, pinnedHostKeys(knownHostsPath(), nil), os.Stdout)
+	fmt.Println()
+	writeReport(os.Stdout, "table", sshResultReport(results))
+}
+
+// parseSelection 解析 "1,3-5" 或 "all" 形式的序号选择，返回 0 起始的下标
+func parseSelection(s string, n int) ([]int, error) {
+	if strings.EqualFold(strings.TrimSpace(s), "all") {
+		idx := make([]int, n)
+		for i := range idx {
+			idx[i] = i
+		}
+		return idx, nil
+	}
+	var idx []int
+	for _, part := range splitList(s) {
+		lo, hi, isRange := strings.Cut(part, "-")
+		a, err1 := strconv.Atoi(lo)
+		b := a
+		var err2 error
+		if isRange {
+			b, err2 = strconv.Atoi(hi)
+		}
+		if err1 != nil || err2 != nil || a < 1 || b > n || a > b {
+			return nil, fmt.Errorf("序号无效: %s", part)
+		}
+		for i := a; i <= b; i++ {
+			idx = append(idx, i-1)
+		}
+	}
+	return idx, nil
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// -------------------- 进程内 SSH 服务器 --------------------
+
+// testSSHServer 接受密码或公钥登录，exec 请求交给 fakeShell 执行；
+// 主机密钥可随时替换，用来模拟实例重建后密钥变化
+type testSSHServer struct {
+	ln       net.Listener
+	password string
+	authKey  ssh.PublicKey
+
+	mu      sync.Mutex
+	hostKey ssh.Signer
+}
+
+func newHostKey(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func startSSHServer(t *testing.T, password string, authKey ssh.PublicKey) *testSSHServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testSSHServer{ln: ln, password: password, authKey: authKey, hostKey: newHostKey(t)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *testSSHServer) setHostKey(k ssh.Signer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hostKey = k
+}
+
+func (s *testSSHServer) target(label string) SSHTarget {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return SSHTarget{Label: label, Host: host, Port: p, User: "root"}
+}
+
+func (s *testSSHServer) serve(c net.Conn) {
+	defer c.Close()
+	cfg := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if s.password != "" && string(pass) == s.password {
+				return nil, nil
+			}
+			return nil, errors.New("wrong password")
+		},
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if s.authKey != nil && bytes.Equal(key.Marshal(), s.authKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}
+	s.mu.Lock()
+	cfg.AddHostKey(s.hostKey)
+	s.mu.Unlock()
+	_, chans, reqs, err := ssh.NewServerConn(c, cfg)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "only session")
+			continue
+		}
+		ch, chReqs, err := nc.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer ch.Close()
+			for req := range chReqs {
+				if req.Type != "exec" {
+					req.Reply(false, nil)
+					continue
+				}
+				var payload struct{ Command string }
+				ssh.Unmarshal(req.Payload, &payload)
+				req.Reply(true, nil)
+				out, code := fakeShell(payload.Command)
+				ch.Write([]byte(out))
+				ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(code)}))
+				return
+			}
+		}()
+	}
+}
+
+// fakeShell 支持 "echo 文本"、"exit 退出码" 与输出多行 (含 \r\n 与不完整末行) 的 "lines"
+func fakeShell(cmd string) (string, int) {
+	switch {
+	case strings.HasPrefix(cmd, "echo "):
+		return strings.TrimPrefix(cmd, "echo ") + "\n", 0
+	case strings.HasPrefix(cmd, "exit "):
+		code, _ := strconv.Atoi(strings.TrimPrefix(cmd, "exit "))
+		return "", code
+	case cmd == "lines":
+		return "first\r\nsecond\nthird", 0
+	}
+	return "sh: " + cmd + ": not found\n", 127
+}
+
+// sshTestEnv 隔离密钥库、主机密钥记录与代理环境变量，返回 known_hosts 路径
+func sshTestEnv(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("AWS_TOOL_KEYRING", filepath.Join(dir, "keys"))
+	for _, k := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "ALL_PROXY", "all_proxy"} {
+		t.Setenv(k, "")
+	}
+	return filepath.Join(dir, "known_hosts")
+}
+
+// -------------------- 认证 --------------------
+
+func TestSSHPasswordAndKeyAuth(t *testing.T) {
+	kh := sshTestEnv(t)
+	pub, err := generateKey("k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startSSHServer(t, "pw", authKey)
+	ctx := context.Background()
+	hk := pinnedHostKeys(kh, nil)
+
+	byPass := srv.target("pass")
+	byPass.Password = "pw"
+	byKey := srv.target("key")
+	byKey.KeyName = "k1"
+	for _, tg := range []SSHTarget{byPass, byKey} {
+		var out bytes.Buffer
+		code, err := sshRun(ctx, tg, "echo hi", hk, &out)
+		if err != nil || code != 0 || out.String() != "hi\n" {
+			t.Errorf("%s 登录执行失败: code=%d out=%q err=%v", tg.Label, code, out.String(), err)
+		}
+	}
+
+	wrong := srv.target("wrong")
+	wrong.Password = "nope"
+	if _, err := sshRun(ctx, wrong, "echo hi", hk, &bytes.Buffer{}); err == nil {
+		t.Error("密码错误时应登录失败")
+	}
+	missing := srv.target("missing")
+	missing.KeyName = "no-such-key"
+	if _, err := sshRun(ctx, missing, "echo hi", hk, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "no-such-key") {
+		t.Errorf("本地没有私钥时应报错，得到 %v", err)
+	}
+	if _, err := sshAuth(srv.target("none")); err == nil {
+		t.Error("既无私钥也无密码时应报错")
+	}
+}
+
+// -------------------- 主机密钥固定 --------------------
+
+func TestPinnedHostKeys(t *testing.T) {
+	kh := sshTestEnv(t)
+	srv := startSSHServer(t, "pw", nil)
+	ctx := context.Background()
+	tg := srv.target("h")
+	tg.Password = "pw"
+
+	// 首次连接拒绝信任时不写入记录
+	reject := pinnedHostKeys(kh, func(string, ssh.PublicKey) bool { return false })
+	if _, err := sshRun(ctx, tg, "echo hi", reject, &bytes.Buffer{}); err == nil {
+		t.Fatal("拒绝信任主机密钥时应连接失败")
+	}
+	if b, _ := os.ReadFile(kh); len(bytes.TrimSpace(b)) != 0 {
+		t.Fatalf("拒绝信任后不应写入记录: %q", b)
+	}
+
+	// 首次信任并记录 (TOFU)，之后不再询问
+	asked := 0
+	trust := pinnedHostKeys(kh, func(string, ssh.PublicKey) bool { asked++; return true })
+	for range 2 {
+		if _, err := sshRun(ctx, tg, "echo hi", trust, &bytes.Buffer{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if asked != 1 {
+		t.Errorf("只应在首次连接时询问，实际询问 %d 次", asked)
+	}
+
+	// 主机密钥变化时拒绝连接
+	srv.setHostKey(newHostKey(t))
+	_, err := sshRun(ctx, tg, "echo hi", trust, &bytes.Buffer{})
+	if !errors.Is(err, errHostKeyChanged) {
+		t.Fatalf("主机密钥变化时应返回 errHostKeyChanged，得到 %v", err)
+	}
+	if asked != 1 {
+		t.Error("密钥变化时不应询问是否信任")
+	}
+
+	// 删除记录后重新信任新密钥
+	if err := forgetHostKey(kh, tg.addr()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sshRun(ctx, tg, "echo hi", trust, &bytes.Buffer{}); err != nil {
+		t.Fatalf("删除记录后应可重新信任: %v", err)
+	}
+	if asked != 2 {
+		t.Errorf("删除记录后应重新询问，实际询问 %d 次", asked)
+	}
+	b, _ := os.ReadFile(kh)
+	if n := strings.Count(string(b), knownHostsLinePrefix(tg.addr())); n != 1 {
+		t.Errorf("记录中应只剩一条 %s，实际 %d 条:\n%s", tg.addr(), n, b)
+	}
+}
+
+func knownHostsLinePrefix(addr string) string {
+	host, port, _ := net.SplitHostPort(addr)
+	return "[" + host + "]:" + port + " "
+}
+
+// -------------------- 执行命令 --------------------
+
+func TestSSHRunExitCodes(t *testing.T) {
+	kh := sshTestEnv(t)
+	srv := startSSHServer(t, "pw", nil)
+	tg := srv.target("h")
+	tg.Password = "pw"
+	hk := pinnedHostKeys(kh, nil)
+	cases := []struct {
+		cmd  string
+		code int
+		out  string
+	}{
+		{"echo hello", 0, "hello\n"},
+		{"exit 3", 3, ""},
+		{"exit 255", 255, ""},
+		{"bogus", 127, "sh: bogus: not found\n"},
+	}
+	for _, c := range cases {
+		var out bytes.Buffer
+		code, err := sshRun(context.Background(), tg, c.cmd, hk, &out)
+		if err != nil || code != c.code || out.String() != c.out {
+			t.Errorf("sshRun(%q) = %d %q %v，期望 %d %q", c.cmd, code, out.String(), err, c.code, c.out)
+		}
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	pw := &prefixWriter{mu: &sync.Mutex{}, w: &buf, prefix: "[x] "}
+	for _, s := range []string{"he", "llo\nwor", "ld\r\n", "tail"} {
+		if n, err := pw.Write([]byte(s)); err != nil || n != len(s) {
+			t.Fatalf("Write(%q) = %d, %v", s, n, err)
+		}
+	}
+	if got := buf.String(); got != "[x] hello\n[x] world\n" {
+		t.Errorf("不完整的行应等到换行再输出，得到 %q", got)
+	}
+	pw.Flush()
+	if got := buf.String(); got != "[x] hello\n[x] world\n[x] tail\n" {
+		t.Errorf("Flush 应输出剩余内容，得到 %q", got)
+	}
+}
+
+func TestSSHRunAllPrefixesLines(t *testing.T) {
+	kh := sshTestEnv(t)
+	srv := startSSHServer(t, "pw", nil)
+	a, web := srv.target("a"), srv.target("web-1")
+	a.Password, web.Password = "pw", "pw"
+	noIP := SSHTarget{Label: "gone"}
+
+	var buf bytes.Buffer
+	res := sshRunAll(context.Background(), []SSHTarget{a, web, noIP}, "lines", 2, pinnedHostKeys(kh, nil), &buf)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := map[string][]string{
+		"[a    ] ": {"first", "second", "third"},
+		"[web-1] ": {"first", "second", "third"},
+	}
+	for prefix, body := range want {
+		var got []string
+		for _, l := range lines {
+			if rest, ok := strings.CutPrefix(l, prefix); ok {
+				got = append(got, rest)
+			}
+		}
+		if strings.Join(got, "|") != strings.Join(body, "|") {
+			t.Errorf("%q 的输出应为 %v，得到 %v\n%s", prefix, body, got, buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), "[gone ] ❌ ") {
+		t.Errorf("失败的主机应带前缀输出错误:\n%s", buf.String())
+	}
+	if len(res) != 3 || res[0].ExitCode != 0 || res[1].ExitCode != 0 || res[2].Error == "" {
+		t.Errorf("结果不符: %+v", res)
+	}
+}
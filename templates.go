@@ -0,0 +1,472 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2t "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	lst "github.com/aws/aws-sdk-go-v2/service/lightsail/types"
+	"gopkg.in/yaml.v3"
+)
+
+// -------------------- 启动模板 (EC2 / Lightsail) --------------------
+
+// defaultTemplateFile 为默认模板文件，可用 AWS_TOOL_TEMPLATES 或 --templates 指定其他路径
+const defaultTemplateFile = "templates.yaml"
+
+// EC2Template 对应模板文件 ec2 段中的一项；未填写的字段使用与交互菜单相同的默认值
+type EC2Template struct {
+	Region       string            `yaml:"region,omitempty"`
+	Arch         string            `yaml:"arch,omitempty"`
+	AMI          string            `yaml:"ami,omitempty"`
+	Type         string            `yaml:"type,omitempty"`
+	Count        int32             `yaml:"count,omitempty"`
+	Disk         int32             `yaml:"disk,omitempty"`
+	IPv6         bool              `yaml:"ipv6,omitempty"` // 旧字段，等同 net: dual
+	Net          string            `yaml:"net,omitempty"`  // ipv4|dual|ipv6
+	DNS64        bool              `yaml:"dns64,omitempty"`
+	NAT64        bool              `yaml:"nat64,omitempty"`
+	RootPwd      string            `yaml:"root_pwd,omitempty"`
+	GenPwd       bool              `yaml:"gen_pwd,omitempty"`
+	PlainPwd     bool              `yaml:"plain_pwd,omitempty"`
+	OpenAll      bool              `yaml:"open_all,omitempty"`
+	Ports        []string          `yaml:"ports,omitempty"`
+	UserData     string            `yaml:"user_data,omitempty"`
+	UserDataFile string            `yaml:"user_data_file,omitempty"`
+	Snippets     []string          `yaml:"snippets,omitempty"`
+	UserDataVars map[string]string `yaml:"user_data_vars,omitempty"`
+	KeyName      string            `yaml:"key_name,omitempty"`
+	Tags         map[string]string `yaml:"tags,omitempty"`
+	Spot         *SpotOptions      `yaml:"spot,omitempty"`
+	AltTypes     []string          `yaml:"alt_types,omitempty"`
+}
+
+// LSTemplate 对应模板文件 lightsail 段中的一项
+type LSTemplate struct {
+	Region       string            `yaml:"region,omitempty"`
+	AZ           string            `yaml:"az,omitempty"`
+	Bundle       string            `yaml:"bundle,omitempty"`
+	Blueprint    string            `yaml:"blueprint,omitempty"`
+	IPType       string            `yaml:"ip_type,omitempty"` // dualstack|ipv4|ipv6，空时按套餐决定
+	RootPwd      string            `yaml:"root_pwd,omitempty"`
+	GenPwd       bool              `yaml:"gen_pwd,omitempty"`
+	PlainPwd     bool              `yaml:"plain_pwd,omitempty"`
+	OpenAll      bool              `yaml:"open_all,omitempty"`
+	Ports        []string          `yaml:"ports,omitempty"`
+	UserData     string            `yaml:"user_data,omitempty"`
+	UserDataFile string            `yaml:"user_data_file,omitempty"`
+	Snippets     []string          `yaml:"snippets,omitempty"`
+	UserDataVars map[string]string `yaml:"user_data_vars,omitempty"`
+	KeyPair      string            `yaml:"key_pair,omitempty"`
+	Tags         map[string]string `yaml:"tags,omitempty"`
+}
+
+type TemplateFile struct {
+	EC2       map[string]*EC2Template `yaml:"ec2"`
+	Lightsail map[string]*LSTemplate  `yaml:"lightsail"`
+}
+
+func templatePath(path string) string {
+	if path != "" {
+		return path
+	}
+	if p := os.Getenv("AWS_TOOL_TEMPLATES"); p != "" {
+		return p
+	}
+	return defaultTemplateFile
+}
+
+// loadTemplates 读取模板文件；JSON 是 YAML 的子集，两种格式统一按 YAML 解析
+func loadTemplates(path string) (*TemplateFile, error) {
+	b, err := os.ReadFile(templatePath(path))
+	if err != nil {
+		return nil, err
+	}
+	tf := &TemplateFile{}
+	if err := yaml.Unmarshal(b, tf); err != nil {
+		return nil, fmt.Errorf("模板文件格式错误: %v", err)
+	}
+	return tf, nil
+}
+
+func templateNames[T any](m map[string]*T) []string {
+	names := make([]string, 0, len(m))
+	for n := range m {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func findEC2Template(path, name string) (*EC2Template, error) {
+	tf, err := loadTemplates(path)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := tf.EC2[name]
+	if !ok || t == nil {
+		return nil, fmt.Errorf("模板 %s 不存在 (可用: %s)", name, strings.Join(templateNames(tf.EC2), ", "))
+	}
+	return t, nil
+}
+
+func findLSTemplate(path, name string) (*LSTemplate, error) {
+	tf, err := loadTemplates(path)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := tf.Lightsail[name]
+	if !ok || t == nil {
+		return nil, fmt.Errorf("模板 %s 不存在 (可用: %s)", name, strings.Join(templateNames(tf.Lightsail), ", "))
+	}
+	return t, nil
+}
+
+// applyOverrides 把 "key=value" 形式的覆盖项写入模板，key 与模板文件字段名相同，
+// tags.Name=web 这样的写法设置单个标签；value 按 YAML 解析 (如 ports=[22,443])
+func applyOverrides(dst any, kvs []string) error {
+	doc := map[string]any{}
+	for _, kv := range kvs {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("覆盖项格式应为 key=value: %q", kv)
+		}
+		var val any
+		if err := yaml.Unmarshal([]byte(v), &val); err != nil || val == nil {
+			val = v
+		}
+		if parent, child, nested := strings.Cut(k, "."); nested {
+			m, _ := doc[parent].(map[string]any)
+			if m == nil {
+				m = map[string]any{}
+				doc[parent] = m
+			}
+			m[child] = fmt.Sprint(val)
+			continue
+		}
+		doc[k] = val
+	}
+	if len(doc) == 0 {
+		return nil
+	}
+	b, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(strings.NewReader(string(b)))
+	dec.KnownFields(true)
+	if err := dec.Decode(dst); err != nil {
+		return fmt.Errorf("覆盖项无效: %v", err)
+	}
+	return nil
+}
+
+func (t *EC2Template) summary() string {
+	parts := []string{orDefault(t.AMI, "debian-12"), orDefault(t.Type, "默认规格"), orDefault(t.Arch, "x86_64")}
+	if t.Count > 1 {
+		parts = append(parts, fmt.Sprintf("×%d", t.Count))
+	}
+	if t.Region != "" {
+		parts = append(parts, t.Region)
+	}
+	if n, err := t.netMode(); err == nil && n != netIPv4 {
+		parts = append(parts, netModeName(n))
+	}
+	if t.Spot != nil {
+		parts = append(parts, "Spot")
+	}
+	if t.OpenAll {
+		parts = append(parts, "全开端口")
+	} else if len(t.Ports) > 0 {
+		parts = append(parts, "端口 "+strings.Join(t.Ports, ","))
+	}
+	if len(t.Snippets) > 0 {
+		parts = append(parts, "片段 "+snippetNames(t.Snippets))
+	}
+	return strings.Join(parts, " / ")
+}
+
+func (t *LSTemplate) summary() string {
+	parts := []string{orDefault(t.Bundle, "nano_3_0"), orDefault(t.Blueprint, "debian_12")}
+	if t.Region != "" {
+		parts = append(parts, t.Region)
+	}
+	if ipt, err := parseLSIPType(t.IPType); err == nil && t.IPType != "" {
+		parts = append(parts, lsIPTypeName(ipt))
+	}
+	if t.OpenAll {
+		parts = append(parts, "全开端口")
+	} else if len(t.Ports) > 0 {
+		parts = append(parts, "端口 "+strings.Join(t.Ports, ","))
+	}
+	if len(t.Snippets) > 0 {
+		parts = append(parts, "片段 "+snippetNames(t.Snippets))
+	}
+	return strings.Join(parts, " / ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func templateUserData(inline, file string) (string, error) {
+	if file == "" {
+		return inline, nil
+	}
+	ud, err := readUserDataFile(file)
+	if err != nil {
+		return "", fmt.Errorf("读取 user_data_file 失败: %v", err)
+	}
+	if inline != "" {
+		ud = inline + "\n" + ud
+	}
+	return ud, nil
+}
+
+func (t *EC2Template) netMode() (string, error) {
+	if t.Net == "" && t.IPv6 {
+		return netDual, nil
+	}
+	return parseNetMode(t.Net)
+}
+
+// launchOptions 把模板转换为 EC2 启动参数
+func (t *EC2Template) launchOptions() (EC2LaunchOptions, error) {
+	if t.Arch != "" && t.Arch != "x86_64" && t.Arch != "arm64" {
+		return EC2LaunchOptions{}, fmt.Errorf("arch 只能是 x86_64 或 arm64: %s", t.Arch)
+	}
+	net, err := t.netMode()
+	if err != nil {
+		return EC2LaunchOptions{}, err
+	}
+	if (t.DNS64 || t.NAT64) && net != netIPv6 {
+		return EC2LaunchOptions{}, fmt.Errorf("dns64 / nat64 只适用于 net: ipv6")
+	}
+	ports, err := parsePortRules(t.Ports)
+	if err != nil {
+		return EC2LaunchOptions{}, err
+	}
+	ud, err := templateUserData(t.UserData, t.UserDataFile)
+	if err != nil {
+		return EC2LaunchOptions{}, err
+	}
+	return EC2LaunchOptions{
+		Arch: t.Arch, AMI: orDefault(t.AMI, "debian-12"), Type: t.Type, Count: t.Count, VolSize: t.Disk,
+		Net: net, DNS64: t.DNS64, NAT64: t.NAT64, RootPwd: t.RootPwd, GenPwd: t.GenPwd, PlainPwd: t.PlainPwd, OpenAll: t.OpenAll, Ports: ports, UserData: ud,
+		Snippets: t.Snippets, UserDataVars: t.UserDataVars,
+		KeyName: t.KeyName, Tags: t.Tags, Spot: t.Spot, AltTypes: t.AltTypes,
+	}, nil
+}
+
+// launchOptions 把模板转换为 Lightsail 启动参数，name 为实例名称
+func (t *LSTemplate) launchOptions(region, name string) (LSLaunchOptions, error) {
+	if _, err := parseLSIPType(t.IPType); err != nil {
+		return LSLaunchOptions{}, err
+	}
+	ports, err := parsePortRules(t.Ports)
+	if err != nil {
+		return LSLaunchOptions{}, err
+	}
+	ud, err := templateUserData(t.UserData, t.UserDataFile)
+	if err != nil {
+		return LSLaunchOptions{}, err
+	}
+	return LSLaunchOptions{
+		AZ: orDefault(t.AZ, region+"a"), Name: name, Bundle: orDefault(t.Bundle, "nano_3_0"),
+		Blueprint: orDefault(t.Blueprint, "debian_12"), IPType: t.IPType, OpenAll: t.OpenAll, Ports: ports, UserData: ud,
+		RootPwd: t.RootPwd, GenPwd: t.GenPwd, PlainPwd: t.PlainPwd,
+		Snippets: t.Snippets, UserDataVars: t.UserDataVars,
+		KeyPair: t.KeyPair, Tags: t.Tags,
+	}, nil
+}
+
+// -------------------- 模板选择 (交互) --------------------
+
+// chooseTemplate 列出模板供选择，返回空字符串表示不使用模板；没有模板文件时直接返回
+func chooseTemplate(names []string, summary func(string) string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	fmt.Println("\n📄 可用启动模板:")
+	fmt.Println("  0) 不使用模板，逐项填写 [默认]")
+	for i, n := range names {
+		fmt.Printf("  %d) %s  (%s)\n", i+1, n, summary(n))
+	}
+	idx := mustInt(input("选择模板 [0]: ", "0"))
+	if idx < 1 || idx > len(names) {
+		return ""
+	}
+	return names[idx-1]
+}
+
+// promptOverrides 询问本次启动要覆盖的模板字段
+func promptOverrides(dst any) bool {
+	raw := input("覆盖参数 (如 count=2 type=t3.small tags.Name=web，留空不修改): ", "")
+	if err := applyOverrides(dst, strings.Fields(raw)); err != nil {
+		fmt.Println("❌", err)
+		return false
+	}
+	return true
+}
+
+func pickEC2Template() (string, *EC2Template) {
+	tf, err := loadTemplates("")
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Println("⚠️ 模板文件无法读取:", err)
+		}
+		return "", nil
+	}
+	name := chooseTemplate(templateNames(tf.EC2), func(n string) string { return tf.EC2[n].summary() })
+	if name == "" {
+		return "", nil
+	}
+	t := *tf.EC2[name]
+	if !promptOverrides(&t) {
+		return "", nil
+	}
+	return name, &t
+}
+
+func pickLSTemplate() (string, *LSTemplate) {
+	tf, err := loadTemplates("")
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Println("⚠️ 模板文件无法读取:", err)
+		}
+		return "", nil
+	}
+	name := chooseTemplate(templateNames(tf.Lightsail), func(n string) string { return tf.Lightsail[n].summary() })
+	if name == "" {
+		return "", nil
+	}
+	t := *tf.Lightsail[name]
+	if !promptOverrides(&t) {
+		return "", nil
+	}
+	return name, &t
+}
+
+// -------------------- 端口规则 --------------------
+
+// PortRule 为一条入站规则，Protocol 取值 tcp|udp|all
+type PortRule struct {
+	Protocol string
+	From, To int32
+}
+
+// parsePortRule 解析 "22"、"80/tcp"、"1000-2000/udp"、"all" 形式的端口规则，协议默认 tcp；
+// all 只能单独使用 (放行全部协议与端口)，"22/all" 这类写法会被拒绝
+func parsePortRule(s string) (PortRule, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "all" {
+		return PortRule{Protocol: "all", From: 0, To: 65535}, nil
+	}
+	spec, proto, ok := strings.Cut(s, "/")
+	if !ok {
+		proto = "tcp"
+	}
+	if proto == "all" {
+		return PortRule{}, fmt.Errorf("端口规则无效: %q (all 放行全部端口，只能单独写 all)", s)
+	}
+	if proto != "tcp" && proto != "udp" {
+		return PortRule{}, fmt.Errorf("端口规则协议无效: %q (可选 tcp/udp)", s)
+	}
+	lo, hi, isRange := strings.Cut(spec, "-")
+	if !isRange {
+		hi = lo
+	}
+	from, err1 := strconv.Atoi(lo)
+	to, err2 := strconv.Atoi(hi)
+	if err1 != nil || err2 != nil || from < 0 || to > 65535 || from > to {
+		return PortRule{}, fmt.Errorf("端口规则无效: %q", s)
+	}
+	return PortRule{Protocol: proto, From: int32(from), To: int32(to)}, nil
+}
+
+func parsePortRules(specs []string) ([]PortRule, error) {
+	var out []PortRule
+	for _, s := range specs {
+		for _, item := range splitList(s) {
+			r, err := parsePortRule(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (r PortRule) String() string {
+	if r.Protocol == "all" && r.From == 0 && r.To == 65535 {
+		return "all"
+	}
+	if r.From == r.To {
+		return fmt.Sprintf("%d/%s", r.From, r.Protocol)
+	}
+	return fmt.Sprintf("%d-%d/%s", r.From, r.To, r.Protocol)
+}
+
+// ec2Permission 转换为同时放行 IPv4 与 IPv6 的安全组规则
+func (r PortRule) ec2Permission() ec2t.IpPermission {
+	p := ec2t.IpPermission{
+		IpRanges:   []ec2t.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+		Ipv6Ranges: []ec2t.Ipv6Range{{CidrIpv6: aws.String("::/0")}},
+	}
+	if r.Protocol == "all" {
+		p.IpProtocol = aws.String("-1")
+		return p
+	}
+	p.IpProtocol = aws.String(r.Protocol)
+	p.FromPort, p.ToPort = aws.Int32(r.From), aws.Int32(r.To)
+	return p
+}
+
+func (r PortRule) lsPortInfo() lst.PortInfo {
+	return lst.PortInfo{FromPort: r.From, ToPort: r.To, Protocol: lst.NetworkProtocol(r.Protocol)}
+}
+
+// ensurePortsSG 在默认 VPC 中查找或创建只放行 rules 的安全组；
+// 组名由规则内容生成，相同端口组合的模板共用一个安全组 (复用前按 ensureRuleSG 核对规则)
+func ensurePortsSG(ctx context.Context, cli EC2API, rules []PortRule) (string, string, error) {
+	vpcs, err := cli.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{Filters: []ec2t.Filter{{Name: aws.String("isDefault"), Values: []string{"true"}}}})
+	if err != nil || len(vpcs.Vpcs) == 0 {
+		return "", "", fmt.Errorf("无默认VPC")
+	}
+	vpcID := *vpcs.Vpcs[0].VpcId
+	var specs []string
+	for _, r := range rules {
+		specs = append(specs, r.String())
+	}
+	sort.Strings(specs)
+	sum := sha1.Sum([]byte(strings.Join(specs, ",")))
+	sgName := "ports-" + hex.EncodeToString(sum[:4])
+	var perms []ec2t.IpPermission
+	for _, r := range rules {
+		perms = append(perms, r.ec2Permission())
+	}
+	id, err := ensureRuleSG(ctx, cli, vpcID, sgName, "Ports "+strings.Join(specs, " "), perms)
+	return id, vpcID, err
+}
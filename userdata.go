@@ -0,0 +1,489 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// -------------------- 启动脚本 (cloud-init user-data) --------------------
+
+// EC2 限制 user-data 原文 (base64 之前) 不超过 16 KB
+const ec2UserDataLimit = 16 * 1024
+
+// Lightsail 会在启动脚本前追加自身的初始化内容，这里保守预留 2 KB
+const lsUserDataLimit = 14 * 1024
+
+// SnippetVar 为片段中的一个变量；Default 为空表示必填
+type SnippetVar struct {
+	Name    string
+	Prompt  string
+	Default string
+	// Resolve 可选，把输入值转换为最终值 (如把本地密钥名换成公钥)
+	Resolve func(string) (string, error)
+}
+
+// Snippet 为可组合的启动脚本片段，Body 中的 {{VAR}} 替换为经过 shell 转义的变量值
+type Snippet struct {
+	Name  string
+	Title string
+	Vars  []SnippetVar
+	Body  string
+}
+
+var snippets = []Snippet{
+	{
+		Name: "root-password", Title: "启用 root 密码登录 (user-data 中只保存 SHA-512 哈希)",
+		Vars: []SnippetVar{{Name: "PASSWORD", Prompt: "root 密码", Resolve: func(v string) (string, error) { return cryptPassword(v), nil }}},
+		Body: rootLoginScript("chpasswd -e"),
+	},
+	{
+		Name: "root-password-plain", Title: "启用 root 密码登录 (明文写入 user-data)",
+		Vars: []SnippetVar{{Name: "PASSWORD", Prompt: "root 密码"}},
+		Body: rootLoginScript("chpasswd"),
+	},
+	{
+		Name: "docker", Title: "安装 Docker",
+		Body: `if ! command -v docker >/dev/null 2>&1; then
+  curl -fsSL https://get.docker.com | sh
+fi
+systemctl enable --now docker
+`,
+	},
+	{
+		Name: "bbr", Title: "启用 BBR 拥塞控制",
+		Body: `cat > /etc/sysctl.d/90-bbr.conf <<'EOF'
+net.core.default_qdisc=fq
+net.ipv4.tcp_congestion_control=bbr
+EOF
+sysctl --system
+`,
+	},
+	{
+		Name: "swap", Title: "创建 swap 文件",
+		Vars: []SnippetVar{{Name: "SIZE", Prompt: "swap 大小", Default: "1G"}},
+		Body: `if ! swapon --show | grep -q /swapfile; then
+  fallocate -l {{SIZE}} /swapfile || dd if=/dev/zero of=/swapfile bs=1M count=$(numfmt --from=iec {{SIZE}} --to-unit=1Mi)
+  chmod 600 /swapfile
+  mkswap /swapfile
+  swapon /swapfile
+  grep -q '^/swapfile ' /etc/fstab || echo '/swapfile none swap sw 0 0' >> /etc/fstab
+fi
+`,
+	},
+	{
+		Name: "hostname", Title: "设置主机名",
+		Vars: []SnippetVar{{Name: "HOSTNAME", Prompt: "主机名"}},
+		Body: `hostnamectl set-hostname {{HOSTNAME}} || echo {{HOSTNAME}} > /etc/hostname
+grep -q "127.0.1.1 "{{HOSTNAME}} /etc/hosts || echo "127.0.1.1 "{{HOSTNAME}} >> /etc/hosts
+# 阻止 cloud-init 在重启后改回默认主机名
+[ -d /etc/cloud/cloud.cfg.d ] && echo 'preserve_hostname: true' > /etc/cloud/cloud.cfg.d/99-hostname.cfg
+`,
+	},
+	{
+		Name: "timezone", Title: "设置时区",
+		Vars: []SnippetVar{{Name: "TZ", Prompt: "时区", Default: "Asia/Shanghai"}},
+		Body: `timedatectl set-timezone {{TZ}} || ln -sf /usr/share/zoneinfo/{{TZ}} /etc/localtime
+`,
+	},
+	{
+		Name: "authorized-keys", Title: "添加 authorized_keys",
+		Vars: []SnippetVar{
+			{Name: "KEYS", Prompt: "公钥或本地密钥名，多个用 ; 分隔", Resolve: resolveAuthorizedKeys},
+			{Name: "USER", Prompt: "用户", Default: "root"},
+		},
+		Body: `home=$(getent passwd {{USER}} | cut -d: -f6)
+mkdir -p "$home/.ssh"
+printf '%s\n' {{KEYS}} >> "$home/.ssh/authorized_keys"
+chmod 700 "$home/.ssh"
+chmod 600 "$home/.ssh/authorized_keys"
+chown -R {{USER}}: "$home/.ssh"
+`,
+	},
+}
+
+// rootLoginScript 设置 root 密码并开启 sshd 的 root 密码登录
+func rootLoginScript(chpasswd string) string {
+	return `echo root:{{PASSWORD}} | ` + chpasswd + `
+sed -i 's/^#\?PermitRootLogin.*/PermitRootLogin yes/' /etc/ssh/sshd_config
+sed -i 's/^#\?PasswordAuthentication.*/PasswordAuthentication yes/' /etc/ssh/sshd_config
+# 新版 Debian/Ubuntu 的 sshd_config.d 中可能关闭了密码登录，按文件名顺序先读到的配置生效
+if [ -d /etc/ssh/sshd_config.d ]; then
+  printf 'PermitRootLogin yes\nPasswordAuthentication yes\n' > /etc/ssh/sshd_config.d/00-root-login.conf
+fi
+systemctl restart ssh 2>/dev/null || systemctl restart sshd 2>/dev/null || service sshd restart
+`
+}
+
+// withRootPassword 在片段列表最前面加上设置 root 密码的片段；plain 为 true 时明文写入
+func withRootPassword(pwd string, plain bool, specs []string, vars map[string]string) ([]string, map[string]string) {
+	if pwd == "" {
+		return specs, vars
+	}
+	name := "root-password"
+	if plain {
+		name = "root-password-plain"
+	}
+	merged := map[string]string{"PASSWORD": pwd}
+	for k, v := range vars {
+		merged[k] = v
+	}
+	return append([]string{name}, specs...), merged
+}
+
+func findSnippet(name string) (*Snippet, error) {
+	for i := range snippets {
+		if snippets[i].Name == name {
+			return &snippets[i], nil
+		}
+	}
+	var names []string
+	for _, s := range snippets {
+		names = append(names, s.Name)
+	}
+	return nil, fmt.Errorf("未知片段: %s (可选 %s)", name, strings.Join(names, ", "))
+}
+
+// snippetNames 返回片段名列表 (去掉参数部分)，用于摘要显示
+func snippetNames(specs []string) string {
+	var names []string
+	for _, spec := range specs {
+		name, _, _ := strings.Cut(spec, ":")
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+// resolveAuthorizedKeys 把 ; 分隔的公钥列表中的本地密钥名替换为对应公钥
+func resolveAuthorizedKeys(v string) (string, error) {
+	var keys []string
+	for _, k := range strings.Split(v, ";") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		if !strings.Contains(k, " ") {
+			pub, err := localPublicKey(k)
+			if err != nil {
+				return "", err
+			}
+			k = pub
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("没有公钥")
+	}
+	return strings.Join(keys, "\n"), nil
+}
+
+// shellQuote 用单引号包裹 s，使其在 shell 中按原样展开为一个参数
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var userDataVarRe = regexp.MustCompile(`\{\{\s*([A-Z][A-Z0-9_]*)\s*\}\}`)
+
+// substituteVars 替换文本中的 {{VAR}}，有未提供的变量时报错；quote 为 true 时值经过 shell 转义
+func substituteVars(text string, vars map[string]string, quote bool) (string, error) {
+	var missing []string
+	out := userDataVarRe.ReplaceAllStringFunc(text, func(m string) string {
+		name := userDataVarRe.FindStringSubmatch(m)[1]
+		v, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+			return m
+		}
+		if quote {
+			return shellQuote(v)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("缺少变量: %s (用 --var KEY=VALUE 或模板 user_data_vars 提供)", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// userDataVarNames 返回文本中出现的变量名 (去重，按出现顺序)
+func userDataVarNames(text string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range userDataVarRe.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// parseSnippetSpec 解析 "name" 或 "name:KEY=VALUE,KEY2=VALUE2"
+func parseSnippetSpec(spec string) (string, map[string]string, error) {
+	name, rest, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	vars := map[string]string{}
+	if rest != "" {
+		for _, kv := range strings.Split(rest, ",") {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok || k == "" {
+				return "", nil, fmt.Errorf("片段参数格式应为 name:KEY=VALUE: %q", spec)
+			}
+			vars[strings.TrimSpace(k)] = v
+		}
+	}
+	return name, vars, nil
+}
+
+// UserDataPart 为组合前的一段 user-data
+type UserDataPart struct {
+	Name string
+	Body string
+}
+
+// contentType 按 cloud-init 的首行约定判断内容类型
+func (p UserDataPart) contentType() string {
+	first, _, _ := strings.Cut(p.Body, "\n")
+	first = strings.TrimSpace(first)
+	switch {
+	case strings.HasPrefix(first, "#!"):
+		return "text/x-shellscript"
+	case strings.HasPrefix(first, "#cloud-config"):
+		return "text/cloud-config"
+	case strings.HasPrefix(first, "#cloud-boothook"):
+		return "text/cloud-boothook"
+	case strings.HasPrefix(first, "#include"):
+		return "text/x-include-url"
+	case strings.HasPrefix(first, "## template: jinja"):
+		return "text/jinja2"
+	case strings.HasPrefix(strings.ToLower(first), "content-type:"), strings.HasPrefix(strings.ToLower(first), "mime-version:"):
+		return "multipart/mixed"
+	}
+	return ""
+}
+
+// renderSnippet 用 inline (片段自带) 与 vars (全局) 中的变量渲染片段
+func renderSnippet(spec string, vars map[string]string) (UserDataPart, error) {
+	name, inline, err := parseSnippetSpec(spec)
+	if err != nil {
+		return UserDataPart{}, err
+	}
+	s, err := findSnippet(name)
+	if err != nil {
+		return UserDataPart{}, err
+	}
+	values := map[string]string{}
+	for _, v := range s.Vars {
+		val, ok := inline[v.Name]
+		if !ok {
+			val, ok = vars[v.Name]
+		}
+		if !ok || val == "" {
+			val = v.Default
+		}
+		if val == "" {
+			return UserDataPart{}, fmt.Errorf("片段 %s 缺少变量 %s (%s)", name, v.Name, v.Prompt)
+		}
+		if v.Resolve != nil {
+			if val, err = v.Resolve(val); err != nil {
+				return UserDataPart{}, fmt.Errorf("片段 %s 的 %s 无效: %v", name, v.Name, err)
+			}
+		}
+		values[v.Name] = val
+	}
+	body, err := substituteVars(s.Body, values, true)
+	if err != nil {
+		return UserDataPart{}, err
+	}
+	return UserDataPart{Name: name, Body: "#!/bin/bash\n" + body}, nil
+}
+
+// renderUserData 依次渲染片段与自定义内容 raw (其中的 {{VAR}} 用 vars 替换)
+func renderUserData(specs []string, raw string, vars map[string]string) ([]UserDataPart, error) {
+	var parts []UserDataPart
+	for _, spec := range specs {
+		p, err := renderSnippet(spec, vars)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+	if strings.TrimSpace(raw) != "" {
+		body, err := substituteVars(raw, vars, false)
+		if err != nil {
+			return nil, err
+		}
+		p := UserDataPart{Name: "custom", Body: body}
+		// 没有类型标记的内容按 bash 脚本执行
+		if p.contentType() == "" {
+			p.Body = "#!/bin/bash\n" + p.Body
+		}
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+// ec2UserDataDoc 把多段内容组合为 multipart/mixed 文档 (只有一段时原样返回)，并校验 EC2 的大小限制
+func ec2UserDataDoc(parts []UserDataPart) (string, error) {
+	var doc string
+	switch len(parts) {
+	case 0:
+		return "", nil
+	case 1:
+		doc = parts[0].Body
+	default:
+		var err error
+		if doc, err = mimeUserData(parts); err != nil {
+			return "", err
+		}
+	}
+	if len(doc) > ec2UserDataLimit {
+		return "", fmt.Errorf("user-data 共 %s，超过 EC2 上限 %s", byteSize(len(doc)), byteSize(ec2UserDataLimit))
+	}
+	return doc, nil
+}
+
+// mimeUserData 生成 cloud-init 可识别的多段 MIME 文档；
+// 各段文件名带序号，cloud-init 按文件名顺序执行脚本
+func mimeUserData(parts []UserDataPart) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for i, p := range parts {
+		ct := p.contentType()
+		if ct == "multipart/mixed" {
+			return "", fmt.Errorf("%s 已是 MIME 文档，不能再与其他片段组合", p.Name)
+		}
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", ct+`; charset="utf-8"`)
+		h.Set("MIME-Version", "1.0")
+		h.Set("Content-Transfer-Encoding", "8bit")
+		h.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%02d-%s"`, i+1, p.Name))
+		pw, err := w.CreatePart(h)
+		if err != nil {
+			return "", err
+		}
+		pw.Write([]byte(p.Body))
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	head := fmt.Sprintf("Content-Type: multipart/mixed; boundary=%q\nMIME-Version: 1.0\n\n", w.Boundary())
+	return head + body.String(), nil
+}
+
+// lsUserDataDoc 为 Lightsail 生成启动脚本：Lightsail 只把 user-data 当作 shell 脚本执行，
+// 多段内容合并为一个 bash 脚本，每段在子 shell 中运行，互不影响
+func lsUserDataDoc(parts []UserDataPart) (string, error) {
+	if len(parts) == 0 {
+		return "", nil
+	}
+	var doc string
+	if len(parts) == 1 && parts[0].contentType() == "text/x-shellscript" {
+		doc = parts[0].Body
+	} else {
+		var b strings.Builder
+		b.WriteString("#!/bin/bash\n")
+		for _, p := range parts {
+			first, rest, _ := strings.Cut(p.Body, "\n")
+			if p.contentType() != "text/x-shellscript" {
+				return "", fmt.Errorf("Lightsail 启动脚本只支持 shell 脚本，%s 为 %s", p.Name, orDefault(p.contentType(), "未知类型"))
+			}
+			if !strings.Contains(first, "bash") && !strings.HasSuffix(first, "/sh") {
+				return "", fmt.Errorf("%s 使用 %s 解释器，无法与其他片段合并", p.Name, strings.TrimPrefix(first, "#!"))
+			}
+			fmt.Fprintf(&b, "\n# ---- %s ----\n(\n%s\n)\n", p.Name, strings.TrimRight(rest, "\n"))
+		}
+		doc = b.String()
+	}
+	if len(doc) > lsUserDataLimit {
+		return "", fmt.Errorf("启动脚本共 %s，超过 Lightsail 上限 %s", byteSize(len(doc)), byteSize(lsUserDataLimit))
+	}
+	return doc, nil
+}
+
+func byteSize(n int) string {
+	if n < 1024 {
+		return strconv.Itoa(n) + " B"
+	}
+	return strconv.FormatFloat(float64(n)/1024, 'f', 1, 64) + " KB"
+}
+
+func snippetReport() Report {
+	r := Report{Header: "名称\t说明\t变量", Columns: []string{"name", "title", "vars"}}
+	type row struct {
+		Name  string   `json:"name" yaml:"name"`
+		Title string   `json:"title" yaml:"title"`
+		Vars  []string `json:"vars" yaml:"vars"`
+	}
+	var data []row
+	for _, s := range snippets {
+		var vars []string
+		for _, v := range s.Vars {
+			if v.Default != "" {
+				vars = append(vars, v.Name+"="+v.Default)
+			} else {
+				vars = append(vars, v.Name)
+			}
+		}
+		data = append(data, row{s.Name, s.Title, vars})
+		r.Rows = append(r.Rows, []string{s.Name, s.Title, strings.Join(vars, " ")})
+	}
+	r.Data = data
+	return r
+}
+
+// promptUserData 交互式选择片段并输入变量，再从文件加载或手动输入自定义脚本。
+// hide 中的片段不在列表中显示 (如 EC2 已单独询问 root 密码)
+func promptUserData(title string, hide ...string) (specs []string, raw string, vars map[string]string) {
+	vars = map[string]string{}
+	fmt.Println(title)
+	var list []Snippet
+	for _, s := range snippets {
+		hidden := false
+		for _, h := range hide {
+			hidden = hidden || s.Name == h
+		}
+		if !hidden {
+			list = append(list, s)
+		}
+	}
+	for i, s := range list {
+		fmt.Printf("  %d) %-16s %s\n", i+1, s.Name, s.Title)
+	}
+	idx, err := parseSelection(input("选择片段 (如 1,3，留空跳过): ", ""), len(list))
+	if err != nil {
+		fmt.Printf("⚠️ %v，不使用片段\n", err)
+		idx = nil
+	}
+	for _, i := range idx {
+		s := list[i]
+		specs = append(specs, s.Name)
+		for _, v := range s.Vars {
+			prompt := fmt.Sprintf("  %s: ", v.Prompt)
+			if v.Default != "" {
+				prompt = fmt.Sprintf("  %s [%s]: ", v.Prompt, v.Default)
+			}
+			vars[v.Name] = input(prompt, v.Default)
+		}
+	}
+	if path := input("从文件加载自定义 user-data (路径，留空则手动输入): ", ""); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Println("⚠️ 读取失败:", err)
+		} else {
+			raw = string(b)
+			for _, name := range userDataVarNames(raw) {
+				if _, ok := vars[name]; !ok {
+					vars[name] = input(fmt.Sprintf("  变量 %s: ", name), "")
+				}
+			}
+		}
+	} else {
+		raw, _ = collectUserData("可选：自定义脚本")
+	}
+	return specs, raw, vars
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// -------------------- 本地加密账户保险库 (scrypt + AES-GCM) --------------------
+
+// 保险库默认位于 ~/.aws-tool/vault.json，可用环境变量 AWS_TOOL_VAULT 覆盖。
+// 文件内容为口令派生密钥加密后的 JSON，明文只在内存中存在。
+// 除 AK/SK 外，工具设置的 root / 数据库密码 (passwords.go) 也保存在这里。
+
+const (
+	vaultScryptN = 1 << 15
+	vaultScryptR = 8
+	vaultScryptP = 1
+)
+
+type VaultAccount struct {
+	Label        string    `json:"label"`
+	AK           string    `json:"ak"`
+	SK           string    `json:"sk"`
+	Proxy        string    `json:"proxy,omitempty"`
+	LastVerified time.Time `json:"last_verified"`
+}
+
+type Vault struct {
+	Accounts    []VaultAccount     `json:"accounts"`
+	Credentials []CredentialRecord `json:"credentials,omitempty"` // 工具设置的 root / 数据库密码
+
+	path string
+	pass string
+}
+
+type vaultFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// activeVault 为本次会话中已解锁的保险库，未解锁时为 nil
+var activeVault *Vault
+
+func vaultPath() string {
+	if p := os.Getenv("AWS_TOOL_VAULT"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "vault.json"
+	}
+	return filepath.Join(home, ".aws-tool", "vault.json")
+}
+
+func vaultGCM(pass string, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(pass), salt, n, r, p, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// openVault 用口令解密保险库；文件不存在时返回一个空保险库，首次 Save 时创建
+func openVault(path, pass string) (*Vault, error) {
+	v := &Vault{path: path, pass: pass}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f vaultFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("保险库文件损坏: %v", err)
+	}
+	if f.KDF != "scrypt" {
+		return nil, fmt.Errorf("不支持的密钥派生算法: %s", f.KDF)
+	}
+	gcm, err := vaultGCM(pass, f.Salt, f.N, f.R, f.P)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return nil, errors.New("口令错误或文件已被篡改")
+	}
+	if err := json.Unmarshal(plain, v); err != nil {
+		return nil, fmt.Errorf("保险库内容损坏: %v", err)
+	}
+	return v, nil
+}
+
+// Save 每次都重新生成 salt 与 nonce，并先写临时文件再替换，避免中途失败损坏原文件
+func (v *Vault) Save() error {
+	plain, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f := vaultFile{Version: 1, KDF: "scrypt", N: vaultScryptN, R: vaultScryptR, P: vaultScryptP}
+	f.Salt = make([]byte, 16)
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+	gcm, err := vaultGCM(v.pass, f.Salt, f.N, f.R, f.P)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Data = gcm.Seal(nil, f.Nonce, plain, nil)
+	out, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(v.path), 0o700); err != nil {
+		return err
+	}
+	tmp := v.path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0o600); err != nil {
+		return err
+	}
+	// WriteFile 不会收紧已存在文件的权限 (如上次中断留下的临时文件)
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, v.path)
+}
+
+func (v *Vault) Find(label string) *VaultAccount {
+	for i := range v.Accounts {
+		if v.Accounts[i].Label == label {
+			return &v.Accounts[i]
+		}
+	}
+	return nil
+}
+
+// MarkVerified 记录账户最近一次 stsCheck 通过的时间并保存
+func (v *Vault) MarkVerified(label string) error {
+	a := v.Find(label)
+	if a == nil {
+		return nil
+	}
+	a.LastVerified = time.Now()
+	return v.Save()
+}
+
+// unlockVault 交互式输入口令解锁保险库；首次使用时要求两次输入以创建。
+// 设置了环境变量 AWS_TOOL_VAULT_PASS 时直接使用其值 (供脚本中的批量命令使用)
+func unlockVault() (*Vault, error) {
+	if activeVault != nil {
+		return activeVault, nil
+	}
+	path := vaultPath()
+	if pass := os.Getenv("AWS_TOOL_VAULT_PASS"); pass != "" {
+		v, err := openVault(path, pass)
+		if err != nil {
+			return nil, err
+		}
+		activeVault = v
+		return v, nil
+	}
+	_, statErr := os.Stat(path)
+	isNew := errors.Is(statErr, os.ErrNotExist)
+	if isNew {
+		fmt.Printf("\n🔐 未找到保险库，将新建: %s\n", path)
+	}
+	pass := inputSecret("保险库口令: ")
+	if pass == "" {
+		return nil, errors.New("口令不能为空")
+	}
+	if isNew && inputSecret("再次输入口令: ") != pass {
+		return nil, errors.New("两次输入的口令不一致")
+	}
+	v, err := openVault(path, pass)
+	if err != nil {
+		return nil, err
+	}
+	activeVault = v
+	return v, nil
+}
+
+func maskKey(ak string) string {
+	if len(ak) <= 8 {
+		return ak
+	}
+	return ak[:4] + strings.Repeat("*", len(ak)-8) + ak[len(ak)-4:]
+}
+
+func printVaultAccounts(v *Vault) {
+	printTable("序号\t标签\tAccess Key\t代理\t上次验证", func(w *tabwriter.Writer) {
+		for i, a := range v.Accounts {
+			verified := "-"
+			if !a.LastVerified.IsZero() {
+				verified = a.LastVerified.Format("2006-01-02 15:04")
+			}
+			proxy := "-"
+			if a.Proxy != "" {
+				proxy = cut(a.Proxy, 30)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", i+1, a.Label, maskKey(a.AK), proxy, verified)
+		}
+	})
+}
+
+// vaultMenu 为保险库的账户切换器，返回被选中的账户；仅做增删时返回 nil
+func vaultMenu() (*VaultAccount, error) {
+	v, err := unlockVault()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		fmt.Printf("\n====== 🔐 账户保险库 (%d 个账户) ======\n", len(v.Accounts))
+		if len(v.Accounts) > 0 {
+			printVaultAccounts(v)
+		}
+		fmt.Print("\n输入序号切换账户 | a) 添加账户 | d) 删除账户")
+		if activePool != nil {
+			fmt.Print(" | p) 从代理池分配代理")
+		}
+		fmt.Println(" | 0) 返回")
+		sel := input("选择: ", "0")
+		switch sel {
+		case "0":
+			return nil, nil
+		case "a":
+			label := input("账户标签: ", "")
+			if label == "" || v.Find(label) != nil {
+				fmt.Println("❌ 标签为空或已存在")
+				continue
+			}
+			ak := input("AWS Access Key ID: ", "")
+			sk := inputSecret("AWS Secret Access Key: ")
+			if ak == "" || sk == "" {
+				fmt.Println("❌ AK/SK 不能为空")
+				continue
+			}
+			proxy := input("绑定代理 (留空则使用全局设置): ", "")
+			v.Accounts = append(v.Accounts, VaultAccount{Label: label, AK: ak, SK: sk, Proxy: proxy})
+			if err := v.Save(); err != nil {
+				fmt.Println("❌ 保存失败:", err)
+				continue
+			}
+			fmt.Println("✅ 已保存")
+		case "d":
+			idx := mustInt(input("要删除的序号: ", "0"))
+			if idx < 1 || idx > len(v.Accounts) {
+				continue
+			}
+			label := v.Accounts[idx-1].Label
+			if !yes(input(fmt.Sprintf("⚠️ 确认删除账户 %s? [y/N]: ", label), "n")) {
+				continue
+			}
+			v.Accounts = append(v.Accounts[:idx-1], v.Accounts[idx:]...)
+			if err := v.Save(); err != nil {
+				fmt.Println("❌ 保存失败:", err)
+				continue
+			}
+			fmt.Println("🗑️ 已删除")
+		case "p":
+			if activePool == nil {
+				continue
+			}
+			n := pinVaultProxies(v, activePool)
+			if err := v.Save(); err != nil {
+				fmt.Println("❌ 保存失败:", err)
+				continue
+			}
+			fmt.Printf("✅ 已为 %d 个账户分配代理\n", n)
+		default:
+			idx := mustInt(sel)
+			if idx >= 1 && idx <= len(v.Accounts) {
+				a := v.Accounts[idx-1]
+				return &a, nil
+			}
+			fmt.Println("无效选项")
+		}
+	}
+}
+
+// pinVaultProxies 为未绑定代理的账户从代理池分配代理并写入保险库，使绑定在以后的会话中保持不变
+func pinVaultProxies(v *Vault, pool *ProxyPool) int {
+	n := 0
+	for i := range v.Accounts {
+		a := &v.Accounts[i]
+		if a.Proxy != "" {
+			continue
+		}
+		if p := pool.Bind(vaultLabelPrefix + a.Label); p != "" {
+			a.Proxy = p
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVaultSaveTightensMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+	// 上次中断留下的临时文件权限过宽
+	if err := os.WriteFile(path+".tmp", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	v, err := openVault(path, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Credentials = []CredentialRecord{{Service: "ec2", Resource: "i-1", User: "root", Password: "secret"}}
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode().Perm() != 0o600 {
+		t.Fatalf("保险库权限应为 0600，得到 %v", st.Mode().Perm())
+	}
+	v, err = openVault(path, "pass")
+	if err != nil || len(v.Credentials) != 1 || v.Credentials[0].Password != "secret" {
+		t.Fatalf("重新打开后凭证记录不符: %+v %v", v, err)
+	}
+}